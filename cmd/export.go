@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"go.miloapis.com/email-provider-resend/internal/bulk"
+)
+
+// createExportCommand returns a cobra command that exports Contacts, ContactGroups and ContactGroupMemberships.
+func createExportCommand() *cobra.Command {
+	var file, format, namespace string
+	var allNamespaces bool
+	var qps float32
+	var burst int
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Exports Contacts, ContactGroups and ContactGroupMemberships to a CSV or JSON file",
+		Long: "Exports Contacts, ContactGroups and ContactGroupMemberships, along with their email provider IDs and " +
+			"status conditions, to a CSV or JSON file.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if allNamespaces {
+				namespace = ""
+			}
+			return runExport(cmd, file, format, namespace, qps, burst)
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "-", "The file to export to. Use - to write to stdout.")
+	cmd.Flags().StringVar(&format, "format", string(bulk.FormatCSV), "The format of the file, csv or json.")
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "default", "The namespace to export.")
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "If set, every namespace is exported.")
+	addRateLimitFlags(cmd, &qps, &burst)
+
+	return cmd
+}
+
+func runExport(cmd *cobra.Command, file, format, namespace string, qps float32, burst int) error {
+	fileFormat, err := bulk.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+
+	k8sClient, err := newRateLimitedClient(qps, burst)
+	if err != nil {
+		return err
+	}
+	records, err := bulk.Export(cmd.Context(), k8sClient, namespace)
+	if err != nil {
+		return err
+	}
+
+	if file == "-" {
+		return bulk.WriteExportRecords(cmd.OutOrStdout(), fileFormat, records)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := bulk.WriteExportRecords(f, fileFormat, records); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d records to %s\n", len(records), file)
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	k8sconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	"go.miloapis.com/email-provider-resend/internal/bulk"
+)
+
+// createImportCommand returns a cobra command that imports Contacts and ContactGroupMemberships from a file.
+func createImportCommand() *cobra.Command {
+	var file, format, namespace string
+	var dryRun bool
+	var qps float32
+	var burst int
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Imports Contacts and ContactGroupMemberships from a CSV or JSON file",
+		Long: "Imports Contacts and ContactGroupMemberships from a CSV or JSON file. Each record is a contact, with the " +
+			"columns namespace, name, email, givenName, familyName and contactGroups (separated by \";\"). The import is " +
+			"idempotent: existing contacts are only updated if they differ, and contacts that opted out of a contact " +
+			"group are not added to it again.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runImport(cmd, file, format, namespace, dryRun, qps, burst)
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "*Required. The file to import. Use - to read from stdin.")
+	cmd.Flags().StringVar(&format, "format", string(bulk.FormatCSV), "The format of the file, csv or json.")
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "default", "The namespace of the records without namespace.")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "If set, the changes are printed but not applied.")
+	addRateLimitFlags(cmd, &qps, &burst)
+
+	return cmd
+}
+
+func runImport(cmd *cobra.Command, file, format, namespace string, dryRun bool, qps float32, burst int) error {
+	if file == "" {
+		return fmt.Errorf("--file is required")
+	}
+	fileFormat, err := bulk.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+
+	in := os.Stdin
+	if file != "-" {
+		if in, err = os.Open(file); err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer func() { _ = in.Close() }()
+	}
+	records, err := bulk.ReadContactRecords(in, fileFormat)
+	if err != nil {
+		return err
+	}
+
+	k8sClient, err := newRateLimitedClient(qps, burst)
+	if err != nil {
+		return err
+	}
+
+	importer := &bulk.Importer{
+		Client:    k8sClient,
+		Namespace: namespace,
+		DryRun:    dryRun,
+		Out:       cmd.OutOrStdout(),
+	}
+	summary, err := importer.Import(cmd.Context(), records)
+	if err != nil {
+		return err
+	}
+
+	mode := ""
+	if dryRun {
+		mode = " (dry run)"
+	}
+	fmt.Fprintf(cmd.OutOrStdout(),
+		"Contacts: %d created, %d updated, %d unchanged. ContactGroupMemberships: %d created, %d unchanged, %d opted out%s\n",
+		summary.ContactsCreated, summary.ContactsUpdated, summary.ContactsUnchanged,
+		summary.MembershipsCreated, summary.MembershipsUnchanged, summary.MembershipsOptedOut, mode)
+
+	return nil
+}
+
+// addRateLimitFlags adds the flags limiting the requests sent to the API server.
+func addRateLimitFlags(cmd *cobra.Command, qps *float32, burst *int) {
+	cmd.Flags().Float32Var(qps, "qps", 5, "The maximum number of requests per second sent to the API server.")
+	cmd.Flags().IntVar(burst, "burst", 10, "The maximum burst of requests sent to the API server.")
+}
+
+// newRateLimitedClient returns a client that sends at most qps requests per second to the API server.
+func newRateLimitedClient(qps float32, burst int) (client.Client, error) {
+	if qps <= 0 || burst <= 0 {
+		return nil, fmt.Errorf("--qps and --burst must be greater than 0")
+	}
+
+	restConfig, err := k8sconfig.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rest config: %w", err)
+	}
+	restConfig.QPS = qps
+	restConfig.Burst = burst
+
+	k8sClient, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return k8sClient, nil
+}
@@ -36,6 +36,9 @@ func main() {
 
 	rootCmd.AddCommand(createManagerCommand())
 	rootCmd.AddCommand(createWebhookCommand())
+	rootCmd.AddCommand(createImportCommand())
+	rootCmd.AddCommand(createExportCommand())
+	rootCmd.AddCommand(createMigrateCommand())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
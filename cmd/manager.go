@@ -53,6 +53,17 @@ func createManagerCommand() *cobra.Command {
 	var enableHTTP2 bool
 	var emailApiKey, emailFrom, emailReplyTo string
 	var lowPriorityEmailWait, normalPriorityEmailWait, highPriorityEmailWait time.Duration
+	var providerResyncInterval time.Duration
+	var renameProviderContactGroups bool
+	var orphanGCInterval, orphanGCGracePeriod time.Duration
+	var orphanGCDryRun bool
+	var unsubscribeBaseURL, unsubscribeSigningKey string
+	var userContactNamespace string
+	var readyProviders []string
+	var readyProvidersMode string
+	// Template config
+	var strictTemplateVariables bool
+	var defaultTemplateLocale string
 	// Leader election configuration options
 	var leaderElectionID, leaderElectionNamespace, leaderElectionResourceLock string
 	var leaseDuration, renewDeadline, retryPeriod time.Duration
@@ -72,6 +83,12 @@ func createManagerCommand() *cobra.Command {
 				enableHTTP2,
 				emailApiKey, emailFrom, emailReplyTo,
 				lowPriorityEmailWait, normalPriorityEmailWait, highPriorityEmailWait,
+				providerResyncInterval, renameProviderContactGroups,
+				orphanGCInterval, orphanGCGracePeriod, orphanGCDryRun,
+				unsubscribeBaseURL, unsubscribeSigningKey,
+				userContactNamespace,
+				readyProviders, readyProvidersMode,
+				strictTemplateVariables, defaultTemplateLocale,
 				leaderElectionID, leaderElectionNamespace, leaderElectionResourceLock,
 				leaseDuration, renewDeadline, retryPeriod)
 		},
@@ -113,6 +130,51 @@ func createManagerCommand() *cobra.Command {
 	cmd.Flags().DurationVar(&highPriorityEmailWait, "wait-time-before-retry-high-priority-email", 1*time.Second,
 		"*Not required. The wait time before retrying a high priority email.")
 
+	// Provider sync config
+	cmd.Flags().DurationVar(&providerResyncInterval, "provider-resync-interval", 1*time.Hour,
+		"*Not required. The interval at which ready Contacts, ContactGroups and ContactGroupMemberships are verified "+
+			"against the email provider, and recreated if missing. Set to 0 to disable drift detection.")
+	cmd.Flags().BoolVar(&renameProviderContactGroups, "rename-provider-contact-groups", true,
+		"*Not required. Rename the email provider contact group when the display name of a ContactGroup changes. "+
+			"The provider cannot rename contact groups, so they are recreated and their contacts added again, "+
+			"losing the history of the previous contact group. When disabled, the contact group keeps its name.")
+
+	// Orphan garbage collection config
+	cmd.Flags().DurationVar(&orphanGCInterval, "orphan-gc-interval", 6*time.Hour,
+		"*Not required. The interval at which email provider contacts and contact groups without an owning resource "+
+			"are garbage collected. Set to 0 to disable the garbage collection.")
+	cmd.Flags().DurationVar(&orphanGCGracePeriod, "orphan-gc-grace-period", 1*time.Hour,
+		"*Not required. The minimum age of an email provider object before it can be garbage collected.")
+	cmd.Flags().BoolVar(&orphanGCDryRun, "orphan-gc-dry-run", true,
+		"*Not required. If set, orphaned email provider objects are only reported. Use --orphan-gc-dry-run=false to delete them.")
+
+	// Unsubscribe links config
+	cmd.Flags().StringVar(&unsubscribeBaseURL, "unsubscribe-base-url", "",
+		"*Not required. The public URL of the unsubscribe endpoint of the resend webhook server. If set, Emails tied to a "+
+			"ContactGroup get an unsubscribe link and List-Unsubscribe headers.")
+	unsubscribeSigningKey = os.Getenv("UNSUBSCRIBE_SIGNING_KEY") // Required if unsubscribe-base-url is set. Shared with the resend webhook server.
+
+	// User contact provisioning config
+	cmd.Flags().StringVar(&userContactNamespace, "user-contact-namespace", "",
+		"*Not required. The namespace in which a Contact is created for each IAM User. Leave empty to disable the "+
+			"provisioning of user contacts.")
+
+	// Provider readiness config
+	cmd.Flags().StringSliceVar(&readyProviders, "ready-providers", config.DefaultReadyProviders,
+		"*Not required. The providers whose ready conditions, e.g. ResendContactReady, make Contacts and "+
+			"ContactGroupMemberships Ready.")
+	cmd.Flags().StringVar(&readyProvidersMode, "ready-providers-mode", string(config.ReadinessModeAllOf),
+		"*Not required. Whether all-of or any-of the ready providers must be ready.")
+
+	// Template config
+	cmd.Flags().BoolVar(&strictTemplateVariables, "strict-template-variables", false,
+		"*Not required. If set, Emails whose variables do not match the variables referenced by their EmailTemplate fail "+
+			"instead of rendering empty values. EmailTemplates can override it with the "+
+			"notification.miloapis.com/strict-variables annotation.")
+	cmd.Flags().StringVar(&defaultTemplateLocale, "default-template-locale", config.DefaultTemplateLocale,
+		"*Not required. The locale of the EmailTemplates without the notification.miloapis.com/locale label. It is the "+
+			"last fallback when selecting the locale variant of an EmailTemplate for the recipient.")
+
 	// Leader election configuration flags
 	cmd.Flags().StringVar(&leaderElectionID, "leader-election-id", "1adf6d2b.resend.notification.miloapis.com",
 		"The name of the resource that leader election will use for holding the leader lock.")
@@ -150,6 +212,12 @@ func runManager(
 	enableHTTP2 bool,
 	emailApiKey, emailFrom, emailReplyTo string,
 	lowPriorityEmailWait, normalPriorityEmailWait, highPriorityEmailWait time.Duration,
+	providerResyncInterval time.Duration, renameProviderContactGroups bool,
+	orphanGCInterval, orphanGCGracePeriod time.Duration, orphanGCDryRun bool,
+	unsubscribeBaseURL, unsubscribeSigningKey string,
+	userContactNamespace string,
+	readyProviders []string, readyProvidersMode string,
+	strictTemplateVariables bool, defaultTemplateLocale string,
 	leaderElectionID, leaderElectionNamespace, leaderElectionResourceLock string,
 	leaseDuration, renewDeadline, retryPeriod time.Duration,
 ) error {
@@ -168,6 +236,48 @@ func runManager(
 		return fmt.Errorf("unable to create email controller config: %w", err)
 	}
 
+	// Create and validate provider sync config
+	providerSyncConfig, err := config.NewProviderSyncConfig(providerResyncInterval, renameProviderContactGroups)
+	if err != nil {
+		setupLog.Error(err, "unable to create provider sync config")
+		return fmt.Errorf("unable to create provider sync config: %w", err)
+	}
+
+	// Create and validate orphan gc config
+	orphanGCConfig, err := config.NewOrphanGCConfig(orphanGCInterval, orphanGCGracePeriod, orphanGCDryRun)
+	if err != nil {
+		setupLog.Error(err, "unable to create orphan gc config")
+		return fmt.Errorf("unable to create orphan gc config: %w", err)
+	}
+
+	// Create and validate unsubscribe config
+	unsubscribeConfig, err := config.NewUnsubscribeConfig(unsubscribeBaseURL, unsubscribeSigningKey)
+	if err != nil {
+		setupLog.Error(err, "unable to create unsubscribe config")
+		return fmt.Errorf("unable to create unsubscribe config: %w", err)
+	}
+
+	// Create and validate user contact config
+	userContactConfig, err := config.NewUserContactConfig(userContactNamespace)
+	if err != nil {
+		setupLog.Error(err, "unable to create user contact config")
+		return fmt.Errorf("unable to create user contact config: %w", err)
+	}
+
+	// Create and validate provider readiness config
+	readinessConfig, err := config.NewProviderReadinessConfig(readyProviders, readyProvidersMode)
+	if err != nil {
+		setupLog.Error(err, "unable to create provider readiness config")
+		return fmt.Errorf("unable to create provider readiness config: %w", err)
+	}
+
+	// Create and validate template config
+	templateConfig, err := config.NewTemplateConfig(strictTemplateVariables, defaultTemplateLocale)
+	if err != nil {
+		setupLog.Error(err, "unable to create template config")
+		return fmt.Errorf("unable to create template config: %w", err)
+	}
+
 	var tlsOpts []func(*tls.Config)
 
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
@@ -293,19 +403,32 @@ func runManager(
 
 	// Setup email controller
 	if err := (&controller.EmailController{
-		Client:        mgr.GetClient(),
-		EmailProvider: *emailProviderService,
-		Config:        *emailCtrlConfig,
+		Client:            mgr.GetClient(),
+		EmailProvider:     *emailProviderService,
+		Config:            *emailCtrlConfig,
+		UnsubscribeConfig: *unsubscribeConfig,
+		TemplateConfig:    *templateConfig,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Email")
 		return fmt.Errorf("unable to create controller: %w", err)
 	}
+
+	// Setup email template controller
+	if err := (&controller.EmailTemplateController{
+		Client:         mgr.GetClient(),
+		TemplateConfig: *templateConfig,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "EmailTemplate")
+		return fmt.Errorf("unable to create controller: %w", err)
+	}
 	// +kubebuilder:scaffold:builder
 
 	// Setup contact controller
 	if err := (&controller.ContactController{
-		Client:        mgr.GetClient(),
-		EmailProvider: *emailProviderService,
+		Client:          mgr.GetClient(),
+		EmailProvider:   *emailProviderService,
+		SyncConfig:      *providerSyncConfig,
+		ReadinessConfig: *readinessConfig,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Contact")
 		return fmt.Errorf("unable to create controller: %w", err)
@@ -315,6 +438,7 @@ func runManager(
 	if err := (&controller.ContactGroupController{
 		Client:        mgr.GetClient(),
 		EmailProvider: *emailProviderService,
+		SyncConfig:    *providerSyncConfig,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ContactGroup")
 		return fmt.Errorf("unable to create controller: %w", err)
@@ -322,8 +446,10 @@ func runManager(
 
 	// Setup contact group membership controller
 	if err := (&controller.ContactGroupMembershipController{
-		Client:        mgr.GetClient(),
-		EmailProvider: *emailProviderService,
+		Client:          mgr.GetClient(),
+		EmailProvider:   *emailProviderService,
+		SyncConfig:      *providerSyncConfig,
+		ReadinessConfig: *readinessConfig,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ContactGroupMembership")
 		return fmt.Errorf("unable to create controller: %w", err)
@@ -337,6 +463,38 @@ func runManager(
 		return fmt.Errorf("unable to create controller: %w", err)
 	}
 
+	// Setup dynamic contact group controller
+	if err := (&controller.DynamicContactGroupController{
+		Client: mgr.GetClient(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DynamicContactGroup")
+		return fmt.Errorf("unable to create controller: %w", err)
+	}
+
+	// Setup user contact controller
+	if userContactConfig.Enabled() {
+		if err := (&controller.UserContactController{
+			Client:    mgr.GetClient(),
+			APIReader: mgr.GetAPIReader(),
+			Config:    *userContactConfig,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "UserContact")
+			return fmt.Errorf("unable to create controller: %w", err)
+		}
+	}
+
+	// Setup orphan garbage collection
+	if orphanGCConfig.Enabled() {
+		if err := mgr.Add(&controller.OrphanCollector{
+			Client:        mgr.GetClient(),
+			EmailProvider: *emailProviderService,
+			Config:        *orphanGCConfig,
+		}); err != nil {
+			setupLog.Error(err, "unable to add orphan collector to manager")
+			return fmt.Errorf("unable to add orphan collector to manager: %w", err)
+		}
+	}
+
 	if metricsCertWatcher != nil {
 		setupLog.Info("Adding metrics certificate watcher to manager")
 		if err := mgr.Add(metricsCertWatcher); err != nil {
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"go.miloapis.com/email-provider-resend/internal/bulk"
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+)
+
+// createMigrateCommand returns a cobra command that migrates the segments and contacts of an existing Resend account.
+func createMigrateCommand() *cobra.Command {
+	var mappingFile, apiKey string
+	var dryRun bool
+	var qps float32
+	var burst int
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrates the segments and contacts of an existing Resend account to ContactGroups and Contacts",
+		Long: "Migrates the segments and contacts of an existing Resend account. Each segment of the mapping file becomes " +
+			"a ContactGroup, each of its contacts a Contact and a ContactGroupMembership. The resources reference their " +
+			"Resend objects, so the controllers adopt them instead of creating new ones. Example mapping file:\n\n" +
+			"  contactNamespace: contacts\n" +
+			"  segments:\n" +
+			"  - segment: Newsletter # The id or the name of the segment\n" +
+			"    namespace: marketing\n" +
+			"    name: newsletter\n" +
+			"    visibility: public\n\n" +
+			"Segments missing from the mapping file are not migrated. The migration is idempotent: existing resources " +
+			"are left untouched.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runMigrate(cmd, mappingFile, apiKey, dryRun, qps, burst)
+		},
+	}
+
+	cmd.Flags().StringVar(&mappingFile, "mapping", "", "*Required. The YAML or JSON file mapping the segments to ContactGroups.")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "If set, the changes are printed but not applied.")
+	addRateLimitFlags(cmd, &qps, &burst)
+	apiKey = os.Getenv("RESEND_API_KEY") // *Required. The API key of the Resend account.
+
+	return cmd
+}
+
+func runMigrate(cmd *cobra.Command, mappingFile, apiKey string, dryRun bool, qps float32, burst int) error {
+	if mappingFile == "" {
+		return fmt.Errorf("--mapping is required")
+	}
+	if apiKey == "" {
+		return fmt.Errorf("RESEND_API_KEY environment variable is required")
+	}
+
+	in, err := os.Open(mappingFile)
+	if err != nil {
+		return fmt.Errorf("failed to open mapping file: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+	mapping, err := bulk.ReadMigrationMapping(in)
+	if err != nil {
+		return err
+	}
+
+	k8sClient, err := newRateLimitedClient(qps, burst)
+	if err != nil {
+		return err
+	}
+
+	migrator := &bulk.Migrator{
+		Client:        k8sClient,
+		EmailProvider: emailprovider.NewResendEmailProvider(apiKey),
+		Mapping:       *mapping,
+		DryRun:        dryRun,
+		Out:           cmd.OutOrStdout(),
+	}
+	summary, err := migrator.Migrate(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	mode := ""
+	if dryRun {
+		mode = " (dry run)"
+	}
+	fmt.Fprintf(cmd.OutOrStdout(),
+		"Segments: %d skipped. ContactGroups: %d created, %d unchanged. Contacts: %d created, %d unchanged. "+
+			"ContactGroupMemberships: %d created, %d unchanged, %d opted out%s\n",
+		summary.SegmentsSkipped, summary.ContactGroupsCreated, summary.ContactGroupsUnchanged,
+		summary.ContactsCreated, summary.ContactsUnchanged,
+		summary.MembershipsCreated, summary.MembershipsUnchanged, summary.MembershipsOptedOut, mode)
+
+	return nil
+}
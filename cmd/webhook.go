@@ -18,6 +18,8 @@ import (
 	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	config "go.miloapis.com/email-provider-resend/internal/config"
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+	"go.miloapis.com/email-provider-resend/internal/unsubscribe"
 	webhook "go.miloapis.com/email-provider-resend/internal/webhook"
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 )
@@ -28,6 +30,8 @@ func createWebhookCommand() *cobra.Command {
 	var certDir, certFile, keyFile string
 	var metricsBindAddress string
 	var webhookSigningKey, contactWebhookSigningKey string
+	var resendAPIKey string
+	var unsubscribeSigningKey string
 
 	cmd := &cobra.Command{
 		Use:   "resend-webhook",
@@ -38,7 +42,9 @@ func createWebhookCommand() *cobra.Command {
 				webhookPort,
 				certDir, certFile, keyFile,
 				metricsBindAddress,
-				webhookSigningKey, contactWebhookSigningKey)
+				webhookSigningKey, contactWebhookSigningKey,
+				resendAPIKey,
+				unsubscribeSigningKey)
 		},
 	}
 
@@ -54,6 +60,8 @@ func createWebhookCommand() *cobra.Command {
 
 	webhookSigningKey = os.Getenv("RESEND_WEBHOOK_SIGNING_KEY")
 	contactWebhookSigningKey = os.Getenv("RESEND_CONTACT_WEBHOOK_SIGNING_KEY")
+	resendAPIKey = os.Getenv("RESEND_API_KEY")                   // Optional. Enables syncing contact topic preferences.
+	unsubscribeSigningKey = os.Getenv("UNSUBSCRIBE_SIGNING_KEY") // Optional. Enables the unsubscribe endpoint.
 
 	return cmd
 }
@@ -63,7 +71,9 @@ func runWebhook(
 	webhookPort int,
 	certDir, certFile, keyFile string,
 	metricsBindAddress string,
-	webhookSigningKey, contactWebhookSigningKey string) error {
+	webhookSigningKey, contactWebhookSigningKey string,
+	resendAPIKey string,
+	unsubscribeSigningKey string) error {
 	logf.SetLogger(zap.New(zap.JSONEncoder()))
 	log := logf.Log.WithName("resend-webhook")
 
@@ -134,7 +144,14 @@ func runWebhook(
 	webhookv1.SetupSvix(svix)
 
 	// Setup contact webhook
-	contactWebhookv1 := webhook.NewResendContactWebhookV1(mgr.GetClient())
+	// Topic preferences are not part of the contact events, so they are read from the email provider
+	var emailProviderService *emailprovider.Service
+	if resendAPIKey != "" {
+		emailProviderService = emailprovider.NewService(emailprovider.NewResendEmailProvider(resendAPIKey), "", "")
+	} else {
+		log.Info("RESEND_API_KEY not set. Contact topic preferences will not be synced.")
+	}
+	contactWebhookv1 := webhook.NewResendContactWebhookV1(mgr.GetClient(), emailProviderService)
 	err = contactWebhookv1.SetupWithManager(mgr)
 	if err != nil {
 		return fmt.Errorf("failed to setup webhook: %w", err)
@@ -146,6 +163,17 @@ func runWebhook(
 	}
 	contactWebhookv1.SetupSvix(cwSvix)
 
+	// Setup unsubscribe endpoint
+	if unsubscribeSigningKey != "" {
+		log.Info("Setting up unsubscribe endpoint", "endpoint", webhook.UnsubscribeEndpoint)
+		unsubscribeHandler := webhook.NewUnsubscribeHandler(mgr.GetClient(), unsubscribe.NewSigner(unsubscribeSigningKey))
+		if err := unsubscribeHandler.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("failed to setup unsubscribe endpoint: %w", err)
+		}
+	} else {
+		log.Info("UNSUBSCRIBE_SIGNING_KEY not set. Unsubscribe endpoint will not be served.")
+	}
+
 	log.Info("Starting manager")
 	return mgr.Start(cmd.Context())
 }
@@ -0,0 +1,167 @@
+package bulk
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ExportRecord is an exported Contact, ContactGroup or ContactGroupMembership. Only the fields of its kind are set.
+type ExportRecord struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// Contact
+	Email      string `json:"email,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+
+	// ContactGroup
+	DisplayName string `json:"displayName,omitempty"`
+	Visibility  string `json:"visibility,omitempty"`
+
+	// ContactGroupMembership, as "<namespace>/<name>"
+	Contact      string `json:"contact,omitempty"`
+	ContactGroup string `json:"contactGroup,omitempty"`
+
+	ProviderID string            `json:"providerID,omitempty"`
+	Conditions []ExportCondition `json:"conditions,omitempty"`
+}
+
+// ExportCondition is an exported status condition.
+type ExportCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// exportRecordCSVHeader is the header of the CSV files of export records. Conditions are exported as
+// "<type>=<status>" separated by ";".
+var exportRecordCSVHeader = []string{
+	"kind", "namespace", "name",
+	"email", "givenName", "familyName",
+	"displayName", "visibility",
+	"contact", "contactGroup",
+	"providerID", "conditions",
+}
+
+// Export returns the Contacts, ContactGroups and ContactGroupMemberships of the namespace. An empty namespace exports
+// every namespace.
+func Export(ctx context.Context, c client.Reader, namespace string) ([]ExportRecord, error) {
+	var records []ExportRecord
+
+	contacts := &notificationmiloapiscomv1alpha1.ContactList{}
+	if err := c.List(ctx, contacts, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list Contacts: %w", err)
+	}
+	for _, contact := range contacts.Items {
+		records = append(records, ExportRecord{
+			Kind:       "Contact",
+			Namespace:  contact.Namespace,
+			Name:       contact.Name,
+			Email:      contact.Spec.Email,
+			GivenName:  contact.Spec.GivenName,
+			FamilyName: contact.Spec.FamilyName,
+			ProviderID: contact.Status.ProviderID,
+			Conditions: exportConditions(contact.Status.Conditions),
+		})
+	}
+
+	contactGroups := &notificationmiloapiscomv1alpha1.ContactGroupList{}
+	if err := c.List(ctx, contactGroups, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ContactGroups: %w", err)
+	}
+	for _, contactGroup := range contactGroups.Items {
+		records = append(records, ExportRecord{
+			Kind:        "ContactGroup",
+			Namespace:   contactGroup.Namespace,
+			Name:        contactGroup.Name,
+			DisplayName: contactGroup.Spec.DisplayName,
+			Visibility:  string(contactGroup.Spec.Visibility),
+			ProviderID:  contactGroup.Status.ProviderID,
+			Conditions:  exportConditions(contactGroup.Status.Conditions),
+		})
+	}
+
+	memberships := &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}
+	if err := c.List(ctx, memberships, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ContactGroupMemberships: %w", err)
+	}
+	for _, membership := range memberships.Items {
+		records = append(records, ExportRecord{
+			Kind:         "ContactGroupMembership",
+			Namespace:    membership.Namespace,
+			Name:         membership.Name,
+			Contact:      membership.Spec.ContactRef.Namespace + "/" + membership.Spec.ContactRef.Name,
+			ContactGroup: membership.Spec.ContactGroupRef.Namespace + "/" + membership.Spec.ContactGroupRef.Name,
+			ProviderID:   membership.Status.ProviderID,
+			Conditions:   exportConditions(membership.Status.Conditions),
+		})
+	}
+
+	return records, nil
+}
+
+// WriteExportRecords writes the records to w.
+func WriteExportRecords(w io.Writer, format Format, records []ExportRecord) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if records == nil {
+			records = []ExportRecord{}
+		}
+		if err := encoder.Encode(records); err != nil {
+			return fmt.Errorf("failed to encode export records: %w", err)
+		}
+		return nil
+
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(exportRecordCSVHeader); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+		for _, record := range records {
+			conditions := make([]string, 0, len(record.Conditions))
+			for _, condition := range record.Conditions {
+				conditions = append(conditions, condition.Type+"="+condition.Status)
+			}
+			if err := writer.Write([]string{
+				record.Kind, record.Namespace, record.Name,
+				record.Email, record.GivenName, record.FamilyName,
+				record.DisplayName, record.Visibility,
+				record.Contact, record.ContactGroup,
+				record.ProviderID, strings.Join(conditions, ";"),
+			}); err != nil {
+				return fmt.Errorf("failed to write CSV row: %w", err)
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+func exportConditions(conditions []metav1.Condition) []ExportCondition {
+	exported := make([]ExportCondition, 0, len(conditions))
+	for _, condition := range conditions {
+		exported = append(exported, ExportCondition{
+			Type:    condition.Type,
+			Status:  string(condition.Status),
+			Reason:  condition.Reason,
+			Message: condition.Message,
+		})
+	}
+	return exported
+}
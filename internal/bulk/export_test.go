@@ -0,0 +1,70 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+
+	notificationv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExport_CSV(t *testing.T) {
+	k8sClient := buildClient(t,
+		&notificationv1alpha1.Contact{
+			ObjectMeta: metav1.ObjectMeta{Name: "ada", Namespace: "default"},
+			Spec:       notificationv1alpha1.ContactSpec{Email: "ada@example.com"},
+			Status: notificationv1alpha1.ContactStatus{
+				ProviderID: "contact-123",
+				Conditions: []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "ContactCreated"}},
+			},
+		},
+		&notificationv1alpha1.ContactGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "newsletter", Namespace: "default"},
+			Spec:       notificationv1alpha1.ContactGroupSpec{DisplayName: "Newsletter", Visibility: notificationv1alpha1.ContactGroupVisibilityPublic},
+			Status:     notificationv1alpha1.ContactGroupStatus{ProviderID: "segment-123"},
+		},
+		&notificationv1alpha1.ContactGroupMembership{
+			ObjectMeta: metav1.ObjectMeta{Name: "ada-newsletter", Namespace: "default"},
+			Spec: notificationv1alpha1.ContactGroupMembershipSpec{
+				ContactRef:      notificationv1alpha1.ContactReference{Name: "ada", Namespace: "default"},
+				ContactGroupRef: notificationv1alpha1.ContactGroupReference{Name: "newsletter", Namespace: "default"},
+			},
+		},
+		&notificationv1alpha1.Contact{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "other"},
+			Spec:       notificationv1alpha1.ContactSpec{Email: "other@example.com"},
+		},
+	)
+
+	records, err := Export(context.TODO(), k8sClient, "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := &bytes.Buffer{}
+	if err := WriteExportRecords(out, FormatCSV, records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+	expected := [][]string{
+		exportRecordCSVHeader,
+		{"Contact", "default", "ada", "ada@example.com", "", "", "", "", "", "", "contact-123", "Ready=True"},
+		{"ContactGroup", "default", "newsletter", "", "", "", "Newsletter", "public", "", "", "segment-123", ""},
+		{"ContactGroupMembership", "default", "ada-newsletter", "", "", "", "", "", "default/ada", "default/newsletter", "", ""},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %d: %v", len(expected), len(rows), rows)
+	}
+	for i := range expected {
+		for j := range expected[i] {
+			if rows[i][j] != expected[i][j] {
+				t.Fatalf("unexpected row %d: got %v want %v", i, rows[i], expected[i])
+			}
+		}
+	}
+}
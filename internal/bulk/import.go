@@ -0,0 +1,202 @@
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"go.miloapis.com/email-provider-resend/internal/controller"
+)
+
+// Importer creates the Contacts and ContactGroupMemberships of contact records.
+// Importing the same records twice is a no-op: existing contacts are updated only if they differ, and memberships
+// are created only if missing. Contacts that opted out of a contact group are not added to it again.
+type Importer struct {
+	Client client.Client
+	// Namespace of the records without namespace
+	Namespace string
+	// DryRun prints the changes without applying them
+	DryRun bool
+	// Out receives a diff-like line for every change: "+" for creations, "~" for updates and "!" for skipped memberships
+	Out io.Writer
+
+	memberships map[string]map[string]bool
+	removals    map[string]map[string]bool
+}
+
+// ImportSummary counts the changes made by an import.
+type ImportSummary struct {
+	ContactsCreated      int
+	ContactsUpdated      int
+	ContactsUnchanged    int
+	MembershipsCreated   int
+	MembershipsUnchanged int
+	MembershipsOptedOut  int
+}
+
+// Import creates or updates the contacts and memberships of the records.
+func (i *Importer) Import(ctx context.Context, records []ContactRecord) (ImportSummary, error) {
+	summary := ImportSummary{}
+	i.memberships = map[string]map[string]bool{}
+	i.removals = map[string]map[string]bool{}
+
+	for _, record := range records {
+		namespace := record.Namespace
+		if namespace == "" {
+			namespace = i.Namespace
+		}
+		contactRef := notificationmiloapiscomv1alpha1.ContactReference{Name: record.contactName(), Namespace: namespace}
+
+		if err := i.importContact(ctx, contactRef, record, &summary); err != nil {
+			return summary, err
+		}
+		for _, contactGroupRef := range record.contactGroupRefs(namespace) {
+			if err := i.importMembership(ctx, contactRef, contactGroupRef, &summary); err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+// importContact creates the contact of the record, or updates it if it differs. Names missing from the record are
+// not compared, so importing a file without names does not clear them.
+func (i *Importer) importContact(ctx context.Context, contactRef notificationmiloapiscomv1alpha1.ContactReference, record ContactRecord, summary *ImportSummary) error {
+	contact := &notificationmiloapiscomv1alpha1.Contact{}
+	err := i.Client.Get(ctx, client.ObjectKey{Namespace: contactRef.Namespace, Name: contactRef.Name}, contact)
+	if errors.IsNotFound(err) {
+		fmt.Fprintf(i.Out, "+ Contact %s/%s <%s>\n", contactRef.Namespace, contactRef.Name, record.Email)
+		summary.ContactsCreated++
+		if i.DryRun {
+			return nil
+		}
+		if err := i.Client.Create(ctx, &notificationmiloapiscomv1alpha1.Contact{
+			ObjectMeta: metav1.ObjectMeta{Name: contactRef.Name, Namespace: contactRef.Namespace},
+			Spec: notificationmiloapiscomv1alpha1.ContactSpec{
+				Email:      record.Email,
+				GivenName:  record.GivenName,
+				FamilyName: record.FamilyName,
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to create Contact %s/%s: %w", contactRef.Namespace, contactRef.Name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get Contact %s/%s: %w", contactRef.Namespace, contactRef.Name, err)
+	}
+
+	var changes []string
+	diff := func(field string, current *string, desired string) {
+		if desired != "" && *current != desired {
+			changes = append(changes, fmt.Sprintf("    %s: %q -> %q", field, *current, desired))
+			*current = desired
+		}
+	}
+	diff("email", &contact.Spec.Email, record.Email)
+	diff("givenName", &contact.Spec.GivenName, record.GivenName)
+	diff("familyName", &contact.Spec.FamilyName, record.FamilyName)
+	if len(changes) == 0 {
+		summary.ContactsUnchanged++
+		return nil
+	}
+
+	fmt.Fprintf(i.Out, "~ Contact %s/%s\n", contactRef.Namespace, contactRef.Name)
+	for _, change := range changes {
+		fmt.Fprintln(i.Out, change)
+	}
+	summary.ContactsUpdated++
+	if i.DryRun {
+		return nil
+	}
+	if err := i.Client.Update(ctx, contact); err != nil {
+		return fmt.Errorf("failed to update Contact %s/%s: %w", contactRef.Namespace, contactRef.Name, err)
+	}
+	return nil
+}
+
+// importMembership creates the membership of the contact to the contact group, unless it exists or the contact opted out.
+func (i *Importer) importMembership(ctx context.Context,
+	contactRef notificationmiloapiscomv1alpha1.ContactReference,
+	contactGroupRef notificationmiloapiscomv1alpha1.ContactGroupReference,
+	summary *ImportSummary,
+) error {
+	if err := i.loadNamespace(ctx, contactRef.Namespace); err != nil {
+		return err
+	}
+
+	key := membershipKey(contactRef, contactGroupRef)
+	name := controller.ContactGroupMembershipName(contactRef, contactGroupRef)
+	switch {
+	case i.memberships[contactRef.Namespace][key]:
+		summary.MembershipsUnchanged++
+		return nil
+	case i.removals[contactRef.Namespace][key]:
+		fmt.Fprintf(i.Out, "! ContactGroupMembership %s/%s skipped, the contact opted out of %s/%s\n",
+			contactRef.Namespace, name, contactGroupRef.Namespace, contactGroupRef.Name)
+		summary.MembershipsOptedOut++
+		return nil
+	}
+
+	fmt.Fprintf(i.Out, "+ ContactGroupMembership %s/%s (%s -> %s/%s)\n",
+		contactRef.Namespace, name, contactRef.Name, contactGroupRef.Namespace, contactGroupRef.Name)
+	i.memberships[contactRef.Namespace][key] = true
+	if i.DryRun {
+		summary.MembershipsCreated++
+		return nil
+	}
+	err := i.Client.Create(ctx, &notificationmiloapiscomv1alpha1.ContactGroupMembership{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: contactRef.Namespace},
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
+			ContactRef:      contactRef,
+			ContactGroupRef: contactGroupRef,
+		},
+	})
+	// The name is derived from the references, so an existing membership was created concurrently for the same pair
+	if errors.IsAlreadyExists(err) {
+		summary.MembershipsUnchanged++
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create ContactGroupMembership %s/%s: %w", contactRef.Namespace, name, err)
+	}
+	summary.MembershipsCreated++
+	return nil
+}
+
+// loadNamespace lists the memberships and removals of the namespace once, instead of once per record.
+func (i *Importer) loadNamespace(ctx context.Context, namespace string) error {
+	if _, ok := i.memberships[namespace]; ok {
+		return nil
+	}
+
+	memberships := &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}
+	if err := i.Client.List(ctx, memberships, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list ContactGroupMemberships: %w", err)
+	}
+	removals := &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList{}
+	if err := i.Client.List(ctx, removals, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list ContactGroupMembershipRemovals: %w", err)
+	}
+
+	i.memberships[namespace] = map[string]bool{}
+	for _, membership := range memberships.Items {
+		i.memberships[namespace][membershipKey(membership.Spec.ContactRef, membership.Spec.ContactGroupRef)] = true
+	}
+	i.removals[namespace] = map[string]bool{}
+	for _, removal := range removals.Items {
+		i.removals[namespace][membershipKey(removal.Spec.ContactRef, removal.Spec.ContactGroupRef)] = true
+	}
+	return nil
+}
+
+// membershipKey returns "<contact-ns>|<contact-name>|<contactgroup-ns>|<contactgroup-name>"
+func membershipKey(contactRef notificationmiloapiscomv1alpha1.ContactReference, contactGroupRef notificationmiloapiscomv1alpha1.ContactGroupReference) string {
+	return fmt.Sprintf("%s|%s|%s|%s", contactRef.Namespace, contactRef.Name, contactGroupRef.Namespace, contactGroupRef.Name)
+}
@@ -0,0 +1,168 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	notificationv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"go.miloapis.com/email-provider-resend/internal/controller"
+)
+
+func buildClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := notificationv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add notification scheme: %v", err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func TestImporter_Import(t *testing.T) {
+	k8sClient := buildClient(t,
+		&notificationv1alpha1.Contact{
+			ObjectMeta: metav1.ObjectMeta{Name: "grace", Namespace: "default"},
+			Spec:       notificationv1alpha1.ContactSpec{Email: "grace@example.com", GivenName: "G"},
+		},
+		&notificationv1alpha1.ContactGroupMembershipRemoval{
+			ObjectMeta: metav1.ObjectMeta{Name: "grace-newsletter", Namespace: "default"},
+			Spec: notificationv1alpha1.ContactGroupMembershipRemovalSpec{
+				ContactRef:      notificationv1alpha1.ContactReference{Name: "grace", Namespace: "default"},
+				ContactGroupRef: notificationv1alpha1.ContactGroupReference{Name: "newsletter", Namespace: "default"},
+			},
+		},
+	)
+	records := []ContactRecord{
+		{Name: "ada", Email: "ada@example.com", GivenName: "Ada", ContactGroups: []string{"newsletter"}},
+		{Name: "grace", Email: "grace@example.com", GivenName: "Grace", ContactGroups: []string{"newsletter"}},
+	}
+
+	// Dry run only prints the changes
+	out := &bytes.Buffer{}
+	importer := &Importer{Client: k8sClient, Namespace: "default", DryRun: true, Out: out}
+	summary, err := importer.Import(context.TODO(), records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := ImportSummary{ContactsCreated: 1, ContactsUpdated: 1, MembershipsCreated: 1, MembershipsOptedOut: 1}
+	if summary != expected {
+		t.Fatalf("unexpected dry run summary: got %+v want %+v", summary, expected)
+	}
+	newsletter := notificationv1alpha1.ContactGroupReference{Name: "newsletter", Namespace: "default"}
+	adaMembership := controller.ContactGroupMembershipName(notificationv1alpha1.ContactReference{Name: "ada", Namespace: "default"}, newsletter)
+	graceMembership := controller.ContactGroupMembershipName(notificationv1alpha1.ContactReference{Name: "grace", Namespace: "default"}, newsletter)
+	for _, line := range []string{
+		"+ Contact default/ada <ada@example.com>",
+		"~ Contact default/grace",
+		`    givenName: "G" -> "Grace"`,
+		"+ ContactGroupMembership default/" + adaMembership + " (ada -> default/newsletter)",
+		"! ContactGroupMembership default/" + graceMembership + " skipped",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Fatalf("expected output to contain %q, got:\n%s", line, out.String())
+		}
+	}
+	contacts := &notificationv1alpha1.ContactList{}
+	if err := k8sClient.List(context.TODO(), contacts); err != nil {
+		t.Fatalf("failed to list contacts: %v", err)
+	}
+	if len(contacts.Items) != 1 {
+		t.Fatalf("expected dry run not to create contacts, got %d", len(contacts.Items))
+	}
+
+	// Import applies the changes
+	importer = &Importer{Client: k8sClient, Namespace: "default", Out: &bytes.Buffer{}}
+	if _, err := importer.Import(context.TODO(), records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	grace := &notificationv1alpha1.Contact{}
+	if err := k8sClient.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "grace"}, grace); err != nil {
+		t.Fatalf("failed to get contact: %v", err)
+	}
+	if grace.Spec.GivenName != "Grace" {
+		t.Fatalf("expected contact to be updated, got %+v", grace.Spec)
+	}
+	membership := &notificationv1alpha1.ContactGroupMembership{}
+	if err := k8sClient.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: adaMembership}, membership); err != nil {
+		t.Fatalf("expected membership to be created: %v", err)
+	}
+
+	// Importing again is a no-op
+	out = &bytes.Buffer{}
+	importer = &Importer{Client: k8sClient, Namespace: "default", Out: out}
+	summary, err = importer.Import(context.TODO(), records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = ImportSummary{ContactsUnchanged: 2, MembershipsUnchanged: 1, MembershipsOptedOut: 1}
+	if summary != expected {
+		t.Fatalf("unexpected summary: got %+v want %+v", summary, expected)
+	}
+}
+
+func TestImporter_ImportKeepsMissingNames(t *testing.T) {
+	k8sClient := buildClient(t, &notificationv1alpha1.Contact{
+		ObjectMeta: metav1.ObjectMeta{Name: "ada", Namespace: "default"},
+		Spec:       notificationv1alpha1.ContactSpec{Email: "ada@example.com", GivenName: "Ada", FamilyName: "Lovelace"},
+	})
+	records, err := ReadContactRecords(strings.NewReader("name,email\nada,ada@example.com\n"), FormatCSV)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	importer := &Importer{Client: k8sClient, Namespace: "default", Out: &bytes.Buffer{}}
+	summary, err := importer.Import(context.TODO(), records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (ImportSummary{ContactsUnchanged: 1}); summary != expected {
+		t.Fatalf("unexpected summary: got %+v want %+v", summary, expected)
+	}
+	ada := &notificationv1alpha1.Contact{}
+	if err := k8sClient.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "ada"}, ada); err != nil {
+		t.Fatalf("failed to get contact: %v", err)
+	}
+	if ada.Spec.GivenName != "Ada" || ada.Spec.FamilyName != "Lovelace" {
+		t.Fatalf("expected the names to be kept, got %+v", ada.Spec)
+	}
+}
+
+func TestImporter_ImportSameNamedContactGroups(t *testing.T) {
+	// A membership named after the contact and contact group names only, for the contact group of another namespace
+	k8sClient := buildClient(t, &notificationv1alpha1.ContactGroupMembership{
+		ObjectMeta: metav1.ObjectMeta{Name: "ada-newsletter", Namespace: "default"},
+		Spec: notificationv1alpha1.ContactGroupMembershipSpec{
+			ContactRef:      notificationv1alpha1.ContactReference{Name: "ada", Namespace: "default"},
+			ContactGroupRef: notificationv1alpha1.ContactGroupReference{Name: "newsletter", Namespace: "other"},
+		},
+	})
+	records := []ContactRecord{
+		{Name: "ada", Email: "ada@example.com", ContactGroups: []string{"newsletter", "other/newsletter"}},
+	}
+
+	importer := &Importer{Client: k8sClient, Namespace: "default", Out: &bytes.Buffer{}}
+	summary, err := importer.Import(context.TODO(), records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (ImportSummary{ContactsCreated: 1, MembershipsCreated: 1, MembershipsUnchanged: 1}); summary != expected {
+		t.Fatalf("unexpected summary: got %+v want %+v", summary, expected)
+	}
+	membership := &notificationv1alpha1.ContactGroupMembership{}
+	name := controller.ContactGroupMembershipName(
+		notificationv1alpha1.ContactReference{Name: "ada", Namespace: "default"},
+		notificationv1alpha1.ContactGroupReference{Name: "newsletter", Namespace: "default"},
+	)
+	if err := k8sClient.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: name}, membership); err != nil {
+		t.Fatalf("expected membership to be created: %v", err)
+	}
+	if membership.Spec.ContactGroupRef.Namespace != "default" {
+		t.Fatalf("unexpected membership %+v", membership.Spec)
+	}
+}
@@ -0,0 +1,336 @@
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"go.miloapis.com/email-provider-resend/internal/controller"
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+)
+
+// MigrationMapping maps the segments of an existing email provider account to ContactGroups.
+type MigrationMapping struct {
+	// ContactNamespace is the namespace of the migrated Contacts and ContactGroupMemberships. Contacts are shared by
+	// every segment on the email provider, so they are migrated to a single namespace.
+	ContactNamespace string `json:"contactNamespace"`
+	// Segments to migrate. Segments missing from the mapping are not migrated.
+	Segments []SegmentMapping `json:"segments"`
+}
+
+// SegmentMapping maps a segment of the email provider to a ContactGroup.
+type SegmentMapping struct {
+	// Segment is the id or the name of the segment.
+	Segment   string `json:"segment"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// DisplayName of the ContactGroup. Defaults to the name of the segment.
+	DisplayName string `json:"displayName,omitempty"`
+	// Visibility of the ContactGroup. Defaults to private.
+	Visibility notificationmiloapiscomv1alpha1.ContactGroupVisibility `json:"visibility,omitempty"`
+}
+
+// ReadMigrationMapping reads and validates the YAML or JSON migration mapping from r.
+func ReadMigrationMapping(r io.Reader) (*MigrationMapping, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration mapping: %w", err)
+	}
+	mapping := &MigrationMapping{}
+	if err := yaml.UnmarshalStrict(data, mapping); err != nil {
+		return nil, fmt.Errorf("failed to decode migration mapping: %w", err)
+	}
+
+	var errs field.ErrorList
+	validateName := func(path *field.Path, value string) {
+		if value == "" {
+			errs = append(errs, field.Required(path, ""))
+			return
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(value) {
+			errs = append(errs, field.Invalid(path, value, msg))
+		}
+	}
+
+	validateName(field.NewPath("contactNamespace"), mapping.ContactNamespace)
+	segments := map[string]bool{}
+	contactGroups := map[string]bool{}
+	for i, segment := range mapping.Segments {
+		path := field.NewPath("segments").Index(i)
+		switch {
+		case segment.Segment == "":
+			errs = append(errs, field.Required(path.Child("segment"), ""))
+		case segments[segment.Segment]:
+			errs = append(errs, field.Duplicate(path.Child("segment"), segment.Segment))
+		}
+		segments[segment.Segment] = true
+
+		validateName(path.Child("namespace"), segment.Namespace)
+		validateName(path.Child("name"), segment.Name)
+		if contactGroups[segment.Namespace+"/"+segment.Name] {
+			errs = append(errs, field.Duplicate(path.Child("name"), segment.Name))
+		}
+		contactGroups[segment.Namespace+"/"+segment.Name] = true
+
+		switch segment.Visibility {
+		case "", notificationmiloapiscomv1alpha1.ContactGroupVisibilityPublic, notificationmiloapiscomv1alpha1.ContactGroupVisibilityPrivate:
+		default:
+			errs = append(errs, field.NotSupported(path.Child("visibility"), segment.Visibility, []string{
+				string(notificationmiloapiscomv1alpha1.ContactGroupVisibilityPublic),
+				string(notificationmiloapiscomv1alpha1.ContactGroupVisibilityPrivate),
+			}))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid migration mapping: %w", errs.ToAggregate())
+	}
+
+	return mapping, nil
+}
+
+// Migrator creates the Contacts, ContactGroups and ContactGroupMemberships of the segments and contacts of an existing
+// email provider account. The created resources reference their email provider object, through their status provider
+// id and the adopt provider id annotation, so the controllers adopt them instead of creating new ones.
+// Migrating twice is a no-op: existing resources are left untouched.
+type Migrator struct {
+	Client        client.Client
+	EmailProvider emailprovider.EmailProvider
+	Mapping       MigrationMapping
+	// DryRun prints the changes without applying them
+	DryRun bool
+	// Out receives a diff-like line for every change: "+" for creations and "!" for skipped segments
+	Out io.Writer
+}
+
+// MigrationSummary counts the changes made by a migration.
+type MigrationSummary struct {
+	SegmentsSkipped        int
+	ContactGroupsCreated   int
+	ContactGroupsUnchanged int
+	ContactsCreated        int
+	ContactsUnchanged      int
+	MembershipsCreated     int
+	MembershipsUnchanged   int
+	// MembershipsOptedOut counts the memberships of contacts who unsubscribed on the email provider, which are
+	// migrated as ContactGroupMembershipRemovals instead
+	MembershipsOptedOut int
+}
+
+// Migrate walks the segments of the email provider account and the contacts of the mapped segments.
+func (m *Migrator) Migrate(ctx context.Context) (MigrationSummary, error) {
+	summary := MigrationSummary{}
+
+	mappings := map[string]SegmentMapping{}
+	for _, segment := range m.Mapping.Segments {
+		mappings[segment.Segment] = segment
+	}
+
+	// Segments are listed first, so the mapping is validated against the account before anything is created
+	type mappedSegment struct {
+		emailprovider.GetContactGroupOutput
+		SegmentMapping
+	}
+	var segments []mappedSegment
+	found := map[string]bool{}
+	for segment, err := range m.EmailProvider.ListContactGroups(ctx) {
+		if err != nil {
+			return summary, fmt.Errorf("failed to list segments from email provider: %w", err)
+		}
+		mapping, ok := mappings[segment.ContactGroupID]
+		if !ok {
+			mapping, ok = mappings[segment.DisplayName]
+		}
+		if !ok {
+			fmt.Fprintf(m.Out, "! Segment %q (%s) skipped, it is not in the mapping\n", segment.DisplayName, segment.ContactGroupID)
+			summary.SegmentsSkipped++
+			continue
+		}
+		if found[mapping.Segment] {
+			return summary, fmt.Errorf("segment %q of the mapping matches several segments, use the segment id instead", mapping.Segment)
+		}
+		found[mapping.Segment] = true
+		segments = append(segments, mappedSegment{GetContactGroupOutput: segment, SegmentMapping: mapping})
+	}
+	for _, segment := range m.Mapping.Segments {
+		if !found[segment.Segment] {
+			return summary, fmt.Errorf("segment %q of the mapping not found on email provider", segment.Segment)
+		}
+	}
+
+	// Memberships are looked up by their references, so memberships created under another name are not duplicated
+	memberships := &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}
+	if err := m.Client.List(ctx, memberships, client.InNamespace(m.Mapping.ContactNamespace)); err != nil {
+		return summary, fmt.Errorf("failed to list ContactGroupMemberships: %w", err)
+	}
+	existingMemberships := map[string]bool{}
+	for _, membership := range memberships.Items {
+		existingMemberships[membershipKey(membership.Spec.ContactRef, membership.Spec.ContactGroupRef)] = true
+	}
+	removals := &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList{}
+	if err := m.Client.List(ctx, removals, client.InNamespace(m.Mapping.ContactNamespace)); err != nil {
+		return summary, fmt.Errorf("failed to list ContactGroupMembershipRemovals: %w", err)
+	}
+	existingRemovals := map[string]bool{}
+	for _, removal := range removals.Items {
+		existingRemovals[membershipKey(removal.Spec.ContactRef, removal.Spec.ContactGroupRef)] = true
+	}
+
+	contacts := map[string]notificationmiloapiscomv1alpha1.ContactReference{}
+	for _, segment := range segments {
+		contactGroup := &notificationmiloapiscomv1alpha1.ContactGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: segment.Name, Namespace: segment.Namespace},
+			Spec: notificationmiloapiscomv1alpha1.ContactGroupSpec{
+				DisplayName: segment.SegmentMapping.DisplayName,
+				Visibility:  segment.Visibility,
+			},
+		}
+		if contactGroup.Spec.DisplayName == "" {
+			contactGroup.Spec.DisplayName = segment.GetContactGroupOutput.DisplayName
+		}
+		if contactGroup.Spec.Visibility == "" {
+			contactGroup.Spec.Visibility = notificationmiloapiscomv1alpha1.ContactGroupVisibilityPrivate
+		}
+		created, err := m.migrate(ctx, "ContactGroup", contactGroup, segment.ContactGroupID, func() { contactGroup.Status.ProviderID = segment.ContactGroupID })
+		if err != nil {
+			return summary, err
+		}
+		if created {
+			summary.ContactGroupsCreated++
+		} else {
+			summary.ContactGroupsUnchanged++
+		}
+		contactGroupRef := notificationmiloapiscomv1alpha1.ContactGroupReference{Name: segment.Name, Namespace: segment.Namespace}
+
+		for providerContact, err := range m.EmailProvider.ListSegmentContacts(ctx, emailprovider.ListSegmentContactsInput{ContactGroupID: segment.ContactGroupID}) {
+			if err != nil {
+				return summary, fmt.Errorf("failed to list contacts of segment %q from email provider: %w", segment.ContactGroupID, err)
+			}
+
+			// Contacts are shared by segments, but migrated once
+			contactRef, ok := contacts[providerContact.ContactId]
+			if !ok {
+				contactRef = notificationmiloapiscomv1alpha1.ContactReference{
+					Name:      ContactRecord{Email: providerContact.Email}.contactName(),
+					Namespace: m.Mapping.ContactNamespace,
+				}
+				contact := &notificationmiloapiscomv1alpha1.Contact{
+					ObjectMeta: metav1.ObjectMeta{Name: contactRef.Name, Namespace: contactRef.Namespace},
+					Spec: notificationmiloapiscomv1alpha1.ContactSpec{
+						Email:      providerContact.Email,
+						GivenName:  providerContact.GivenName,
+						FamilyName: providerContact.FamilyName,
+					},
+				}
+				created, err := m.migrate(ctx, "Contact", contact, providerContact.ContactId, func() { contact.Status.ProviderID = providerContact.ContactId })
+				if err != nil {
+					return summary, err
+				}
+				if created {
+					summary.ContactsCreated++
+				} else {
+					summary.ContactsUnchanged++
+				}
+				contacts[providerContact.ContactId] = contactRef
+			}
+
+			key := membershipKey(contactRef, contactGroupRef)
+			switch {
+			case existingMemberships[key]:
+				summary.MembershipsUnchanged++
+				continue
+			case providerContact.Unsubscribed:
+				// Contacts who unsubscribed on the email provider must not be subscribed again by the migration
+				if !existingRemovals[key] {
+					if err := m.migrateOptOut(ctx, contactRef, contactGroupRef); err != nil {
+						return summary, err
+					}
+					existingRemovals[key] = true
+				}
+				summary.MembershipsOptedOut++
+				continue
+			}
+
+			// The email provider identifies memberships by their segment
+			membership := &notificationmiloapiscomv1alpha1.ContactGroupMembership{
+				ObjectMeta: metav1.ObjectMeta{Name: controller.ContactGroupMembershipName(contactRef, contactGroupRef), Namespace: contactRef.Namespace},
+				Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
+					ContactRef:      contactRef,
+					ContactGroupRef: contactGroupRef,
+				},
+			}
+			created, err := m.migrate(ctx, "ContactGroupMembership", membership, segment.ContactGroupID, func() { membership.Status.ProviderID = segment.ContactGroupID })
+			if err != nil {
+				return summary, err
+			}
+			if created {
+				summary.MembershipsCreated++
+			} else {
+				summary.MembershipsUnchanged++
+			}
+			existingMemberships[key] = true
+		}
+	}
+
+	return summary, nil
+}
+
+// migrateOptOut creates the ContactGroupMembershipRemoval of a contact who unsubscribed on the email provider, so the
+// contact is not added to the contact group again, e.g. by an import.
+func (m *Migrator) migrateOptOut(ctx context.Context,
+	contactRef notificationmiloapiscomv1alpha1.ContactReference,
+	contactGroupRef notificationmiloapiscomv1alpha1.ContactGroupReference,
+) error {
+	removal := &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{
+		ObjectMeta: metav1.ObjectMeta{Name: controller.ContactGroupMembershipName(contactRef, contactGroupRef), Namespace: contactRef.Namespace},
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalSpec{
+			ContactRef:      contactRef,
+			ContactGroupRef: contactGroupRef,
+		},
+	}
+
+	fmt.Fprintf(m.Out, "+ ContactGroupMembershipRemoval %s/%s (%s unsubscribed on email provider, not added to %s/%s)\n",
+		removal.Namespace, removal.Name, contactRef.Name, contactGroupRef.Namespace, contactGroupRef.Name)
+	if m.DryRun {
+		return nil
+	}
+
+	if err := m.Client.Create(ctx, removal); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create ContactGroupMembershipRemoval %s/%s: %w", removal.Namespace, removal.Name, err)
+	}
+	return nil
+}
+
+// migrate creates the object, unless it exists, and sets its status provider id. It returns whether the object was
+// created, or would have been created in a dry run.
+func (m *Migrator) migrate(ctx context.Context, kind string, obj client.Object, providerID string, setProviderID func()) (bool, error) {
+	err := m.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj.DeepCopyObject().(client.Object))
+	if err == nil {
+		return false, nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to get %s %s/%s: %w", kind, obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	fmt.Fprintf(m.Out, "+ %s %s/%s (%s)\n", kind, obj.GetNamespace(), obj.GetName(), providerID)
+	if m.DryRun {
+		return true, nil
+	}
+
+	obj.SetAnnotations(map[string]string{controller.AdoptProviderIDAnnotation: providerID})
+	if err := m.Client.Create(ctx, obj); err != nil {
+		return false, fmt.Errorf("failed to create %s %s/%s: %w", kind, obj.GetNamespace(), obj.GetName(), err)
+	}
+	setProviderID()
+	if err := m.Client.Status().Update(ctx, obj); err != nil {
+		return false, fmt.Errorf("failed to set provider id of %s %s/%s: %w", kind, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return true, nil
+}
@@ -0,0 +1,232 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	notificationv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"go.miloapis.com/email-provider-resend/internal/controller"
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+	"go.miloapis.com/email-provider-resend/internal/emailprovider/mockprovider"
+)
+
+func TestReadMigrationMapping(t *testing.T) {
+	mapping, err := ReadMigrationMapping(strings.NewReader(`
+contactNamespace: contacts
+segments:
+- segment: Newsletter
+  namespace: marketing
+  name: newsletter
+  visibility: public
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mapping.ContactNamespace != "contacts" || len(mapping.Segments) != 1 || mapping.Segments[0].Visibility != notificationv1alpha1.ContactGroupVisibilityPublic {
+		t.Fatalf("unexpected mapping %+v", mapping)
+	}
+
+	for name, input := range map[string]string{
+		"missing contact namespace": "segments: []",
+		"unknown field":             "contactNamespace: contacts\nsegmentz: []",
+		"missing name":              "contactNamespace: contacts\nsegments:\n- segment: a\n  namespace: marketing",
+		"duplicate segment":         "contactNamespace: contacts\nsegments:\n- {segment: a, namespace: b, name: c}\n- {segment: a, namespace: b, name: d}",
+		"invalid visibility":        "contactNamespace: contacts\nsegments:\n- {segment: a, namespace: b, name: c, visibility: secret}",
+	} {
+		if _, err := ReadMigrationMapping(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMigrator_Migrate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := notificationv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add notification scheme: %v", err)
+	}
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&notificationv1alpha1.Contact{}, &notificationv1alpha1.ContactGroup{}, &notificationv1alpha1.ContactGroupMembership{}).
+		WithObjects(&notificationv1alpha1.Contact{
+			ObjectMeta: metav1.ObjectMeta{Name: ContactRecord{Email: "grace@example.com"}.contactName(), Namespace: "contacts"},
+			Spec:       notificationv1alpha1.ContactSpec{Email: "grace@example.com"},
+		}).
+		Build()
+	provider := &mockprovider.MockEmailProvider{
+		ListContactGroupsOutput: []emailprovider.GetContactGroupOutput{
+			{ContactGroupID: "seg-1", DisplayName: "Newsletter"},
+			{ContactGroupID: "seg-2", DisplayName: "Unmapped"},
+		},
+		ListSegmentContactsOutput: []emailprovider.GetContactOutput{
+			{ContactId: "c-1", Email: "ada@example.com", GivenName: "Ada"},
+			{ContactId: "c-2", Email: "grace@example.com"},
+			{ContactId: "c-3", Email: "linus@example.com", Unsubscribed: true},
+		},
+	}
+	mapping := MigrationMapping{
+		ContactNamespace: "contacts",
+		Segments:         []SegmentMapping{{Segment: "Newsletter", Namespace: "marketing", Name: "newsletter"}},
+	}
+
+	// Dry run only prints the changes
+	out := &bytes.Buffer{}
+	migrator := &Migrator{Client: k8sClient, EmailProvider: provider, Mapping: mapping, DryRun: true, Out: out}
+	summary, err := migrator.Migrate(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := MigrationSummary{SegmentsSkipped: 1, ContactGroupsCreated: 1, ContactsCreated: 2, ContactsUnchanged: 1, MembershipsCreated: 2, MembershipsOptedOut: 1}
+	if summary != expected {
+		t.Fatalf("unexpected dry run summary: got %+v want %+v", summary, expected)
+	}
+	for _, line := range []string{
+		`! Segment "Unmapped" (seg-2) skipped`,
+		"+ ContactGroup marketing/newsletter (seg-1)",
+		"+ Contact contacts/" + ContactRecord{Email: "ada@example.com"}.contactName() + " (c-1)",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Fatalf("expected output to contain %q, got:\n%s", line, out.String())
+		}
+	}
+	contactGroups := &notificationv1alpha1.ContactGroupList{}
+	if err := k8sClient.List(context.TODO(), contactGroups); err != nil {
+		t.Fatalf("failed to list contact groups: %v", err)
+	}
+	if len(contactGroups.Items) != 0 {
+		t.Fatalf("expected dry run not to create contact groups, got %d", len(contactGroups.Items))
+	}
+
+	// Migration creates the resources with their provider id
+	migrator = &Migrator{Client: k8sClient, EmailProvider: provider, Mapping: mapping, Out: &bytes.Buffer{}}
+	if _, err := migrator.Migrate(context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	contactGroup := &notificationv1alpha1.ContactGroup{}
+	if err := k8sClient.Get(context.TODO(), client.ObjectKey{Namespace: "marketing", Name: "newsletter"}, contactGroup); err != nil {
+		t.Fatalf("failed to get contact group: %v", err)
+	}
+	if contactGroup.Status.ProviderID != "seg-1" || contactGroup.Annotations[controller.AdoptProviderIDAnnotation] != "seg-1" {
+		t.Fatalf("expected contact group to reference the segment, got %+v", contactGroup)
+	}
+	if contactGroup.Spec.DisplayName != "Newsletter" || contactGroup.Spec.Visibility != notificationv1alpha1.ContactGroupVisibilityPrivate {
+		t.Fatalf("unexpected contact group spec %+v", contactGroup.Spec)
+	}
+	contact := &notificationv1alpha1.Contact{}
+	if err := k8sClient.Get(context.TODO(), client.ObjectKey{Namespace: "contacts", Name: ContactRecord{Email: "ada@example.com"}.contactName()}, contact); err != nil {
+		t.Fatalf("failed to get contact: %v", err)
+	}
+	if contact.Status.ProviderID != "c-1" || contact.Spec.GivenName != "Ada" {
+		t.Fatalf("unexpected contact %+v", contact)
+	}
+	membership := &notificationv1alpha1.ContactGroupMembership{}
+	membershipName := controller.ContactGroupMembershipName(
+		notificationv1alpha1.ContactReference{Name: ContactRecord{Email: "ada@example.com"}.contactName(), Namespace: "contacts"},
+		notificationv1alpha1.ContactGroupReference{Name: "newsletter", Namespace: "marketing"},
+	)
+	if err := k8sClient.Get(context.TODO(), client.ObjectKey{Namespace: "contacts", Name: membershipName}, membership); err != nil {
+		t.Fatalf("failed to get membership: %v", err)
+	}
+	if membership.Status.ProviderID != "seg-1" || membership.Spec.ContactGroupRef.Namespace != "marketing" {
+		t.Fatalf("unexpected membership %+v", membership)
+	}
+
+	// Contacts who unsubscribed on the email provider are opted out instead of subscribed again
+	unsubscribedRef := notificationv1alpha1.ContactReference{Name: ContactRecord{Email: "linus@example.com"}.contactName(), Namespace: "contacts"}
+	newsletterRef := notificationv1alpha1.ContactGroupReference{Name: "newsletter", Namespace: "marketing"}
+	if err := k8sClient.Get(context.TODO(), client.ObjectKey{Namespace: "contacts", Name: controller.ContactGroupMembershipName(unsubscribedRef, newsletterRef)}, &notificationv1alpha1.ContactGroupMembership{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected no membership for the unsubscribed contact, got %v", err)
+	}
+	removal := &notificationv1alpha1.ContactGroupMembershipRemoval{}
+	if err := k8sClient.Get(context.TODO(), client.ObjectKey{Namespace: "contacts", Name: controller.ContactGroupMembershipName(unsubscribedRef, newsletterRef)}, removal); err != nil {
+		t.Fatalf("failed to get removal of the unsubscribed contact: %v", err)
+	}
+	if removal.Spec.ContactRef != unsubscribedRef || removal.Spec.ContactGroupRef != newsletterRef {
+		t.Fatalf("unexpected removal %+v", removal.Spec)
+	}
+
+	// Migrating again is a no-op
+	migrator = &Migrator{Client: k8sClient, EmailProvider: provider, Mapping: mapping, Out: &bytes.Buffer{}}
+	summary, err = migrator.Migrate(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = MigrationSummary{SegmentsSkipped: 1, ContactGroupsUnchanged: 1, ContactsUnchanged: 3, MembershipsUnchanged: 2, MembershipsOptedOut: 1}
+	if summary != expected {
+		t.Fatalf("unexpected summary: got %+v want %+v", summary, expected)
+	}
+}
+
+func TestMigrator_MigrateSameNamedContactGroups(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := notificationv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add notification scheme: %v", err)
+	}
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&notificationv1alpha1.Contact{}, &notificationv1alpha1.ContactGroup{}, &notificationv1alpha1.ContactGroupMembership{}).
+		Build()
+	provider := &mockprovider.MockEmailProvider{
+		ListContactGroupsOutput: []emailprovider.GetContactGroupOutput{
+			{ContactGroupID: "seg-1", DisplayName: "News EU"},
+			{ContactGroupID: "seg-2", DisplayName: "News US"},
+		},
+		ListSegmentContactsOutput: []emailprovider.GetContactOutput{
+			{ContactId: "c-1", Email: "ada@example.com"},
+		},
+	}
+	migrator := &Migrator{
+		Client:        k8sClient,
+		EmailProvider: provider,
+		Mapping: MigrationMapping{
+			ContactNamespace: "contacts",
+			Segments: []SegmentMapping{
+				{Segment: "seg-1", Namespace: "eu", Name: "news"},
+				{Segment: "seg-2", Namespace: "us", Name: "news"},
+			},
+		},
+		Out: &bytes.Buffer{},
+	}
+
+	summary, err := migrator.Migrate(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.MembershipsCreated != 2 || summary.MembershipsUnchanged != 0 {
+		t.Fatalf("expected a membership per contact group, got %+v", summary)
+	}
+
+	memberships := &notificationv1alpha1.ContactGroupMembershipList{}
+	if err := k8sClient.List(context.TODO(), memberships, client.InNamespace("contacts")); err != nil {
+		t.Fatalf("failed to list memberships: %v", err)
+	}
+	groups := map[string]string{}
+	for _, membership := range memberships.Items {
+		groups[membership.Spec.ContactGroupRef.Namespace+"/"+membership.Spec.ContactGroupRef.Name] = membership.Status.ProviderID
+	}
+	if len(groups) != 2 || groups["eu/news"] != "seg-1" || groups["us/news"] != "seg-2" {
+		t.Fatalf("unexpected memberships %v", groups)
+	}
+}
+
+func TestMigrator_MissingSegment(t *testing.T) {
+	migrator := &Migrator{
+		Client:        buildClient(t),
+		EmailProvider: &mockprovider.MockEmailProvider{},
+		Mapping: MigrationMapping{
+			ContactNamespace: "contacts",
+			Segments:         []SegmentMapping{{Segment: "seg-1", Namespace: "marketing", Name: "newsletter"}},
+		},
+		Out: &bytes.Buffer{},
+	}
+	if _, err := migrator.Migrate(context.TODO()); err == nil {
+		t.Fatalf("expected an error for a segment missing on the email provider")
+	}
+}
@@ -0,0 +1,143 @@
+// Package bulk imports Contacts and ContactGroupMemberships from CSV or JSON files, and exports Contacts,
+// ContactGroups and ContactGroupMemberships, with their email provider state, to CSV or JSON files.
+package bulk
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+// Format is the file format of the imported and exported records.
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, must be one of %q or %q", name, FormatCSV, FormatJSON)
+	}
+}
+
+// ContactRecord is a contact to import, along with the contact groups it is a member of.
+type ContactRecord struct {
+	// Namespace of the contact. Defaults to the namespace of the import.
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the contact. Defaults to a name derived from the email address, so the import is idempotent.
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email"`
+	// GivenName and FamilyName of the contact. Empty or missing names, e.g. because the CSV file has no such
+	// column, leave the names of an existing contact unchanged.
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	// ContactGroups the contact is a member of, either "<name>", for a contact group in the namespace of the
+	// contact, or "<namespace>/<name>".
+	ContactGroups []string `json:"contactGroups,omitempty"`
+}
+
+// contactRecordCSVHeader is the header of the CSV files of contact records. The contact groups are separated by ";".
+var contactRecordCSVHeader = []string{"namespace", "name", "email", "givenName", "familyName", "contactGroups"}
+
+// ReadContactRecords reads the contact records from r.
+func ReadContactRecords(r io.Reader, format Format) ([]ContactRecord, error) {
+	var records []ContactRecord
+
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(&records); err != nil {
+			return nil, fmt.Errorf("failed to decode contact records: %w", err)
+		}
+
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.TrimLeadingSpace = true
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		columns := map[string]int{}
+		for i, column := range header {
+			columns[strings.TrimSpace(column)] = i
+		}
+		if _, ok := columns["email"]; !ok {
+			return nil, fmt.Errorf("CSV header must contain the email column, expected %v", contactRecordCSVHeader)
+		}
+		for {
+			row, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CSV row: %w", err)
+			}
+			value := func(column string) string {
+				if i, ok := columns[column]; ok && i < len(row) {
+					return strings.TrimSpace(row[i])
+				}
+				return ""
+			}
+			record := ContactRecord{
+				Namespace:  value("namespace"),
+				Name:       value("name"),
+				Email:      value("email"),
+				GivenName:  value("givenName"),
+				FamilyName: value("familyName"),
+			}
+			for _, contactGroup := range strings.Split(value("contactGroups"), ";") {
+				if contactGroup = strings.TrimSpace(contactGroup); contactGroup != "" {
+					record.ContactGroups = append(record.ContactGroups, contactGroup)
+				}
+			}
+			records = append(records, record)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+
+	for i, record := range records {
+		if record.Email == "" {
+			return nil, fmt.Errorf("record %d has no email", i+1)
+		}
+	}
+
+	return records, nil
+}
+
+// contactName returns the name of the contact of the record.
+func (r ContactRecord) contactName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(r.Email)))
+	return "contact-" + hex.EncodeToString(sum[:])[:16]
+}
+
+// contactGroupRefs returns the references of the contact groups of the record.
+func (r ContactRecord) contactGroupRefs(namespace string) []notificationmiloapiscomv1alpha1.ContactGroupReference {
+	refs := make([]notificationmiloapiscomv1alpha1.ContactGroupReference, 0, len(r.ContactGroups))
+	for _, contactGroup := range r.ContactGroups {
+		if ns, name, found := strings.Cut(contactGroup, "/"); found {
+			refs = append(refs, notificationmiloapiscomv1alpha1.ContactGroupReference{Name: name, Namespace: ns})
+			continue
+		}
+		refs = append(refs, notificationmiloapiscomv1alpha1.ContactGroupReference{Name: contactGroup, Namespace: namespace})
+	}
+	return refs
+}
@@ -0,0 +1,57 @@
+package bulk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadContactRecords_CSV(t *testing.T) {
+	input := `email,name,givenName,familyName,contactGroups,namespace
+ada@example.com,ada,Ada,Lovelace,newsletter; other/beta,
+grace@example.com,,Grace,,,team
+`
+	records, err := ReadContactRecords(strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	ada := records[0]
+	if ada.Name != "ada" || ada.Email != "ada@example.com" || ada.GivenName != "Ada" || ada.FamilyName != "Lovelace" {
+		t.Fatalf("unexpected record %+v", ada)
+	}
+	refs := ada.contactGroupRefs("default")
+	if len(refs) != 2 || refs[0].Namespace != "default" || refs[0].Name != "newsletter" || refs[1].Namespace != "other" || refs[1].Name != "beta" {
+		t.Fatalf("unexpected contact group refs %+v", refs)
+	}
+
+	grace := records[1]
+	if grace.Namespace != "team" || len(grace.ContactGroups) != 0 {
+		t.Fatalf("unexpected record %+v", grace)
+	}
+	if grace.contactName() != (ContactRecord{Email: "GRACE@example.com"}).contactName() {
+		t.Fatalf("expected the derived contact name to ignore the email case")
+	}
+}
+
+func TestReadContactRecords_JSON(t *testing.T) {
+	input := `[{"email":"ada@example.com","name":"ada","contactGroups":["newsletter"]}]`
+	records, err := ReadContactRecords(strings.NewReader(input), FormatJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].Name != "ada" || records[0].ContactGroups[0] != "newsletter" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+}
+
+func TestReadContactRecords_RequiresEmail(t *testing.T) {
+	if _, err := ReadContactRecords(strings.NewReader("name\nada\n"), FormatCSV); err == nil {
+		t.Fatalf("expected an error for a CSV without email column")
+	}
+	if _, err := ReadContactRecords(strings.NewReader(`[{"name":"ada"}]`), FormatJSON); err == nil {
+		t.Fatalf("expected an error for a record without email")
+	}
+}
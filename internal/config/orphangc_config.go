@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// OrphanGCConfig configures the garbage collection of email provider objects that are not owned by any resource.
+type OrphanGCConfig struct {
+	interval    time.Duration
+	gracePeriod time.Duration
+	dryRun      bool
+}
+
+// NewOrphanGCConfig creates a new OrphanGCConfig.
+// An interval of 0 disables the garbage collection.
+func NewOrphanGCConfig(interval, gracePeriod time.Duration, dryRun bool) (*OrphanGCConfig, error) {
+	var errs field.ErrorList
+
+	if interval < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("interval"), interval.String(), "interval must be greater than or equal to 0"))
+	}
+	if gracePeriod <= 0 {
+		errs = append(errs, field.Invalid(field.NewPath("gracePeriod"), gracePeriod.String(), "gracePeriod must be greater than 0"))
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid orphan gc config: %w", errs.ToAggregate())
+	}
+
+	return &OrphanGCConfig{
+		interval:    interval,
+		gracePeriod: gracePeriod,
+		dryRun:      dryRun,
+	}, nil
+}
+
+// Enabled returns whether the garbage collection is enabled.
+func (c *OrphanGCConfig) Enabled() bool {
+	return c.interval > 0
+}
+
+// GetInterval returns the interval between garbage collection runs.
+func (c *OrphanGCConfig) GetInterval() time.Duration {
+	return c.interval
+}
+
+// GetGracePeriod returns the minimum age of an email provider object before it can be collected.
+// It protects objects whose owning resource has not recorded the provider id yet.
+func (c *OrphanGCConfig) GetGracePeriod() time.Duration {
+	return c.gracePeriod
+}
+
+// DryRun returns whether orphans are only reported instead of deleted.
+func (c *OrphanGCConfig) DryRun() bool {
+	return c.dryRun
+}
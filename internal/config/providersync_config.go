@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ProviderSyncConfig configures how controllers keep the email provider state in sync with the cluster state.
+type ProviderSyncConfig struct {
+	resyncInterval      time.Duration
+	renameContactGroups bool
+}
+
+// NewProviderSyncConfig creates a new ProviderSyncConfig.
+// A resync interval of 0 disables the periodic drift detection.
+func NewProviderSyncConfig(resyncInterval time.Duration, renameContactGroups bool) (*ProviderSyncConfig, error) {
+	var errs field.ErrorList
+
+	if resyncInterval < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("resyncInterval"), resyncInterval.String(), "resyncInterval must be greater than or equal to 0"))
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid provider sync config: %w", errs.ToAggregate())
+	}
+
+	return &ProviderSyncConfig{
+		resyncInterval:      resyncInterval,
+		renameContactGroups: renameContactGroups,
+	}, nil
+}
+
+// GetResyncInterval returns the interval at which ready objects are verified against the email provider.
+func (c *ProviderSyncConfig) GetResyncInterval() time.Duration {
+	return c.resyncInterval
+}
+
+// ResyncEnabled returns whether the periodic drift detection is enabled.
+func (c *ProviderSyncConfig) ResyncEnabled() bool {
+	return c.resyncInterval > 0
+}
+
+// RenameContactGroupsEnabled returns whether contact groups are renamed on the email provider when their display
+// name changes. Resend cannot rename segments, so a rename recreates the segment: its contacts are added again, but
+// its history, e.g. past broadcasts, stays with the deleted segment.
+func (c *ProviderSyncConfig) RenameContactGroupsEnabled() bool {
+	return c.renameContactGroups
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ReadinessMode is how the ready conditions of the providers are aggregated.
+type ReadinessMode string
+
+const (
+	// ReadinessModeAllOf requires every provider to be ready.
+	ReadinessModeAllOf ReadinessMode = "all-of"
+	// ReadinessModeAnyOf requires at least one provider to be ready.
+	ReadinessModeAnyOf ReadinessMode = "any-of"
+)
+
+// DefaultReadyProviders are the providers required by a zero ProviderReadinessConfig.
+var DefaultReadyProviders = []string{"Loops", "Resend"}
+
+// providerNameRegexp matches provider names that can prefix a condition type, e.g. "Resend" in "ResendContactReady".
+var providerNameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+// ProviderReadinessConfig configures which provider ready conditions make Contacts and ContactGroupMemberships Ready.
+// A zero ProviderReadinessConfig requires all the DefaultReadyProviders.
+type ProviderReadinessConfig struct {
+	providers []string
+	mode      ReadinessMode
+}
+
+// NewProviderReadinessConfig creates a new ProviderReadinessConfig.
+func NewProviderReadinessConfig(providers []string, mode string) (*ProviderReadinessConfig, error) {
+	var errs field.ErrorList
+
+	if len(providers) == 0 {
+		errs = append(errs, field.Required(field.NewPath("providers"), "at least one provider is required"))
+	}
+	seen := map[string]bool{}
+	for i, provider := range providers {
+		path := field.NewPath("providers").Index(i)
+		switch {
+		case !providerNameRegexp.MatchString(provider):
+			errs = append(errs, field.Invalid(path, provider, "provider must be alphanumeric and start with a letter"))
+		case seen[provider]:
+			errs = append(errs, field.Duplicate(path, provider))
+		}
+		seen[provider] = true
+	}
+
+	switch ReadinessMode(mode) {
+	case ReadinessModeAllOf, ReadinessModeAnyOf:
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("mode"), mode, []string{string(ReadinessModeAllOf), string(ReadinessModeAnyOf)}))
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid provider readiness config: %w", errs.ToAggregate())
+	}
+
+	return &ProviderReadinessConfig{
+		providers: providers,
+		mode:      ReadinessMode(mode),
+	}, nil
+}
+
+// GetProviders returns the providers whose ready conditions are aggregated.
+func (c *ProviderReadinessConfig) GetProviders() []string {
+	if len(c.providers) == 0 {
+		return DefaultReadyProviders
+	}
+	return c.providers
+}
+
+// GetMode returns how the ready conditions of the providers are aggregated.
+func (c *ProviderReadinessConfig) GetMode() ReadinessMode {
+	if c.mode == "" {
+		return ReadinessModeAllOf
+	}
+	return c.mode
+}
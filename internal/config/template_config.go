@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+
+	"golang.org/x/text/language"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// DefaultTemplateLocale is the locale of the EmailTemplates without a locale label, unless configured otherwise.
+const DefaultTemplateLocale = "en"
+
+// TemplateConfig configures the rendering of the EmailTemplates.
+type TemplateConfig struct {
+	strictVariables bool
+	defaultLocale   string
+}
+
+// NewTemplateConfig creates a new TemplateConfig.
+func NewTemplateConfig(strictVariables bool, defaultLocale string) (*TemplateConfig, error) {
+	var errs field.ErrorList
+
+	tag, err := language.Parse(defaultLocale)
+	if err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("defaultLocale"), defaultLocale, "defaultLocale must be a BCP 47 language tag"))
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid template config: %w", errs.ToAggregate())
+	}
+
+	return &TemplateConfig{
+		strictVariables: strictVariables,
+		defaultLocale:   tag.String(),
+	}, nil
+}
+
+// IsStrictVariables returns whether the EmailTemplates are rendered in strict variables mode by default.
+// EmailTemplates can override it with the notification.miloapis.com/strict-variables annotation.
+func (c *TemplateConfig) IsStrictVariables() bool {
+	return c.strictVariables
+}
+
+// GetDefaultLocale returns the locale of the EmailTemplates without the notification.miloapis.com/locale label.
+// It is the last fallback when selecting the locale variant of an EmailTemplate.
+func (c *TemplateConfig) GetDefaultLocale() string {
+	return c.defaultLocale
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// UnsubscribeConfig configures the unsubscribe links of the emails sent to the members of a ContactGroup.
+type UnsubscribeConfig struct {
+	baseURL    string
+	signingKey string
+}
+
+// NewUnsubscribeConfig creates a new UnsubscribeConfig.
+// An empty base URL disables the unsubscribe links.
+func NewUnsubscribeConfig(baseURL, signingKey string) (*UnsubscribeConfig, error) {
+	var errs field.ErrorList
+
+	if baseURL != "" {
+		u, err := url.Parse(baseURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs = append(errs, field.Invalid(field.NewPath("baseURL"), baseURL, "baseURL must be an absolute http(s) URL"))
+		}
+		if signingKey == "" {
+			errs = append(errs, field.Required(field.NewPath("signingKey"), "signingKey is required when baseURL is set"))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid unsubscribe config: %w", errs.ToAggregate())
+	}
+
+	return &UnsubscribeConfig{
+		baseURL:    baseURL,
+		signingKey: signingKey,
+	}, nil
+}
+
+// Enabled returns whether unsubscribe links are added to the emails.
+func (c *UnsubscribeConfig) Enabled() bool {
+	return c.baseURL != ""
+}
+
+// GetBaseURL returns the URL of the unsubscribe endpoint of the webhook server.
+func (c *UnsubscribeConfig) GetBaseURL() string {
+	return c.baseURL
+}
+
+// GetSigningKey returns the key used to sign the unsubscribe tokens.
+func (c *UnsubscribeConfig) GetSigningKey() string {
+	return c.signingKey
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// UserContactConfig configures the provisioning of a Contact for each IAM User.
+type UserContactConfig struct {
+	namespace string
+}
+
+// NewUserContactConfig creates a new UserContactConfig.
+// An empty namespace disables the provisioning.
+func NewUserContactConfig(namespace string) (*UserContactConfig, error) {
+	var errs field.ErrorList
+
+	if namespace != "" {
+		if msgs := validation.IsDNS1123Label(namespace); len(msgs) > 0 {
+			errs = append(errs, field.Invalid(field.NewPath("namespace"), namespace, strings.Join(msgs, ", ")))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid user contact config: %w", errs.ToAggregate())
+	}
+
+	return &UserContactConfig{
+		namespace: namespace,
+	}, nil
+}
+
+// Enabled returns whether a Contact is provisioned for each User.
+func (c *UserContactConfig) Enabled() bool {
+	return c.namespace != ""
+}
+
+// GetNamespace returns the namespace the Contacts of the Users are created in.
+func (c *UserContactConfig) GetNamespace() string {
+	return c.namespace
+}
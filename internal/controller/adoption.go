@@ -0,0 +1,136 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// AdoptProviderIDAnnotation references an email provider object created before the resource, e.g. by a migration
+	// from an existing email provider account. The resource adopts that object instead of creating a new one.
+	// Unlike the status provider id, the annotation is set when the resource is created, so it is never reconciled
+	// without it.
+	AdoptProviderIDAnnotation = "notification.miloapis.com/adopt-provider-id"
+
+	// ResendObjectAdoptedReason is set when an existing object on Resend has been adopted
+	ResendObjectAdoptedReason = "ResendObjectAdopted"
+)
+
+// adoptionProviderID returns the provider id of the email provider object the resource must adopt, if any.
+func adoptionProviderID(obj client.Object, statusProviderID string) string {
+	if statusProviderID != "" {
+		return statusProviderID
+	}
+	return obj.GetAnnotations()[AdoptProviderIDAnnotation]
+}
+
+// isAdoptedProviderID returns whether the provider id is the one the resource adopted on creation.
+func isAdoptedProviderID(obj client.Object, providerID string) bool {
+	adopted, ok := obj.GetAnnotations()[AdoptProviderIDAnnotation]
+	return ok && adopted == providerID
+}
+
+// adoptProviderContact adopts the email provider contact referenced by the contact instead of creating a new one.
+// It returns false if there is nothing to adopt, or if the email provider contact is missing or has another email.
+func (r *ContactController) adoptProviderContact(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact) (bool, error) {
+	log := logf.FromContext(ctx).WithValues("controller", "ContactController", "trigger", contact.Name)
+
+	providerID := adoptionProviderID(contact, contact.Status.ProviderID)
+	if providerID == "" {
+		return false, nil
+	}
+
+	candidate := contact.DeepCopy()
+	candidate.Status.ProviderID = providerID
+	emailProviderContact, err := r.EmailProvider.GetContact(ctx, *candidate)
+	if errors.IsNotFound(err) {
+		log.Info("Contact to adopt not found on email provider. Creating it.", "providerID", providerID)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get Contact to adopt from email provider: %w", err)
+	}
+	if !strings.EqualFold(emailProviderContact.Email, contact.Spec.Email) {
+		log.Info("Contact to adopt has another email on email provider. Creating it.", "providerID", providerID)
+		return false, nil
+	}
+
+	log.Info("Adopting Contact from email provider", "providerID", providerID)
+	contact.Status.ProviderID = providerID
+	return true, nil
+}
+
+// adoptProviderContactGroup adopts the email provider contact group referenced by the contact group instead of
+// creating a new one. It returns false if there is nothing to adopt, or if the email provider contact group is missing.
+func (r *ContactGroupController) adoptProviderContactGroup(ctx context.Context, contactGroup *notificationmiloapiscomv1alpha1.ContactGroup) (bool, error) {
+	log := logf.FromContext(ctx).WithValues("controller", "ContactGroupController", "trigger", contactGroup.Name)
+
+	providerID := adoptionProviderID(contactGroup, contactGroup.Status.ProviderID)
+	if providerID == "" {
+		return false, nil
+	}
+
+	candidate := contactGroup.DeepCopy()
+	candidate.Status.ProviderID = providerID
+	if _, err := r.EmailProvider.GetContactGroup(ctx, *candidate); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("ContactGroup to adopt not found on email provider. Creating it.", "providerID", providerID)
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get ContactGroup to adopt from email provider: %w", err)
+	}
+
+	log.Info("Adopting ContactGroup from email provider", "providerID", providerID)
+	contactGroup.Status.ProviderID = providerID
+	return true, nil
+}
+
+// adoptProviderContactGroupMembership adopts the email provider membership of the contact to the contact group
+// instead of creating it again. It returns false if there is nothing to adopt, or if the contact is not part of the
+// contact group on the email provider.
+func (r *ContactGroupMembershipController) adoptProviderContactGroupMembership(
+	ctx context.Context,
+	cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership,
+	contactGroup *notificationmiloapiscomv1alpha1.ContactGroup,
+	contact *notificationmiloapiscomv1alpha1.Contact) (bool, error) {
+	log := logf.FromContext(ctx).WithValues("controller", "ContactGroupMembershipController", "trigger", cgm.Name)
+
+	providerID := adoptionProviderID(cgm, cgm.Status.ProviderID)
+	if providerID == "" {
+		return false, nil
+	}
+
+	if _, err := r.EmailProvider.GetContactGroupMembership(ctx, *contactGroup, *contact); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("ContactGroupMembership to adopt not found on email provider. Creating it.")
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get ContactGroupMembership to adopt from email provider: %w", err)
+	}
+
+	log.Info("Adopting ContactGroupMembership from email provider", "providerID", providerID)
+	cgm.Status.ProviderID = providerID
+	return true, nil
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 
+	"go.miloapis.com/email-provider-resend/internal/config"
 	"go.miloapis.com/email-provider-resend/internal/emailprovider"
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -36,8 +37,18 @@ import (
 const (
 	contactFinalizerKey       = "notification.miloapis.com/contact"
 	contactNamespacedIndexKey = "contact-namespaced-index"
+	contactProviderIDIndexKey = "status.providerID"
 )
 
+// contactProviderIDIndex returns the contactProviderIDIndexKey values of a Contact.
+func contactProviderIDIndex(rawObj client.Object) []string {
+	contact := rawObj.(*notificationmiloapiscomv1alpha1.Contact)
+	if contact.Status.ProviderID == "" {
+		return nil
+	}
+	return []string{contact.Status.ProviderID}
+}
+
 const (
 	// ResendContactReadyCondition is a condition that is set to true when the contact is ready
 	ResendContactReadyCondition = "ResendContactReady"
@@ -49,6 +60,9 @@ const (
 
 const (
 	LoopsContactReadyCondition = "LoopsContactReady"
+
+	// contactProviderReadyConditionSuffix follows the provider name in the provider contact ready conditions
+	contactProviderReadyConditionSuffix = "ContactReady"
 )
 
 // buildContactNamespacedIndexKey returns "<contact-ns>|<contact-name>"
@@ -58,9 +72,13 @@ func buildContactNamespacedIndexKey(contactName, contactNamespace string) string
 
 // ContactReconciler reconciles a Contact object
 type ContactController struct {
-	Client        client.Client
-	Finalizers    finalizer.Finalizers
-	EmailProvider emailprovider.Service
+	Client          client.Client
+	Finalizers      finalizer.Finalizers
+	EmailProvider   emailprovider.Service
+	SyncConfig      config.ProviderSyncConfig
+	ReadinessConfig config.ProviderReadinessConfig
+
+	drift driftTracker
 }
 
 // contactFinalizer is a finalizer for the Contact object
@@ -80,15 +98,26 @@ func (f *contactFinalizer) Finalize(ctx context.Context, obj client.Object) (fin
 		return finalizer.Result{}, fmt.Errorf("object is not a Contact")
 	}
 
-	// Delete Contact on email provider
-	deleted, err := f.EmailProvider.DeleteContact(ctx, *contact)
-	if err != nil && !errors.IsNotFound(err) {
-		log.Error(err, "Failed to delete Contact on email provider")
-		return finalizer.Result{}, fmt.Errorf("failed to delete Contact on email provider: %w", err)
+	// Contacts with the same email address adopt the same email provider contact, which is only deleted by the last
+	shared, err := f.isProviderContactShared(ctx, contact)
+	if err != nil {
+		log.Error(err, "Failed to list Contacts sharing the email provider contact")
+		return finalizer.Result{}, err
 	}
-	if err == nil && !deleted.Deleted {
-		log.Error(fmt.Errorf("failed to delete Contact on email provider. Expected deleted to be true, got %t", deleted.Deleted), "Failed to delete Contact on email provider")
-		return finalizer.Result{}, fmt.Errorf("failed to delete Contact on email provider. Expected deleted to be true, got %t", deleted.Deleted)
+
+	// Delete Contact on email provider
+	if shared {
+		log.Info("Email provider contact is used by another Contact. Keeping it.", "providerID", contact.Status.ProviderID)
+	} else {
+		deleted, err := f.EmailProvider.DeleteContact(ctx, *contact)
+		if err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete Contact on email provider")
+			return finalizer.Result{}, fmt.Errorf("failed to delete Contact on email provider: %w", err)
+		}
+		if err == nil && !deleted.Deleted {
+			log.Error(fmt.Errorf("failed to delete Contact on email provider. Expected deleted to be true, got %t", deleted.Deleted), "Failed to delete Contact on email provider")
+			return finalizer.Result{}, fmt.Errorf("failed to delete Contact on email provider. Expected deleted to be true, got %t", deleted.Deleted)
+		}
 	}
 
 	// Get associated ContactGroupMemberships to contact name
@@ -169,6 +198,10 @@ func (r *ContactController) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, fmt.Errorf("failed to get contact: %w", err)
 	}
 
+	if !contact.GetDeletionTimestamp().IsZero() {
+		r.drift.forget(contact.GetUID())
+	}
+
 	// Run finalizers
 	finalizeResult, err := r.Finalizers.Finalize(ctx, contact)
 	if err != nil {
@@ -196,23 +229,35 @@ func (r *ContactController) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	switch {
 	// First creation – condition not present yet
 	case resendReadyCond == nil:
-		// Create Contact on email provider
-		emailProviderContact, err := r.EmailProvider.CreateContactIdempotent(ctx, *contact)
+		// Contacts migrated from an existing email provider account adopt their contact
+		adopted, err := r.adoptProviderContact(ctx, contact)
 		if err != nil {
-			log.Error(err, "Failed to create Contact on email provider")
-			return ctrl.Result{}, fmt.Errorf("failed to create Contact on email provider: %w", err)
+			log.Error(err, "Failed to adopt Contact from email provider")
+			return ctrl.Result{}, err
 		}
-		contact.Status.ProviderID = emailProviderContact.ContactId
 
-		log.Info("Contact first creation")
-		meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
+		readyCond := metav1.Condition{
 			Type:               ResendContactReadyCondition,
 			Status:             metav1.ConditionTrue,
-			Reason:             ResendContactPendingReason,
-			Message:            "Contact created on email provider. Waiting for webhook confirmation.",
+			Reason:             ResendObjectAdoptedReason,
+			Message:            "Existing contact adopted from email provider",
 			LastTransitionTime: metav1.Now(),
 			ObservedGeneration: contact.GetGeneration(),
-		})
+		}
+		if !adopted {
+			// Create Contact on email provider
+			emailProviderContact, err := r.EmailProvider.CreateContactIdempotent(ctx, *contact)
+			if err != nil {
+				log.Error(err, "Failed to create Contact on email provider")
+				return ctrl.Result{}, fmt.Errorf("failed to create Contact on email provider: %w", err)
+			}
+			contact.Status.ProviderID = emailProviderContact.ContactId
+			readyCond.Reason = ResendContactPendingReason
+			readyCond.Message = "Contact created on email provider. Waiting for webhook confirmation."
+		}
+
+		log.Info("Contact first creation", "adopted", adopted)
+		meta.SetStatusCondition(&contact.Status.Conditions, readyCond)
 
 		// Set ContactUpdatedCondition to true to indicate that the contact is ready to accept incoming updates
 		meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
@@ -246,29 +291,20 @@ func (r *ContactController) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			ObservedGeneration: contact.GetGeneration(),
 		})
 
-		// Get associated ContactGroupMemberships to contact
-		contactGroupMemberships := &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}
-		err = r.Client.List(ctx, contactGroupMemberships, client.MatchingFields{contactNamespacedIndexKey: buildContactNamespacedIndexKey(contact.Name, contact.Namespace)})
-		if err != nil {
-			log.Error(err, "Failed to list ContactGroupMemberships")
-			return ctrl.Result{}, fmt.Errorf("failed to list ContactGroupMemberships: %w", err)
+		// Memberships only need to be synced again if the contact was recreated on the email provider.
+		// An in-place update keeps the contact in all its contact groups.
+		if emailProviderContact.Recreated {
+			if err := r.resyncContactGroupMemberships(ctx, contact, original, "ContactGroupMembership update requested from contact update"); err != nil {
+				return ctrl.Result{}, err
+			}
 		}
 
-		// Update ContactGroupMembership conditions
-		for _, cgm := range contactGroupMemberships.Items {
-			// Update ContactGroupMembership condition
-			// ContactGroupMembershipUpdateRequestedReason set so the ContactGroupMembershipController will update the ContactGroupMembership
-			meta.SetStatusCondition(&cgm.Status.Conditions, metav1.Condition{
-				Type:    notificationmiloapiscomv1alpha1.ContactGroupMembershipUpdatedCondition,
-				Status:  metav1.ConditionFalse,
-				Reason:  notificationmiloapiscomv1alpha1.ContactGroupMembershipUpdateRequestedReason,
-				Message: "ContactGroupMembership update requested from contact update",
-			})
-
-			// Update ContactGroupMembership status
-			if err := r.Client.Status().Update(ctx, &cgm); err != nil {
-				log.Error(err, "Failed to update ContactGroupMembership status")
-				return ctrl.Result{}, fmt.Errorf("failed to update ContactGroupMembership status: %w", err)
+	// Ready – verify the contact still exists on the email provider once per resync interval
+	default:
+		if r.SyncConfig.ResyncEnabled() && r.drift.due(contact.GetUID(), r.SyncConfig.GetResyncInterval()) {
+			if err := r.verifyProviderContact(ctx, contact, original); err != nil {
+				log.Error(err, "Failed to verify Contact on email provider")
+				return ctrl.Result{}, err
 			}
 		}
 	}
@@ -291,11 +327,114 @@ func (r *ContactController) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	log.Info("Contact reconciled")
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: r.drift.requeueAfter(contact.GetUID(), r.SyncConfig.GetResyncInterval())}, nil
+}
+
+// verifyProviderContact verifies the contact still exists on the email provider, and recreates it if it is missing.
+// A recreated contact is not part of any contact group anymore, so its memberships are flagged for update.
+// original is the contact as read from the API server, before any status change.
+func (r *ContactController) verifyProviderContact(ctx context.Context, contact, original *notificationmiloapiscomv1alpha1.Contact) error {
+	log := logf.FromContext(ctx).WithValues("controller", "ContactController", "trigger", contact.Name)
+
+	_, err := r.EmailProvider.GetContact(ctx, *contact)
+	switch {
+	case err == nil:
+		providerDriftChecksTotal.WithLabelValues("Contact", driftResultInSync).Inc()
+		setDriftCondition(&contact.Status.Conditions, contact.GetGeneration(), "Contact", false)
+
+	case errors.IsNotFound(err):
+		providerDriftChecksTotal.WithLabelValues("Contact", driftResultDrifted).Inc()
+		log.Info("Contact not found on email provider. Recreating it.", "providerID", contact.Status.ProviderID)
+		emailProviderContact, err := r.EmailProvider.CreateContactIdempotent(ctx, *contact)
+		if err != nil {
+			return fmt.Errorf("failed to recreate Contact on email provider: %w", err)
+		}
+		contact.Status.ProviderID = emailProviderContact.ContactId
+		providerDriftRecreationsTotal.WithLabelValues("Contact").Inc()
+		setDriftCondition(&contact.Status.Conditions, contact.GetGeneration(), "Contact", true)
+
+		if err := r.resyncContactGroupMemberships(ctx, contact, original, "ContactGroupMembership update requested as the contact was recreated on the email provider"); err != nil {
+			return err
+		}
+
+	default:
+		providerDriftChecksTotal.WithLabelValues("Contact", driftResultError).Inc()
+		return fmt.Errorf("failed to get Contact from email provider: %w", err)
+	}
+
+	r.drift.record(contact.GetUID())
+	return nil
+}
+
+// resyncContactGroupMemberships persists the contact provider id and flags every ContactGroupMembership of the contact
+// so they are created again on the email provider.
+func (r *ContactController) resyncContactGroupMemberships(ctx context.Context, contact, original *notificationmiloapiscomv1alpha1.Contact, message string) error {
+	// The new provider id must be persisted before the memberships are created again, otherwise they could add the
+	// previous contact back to the contact groups
+	if err := r.Client.Status().Patch(ctx, contact, client.MergeFrom(original), client.FieldOwner("contact-controller")); err != nil {
+		return fmt.Errorf("failed to patch contact status: %w", err)
+	}
+	return requestContactGroupMembershipsUpdate(ctx, r.Client, contactNamespacedIndexKey, buildContactNamespacedIndexKey(contact.Name, contact.Namespace), message)
+}
+
+// requestContactGroupMembershipsUpdate flags every ContactGroupMembership matching the given index so the
+// ContactGroupMembershipController creates them again on the email provider.
+func requestContactGroupMembershipsUpdate(ctx context.Context, c client.Client, indexKey, indexValue, message string) error {
+	log := logf.FromContext(ctx).WithValues("index", indexKey, "value", indexValue)
+
+	// Get associated ContactGroupMemberships
+	contactGroupMemberships := &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}
+	err := c.List(ctx, contactGroupMemberships, client.MatchingFields{indexKey: indexValue})
+	if err != nil {
+		log.Error(err, "Failed to list ContactGroupMemberships")
+		return fmt.Errorf("failed to list ContactGroupMemberships: %w", err)
+	}
+
+	// Update ContactGroupMembership conditions
+	for _, cgm := range contactGroupMemberships.Items {
+		// Update ContactGroupMembership condition
+		// ContactGroupMembershipUpdateRequestedReason set so the ContactGroupMembershipController will update the ContactGroupMembership
+		meta.SetStatusCondition(&cgm.Status.Conditions, metav1.Condition{
+			Type:    notificationmiloapiscomv1alpha1.ContactGroupMembershipUpdatedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  notificationmiloapiscomv1alpha1.ContactGroupMembershipUpdateRequestedReason,
+			Message: message,
+		})
+
+		// Update ContactGroupMembership status
+		if err := c.Status().Update(ctx, &cgm); err != nil {
+			log.Error(err, "Failed to update ContactGroupMembership status")
+			return fmt.Errorf("failed to update ContactGroupMembership status: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// isProviderContactShared reports whether another Contact references the email provider contact of the contact.
+func (f *contactFinalizer) isProviderContactShared(ctx context.Context, contact *notificationmiloapiscomv1alpha1.Contact) (bool, error) {
+	if contact.Status.ProviderID == "" {
+		return false, nil
+	}
+	contacts := &notificationmiloapiscomv1alpha1.ContactList{}
+	if err := f.Client.List(ctx, contacts, client.MatchingFields{contactProviderIDIndexKey: contact.Status.ProviderID}); err != nil {
+		return false, fmt.Errorf("failed to list Contacts by provider id: %w", err)
+	}
+	for _, other := range contacts.Items {
+		if other.UID != contact.UID {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ContactController) SetupWithManager(mgr ctrl.Manager) error {
+	// Index by provider id to find the Contacts sharing an email provider contact
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &notificationmiloapiscomv1alpha1.Contact{}, contactProviderIDIndexKey, contactProviderIDIndex); err != nil {
+		return fmt.Errorf("failed to index contact by provider id: %w", err)
+	}
+
 	// Index by contact group membership for efficient contact group membership lookup
 	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &notificationmiloapiscomv1alpha1.ContactGroupMembership{}, contactNamespacedIndexKey, func(rawObj client.Object) []string {
 		cgm := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
@@ -327,44 +466,7 @@ func (r *ContactController) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(r)
 }
 
-// verifyContactReadyCondition is a function that verifies the ContactReadyCondition
+// verifyContactReadyCondition aggregates the ready conditions of the required providers into the Ready condition
 func (r *ContactController) verifyContactReadyCondition(contact *notificationmiloapiscomv1alpha1.Contact) {
-	// Update contact status if it changed
-	// Aggregate readiness across providers: Loops and Resend
-	loopsCond := meta.FindStatusCondition(contact.Status.Conditions, LoopsContactReadyCondition)
-	resendCond := meta.FindStatusCondition(contact.Status.Conditions, ResendContactReadyCondition)
-	allReady := loopsCond != nil && loopsCond.Status == metav1.ConditionTrue &&
-		resendCond != nil && resendCond.Status == metav1.ConditionTrue
-
-	if allReady {
-		meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
-			Type:               notificationmiloapiscomv1alpha1.ContactReadyCondition,
-			Status:             metav1.ConditionTrue,
-			Reason:             "AllProvidersReady",
-			Message:            "Loops and Resend contacts are ready",
-			LastTransitionTime: metav1.Now(),
-			ObservedGeneration: contact.GetGeneration(),
-		})
-	} else {
-		// Build informative not-ready message
-		notReadyMsg := ""
-		if loopsCond == nil {
-			notReadyMsg += "Loops: condition missing; "
-		} else if loopsCond.Status != metav1.ConditionTrue {
-			notReadyMsg += fmt.Sprintf("Loops: %s (%s); ", loopsCond.Reason, loopsCond.Message)
-		}
-		if resendCond == nil {
-			notReadyMsg += "Resend: condition missing; "
-		} else if resendCond.Status != metav1.ConditionTrue {
-			notReadyMsg += fmt.Sprintf("Resend: %s (%s); ", resendCond.Reason, resendCond.Message)
-		}
-		meta.SetStatusCondition(&contact.Status.Conditions, metav1.Condition{
-			Type:               notificationmiloapiscomv1alpha1.ContactReadyCondition,
-			Status:             metav1.ConditionFalse,
-			Reason:             "ProvidersNotReady",
-			Message:            notReadyMsg,
-			LastTransitionTime: metav1.Now(),
-			ObservedGeneration: contact.GetGeneration(),
-		})
-	}
+	setAggregatedReadyCondition(&contact.Status.Conditions, contact.GetGeneration(), r.ReadinessConfig, notificationmiloapiscomv1alpha1.ContactReadyCondition, contactProviderReadyConditionSuffix)
 }
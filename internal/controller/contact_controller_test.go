@@ -2,19 +2,25 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	ginkgo "github.com/onsi/ginkgo/v2"
 	gomega "github.com/onsi/gomega"
 	notificationv1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	finalizerpkg "sigs.k8s.io/controller-runtime/pkg/finalizer"
 
+	"go.miloapis.com/email-provider-resend/internal/config"
 	"go.miloapis.com/email-provider-resend/internal/emailprovider"
 	"go.miloapis.com/email-provider-resend/internal/emailprovider/mockprovider"
 )
@@ -90,12 +96,85 @@ var _ = ginkgo.Describe("ContactController", func() {
 		})
 	})
 
+	ginkgo.Context("when the contact already exists on the provider", func() {
+		ginkgo.It("adopts the existing provider contact", func() {
+			prov.CreateContactErr = apierrors.NewAlreadyExists(schema.GroupResource{Group: "resend", Resource: "contacts"}, "john@example.com")
+			prov.GetContactOutput = emailprovider.GetContactOutput{ContactId: "c-existing", Email: "john@example.com", GivenName: "John", FamilyName: "Doe"}
+
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			fetched := &notificationv1.Contact{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}, fetched)).To(gomega.Succeed())
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("c-existing"))
+			gomega.Expect(prov.LastGetContactInput.Email).To(gomega.Equal("john@example.com"))
+			// Names already match, no update needed
+			gomega.Expect(prov.UpdateContactCallCount).To(gomega.Equal(0))
+		})
+	})
+
+	ginkgo.Context("when the contact creation fails for another reason", func() {
+		ginkgo.It("does not adopt the provider contact with the same email address", func() {
+			prov.CreateContactErr = fmt.Errorf("rate limited")
+			prov.GetContactOutput = emailprovider.GetContactOutput{ContactId: "c-existing", Email: "john@example.com"}
+
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}})
+			gomega.Expect(err).To(gomega.HaveOccurred())
+
+			fetched := &notificationv1.Contact{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}, fetched)).To(gomega.Succeed())
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.BeEmpty())
+			gomega.Expect(prov.GetContactCallCount).To(gomega.BeZero())
+		})
+	})
+
+	ginkgo.Context("when the contact was migrated from the provider", func() {
+		ginkgo.It("adopts the referenced provider contact without creating it", func() {
+			existing := &notificationv1.Contact{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}, existing)).To(gomega.Succeed())
+			existing.Annotations = map[string]string{AdoptProviderIDAnnotation: "c-legacy"}
+			gomega.Expect(k8sClient.Update(ctx, existing)).To(gomega.Succeed())
+			prov.GetContactOutput = emailprovider.GetContactOutput{ContactId: "c-legacy", Email: "John@example.com"}
+
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			fetched := &notificationv1.Contact{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}, fetched)).To(gomega.Succeed())
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("c-legacy"))
+			resendCond := meta.FindStatusCondition(fetched.Status.Conditions, ResendContactReadyCondition)
+			gomega.Expect(resendCond).NotTo(gomega.BeNil())
+			gomega.Expect(resendCond.Reason).To(gomega.Equal(ResendObjectAdoptedReason))
+			gomega.Expect(prov.LastGetContactInput.ContactId).To(gomega.Equal("c-legacy"))
+			gomega.Expect(prov.CreateContactCallCount).To(gomega.Equal(0))
+		})
+
+		ginkgo.It("creates the contact when the provider contact has another email", func() {
+			existing := &notificationv1.Contact{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}, existing)).To(gomega.Succeed())
+			existing.Annotations = map[string]string{AdoptProviderIDAnnotation: "c-legacy"}
+			gomega.Expect(k8sClient.Update(ctx, existing)).To(gomega.Succeed())
+			prov.GetContactOutput = emailprovider.GetContactOutput{ContactId: "c-legacy", Email: "jane@example.com"}
+
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			fetched := &notificationv1.Contact{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}, fetched)).To(gomega.Succeed())
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("c-123"))
+			gomega.Expect(prov.CreateContactCallCount).To(gomega.Equal(1))
+		})
+	})
+
 	ginkgo.Context("when the contact is updated", func() {
 		ginkgo.It("sets the Updated condition", func() {
 			// First reconcile to add Ready condition with observedGeneration 1
 			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}})
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
+			// Provider still holds the same email address
+			prov.GetContactOutput = emailprovider.GetContactOutput{ContactId: "c-123", Email: "john@example.com"}
+
 			// Simulate spec update -> increase generation
 			fetched := &notificationv1.Contact{}
 			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}, fetched)).To(gomega.Succeed())
@@ -116,11 +195,94 @@ var _ = ginkgo.Describe("ContactController", func() {
 			gomega.Expect(cond).NotTo(gomega.BeNil())
 			gomega.Expect(cond.ObservedGeneration).To(gomega.Equal(int64(2)))
 
+			// Provider should have been called to update the contact in place
+			gomega.Expect(prov.UpdateContactCallCount).To(gomega.Equal(1))
+			gomega.Expect(prov.LastUpdateContactInput.FamilyName).To(gomega.Equal("Smith"))
+			gomega.Expect(prov.DeleteContactCallCount).To(gomega.Equal(0))
+			gomega.Expect(prov.CreateContactCallCount).To(gomega.Equal(1)) // initial only
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("c-123"))
+		})
+
+		ginkgo.It("recreates the contact when the email address changes", func() {
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			prov.GetContactOutput = emailprovider.GetContactOutput{ContactId: "c-123", Email: "john@example.com"}
+			prov.CreateContactOutput = emailprovider.CreateContactOutput{ContactId: "c-456"}
+
+			fetched := &notificationv1.Contact{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}, fetched)).To(gomega.Succeed())
+			fetched.Spec.Email = "john.doe@example.com"
+			fetched.Generation = 2
+			gomega.Expect(k8sClient.Update(ctx, fetched)).To(gomega.Succeed())
+
+			_, err = controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}, fetched)).To(gomega.Succeed())
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("c-456"))
+
 			// Provider should have been called to delete and recreate contact
+			gomega.Expect(prov.UpdateContactCallCount).To(gomega.Equal(0))
 			gomega.Expect(prov.DeleteContactCallCount).To(gomega.Equal(1))
 			gomega.Expect(prov.CreateContactCallCount).To(gomega.Equal(2)) // initial plus recreate
 		})
 	})
+
+	ginkgo.Context("when the contact is missing on the provider", func() {
+		ginkgo.It("persists the recreated contact before flagging its memberships", func() {
+			membership := &notificationv1.ContactGroupMembership{
+				ObjectMeta: metav1.ObjectMeta{Name: "john-doe-devs", Namespace: "default"},
+				Spec: notificationv1.ContactGroupMembershipSpec{
+					ContactRef:      notificationv1.ContactReference{Name: contact.Name, Namespace: contact.Namespace},
+					ContactGroupRef: notificationv1.ContactGroupReference{Name: "devs", Namespace: "default"},
+				},
+			}
+			// Records the contact provider id persisted when the membership is flagged for update
+			var providerIDWhenFlagged string
+			k8sClient = fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithStatusSubresource(&notificationv1.Contact{}, &notificationv1.ContactGroupMembership{}).
+				WithObjects(contact.DeepCopy(), membership).
+				WithIndex(&notificationv1.ContactGroupMembership{}, contactNamespacedIndexKey, func(raw client.Object) []string {
+					c := raw.(*notificationv1.ContactGroupMembership)
+					return []string{buildContactNamespacedIndexKey(c.Spec.ContactRef.Name, c.Spec.ContactRef.Namespace)}
+				}).
+				WithInterceptorFuncs(interceptor.Funcs{
+					SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+						if _, ok := obj.(*notificationv1.ContactGroupMembership); ok {
+							persisted := &notificationv1.Contact{}
+							if err := c.Get(ctx, types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}, persisted); err != nil {
+								return err
+							}
+							providerIDWhenFlagged = persisted.Status.ProviderID
+						}
+						return c.SubResource(subResourceName).Update(ctx, obj, opts...)
+					},
+				}).
+				Build()
+			syncConfig, err := config.NewProviderSyncConfig(time.Hour, false)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			controller.Client = k8sClient
+			controller.SyncConfig = *syncConfig
+
+			_, err = controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			prov.GetContactErr = apierrors.NewNotFound(schema.GroupResource{Group: "resend", Resource: "contacts"}, "c-123")
+			prov.CreateContactOutput = emailprovider.CreateContactOutput{ContactId: "c-456"}
+			controller.drift.expire(contact.UID)
+			_, err = controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: contact.Name, Namespace: contact.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			gomega.Expect(providerIDWhenFlagged).To(gomega.Equal("c-456"))
+			fetched := &notificationv1.ContactGroupMembership{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: membership.Name, Namespace: membership.Namespace}, fetched)).To(gomega.Succeed())
+			updated := meta.FindStatusCondition(fetched.Status.Conditions, notificationv1.ContactGroupMembershipUpdatedCondition)
+			gomega.Expect(updated).NotTo(gomega.BeNil())
+			gomega.Expect(updated.Reason).To(gomega.Equal(notificationv1.ContactGroupMembershipUpdateRequestedReason))
+		})
+	})
 })
 
 var _ = ginkgo.Describe("contactFinalizer", func() {
@@ -183,6 +345,40 @@ var _ = ginkgo.Describe("contactFinalizer", func() {
 		gomega.Expect(prov.DeleteContactCallCount).To(gomega.Equal(1))
 	})
 
+	ginkgo.It("keeps the provider contact while another Contact uses it", func() {
+		withProviderID := contact.DeepCopy()
+		withProviderID.UID = "contact-uid"
+		withProviderID.Status.ProviderID = "c-shared"
+		other := &notificationv1.Contact{
+			ObjectMeta: metav1.ObjectMeta{Name: "john-doe", Namespace: "other-ns", UID: "other-contact-uid"},
+			Spec:       notificationv1.ContactSpec{Email: "john@example.com"},
+			Status:     notificationv1.ContactStatus{ProviderID: "c-shared"},
+		}
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(withProviderID.DeepCopy(), other).
+			WithIndex(&notificationv1.Contact{}, contactProviderIDIndexKey, contactProviderIDIndex).
+			WithIndex(&notificationv1.ContactGroupMembership{}, contactNamespacedIndexKey, func(raw client.Object) []string {
+				c := raw.(*notificationv1.ContactGroupMembership)
+				return []string{buildContactNamespacedIndexKey(c.Spec.ContactRef.Name, c.Spec.ContactRef.Namespace)}
+			}).
+			WithIndex(&notificationv1.ContactGroupMembershipRemoval{}, contactNamespacedIndexKey, func(raw client.Object) []string {
+				c := raw.(*notificationv1.ContactGroupMembershipRemoval)
+				return []string{buildContactNamespacedIndexKey(c.Spec.ContactRef.Name, c.Spec.ContactRef.Namespace)}
+			}).
+			Build()
+		finalizer.Client = k8sClient
+
+		_, err := finalizer.Finalize(ctx, withProviderID)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(prov.DeleteContactCallCount).To(gomega.BeZero())
+
+		gomega.Expect(k8sClient.Delete(ctx, other)).To(gomega.Succeed())
+		_, err = finalizer.Finalize(ctx, withProviderID)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(prov.DeleteContactCallCount).To(gomega.Equal(1))
+	})
+
 	ginkgo.It("ignores memberships in other namespaces", func() {
 		// recreate client with membership in another ns
 		other := cgm.DeepCopy()
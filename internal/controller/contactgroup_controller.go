@@ -19,7 +19,10 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
+	"go.miloapis.com/email-provider-resend/internal/config"
 	"go.miloapis.com/email-provider-resend/internal/emailprovider"
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -36,8 +39,18 @@ const (
 	contactGroupFinalizerKey             = "notification.miloapis.com/contactgroup"
 	contactGroupNamespacedIndexKey       = "contactgroup-namespaced-index"
 	contactGroupToCgmrNamespacedIndexKey = "contactgroup-to-cgmr-namespaced-index"
+	contactGroupProviderIDIndexKey       = "status.providerID"
 )
 
+// contactGroupProviderIDIndex returns the contactGroupProviderIDIndexKey values of a ContactGroup.
+func contactGroupProviderIDIndex(rawObj client.Object) []string {
+	contactGroup := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroup)
+	if contactGroup.Status.ProviderID == "" {
+		return nil
+	}
+	return []string{contactGroup.Status.ProviderID}
+}
+
 // buildContactGroupNamespacedIndexKey returns "<group-ns>|<group-name>"
 func buildContactGroupNamespacedIndexKey(cgName, cgNamespace string) string {
 	return fmt.Sprintf("%s|%s", cgNamespace, cgName)
@@ -48,6 +61,10 @@ type ContactGroupController struct {
 	Client        client.Client
 	Finalizers    finalizer.Finalizers
 	EmailProvider emailprovider.Service
+	SyncConfig    config.ProviderSyncConfig
+
+	drift   driftTracker
+	members driftTracker
 }
 
 // contactGroupFinalizer is a finalizer for the ContactGroup object
@@ -120,6 +137,14 @@ func (f *contactGroupFinalizer) Finalize(ctx context.Context, obj client.Object)
 		}
 	}
 
+	// Delete email provider contact group topic
+	if topicID := contactGroupTopicID(contactGroup); topicID != "" {
+		if _, err := f.EmailProvider.DeleteTopic(ctx, topicID); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete ContactGroup topic from email provider")
+			return finalizer.Result{}, fmt.Errorf("failed to delete ContactGroup topic from email provider: %w", err)
+		}
+	}
+
 	// Delete email provider contact group
 	// 1. Get contact group from email provider
 	_, err = f.EmailProvider.GetContactGroup(ctx, *contactGroup)
@@ -151,10 +176,12 @@ func (f *contactGroupFinalizer) Finalize(ctx context.Context, obj client.Object)
 	return finalizer.Result{}, nil
 }
 
-// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups,verbs=get;list;watch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups,verbs=get;list;watch;patch
 // +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups/status,verbs=get;update
 // +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups/finalizers,verbs=update
 // +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contacts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create
 
 // Reconcile is the main function that reconciles the ContactGroup object
 func (r *ContactGroupController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -173,6 +200,11 @@ func (r *ContactGroupController) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, fmt.Errorf("failed to get ContactGroup: %w", err)
 	}
 
+	if !contactGroup.GetDeletionTimestamp().IsZero() {
+		r.drift.forget(contactGroup.GetUID())
+		r.members.forget(contactGroup.GetUID())
+	}
+
 	// Run finalizers
 	finalizeResult, err := r.Finalizers.Finalize(ctx, contactGroup)
 	if err != nil {
@@ -194,25 +226,46 @@ func (r *ContactGroupController) Reconcile(ctx context.Context, req ctrl.Request
 	switch {
 	// First creation – condition not present yet
 	case existingCond == nil:
-		// Create ContactGroup on email provider
-		emailProviderContactGroup, err := r.EmailProvider.CreateContactGroup(ctx, *contactGroup)
+		// Contact groups migrated from an existing email provider account adopt their contact group
+		adopted, err := r.adoptProviderContactGroup(ctx, contactGroup)
 		if err != nil {
-			log.Error(err, "Failed to create ContactGroup on email provider")
-			return ctrl.Result{}, fmt.Errorf("failed to create ContactGroup on email provider: %w", err)
+			log.Error(err, "Failed to adopt ContactGroup from email provider")
+			return ctrl.Result{}, err
 		}
-		contactGroup.Status.ProviderID = emailProviderContactGroup.ContactGroupID
-		meta.SetStatusCondition(&contactGroup.Status.Conditions, metav1.Condition{
+
+		readyCond := metav1.Condition{
 			Type:               notificationmiloapiscomv1alpha1.ContactGroupReadyCondition,
 			Status:             metav1.ConditionTrue,
-			Reason:             notificationmiloapiscomv1alpha1.ContactGroupCreatedReason,
-			Message:            "Contact group created on email provider",
+			Reason:             ResendObjectAdoptedReason,
+			Message:            "Existing contact group adopted from email provider",
 			LastTransitionTime: metav1.Now(),
 			ObservedGeneration: contactGroup.GetGeneration(),
-		})
+		}
+		if !adopted {
+			// Create ContactGroup on email provider
+			emailProviderContactGroup, err := r.EmailProvider.CreateContactGroup(ctx, *contactGroup)
+			if err != nil {
+				log.Error(err, "Failed to create ContactGroup on email provider")
+				return ctrl.Result{}, fmt.Errorf("failed to create ContactGroup on email provider: %w", err)
+			}
+			contactGroup.Status.ProviderID = emailProviderContactGroup.ContactGroupID
+			readyCond.Reason = notificationmiloapiscomv1alpha1.ContactGroupCreatedReason
+			readyCond.Message = "Contact group created on email provider"
+		}
+		meta.SetStatusCondition(&contactGroup.Status.Conditions, readyCond)
 
 	// Update – generation changed since we last processed the object
 	case existingCond.ObservedGeneration != contactGroup.GetGeneration():
-		// No update on email provider needed, displayName change do not impact the contact group on the email provider.
+		// The contact group name on the email provider is derived from the display name, so it may need to be renamed
+		emailProviderContactGroup, err := r.EmailProvider.GetContactGroup(ctx, *contactGroup)
+		if err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to get ContactGroup from email provider")
+			return ctrl.Result{}, fmt.Errorf("failed to get ContactGroup from email provider: %w", err)
+		}
+		if err := r.syncProviderContactGroupName(ctx, contactGroup, emailProviderContactGroup.DisplayName); err != nil {
+			log.Error(err, "Failed to rename ContactGroup on email provider")
+			return ctrl.Result{}, err
+		}
 		meta.SetStatusCondition(&contactGroup.Status.Conditions, metav1.Condition{
 			Type:               notificationmiloapiscomv1alpha1.ContactGroupUpdatedCondition,
 			Status:             metav1.ConditionTrue,
@@ -221,6 +274,30 @@ func (r *ContactGroupController) Reconcile(ctx context.Context, req ctrl.Request
 			LastTransitionTime: metav1.Now(),
 			ObservedGeneration: contactGroup.GetGeneration(),
 		})
+
+	// Ready – verify the contact group still exists on the email provider once per resync interval
+	default:
+		if r.SyncConfig.ResyncEnabled() && r.drift.due(contactGroup.GetUID(), r.SyncConfig.GetResyncInterval()) {
+			if err := r.verifyProviderContactGroup(ctx, contactGroup); err != nil {
+				log.Error(err, "Failed to verify ContactGroup on email provider")
+				return ctrl.Result{}, err
+			}
+		}
+		// A contact group recreated on the email provider has no members until its memberships are synced again
+		if r.SyncConfig.ResyncEnabled() && contactGroup.Status.ProviderID == oldStatus.ProviderID &&
+			r.members.due(contactGroup.GetUID(), r.SyncConfig.GetResyncInterval()) {
+			if err := r.verifyContactGroupMembers(ctx, contactGroup); err != nil {
+				log.Error(err, "Failed to verify ContactGroup members on email provider")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	// Public contact groups are mapped to a topic on the email provider
+	specChanged := existingCond != nil && existingCond.ObservedGeneration != contactGroup.GetGeneration()
+	if err := r.reconcileContactGroupTopic(ctx, contactGroup, specChanged); err != nil {
+		log.Error(err, "Failed to reconcile ContactGroup topic on email provider")
+		return ctrl.Result{}, err
 	}
 
 	// Update status if it changed
@@ -235,7 +312,98 @@ func (r *ContactGroupController) Reconcile(ctx context.Context, req ctrl.Request
 
 	log.Info("Contact group reconciled")
 
-	return ctrl.Result{}, nil
+	interval := r.SyncConfig.GetResyncInterval()
+	return ctrl.Result{RequeueAfter: min(r.drift.requeueAfter(contactGroup.GetUID(), interval), r.members.requeueAfter(contactGroup.GetUID(), interval))}, nil
+}
+
+// verifyProviderContactGroup verifies the contact group still exists on the email provider, and recreates it if it is missing.
+// A recreated contact group has no contacts, so its memberships are flagged for update.
+func (r *ContactGroupController) verifyProviderContactGroup(ctx context.Context, contactGroup *notificationmiloapiscomv1alpha1.ContactGroup) error {
+	log := logf.FromContext(ctx).WithValues("controller", "ContactGroupController", "trigger", contactGroup.Name)
+
+	emailProviderContactGroup, err := r.EmailProvider.GetContactGroup(ctx, *contactGroup)
+	switch {
+	case err == nil:
+		providerDriftChecksTotal.WithLabelValues("ContactGroup", driftResultInSync).Inc()
+		setDriftCondition(&contactGroup.Status.Conditions, contactGroup.GetGeneration(), "ContactGroup", false)
+
+		// Contact groups created before names were derived from the UID are migrated to the current naming scheme
+		if err := r.syncProviderContactGroupName(ctx, contactGroup, emailProviderContactGroup.DisplayName); err != nil {
+			return err
+		}
+
+	case errors.IsNotFound(err):
+		providerDriftChecksTotal.WithLabelValues("ContactGroup", driftResultDrifted).Inc()
+		log.Info("ContactGroup not found on email provider. Recreating it.", "providerID", contactGroup.Status.ProviderID)
+		emailProviderContactGroup, err := r.EmailProvider.CreateContactGroup(ctx, *contactGroup)
+		if err != nil {
+			return fmt.Errorf("failed to recreate ContactGroup on email provider: %w", err)
+		}
+		contactGroup.Status.ProviderID = emailProviderContactGroup.ContactGroupID
+		providerDriftRecreationsTotal.WithLabelValues("ContactGroup").Inc()
+		setDriftCondition(&contactGroup.Status.Conditions, contactGroup.GetGeneration(), "ContactGroup", true)
+
+		if err := r.resyncContactGroupMemberships(ctx, contactGroup, "ContactGroupMembership update requested as the contact group was recreated on the email provider"); err != nil {
+			return err
+		}
+
+	default:
+		providerDriftChecksTotal.WithLabelValues("ContactGroup", driftResultError).Inc()
+		return fmt.Errorf("failed to get ContactGroup from email provider: %w", err)
+	}
+
+	r.drift.record(contactGroup.GetUID())
+	return nil
+}
+
+// syncProviderContactGroupName renames the contact group on the email provider if its current name does not match the
+// deterministic display name, e.g. because the display name changed. The renamed contact group has no contacts, so its
+// memberships are flagged for update. Contact groups still using the legacy name, which may be shared with other
+// ContactGroups, are always migrated. As renaming recreates the contact group, display name changes only rename it
+// when enabled in the sync config; otherwise it keeps its name, which stays unique as it is derived from the UID.
+// Adopted contact groups are never renamed.
+func (r *ContactGroupController) syncProviderContactGroupName(ctx context.Context, contactGroup *notificationmiloapiscomv1alpha1.ContactGroup, providerDisplayName string) error {
+	log := logf.FromContext(ctx).WithValues("controller", "ContactGroupController", "trigger", contactGroup.Name)
+
+	displayName := emailprovider.GetDeterministicContactGroupDisplayName(contactGroup)
+	if providerDisplayName == displayName {
+		return nil
+	}
+	if strings.HasSuffix(providerDisplayName, "-"+string(contactGroup.UID)) && !r.SyncConfig.RenameContactGroupsEnabled() {
+		return nil
+	}
+	// Adopted contact groups keep their name, renaming would recreate them and lose their history
+	if isAdoptedProviderID(contactGroup, contactGroup.Status.ProviderID) {
+		return nil
+	}
+
+	// Contact groups using the legacy name may be shared, so the previous one is only deleted by the last to leave it
+	sharing := &notificationmiloapiscomv1alpha1.ContactGroupList{}
+	if err := r.Client.List(ctx, sharing, client.MatchingFields{contactGroupProviderIDIndexKey: contactGroup.Status.ProviderID}); err != nil {
+		return fmt.Errorf("failed to list ContactGroups sharing the email provider contact group: %w", err)
+	}
+	shared := slices.ContainsFunc(sharing.Items, func(other notificationmiloapiscomv1alpha1.ContactGroup) bool {
+		return other.UID != contactGroup.UID
+	})
+
+	log.Info("Renaming ContactGroup on email provider", "from", providerDisplayName, "to", displayName, "shared", shared)
+	renamed, err := r.EmailProvider.RenameContactGroup(ctx, *contactGroup, !shared)
+	if err != nil {
+		return fmt.Errorf("failed to rename ContactGroup on email provider: %w", err)
+	}
+	contactGroup.Status.ProviderID = renamed.ContactGroupID
+
+	return r.resyncContactGroupMemberships(ctx, contactGroup, "ContactGroupMembership update requested as the contact group was renamed on the email provider")
+}
+
+// resyncContactGroupMemberships persists the contact group provider id and flags every ContactGroupMembership of the
+// contact group so they are created again on the email provider.
+func (r *ContactGroupController) resyncContactGroupMemberships(ctx context.Context, contactGroup *notificationmiloapiscomv1alpha1.ContactGroup, message string) error {
+	// The new provider id must be persisted before the memberships are created again
+	if err := r.Client.Status().Update(ctx, contactGroup); err != nil {
+		return fmt.Errorf("failed to update contact group status: %w", err)
+	}
+	return requestContactGroupMembershipsUpdate(ctx, r.Client, contactGroupNamespacedIndexKey, buildContactGroupNamespacedIndexKey(contactGroup.Name, contactGroup.Namespace), message)
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -248,6 +416,11 @@ func (r *ContactGroupController) SetupWithManager(mgr ctrl.Manager) error {
 		return fmt.Errorf("failed to index contactgroupmembership by contact name: %w", err)
 	}
 
+	// Index by provider id to find the ContactGroups sharing an email provider contact group
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &notificationmiloapiscomv1alpha1.ContactGroup{}, contactGroupProviderIDIndexKey, contactGroupProviderIDIndex); err != nil {
+		return fmt.Errorf("failed to index contactgroup by provider id: %w", err)
+	}
+
 	// Index by contact group membership removal for efficient contact group membership removal lookup
 	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{}, contactGroupToCgmrNamespacedIndexKey, func(rawObj client.Object) []string {
 		cgmr := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval)
@@ -2,12 +2,16 @@ package controller
 
 import (
 	"context"
+	"time"
 
 	ginkgo "github.com/onsi/ginkgo/v2"
 	gomega "github.com/onsi/gomega"
 	notificationv1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	eventsv1 "k8s.io/api/events/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -15,6 +19,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	finalizerpkg "sigs.k8s.io/controller-runtime/pkg/finalizer"
 
+	"go.miloapis.com/email-provider-resend/internal/config"
 	"go.miloapis.com/email-provider-resend/internal/emailprovider"
 	"go.miloapis.com/email-provider-resend/internal/emailprovider/mockprovider"
 )
@@ -50,6 +55,11 @@ var _ = ginkgo.Describe("ContactGroupController", func() {
 			WithScheme(sch).
 			WithStatusSubresource(&notificationv1.ContactGroup{}).
 			WithObjects(group.DeepCopy()).
+			WithIndex(&notificationv1.ContactGroupMembership{}, contactGroupNamespacedIndexKey, func(raw client.Object) []string {
+				c := raw.(*notificationv1.ContactGroupMembership)
+				return []string{buildContactGroupNamespacedIndexKey(c.Spec.ContactGroupRef.Name, c.Spec.ContactGroupRef.Namespace)}
+			}).
+			WithIndex(&notificationv1.ContactGroup{}, contactGroupProviderIDIndexKey, contactGroupProviderIDIndex).
 			Build()
 
 		controller = &ContactGroupController{Client: k8sClient, EmailProvider: *svc}
@@ -59,7 +69,7 @@ var _ = ginkgo.Describe("ContactGroupController", func() {
 	ginkgo.Context("when the contact group is created for the first time", func() {
 		ginkgo.It("sets the Ready condition and providerID", func() {
 			// Stub provider so that ListContactGroups returns empty, triggering CreateContactGroup.
-			provider.ListContactGroupsOutput = emailprovider.ListContactGroupsOutput{}
+			provider.ListContactGroupsOutput = nil
 			provider.CreateContactGroupOutput = emailprovider.CreateContactGroupOutput{ContactGroupID: "cg-123"}
 
 			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: group.Name, Namespace: group.Namespace}})
@@ -76,10 +86,57 @@ var _ = ginkgo.Describe("ContactGroupController", func() {
 		})
 	})
 
+	ginkgo.Context("when a segment with the deterministic name already exists", func() {
+		ginkgo.It("adopts the existing segment instead of creating a new one", func() {
+			provider.ListContactGroupsOutput = []emailprovider.GetContactGroupOutput{
+				{ContactGroupID: "cg-existing", DisplayName: emailprovider.GetDeterministicContactGroupDisplayName(group)},
+			}
+
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: group.Name, Namespace: group.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			fetched := &notificationv1.ContactGroup{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: group.Name, Namespace: group.Namespace}, fetched)).To(gomega.Succeed())
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("cg-existing"))
+			gomega.Expect(provider.CreatedGroups).To(gomega.BeEmpty())
+		})
+	})
+
+	ginkgo.Context("when the contact group was migrated from the provider", func() {
+		ginkgo.It("adopts the referenced segment and keeps its name", func() {
+			existing := &notificationv1.ContactGroup{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: group.Name, Namespace: group.Namespace}, existing)).To(gomega.Succeed())
+			existing.Annotations = map[string]string{AdoptProviderIDAnnotation: "cg-legacy"}
+			gomega.Expect(k8sClient.Update(ctx, existing)).To(gomega.Succeed())
+			provider.GetContactGroupOutput = emailprovider.GetContactGroupOutput{ContactGroupID: "cg-legacy", DisplayName: "Legacy newsletter"}
+
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: group.Name, Namespace: group.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			fetched := &notificationv1.ContactGroup{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: group.Name, Namespace: group.Namespace}, fetched)).To(gomega.Succeed())
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("cg-legacy"))
+			cond := meta.FindStatusCondition(fetched.Status.Conditions, notificationv1.ContactGroupReadyCondition)
+			gomega.Expect(cond).NotTo(gomega.BeNil())
+			gomega.Expect(cond.Reason).To(gomega.Equal(ResendObjectAdoptedReason))
+			gomega.Expect(provider.CreatedGroups).To(gomega.BeEmpty())
+
+			// An update of the adopted contact group does not rename the segment
+			fetched.Spec.DisplayName = "Newsletter"
+			gomega.Expect(k8sClient.Update(ctx, fetched)).To(gomega.Succeed())
+			_, err = controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: group.Name, Namespace: group.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: group.Name, Namespace: group.Namespace}, fetched)).To(gomega.Succeed())
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("cg-legacy"))
+			gomega.Expect(provider.CreatedGroups).To(gomega.BeEmpty())
+			gomega.Expect(provider.DeletedGroupID).To(gomega.BeEmpty())
+		})
+	})
+
 	ginkgo.Context("when the contact group is updated", func() {
-		ginkgo.It("sets the Updated condition", func() {
-			// First reconcile to create it
-			provider.ListContactGroupsOutput = emailprovider.ListContactGroupsOutput{}
+		// updateDisplayName creates the contact group as cg-123, then changes its display name and reconciles it again
+		updateDisplayName := func() *notificationv1.ContactGroup {
+			provider.ListContactGroupsOutput = nil
 			provider.CreateContactGroupOutput = emailprovider.CreateContactGroupOutput{ContactGroupID: "cg-123"}
 			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: group.Name, Namespace: group.Namespace}})
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
@@ -92,6 +149,8 @@ var _ = ginkgo.Describe("ContactGroupController", func() {
 			fetched.Generation = 2
 			gomega.Expect(k8sClient.Update(ctx, fetched)).To(gomega.Succeed())
 
+			provider.GetContactGroupOutput = emailprovider.GetContactGroupOutput{ContactGroupID: "cg-123", DisplayName: emailprovider.GetDeterministicContactGroupDisplayName(group)}
+			provider.CreateContactGroupOutput = emailprovider.CreateContactGroupOutput{ContactGroupID: "cg-456"}
 			_, err = controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: group.Name, Namespace: group.Namespace}})
 			gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
@@ -99,6 +158,28 @@ var _ = ginkgo.Describe("ContactGroupController", func() {
 			cond := meta.FindStatusCondition(fetched.Status.Conditions, notificationv1.ContactGroupUpdatedCondition)
 			gomega.Expect(cond).NotTo(gomega.BeNil())
 			gomega.Expect(cond.ObservedGeneration).To(gomega.Equal(int64(2)))
+			return fetched
+		}
+
+		ginkgo.It("keeps the contact group on the email provider and sets the Updated condition", func() {
+			fetched := updateDisplayName()
+
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("cg-123"))
+			gomega.Expect(provider.CreatedGroups).To(gomega.HaveLen(1))
+			gomega.Expect(provider.DeletedGroupID).To(gomega.BeEmpty())
+		})
+
+		ginkgo.It("renames the contact group on the email provider when renaming is enabled", func() {
+			syncConfig, err := config.NewProviderSyncConfig(time.Hour, true)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			controller.SyncConfig = *syncConfig
+
+			fetched := updateDisplayName()
+
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("cg-456"))
+			gomega.Expect(provider.CreatedGroups).To(gomega.HaveLen(2))
+			gomega.Expect(provider.CreatedGroups[1].DisplayName).To(gomega.HavePrefix("Devs-"))
+			gomega.Expect(provider.DeletedGroupID).To(gomega.Equal("cg-123"))
 		})
 	})
 })
@@ -188,3 +269,428 @@ var _ = ginkgo.Describe("contactGroupFinalizer", func() {
 		gomega.Expect(list.Items).To(gomega.HaveLen(1)) // still present
 	})
 })
+
+var _ = ginkgo.Describe("ContactGroupController drift detection", func() {
+	var (
+		ctx        context.Context
+		k8sClient  client.Client
+		controller *ContactGroupController
+		group      *notificationv1.ContactGroup
+		cgm        *notificationv1.ContactGroupMembership
+		provider   *mockprovider.MockEmailProvider
+		req        ctrl.Request
+	)
+
+	ginkgo.BeforeEach(func() {
+		ctx = context.Background()
+		provider = &mockprovider.MockEmailProvider{}
+		svc := emailprovider.NewService(provider, "from@example.com", "reply@example.com")
+
+		group = &notificationv1.ContactGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "dev-team", Namespace: "default", UID: "cg-uid", Generation: 1},
+			Spec:       notificationv1.ContactGroupSpec{DisplayName: "Developers"},
+			Status: notificationv1.ContactGroupStatus{
+				ProviderID: "cg-old",
+				Conditions: []metav1.Condition{{
+					Type:               notificationv1.ContactGroupReadyCondition,
+					Status:             metav1.ConditionTrue,
+					Reason:             notificationv1.ContactGroupCreatedReason,
+					LastTransitionTime: metav1.Now(),
+					ObservedGeneration: 1,
+				}},
+			},
+		}
+
+		cgm = &notificationv1.ContactGroupMembership{
+			ObjectMeta: metav1.ObjectMeta{Name: "member-1", Namespace: "default"},
+			Spec: notificationv1.ContactGroupMembershipSpec{
+				ContactRef:      notificationv1.ContactReference{Name: "john", Namespace: "default"},
+				ContactGroupRef: notificationv1.ContactGroupReference{Name: group.Name, Namespace: group.Namespace},
+			},
+		}
+
+		sch := scheme.Scheme
+		gomega.Expect(notificationv1.AddToScheme(sch)).To(gomega.Succeed())
+
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(sch).
+			WithStatusSubresource(&notificationv1.ContactGroup{}, &notificationv1.ContactGroupMembership{}).
+			WithObjects(group.DeepCopy(), cgm.DeepCopy()).
+			WithIndex(&notificationv1.ContactGroupMembership{}, contactGroupNamespacedIndexKey, func(raw client.Object) []string {
+				c := raw.(*notificationv1.ContactGroupMembership)
+				return []string{buildContactGroupNamespacedIndexKey(c.Spec.ContactGroupRef.Name, c.Spec.ContactGroupRef.Namespace)}
+			}).
+			WithIndex(&notificationv1.ContactGroup{}, contactGroupProviderIDIndexKey, contactGroupProviderIDIndex).
+			Build()
+
+		syncConfig, err := config.NewProviderSyncConfig(time.Hour, false)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		controller = &ContactGroupController{Client: k8sClient, EmailProvider: *svc, SyncConfig: *syncConfig}
+		controller.Finalizers = finalizerpkg.NewFinalizers()
+		req = ctrl.Request{NamespacedName: types.NamespacedName{Name: group.Name, Namespace: group.Namespace}}
+		provider.GetContactGroupOutput = emailprovider.GetContactGroupOutput{ContactGroupID: "cg-old", DisplayName: emailprovider.GetDeterministicContactGroupDisplayName(group)}
+		controller.drift.expire(group.UID)
+		controller.members.expire(group.UID)
+	})
+
+	ginkgo.It("reports the contact group in sync and requeues after the resync interval", func() {
+		res, err := controller.Reconcile(ctx, req)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(res.RequeueAfter).To(gomega.BeNumerically("~", time.Hour, time.Second))
+
+		fetched := &notificationv1.ContactGroup{}
+		gomega.Expect(k8sClient.Get(ctx, req.NamespacedName, fetched)).To(gomega.Succeed())
+		cond := meta.FindStatusCondition(fetched.Status.Conditions, ResendDriftDetectedCondition)
+		gomega.Expect(cond).NotTo(gomega.BeNil())
+		gomega.Expect(cond.Status).To(gomega.Equal(metav1.ConditionFalse))
+		gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("cg-old"))
+		gomega.Expect(provider.CreatedGroups).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("recreates a contact group missing on the email provider and requests membership updates", func() {
+		provider.GetContactGroupErr = errors.NewNotFound(schema.GroupResource{Group: "resend", Resource: "segments"}, "cg-old")
+		provider.CreateContactGroupOutput = emailprovider.CreateContactGroupOutput{ContactGroupID: "cg-new"}
+
+		_, err := controller.Reconcile(ctx, req)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		fetched := &notificationv1.ContactGroup{}
+		gomega.Expect(k8sClient.Get(ctx, req.NamespacedName, fetched)).To(gomega.Succeed())
+		gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("cg-new"))
+		cond := meta.FindStatusCondition(fetched.Status.Conditions, ResendDriftDetectedCondition)
+		gomega.Expect(cond).NotTo(gomega.BeNil())
+		gomega.Expect(cond.Status).To(gomega.Equal(metav1.ConditionTrue))
+		gomega.Expect(cond.Reason).To(gomega.Equal(ResendObjectRecreatedReason))
+
+		fetchedCgm := &notificationv1.ContactGroupMembership{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: cgm.Name, Namespace: cgm.Namespace}, fetchedCgm)).To(gomega.Succeed())
+		updated := meta.FindStatusCondition(fetchedCgm.Status.Conditions, notificationv1.ContactGroupMembershipUpdatedCondition)
+		gomega.Expect(updated).NotTo(gomega.BeNil())
+		gomega.Expect(updated.Reason).To(gomega.Equal(notificationv1.ContactGroupMembershipUpdateRequestedReason))
+	})
+
+	ginkgo.It("migrates a contact group using the legacy name to the current naming scheme", func() {
+		provider.GetContactGroupOutput = emailprovider.GetContactGroupOutput{ContactGroupID: "cg-old", DisplayName: emailprovider.GetLegacyContactGroupDisplayName(group)}
+		provider.CreateContactGroupOutput = emailprovider.CreateContactGroupOutput{ContactGroupID: "cg-new"}
+
+		_, err := controller.Reconcile(ctx, req)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		fetched := &notificationv1.ContactGroup{}
+		gomega.Expect(k8sClient.Get(ctx, req.NamespacedName, fetched)).To(gomega.Succeed())
+		gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("cg-new"))
+		gomega.Expect(provider.CreatedGroups).To(gomega.HaveLen(1))
+		gomega.Expect(provider.CreatedGroups[0].DisplayName).To(gomega.Equal("Developers-cg-uid"))
+		gomega.Expect(provider.DeletedGroupID).To(gomega.Equal("cg-old"))
+	})
+
+	ginkgo.It("keeps the legacy contact group while other ContactGroups still use it", func() {
+		other := &notificationv1.ContactGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "developers-copy", Namespace: "default", UID: "other-cg-uid"},
+			Spec:       notificationv1.ContactGroupSpec{DisplayName: "Developers"},
+		}
+		gomega.Expect(k8sClient.Create(ctx, other)).To(gomega.Succeed())
+		other.Status.ProviderID = "cg-old"
+		gomega.Expect(k8sClient.Status().Update(ctx, other)).To(gomega.Succeed())
+
+		provider.GetContactGroupOutput = emailprovider.GetContactGroupOutput{ContactGroupID: "cg-old", DisplayName: emailprovider.GetLegacyContactGroupDisplayName(group)}
+		provider.CreateContactGroupOutput = emailprovider.CreateContactGroupOutput{ContactGroupID: "cg-new"}
+
+		_, err := controller.Reconcile(ctx, req)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		fetched := &notificationv1.ContactGroup{}
+		gomega.Expect(k8sClient.Get(ctx, req.NamespacedName, fetched)).To(gomega.Succeed())
+		gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("cg-new"))
+		gomega.Expect(provider.DeletedGroupID).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("verifies the contact group at most once per resync interval", func() {
+		provider.GetContactGroupErr = errors.NewNotFound(schema.GroupResource{Group: "resend", Resource: "segments"}, "cg-old")
+
+		controller.drift.record(group.UID)
+		_, err := controller.Reconcile(ctx, req)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(provider.CreatedGroups).To(gomega.BeEmpty())
+	})
+})
+
+var _ = ginkgo.Describe("ContactGroupController topics", func() {
+	var (
+		ctx        context.Context
+		k8sClient  client.Client
+		controller *ContactGroupController
+		provider   *mockprovider.MockEmailProvider
+		key        types.NamespacedName
+	)
+
+	ginkgo.BeforeEach(func() {
+		ctx = context.Background()
+		provider = &mockprovider.MockEmailProvider{
+			CreateContactGroupOutput: emailprovider.CreateContactGroupOutput{ContactGroupID: "cg-123"},
+			CreateTopicOutput:        emailprovider.CreateTopicOutput{TopicID: "topic-123"},
+		}
+		svc := emailprovider.NewService(provider, "from@example.com", "reply@example.com")
+
+		group := &notificationv1.ContactGroup{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "newsletter", UID: "cg-uid"},
+			Spec:       notificationv1.ContactGroupSpec{DisplayName: "Newsletter", Visibility: notificationv1.ContactGroupVisibilityPublic},
+		}
+		key = types.NamespacedName{Name: group.Name, Namespace: group.Namespace}
+		membership := &notificationv1.ContactGroupMembership{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "jane-newsletter"},
+			Spec: notificationv1.ContactGroupMembershipSpec{
+				ContactRef:      notificationv1.ContactReference{Name: "jane", Namespace: "default"},
+				ContactGroupRef: notificationv1.ContactGroupReference{Name: "newsletter", Namespace: "default"},
+			},
+		}
+
+		sch := scheme.Scheme
+		gomega.Expect(notificationv1.AddToScheme(sch)).To(gomega.Succeed())
+
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(sch).
+			WithStatusSubresource(&notificationv1.ContactGroup{}, &notificationv1.ContactGroupMembership{}).
+			WithObjects(group, membership).
+			WithIndex(&notificationv1.ContactGroupMembership{}, contactGroupNamespacedIndexKey, func(raw client.Object) []string {
+				c := raw.(*notificationv1.ContactGroupMembership)
+				return []string{buildContactGroupNamespacedIndexKey(c.Spec.ContactGroupRef.Name, c.Spec.ContactGroupRef.Namespace)}
+			}).
+			Build()
+
+		controller = &ContactGroupController{Client: k8sClient, EmailProvider: *svc}
+		controller.Finalizers = finalizerpkg.NewFinalizers()
+	})
+
+	reconcileAndFetch := func() *notificationv1.ContactGroup {
+		_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		fetched := &notificationv1.ContactGroup{}
+		gomega.Expect(k8sClient.Get(ctx, key, fetched)).To(gomega.Succeed())
+		return fetched
+	}
+
+	ginkgo.It("creates an opt-out topic for public contact groups and requests membership updates", func() {
+		fetched := reconcileAndFetch()
+
+		gomega.Expect(provider.CreatedTopics).To(gomega.HaveLen(1))
+		gomega.Expect(provider.CreatedTopics[0].DisplayName).To(gomega.Equal("Newsletter"))
+		gomega.Expect(provider.CreatedTopics[0].DefaultSubscription).To(gomega.Equal(emailprovider.TopicSubscriptionOptOut))
+		gomega.Expect(fetched.Annotations).To(gomega.HaveKeyWithValue(ContactGroupTopicIDAnnotation, "topic-123"))
+		gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("cg-123"))
+		gomega.Expect(meta.IsStatusConditionTrue(fetched.Status.Conditions, ResendTopicReadyCondition)).To(gomega.BeTrue())
+
+		cgm := &notificationv1.ContactGroupMembership{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "jane-newsletter", Namespace: "default"}, cgm)).To(gomega.Succeed())
+		gomega.Expect(meta.FindStatusCondition(cgm.Status.Conditions, notificationv1.ContactGroupMembershipUpdatedCondition).Reason).
+			To(gomega.Equal(notificationv1.ContactGroupMembershipUpdateRequestedReason))
+
+		// The topic is created only once
+		reconcileAndFetch()
+		gomega.Expect(provider.CreatedTopics).To(gomega.HaveLen(1))
+	})
+
+	ginkgo.It("renames the topic when the display name changes", func() {
+		fetched := reconcileAndFetch()
+
+		fetched.Spec.DisplayName = "Product News"
+		fetched.Generation = 2
+		gomega.Expect(k8sClient.Update(ctx, fetched)).To(gomega.Succeed())
+		provider.GetContactGroupOutput = emailprovider.GetContactGroupOutput{ContactGroupID: "cg-123", DisplayName: "Product News-cg-uid"}
+		provider.GetTopicOutput = emailprovider.GetTopicOutput{TopicID: "topic-123", DisplayName: "Newsletter"}
+
+		reconcileAndFetch()
+		gomega.Expect(provider.UpdatedTopics).To(gomega.HaveLen(1))
+		gomega.Expect(provider.UpdatedTopics[0]).To(gomega.Equal(emailprovider.UpdateTopicInput{TopicID: "topic-123", DisplayName: "Product News"}))
+	})
+
+	ginkgo.It("deletes the topic when the contact group becomes private", func() {
+		fetched := reconcileAndFetch()
+
+		fetched.Spec.Visibility = notificationv1.ContactGroupVisibilityPrivate
+		fetched.Generation = 2
+		gomega.Expect(k8sClient.Update(ctx, fetched)).To(gomega.Succeed())
+		provider.GetContactGroupOutput = emailprovider.GetContactGroupOutput{ContactGroupID: "cg-123", DisplayName: "Newsletter-cg-uid"}
+
+		fetched = reconcileAndFetch()
+		gomega.Expect(provider.DeletedTopicIDs).To(gomega.Equal([]string{"topic-123"}))
+		gomega.Expect(fetched.Annotations).NotTo(gomega.HaveKey(ContactGroupTopicIDAnnotation))
+		cond := meta.FindStatusCondition(fetched.Status.Conditions, ResendTopicReadyCondition)
+		gomega.Expect(cond.Reason).To(gomega.Equal(ResendTopicNotRequiredReason))
+	})
+})
+
+var _ = ginkgo.Describe("ContactGroupController members", func() {
+	var (
+		ctx        context.Context
+		k8sClient  client.Client
+		controller *ContactGroupController
+		provider   *mockprovider.MockEmailProvider
+		req        ctrl.Request
+	)
+
+	ginkgo.BeforeEach(func() {
+		ctx = context.Background()
+		provider = &mockprovider.MockEmailProvider{}
+		svc := emailprovider.NewService(provider, "from@example.com", "reply@example.com")
+
+		group := &notificationv1.ContactGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "dev-team", Namespace: "default", UID: "cg-uid", Generation: 1},
+			Spec:       notificationv1.ContactGroupSpec{DisplayName: "Developers"},
+			Status: notificationv1.ContactGroupStatus{
+				ProviderID: "cg-1",
+				Conditions: []metav1.Condition{{
+					Type:               notificationv1.ContactGroupReadyCondition,
+					Status:             metav1.ConditionTrue,
+					Reason:             notificationv1.ContactGroupCreatedReason,
+					LastTransitionTime: metav1.Now(),
+					ObservedGeneration: 1,
+				}},
+			},
+		}
+		member := func(contact string, ready bool) *notificationv1.ContactGroupMembership {
+			cgm := &notificationv1.ContactGroupMembership{
+				ObjectMeta: metav1.ObjectMeta{Name: contact + "-dev-team", Namespace: "default"},
+				Spec: notificationv1.ContactGroupMembershipSpec{
+					ContactRef:      notificationv1.ContactReference{Name: contact, Namespace: "default"},
+					ContactGroupRef: notificationv1.ContactGroupReference{Name: group.Name, Namespace: group.Namespace},
+				},
+			}
+			if ready {
+				cgm.Status.Conditions = []metav1.Condition{{
+					Type:               ResendContactGroupMembershipReadyCondition,
+					Status:             metav1.ConditionTrue,
+					Reason:             notificationv1.ContactGroupMembershipCreatedReason,
+					LastTransitionTime: metav1.Now(),
+				}}
+			}
+			return cgm
+		}
+
+		sch := scheme.Scheme
+		gomega.Expect(notificationv1.AddToScheme(sch)).To(gomega.Succeed())
+
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(sch).
+			WithStatusSubresource(&notificationv1.ContactGroup{}).
+			WithObjects(group,
+				&notificationv1.Contact{
+					ObjectMeta: metav1.ObjectMeta{Name: "john", Namespace: "default"},
+					Spec:       notificationv1.ContactSpec{Email: "john@example.com"},
+					Status:     notificationv1.ContactStatus{ProviderID: "c-john"},
+				},
+				&notificationv1.Contact{
+					ObjectMeta: metav1.ObjectMeta{Name: "jane", Namespace: "default"},
+					Spec:       notificationv1.ContactSpec{Email: "jane@example.com"},
+					Status:     notificationv1.ContactStatus{ProviderID: "c-jane"},
+				},
+				&notificationv1.Contact{
+					ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "default"},
+					Spec:       notificationv1.ContactSpec{Email: "new@example.com"},
+				},
+				// joe is created on the email provider, but not added to the contact group yet
+				&notificationv1.Contact{
+					ObjectMeta: metav1.ObjectMeta{Name: "joe", Namespace: "default"},
+					Spec:       notificationv1.ContactSpec{Email: "joe@example.com"},
+					Status:     notificationv1.ContactStatus{ProviderID: "c-joe"},
+				},
+				member("john", true), member("jane", true), member("new", false), member("joe", false),
+			).
+			WithIndex(&notificationv1.ContactGroupMembership{}, contactGroupNamespacedIndexKey, func(raw client.Object) []string {
+				c := raw.(*notificationv1.ContactGroupMembership)
+				return []string{buildContactGroupNamespacedIndexKey(c.Spec.ContactGroupRef.Name, c.Spec.ContactGroupRef.Namespace)}
+			}).
+			Build()
+
+		syncConfig, err := config.NewProviderSyncConfig(time.Hour, false)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		controller = &ContactGroupController{Client: k8sClient, EmailProvider: *svc, SyncConfig: *syncConfig}
+		controller.Finalizers = finalizerpkg.NewFinalizers()
+		req = ctrl.Request{NamespacedName: types.NamespacedName{Name: group.Name, Namespace: group.Namespace}}
+		provider.GetContactGroupOutput = emailprovider.GetContactGroupOutput{ContactGroupID: "cg-1", DisplayName: emailprovider.GetDeterministicContactGroupDisplayName(group)}
+		controller.members.expire(group.UID)
+	})
+
+	getCondition := func() *metav1.Condition {
+		fetched := &notificationv1.ContactGroup{}
+		gomega.Expect(k8sClient.Get(ctx, req.NamespacedName, fetched)).To(gomega.Succeed())
+		return meta.FindStatusCondition(fetched.Status.Conditions, ResendMembersDivergedCondition)
+	}
+
+	ginkgo.It("reports the member counts when the members are in sync", func() {
+		provider.ListSegmentContactsOutput = []emailprovider.GetContactOutput{
+			{ContactId: "c-john", Email: "john@example.com"},
+			{ContactId: "c-jane", Email: "jane@example.com"},
+		}
+
+		_, err := controller.Reconcile(ctx, req)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		cond := getCondition()
+		gomega.Expect(cond).NotTo(gomega.BeNil())
+		gomega.Expect(cond.Status).To(gomega.Equal(metav1.ConditionFalse))
+		gomega.Expect(cond.Reason).To(gomega.Equal(ResendMembersInSyncReason))
+		gomega.Expect(cond.Message).To(gomega.Equal("4 members (2 pending), 2 contacts on email provider"))
+		gomega.Expect(provider.LastListSegmentContactsInput.ContactGroupID).To(gomega.Equal("cg-1"))
+
+		events := &eventsv1.EventList{}
+		gomega.Expect(k8sClient.List(ctx, events)).To(gomega.Succeed())
+		gomega.Expect(events.Items).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("raises the condition and emits an event listing the discrepancies", func() {
+		provider.ListSegmentContactsOutput = []emailprovider.GetContactOutput{
+			{ContactId: "c-john", Email: "john@example.com"},
+			{ContactId: "c-other", Email: "other@example.com"},
+		}
+
+		_, err := controller.Reconcile(ctx, req)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		cond := getCondition()
+		gomega.Expect(cond).NotTo(gomega.BeNil())
+		gomega.Expect(cond.Status).To(gomega.Equal(metav1.ConditionTrue))
+		gomega.Expect(cond.Reason).To(gomega.Equal(ResendMembersDivergedReason))
+		gomega.Expect(cond.Message).To(gomega.Equal("4 members (2 pending), 2 contacts on email provider. " +
+			"1 members missing on email provider, 1 contacts on email provider without membership"))
+
+		events := &eventsv1.EventList{}
+		gomega.Expect(k8sClient.List(ctx, events)).To(gomega.Succeed())
+		gomega.Expect(events.Items).To(gomega.HaveLen(1))
+		gomega.Expect(events.Items[0].Type).To(gomega.Equal("Warning"))
+		gomega.Expect(events.Items[0].Regarding.Name).To(gomega.Equal("dev-team"))
+		gomega.Expect(events.Items[0].Note).To(gomega.Equal("Members missing on email provider: default/jane. " +
+			"Contacts on email provider without membership: other@example.com"))
+
+		// Members are compared at most once per resync interval
+		_, err = controller.Reconcile(ctx, req)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(k8sClient.List(ctx, events)).To(gomega.Succeed())
+		gomega.Expect(events.Items).To(gomega.HaveLen(1))
+
+		// Members still diverging on the next check only update the condition
+		controller.members.forget("cg-uid")
+		_, err = controller.Reconcile(ctx, req)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(getCondition().Status).To(gomega.Equal(metav1.ConditionTrue))
+		gomega.Expect(k8sClient.List(ctx, events)).To(gomega.Succeed())
+		gomega.Expect(events.Items).To(gomega.HaveLen(1))
+	})
+
+	ginkgo.It("does not compare the memberships not ready on the email provider", func() {
+		// joe may or may not be in the contact group yet
+		provider.ListSegmentContactsOutput = []emailprovider.GetContactOutput{
+			{ContactId: "c-john", Email: "john@example.com"},
+			{ContactId: "c-jane", Email: "jane@example.com"},
+			{ContactId: "c-joe", Email: "joe@example.com"},
+		}
+
+		_, err := controller.Reconcile(ctx, req)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		cond := getCondition()
+		gomega.Expect(cond).NotTo(gomega.BeNil())
+		gomega.Expect(cond.Status).To(gomega.Equal(metav1.ConditionFalse))
+		gomega.Expect(cond.Message).To(gomega.Equal("4 members (2 pending), 3 contacts on email provider"))
+	})
+})
@@ -0,0 +1,221 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ResendMembersDivergedCondition reports whether the members of the ContactGroup differ from the contacts of the
+	// contact group on Resend. Its message holds both member counts.
+	ResendMembersDivergedCondition = "ResendMembersDiverged"
+	// ResendMembersInSyncReason is set when the members match the contacts of the contact group on Resend
+	ResendMembersInSyncReason = "ResendMembersInSync"
+	// ResendMembersDivergedReason is set when the members differ from the contacts of the contact group on Resend
+	ResendMembersDivergedReason = "ResendMembersDiverged"
+
+	// maxReportedMemberDiscrepancies limits the discrepancies listed in an event, so its note stays within the API limit
+	maxReportedMemberDiscrepancies = 10
+)
+
+// contactGroupMemberDiscrepancies are the differences between the members of a ContactGroup and the contacts of its
+// contact group on the email provider.
+type contactGroupMemberDiscrepancies struct {
+	// members counts the ContactGroupMemberships, pending ones included
+	members int
+	// pending counts the ContactGroupMemberships not ready on the email provider yet
+	pending int
+	// providerMembers counts the contacts of the contact group on the email provider
+	providerMembers int
+	// missingOnProvider are the "<namespace>/<name>" of the contacts missing from the contact group on the email provider
+	missingOnProvider []string
+	// unknownOnProvider are the emails of the contacts of the contact group on the email provider without membership
+	unknownOnProvider []string
+}
+
+func (d contactGroupMemberDiscrepancies) diverged() bool {
+	return len(d.missingOnProvider) > 0 || len(d.unknownOnProvider) > 0
+}
+
+func (d contactGroupMemberDiscrepancies) message() string {
+	msg := fmt.Sprintf("%d members (%d pending), %d contacts on email provider", d.members, d.pending, d.providerMembers)
+	if d.diverged() {
+		msg += fmt.Sprintf(". %d members missing on email provider, %d contacts on email provider without membership",
+			len(d.missingOnProvider), len(d.unknownOnProvider))
+	}
+	return msg
+}
+
+// verifyContactGroupMembers compares the members of the contact group with the contacts of the contact group on the
+// email provider. It records the member counts in the ResendMembersDiverged condition, and emits an event listing the
+// discrepancies when they start diverging. Later checks that still diverge only update the condition.
+func (r *ContactGroupController) verifyContactGroupMembers(ctx context.Context, contactGroup *notificationmiloapiscomv1alpha1.ContactGroup) error {
+	log := logf.FromContext(ctx).WithValues("controller", "ContactGroupController", "trigger", contactGroup.Name)
+
+	discrepancies, err := r.getContactGroupMemberDiscrepancies(ctx, contactGroup)
+	if errors.IsNotFound(err) {
+		// The contact group is recreated by the drift detection, members are compared on the next check
+		log.Info("ContactGroup not found on email provider. Skipping member comparison.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cond := metav1.Condition{
+		Type:               ResendMembersDivergedCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             ResendMembersInSyncReason,
+		Message:            discrepancies.message(),
+		LastTransitionTime: metav1.Now(),
+		ObservedGeneration: contactGroup.GetGeneration(),
+	}
+	if discrepancies.diverged() {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = ResendMembersDivergedReason
+		log.Info("ContactGroup members diverged from email provider",
+			"missingOnProvider", len(discrepancies.missingOnProvider), "unknownOnProvider", len(discrepancies.unknownOnProvider))
+		if !meta.IsStatusConditionTrue(contactGroup.Status.Conditions, ResendMembersDivergedCondition) {
+			if err := r.createMembersDivergedEvent(ctx, contactGroup, discrepancies); err != nil {
+				return err
+			}
+		}
+	}
+	meta.SetStatusCondition(&contactGroup.Status.Conditions, cond)
+
+	r.members.record(contactGroup.GetUID())
+	return nil
+}
+
+// getContactGroupMemberDiscrepancies lists the members of the contact group on both sides. Memberships not ready on
+// the email provider yet, e.g. because their contact is not created yet, are pending, and not compared: their contact
+// is neither missing on the email provider nor unknown there. Neither are the contacts of memberships being deleted.
+func (r *ContactGroupController) getContactGroupMemberDiscrepancies(ctx context.Context, contactGroup *notificationmiloapiscomv1alpha1.ContactGroup) (contactGroupMemberDiscrepancies, error) {
+	discrepancies := contactGroupMemberDiscrepancies{}
+
+	cgms := &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}
+	if err := r.Client.List(ctx, cgms, client.MatchingFields{contactGroupNamespacedIndexKey: buildContactGroupNamespacedIndexKey(contactGroup.Name, contactGroup.Namespace)}); err != nil {
+		return discrepancies, fmt.Errorf("failed to list ContactGroupMemberships: %w", err)
+	}
+
+	// Provider contact id -> "<namespace>/<name>" of the member contact
+	members := map[string]string{}
+	// Provider contact ids of the pending memberships and of the memberships being deleted
+	unsettled := map[string]bool{}
+	for _, cgm := range cgms.Items {
+		deleting := !cgm.GetDeletionTimestamp().IsZero()
+		if !deleting {
+			discrepancies.members++
+		}
+
+		contact := &notificationmiloapiscomv1alpha1.Contact{}
+		err := r.Client.Get(ctx, client.ObjectKey{Namespace: cgm.Spec.ContactRef.Namespace, Name: cgm.Spec.ContactRef.Name}, contact)
+		if err != nil && !errors.IsNotFound(err) {
+			return discrepancies, fmt.Errorf("failed to get Contact: %w", err)
+		}
+		ready := meta.IsStatusConditionTrue(cgm.Status.Conditions, ResendContactGroupMembershipReadyCondition)
+		if !deleting && (errors.IsNotFound(err) || contact.Status.ProviderID == "" || !ready) {
+			discrepancies.pending++
+		}
+		if errors.IsNotFound(err) || contact.Status.ProviderID == "" {
+			continue
+		}
+		if deleting || !ready {
+			unsettled[contact.Status.ProviderID] = true
+			continue
+		}
+		members[contact.Status.ProviderID] = contact.Namespace + "/" + contact.Name
+	}
+
+	providerMembers := map[string]bool{}
+	for providerContact, err := range r.EmailProvider.ListContactGroupContacts(ctx, *contactGroup) {
+		if err != nil {
+			return discrepancies, err
+		}
+		discrepancies.providerMembers++
+		providerMembers[providerContact.ContactId] = true
+		if _, ok := members[providerContact.ContactId]; !ok && !unsettled[providerContact.ContactId] {
+			discrepancies.unknownOnProvider = append(discrepancies.unknownOnProvider, providerContact.Email)
+		}
+	}
+	for providerID, contact := range members {
+		if !providerMembers[providerID] {
+			discrepancies.missingOnProvider = append(discrepancies.missingOnProvider, contact)
+		}
+	}
+	sort.Strings(discrepancies.missingOnProvider)
+	sort.Strings(discrepancies.unknownOnProvider)
+
+	return discrepancies, nil
+}
+
+// createMembersDivergedEvent emits a warning event on the contact group listing the discrepancies.
+func (r *ContactGroupController) createMembersDivergedEvent(ctx context.Context, contactGroup *notificationmiloapiscomv1alpha1.ContactGroup, discrepancies contactGroupMemberDiscrepancies) error {
+	list := func(items []string) string {
+		if len(items) > maxReportedMemberDiscrepancies {
+			return fmt.Sprintf("%s and %d more", strings.Join(items[:maxReportedMemberDiscrepancies], ", "), len(items)-maxReportedMemberDiscrepancies)
+		}
+		return strings.Join(items, ", ")
+	}
+	var notes []string
+	if len(discrepancies.missingOnProvider) > 0 {
+		notes = append(notes, "Members missing on email provider: "+list(discrepancies.missingOnProvider))
+	}
+	if len(discrepancies.unknownOnProvider) > 0 {
+		notes = append(notes, "Contacts on email provider without membership: "+list(discrepancies.unknownOnProvider))
+	}
+
+	event := &eventsv1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-", contactGroup.Name),
+			Namespace:    contactGroup.Namespace,
+		},
+		Action:              "VerifyMembers",
+		Reason:              ResendMembersDivergedReason,
+		Note:                strings.Join(notes, ". "),
+		Type:                corev1.EventTypeWarning,
+		EventTime:           metav1.MicroTime{Time: time.Now()},
+		ReportingController: "email-provider-resend-controller",
+		ReportingInstance:   "email-provider-resend-controller-1",
+		Regarding: corev1.ObjectReference{
+			Kind:            "ContactGroup",
+			Namespace:       contactGroup.Namespace,
+			Name:            contactGroup.Name,
+			UID:             contactGroup.UID,
+			ResourceVersion: contactGroup.ResourceVersion,
+			APIVersion:      notificationmiloapiscomv1alpha1.SchemeGroupVersion.String(),
+		},
+	}
+	if err := r.Client.Create(ctx, event); err != nil {
+		return fmt.Errorf("failed to create ContactGroup members diverged event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ContactGroupTopicIDAnnotation holds the id of the Resend topic of a public ContactGroup
+	ContactGroupTopicIDAnnotation = "notification.miloapis.com/resend-topic-id"
+	// ContactGroupMembershipTopicOptInAnnotation holds the id of the Resend topic the contact of a
+	// ContactGroupMembership was opted in to
+	ContactGroupMembershipTopicOptInAnnotation = "notification.miloapis.com/resend-topic-opted-in"
+
+	// ResendTopicReadyCondition reports whether the ContactGroup topic on Resend matches its visibility
+	ResendTopicReadyCondition = "ResendTopicReady"
+	// ResendTopicCreatedReason is set when the topic of a public ContactGroup exists on Resend
+	ResendTopicCreatedReason = "TopicCreated"
+	// ResendTopicNotRequiredReason is set when the ContactGroup is private, so it has no topic on Resend
+	ResendTopicNotRequiredReason = "TopicNotRequired"
+)
+
+// contactGroupTopicID returns the id of the Resend topic of the contact group, or an empty string if it has none.
+func contactGroupTopicID(contactGroup *notificationmiloapiscomv1alpha1.ContactGroup) string {
+	return contactGroup.GetAnnotations()[ContactGroupTopicIDAnnotation]
+}
+
+// IsContactGroupTopicOptedIn reports whether the contact of a ready membership, with no update pending, was opted in
+// to the given topic. Until then, the contact is opted out of the topic on the email provider, which is not an opt out
+// of the membership.
+func IsContactGroupTopicOptedIn(contactGroupMembership *notificationmiloapiscomv1alpha1.ContactGroupMembership, topicID string) bool {
+	if topicID == "" || contactGroupMembership.GetAnnotations()[ContactGroupMembershipTopicOptInAnnotation] != topicID {
+		return false
+	}
+	if !meta.IsStatusConditionTrue(contactGroupMembership.Status.Conditions, ResendContactGroupMembershipReadyCondition) {
+		return false
+	}
+	updatedCond := meta.FindStatusCondition(contactGroupMembership.Status.Conditions, notificationmiloapiscomv1alpha1.ContactGroupMembershipUpdatedCondition)
+	return updatedCond == nil || updatedCond.Status != metav1.ConditionFalse ||
+		updatedCond.Reason != notificationmiloapiscomv1alpha1.ContactGroupMembershipUpdateRequestedReason
+}
+
+// reconcileContactGroupTopic makes public contact groups have a topic on the email provider, so recipients can opt in
+// and out on the email provider hosted preferences page, and removes the topic of private contact groups.
+// specChanged requests the topic name to be checked against the display name.
+func (r *ContactGroupController) reconcileContactGroupTopic(ctx context.Context, contactGroup *notificationmiloapiscomv1alpha1.ContactGroup, specChanged bool) error {
+	log := logf.FromContext(ctx).WithValues("controller", "ContactGroupController", "trigger", contactGroup.Name)
+
+	topicID := contactGroupTopicID(contactGroup)
+	public := contactGroup.Spec.Visibility == notificationmiloapiscomv1alpha1.ContactGroupVisibilityPublic
+
+	switch {
+	case public && topicID == "":
+		log.Info("Creating ContactGroup topic on email provider")
+		created, err := r.EmailProvider.CreateContactGroupTopic(ctx, *contactGroup)
+		if err != nil {
+			return fmt.Errorf("failed to create ContactGroup topic on email provider: %w", err)
+		}
+		if err := r.setContactGroupTopicID(ctx, contactGroup, created.TopicID); err != nil {
+			return err
+		}
+		// Contacts are opted out of new topics, so existing members must be opted in
+		if err := requestContactGroupMembershipsUpdate(ctx, r.Client, contactGroupNamespacedIndexKey, buildContactGroupNamespacedIndexKey(contactGroup.Name, contactGroup.Namespace), "ContactGroupMembership update requested as the contact group topic was created on the email provider"); err != nil {
+			return err
+		}
+
+	case public && specChanged:
+		topic, err := r.EmailProvider.GetTopic(ctx, topicID)
+		if errors.IsNotFound(err) {
+			log.Info("ContactGroup topic not found on email provider. Recreating it.", "topicID", topicID)
+			if err := r.setContactGroupTopicID(ctx, contactGroup, ""); err != nil {
+				return err
+			}
+			return r.reconcileContactGroupTopic(ctx, contactGroup, false)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get ContactGroup topic from email provider: %w", err)
+		}
+		if topic.DisplayName != contactGroup.Spec.DisplayName {
+			log.Info("Renaming ContactGroup topic on email provider", "from", topic.DisplayName, "to", contactGroup.Spec.DisplayName)
+			if _, err := r.EmailProvider.UpdateContactGroupTopic(ctx, *contactGroup, topicID); err != nil {
+				return fmt.Errorf("failed to update ContactGroup topic on email provider: %w", err)
+			}
+		}
+
+	case !public && topicID != "":
+		log.Info("ContactGroup is no longer public. Deleting its topic from email provider.", "topicID", topicID)
+		if _, err := r.EmailProvider.DeleteTopic(ctx, topicID); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete ContactGroup topic from email provider: %w", err)
+		}
+		if err := r.setContactGroupTopicID(ctx, contactGroup, ""); err != nil {
+			return err
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               ResendTopicReadyCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             ResendTopicCreatedReason,
+		Message:            "Contact group topic exists on email provider",
+		ObservedGeneration: contactGroup.GetGeneration(),
+	}
+	if !public {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ResendTopicNotRequiredReason
+		condition.Message = "Private contact groups have no topic on email provider"
+	}
+	meta.SetStatusCondition(&contactGroup.Status.Conditions, condition)
+
+	return nil
+}
+
+// setContactGroupTopicID records the topic id in the contact group annotations, or removes it if empty.
+// The in-memory status is kept, as it may hold changes not yet persisted.
+func (r *ContactGroupController) setContactGroupTopicID(ctx context.Context, contactGroup *notificationmiloapiscomv1alpha1.ContactGroup, topicID string) error {
+	status := contactGroup.Status.DeepCopy()
+	patch := client.MergeFrom(contactGroup.DeepCopy())
+
+	annotations := contactGroup.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if topicID == "" {
+		delete(annotations, ContactGroupTopicIDAnnotation)
+	} else {
+		annotations[ContactGroupTopicIDAnnotation] = topicID
+	}
+	contactGroup.SetAnnotations(annotations)
+
+	if err := r.Client.Patch(ctx, contactGroup, patch); err != nil {
+		return fmt.Errorf("failed to update ContactGroup topic annotation: %w", err)
+	}
+	contactGroup.Status = *status
+
+	return nil
+}
+
+// setContactGroupTopicSubscription sets the subscription of the contact to the contact group topic, if the contact
+// group has one. Missing topics or contacts are ignored, as there is nothing left to subscribe to or from.
+func setContactGroupTopicSubscription(
+	ctx context.Context,
+	provider emailprovider.Service,
+	contactGroup *notificationmiloapiscomv1alpha1.ContactGroup,
+	contact *notificationmiloapiscomv1alpha1.Contact,
+	subscription emailprovider.TopicSubscription) error {
+	topicID := contactGroupTopicID(contactGroup)
+	if topicID == "" || contact.Status.ProviderID == "" {
+		return nil
+	}
+	if _, err := provider.SetContactTopicSubscription(ctx, *contact, topicID, subscription); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to set contact subscription to ContactGroup topic on email provider: %w", err)
+	}
+	return nil
+}
+
+// optInContactGroupTopic opts the contact of the membership in to the contact group topic, and records the topic in
+// the membership annotations, so opt outs on the email provider are only mirrored once the contact was opted in.
+// The in-memory status is kept, as it may hold changes not yet persisted.
+func (r *ContactGroupMembershipController) optInContactGroupTopic(
+	ctx context.Context,
+	contactGroupMembership *notificationmiloapiscomv1alpha1.ContactGroupMembership,
+	contactGroup *notificationmiloapiscomv1alpha1.ContactGroup,
+	contact *notificationmiloapiscomv1alpha1.Contact) error {
+	if err := setContactGroupTopicSubscription(ctx, r.EmailProvider, contactGroup, contact, emailprovider.TopicSubscriptionOptIn); err != nil {
+		return err
+	}
+
+	topicID := contactGroupTopicID(contactGroup)
+	if topicID == "" || contact.Status.ProviderID == "" || contactGroupMembership.GetAnnotations()[ContactGroupMembershipTopicOptInAnnotation] == topicID {
+		return nil
+	}
+
+	status := contactGroupMembership.Status.DeepCopy()
+	patch := client.MergeFrom(contactGroupMembership.DeepCopy())
+
+	annotations := contactGroupMembership.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[ContactGroupMembershipTopicOptInAnnotation] = topicID
+	contactGroupMembership.SetAnnotations(annotations)
+
+	if err := r.Client.Patch(ctx, contactGroupMembership, patch); err != nil {
+		return fmt.Errorf("failed to update ContactGroupMembership topic annotation: %w", err)
+	}
+	contactGroupMembership.Status = *status
+
+	return nil
+}
@@ -18,14 +18,19 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"strings"
 
+	"go.miloapis.com/email-provider-resend/internal/config"
 	"go.miloapis.com/email-provider-resend/internal/emailprovider"
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/finalizer"
@@ -46,13 +51,34 @@ const (
 const (
 	ResendContactGroupMembershipReadyCondition = "ResendContactGroupMembershipReady"
 	LoopsContactGroupMembershipReadyCondition  = "LoopsContactGroupMembershipReady"
+
+	// contactGroupMembershipProviderReadyConditionSuffix follows the provider name in the provider membership ready conditions
+	contactGroupMembershipProviderReadyConditionSuffix = "ContactGroupMembershipReady"
 )
 
+// ContactGroupMembershipName returns the name of the ContactGroupMembership, or ContactGroupMembershipRemoval, of the
+// contact for the contact group: "<contact>-<contactgroup>-<hash>". The hash of the full contact and contact group
+// references keeps names unique across contact groups with the same name in different namespaces.
+func ContactGroupMembershipName(contactRef notificationmiloapiscomv1alpha1.ContactReference, contactGroupRef notificationmiloapiscomv1alpha1.ContactGroupReference) string {
+	sum := sha256.Sum256([]byte(buildContactAndContactGroupTupleIndexKey(contactRef, contactGroupRef)))
+	hash := hex.EncodeToString(sum[:])[:10]
+
+	prefix := fmt.Sprintf("%s-%s", contactRef.Name, contactGroupRef.Name)
+	if maxLength := validation.DNS1123SubdomainMaxLength - len(hash) - 1; len(prefix) > maxLength {
+		prefix = strings.TrimRight(prefix[:maxLength], "-.")
+	}
+	return prefix + "-" + hash
+}
+
 // ContactGroupMembershipReconciler reconciles a ContactGroupMembership object
 type ContactGroupMembershipController struct {
-	Client        client.Client
-	EmailProvider emailprovider.Service
-	Finalizers    finalizer.Finalizers
+	Client          client.Client
+	EmailProvider   emailprovider.Service
+	Finalizers      finalizer.Finalizers
+	SyncConfig      config.ProviderSyncConfig
+	ReadinessConfig config.ProviderReadinessConfig
+
+	drift driftTracker
 }
 
 type contactGroupMembershipFinalizer struct {
@@ -88,6 +114,12 @@ func (f *contactGroupMembershipFinalizer) Finalize(ctx context.Context, obj clie
 		return finalizer.Result{}, fmt.Errorf("failed to get Contact: %w", err)
 	}
 
+	// Opt the contact out of the contact group topic, so it is no longer offered on the preferences page
+	if err := setContactGroupTopicSubscription(ctx, f.EmailProvider, contactGroup, contact, emailprovider.TopicSubscriptionOptOut); err != nil {
+		log.Error(err, "Failed to opt contact out of ContactGroup topic")
+		return finalizer.Result{}, err
+	}
+
 	// Delete ContactGroupMembership from email provider
 	deleted, err := f.EmailProvider.DeleteContactGroupMembershipIdempotent(ctx, *contactGroup, *contact)
 	if err != nil {
@@ -106,7 +138,7 @@ func (f *contactGroupMembershipFinalizer) Finalize(ctx context.Context, obj clie
 	return finalizer.Result{}, nil
 }
 
-// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships,verbs=get;list;watch;create;update;patch
 // +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships/finalizers,verbs=update
 // +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmembershipremovals,verbs=get;list;watch;delete
@@ -130,6 +162,10 @@ func (r *ContactGroupMembershipController) Reconcile(ctx context.Context, req ct
 		return ctrl.Result{}, fmt.Errorf("failed to get ContactGroupMembership: %w", err)
 	}
 
+	if !contactGroupMembership.GetDeletionTimestamp().IsZero() {
+		r.drift.forget(contactGroupMembership.GetUID())
+	}
+
 	// Run finalizers
 	finalizeResult, err := r.Finalizers.Finalize(ctx, contactGroupMembership)
 	if err != nil {
@@ -162,8 +198,8 @@ func (r *ContactGroupMembershipController) Reconcile(ctx context.Context, req ct
 	}
 
 	// Set Status.Username based on Contact.Spec.SubjectRef.Kind
-	if contact.Spec.SubjectRef != nil && contact.Spec.SubjectRef.Kind == "User" {
-		contactGroupMembership.Status.Username = contact.Spec.SubjectRef.Name
+	if username, ok := contactSubjectUsername(contact); ok {
+		contactGroupMembership.Status.Username = username
 	}
 
 	oldStatus := contactGroupMembership.Status.DeepCopy()
@@ -174,26 +210,42 @@ func (r *ContactGroupMembershipController) Reconcile(ctx context.Context, req ct
 	case readyCond == nil:
 		log.Info("ContactGroupMembership first creation")
 		// First creation – condition not present yet
-		// Create ContactGroupMembership on email provider
-		emailProviderContactGroupMembership, err := r.EmailProvider.CreateContactGroupMembershipIdempotent(ctx, *contactGroup, *contact)
+		// Memberships migrated from an existing email provider account adopt their membership
+		adopted, err := r.adoptProviderContactGroupMembership(ctx, contactGroupMembership, contactGroup, contact)
 		if err != nil {
-			log.Error(err, "Failed to create ContactGroupMembership on email provider")
-			return ctrl.Result{}, fmt.Errorf("failed to create ContactGroupMembership on email provider: %w", err)
+			log.Error(err, "Failed to adopt ContactGroupMembership from email provider")
+			return ctrl.Result{}, err
 		}
-		contactGroupMembership.Status.ProviderID = emailProviderContactGroupMembership.ContactGroupMembershipID
-		meta.SetStatusCondition(&contactGroupMembership.Status.Conditions, metav1.Condition{
+
+		readyCond := metav1.Condition{
 			Type:               ResendContactGroupMembershipReadyCondition,
 			Status:             metav1.ConditionTrue,
-			Reason:             notificationmiloapiscomv1alpha1.ContactGroupMembershipCreatedReason,
-			Message:            "ContactGroupMembership created and synced with email provider",
+			Reason:             ResendObjectAdoptedReason,
+			Message:            "Existing ContactGroupMembership adopted from email provider",
 			LastTransitionTime: metav1.Now(),
 			ObservedGeneration: contactGroupMembership.GetGeneration(),
-		})
+		}
+		if !adopted {
+			// Create ContactGroupMembership on email provider
+			emailProviderContactGroupMembership, err := r.EmailProvider.CreateContactGroupMembershipIdempotent(ctx, *contactGroup, *contact)
+			if err != nil {
+				log.Error(err, "Failed to create ContactGroupMembership on email provider")
+				return ctrl.Result{}, fmt.Errorf("failed to create ContactGroupMembership on email provider: %w", err)
+			}
+			contactGroupMembership.Status.ProviderID = emailProviderContactGroupMembership.ContactGroupMembershipID
+			readyCond.Reason = notificationmiloapiscomv1alpha1.ContactGroupMembershipCreatedReason
+			readyCond.Message = "ContactGroupMembership created and synced with email provider"
+		}
+		if err := r.optInContactGroupTopic(ctx, contactGroupMembership, contactGroup, contact); err != nil {
+			log.Error(err, "Failed to opt contact in to ContactGroup topic")
+			return ctrl.Result{}, err
+		}
+		meta.SetStatusCondition(&contactGroupMembership.Status.Conditions, readyCond)
 
 		contactGroupMembership.Status.Providers = []notificationmiloapiscomv1alpha1.ContactProviderStatus{
 			{
 				Name: "Resend",
-				ID:   emailProviderContactGroupMembership.ContactGroupMembershipID,
+				ID:   contactGroupMembership.Status.ProviderID,
 			},
 		}
 
@@ -201,13 +253,17 @@ func (r *ContactGroupMembershipController) Reconcile(ctx context.Context, req ct
 	case updatedCond != nil && updatedCond.Status == metav1.ConditionFalse && updatedCond.Reason == notificationmiloapiscomv1alpha1.ContactGroupMembershipUpdateRequestedReason:
 		log.Info("ContactGroupMembership update requested")
 		// Create ContactGroupMembership on email provider again
-		// As Resend does not supports updating the Contact email address, on Contact update, the contact has been deleted (which removes the Contacts from all ContactGroups on resend side),
+		// As Resend does not supports updating the Contact email address, on Contact email address update, the contact has been deleted (which removes the Contacts from all ContactGroups on resend side),
 		// and created again with the new email address, so we need to create the ContactGroupMembership again.
 		emailProviderContactGroupMembership, err := r.EmailProvider.CreateContactGroupMembershipIdempotent(ctx, *contactGroup, *contact)
 		if err != nil {
 			log.Error(err, "Failed to update ContactGroupMembership on email provider")
 			return ctrl.Result{}, fmt.Errorf("failed to update ContactGroupMembership on email provider: %w", err)
 		}
+		if err := r.optInContactGroupTopic(ctx, contactGroupMembership, contactGroup, contact); err != nil {
+			log.Error(err, "Failed to opt contact in to ContactGroup topic")
+			return ctrl.Result{}, err
+		}
 		contactGroupMembership.Status.ProviderID = emailProviderContactGroupMembership.ContactGroupMembershipID
 
 		// Update ContactGroupMembership status to updated. This will avoid multiple updates to the email provider.
@@ -219,6 +275,15 @@ func (r *ContactGroupMembershipController) Reconcile(ctx context.Context, req ct
 			LastTransitionTime: metav1.Now(),
 			ObservedGeneration: contactGroupMembership.GetGeneration(),
 		})
+
+	// Ready – verify the membership still exists on the email provider once per resync interval
+	default:
+		if r.SyncConfig.ResyncEnabled() && r.drift.due(contactGroupMembership.GetUID(), r.SyncConfig.GetResyncInterval()) {
+			if err := r.verifyProviderContactGroupMembership(ctx, contactGroupMembership, contactGroup, contact); err != nil {
+				log.Error(err, "Failed to verify ContactGroupMembership on email provider")
+				return ctrl.Result{}, err
+			}
+		}
 	}
 
 	r.verifyContactGroupMembershipReadyCondition(contactGroupMembership)
@@ -235,7 +300,42 @@ func (r *ContactGroupMembershipController) Reconcile(ctx context.Context, req ct
 
 	log.Info("Contact group membership reconciled")
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: r.drift.requeueAfter(contactGroupMembership.GetUID(), r.SyncConfig.GetResyncInterval())}, nil
+}
+
+// verifyProviderContactGroupMembership verifies the contact is still part of the contact group on the email provider,
+// and adds it again if it is missing.
+func (r *ContactGroupMembershipController) verifyProviderContactGroupMembership(
+	ctx context.Context,
+	cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership,
+	contactGroup *notificationmiloapiscomv1alpha1.ContactGroup,
+	contact *notificationmiloapiscomv1alpha1.Contact) error {
+	log := logf.FromContext(ctx).WithValues("controller", "ContactGroupMembershipController", "trigger", cgm.Name)
+
+	_, err := r.EmailProvider.GetContactGroupMembership(ctx, *contactGroup, *contact)
+	switch {
+	case err == nil:
+		providerDriftChecksTotal.WithLabelValues("ContactGroupMembership", driftResultInSync).Inc()
+		setDriftCondition(&cgm.Status.Conditions, cgm.GetGeneration(), "ContactGroupMembership", false)
+
+	case errors.IsNotFound(err):
+		providerDriftChecksTotal.WithLabelValues("ContactGroupMembership", driftResultDrifted).Inc()
+		log.Info("ContactGroupMembership not found on email provider. Recreating it.")
+		emailProviderContactGroupMembership, err := r.EmailProvider.CreateContactGroupMembershipIdempotent(ctx, *contactGroup, *contact)
+		if err != nil {
+			return fmt.Errorf("failed to recreate ContactGroupMembership on email provider: %w", err)
+		}
+		cgm.Status.ProviderID = emailProviderContactGroupMembership.ContactGroupMembershipID
+		providerDriftRecreationsTotal.WithLabelValues("ContactGroupMembership").Inc()
+		setDriftCondition(&cgm.Status.Conditions, cgm.GetGeneration(), "ContactGroupMembership", true)
+
+	default:
+		providerDriftChecksTotal.WithLabelValues("ContactGroupMembership", driftResultError).Inc()
+		return fmt.Errorf("failed to get ContactGroupMembership from email provider: %w", err)
+	}
+
+	r.drift.record(cgm.GetUID())
+	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -304,44 +404,7 @@ func (r *ContactGroupMembershipController) enqueueContactForContactGroupMembersh
 	return reqs
 }
 
-// verifyContactReadyCondition is a function that verifies the ContactReadyCondition
+// verifyContactGroupMembershipReadyCondition aggregates the ready conditions of the required providers into the Ready condition
 func (r *ContactGroupMembershipController) verifyContactGroupMembershipReadyCondition(cgm *notificationmiloapiscomv1alpha1.ContactGroupMembership) {
-	// Update contact status if it changed
-	// Aggregate readiness across providers: Loops and Resend
-	loopsCond := meta.FindStatusCondition(cgm.Status.Conditions, LoopsContactGroupMembershipReadyCondition)
-	resendCond := meta.FindStatusCondition(cgm.Status.Conditions, ResendContactGroupMembershipReadyCondition)
-	allReady := loopsCond != nil && loopsCond.Status == metav1.ConditionTrue &&
-		resendCond != nil && resendCond.Status == metav1.ConditionTrue
-
-	if allReady {
-		meta.SetStatusCondition(&cgm.Status.Conditions, metav1.Condition{
-			Type:               notificationmiloapiscomv1alpha1.ContactGroupMembershipReadyCondition,
-			Status:             metav1.ConditionTrue,
-			Reason:             "AllProvidersReady",
-			Message:            "Loops and Resend contact group membership are ready",
-			LastTransitionTime: metav1.Now(),
-			ObservedGeneration: cgm.GetGeneration(),
-		})
-	} else {
-		// Build informative not-ready message
-		notReadyMsg := ""
-		if loopsCond == nil {
-			notReadyMsg += "Loops: condition missing; "
-		} else if loopsCond.Status != metav1.ConditionTrue {
-			notReadyMsg += fmt.Sprintf("Loops: %s (%s); ", loopsCond.Reason, loopsCond.Message)
-		}
-		if resendCond == nil {
-			notReadyMsg += "Resend: condition missing; "
-		} else if resendCond.Status != metav1.ConditionTrue {
-			notReadyMsg += fmt.Sprintf("Resend: %s (%s); ", resendCond.Reason, resendCond.Message)
-		}
-		meta.SetStatusCondition(&cgm.Status.Conditions, metav1.Condition{
-			Type:               notificationmiloapiscomv1alpha1.ContactGroupMembershipReadyCondition,
-			Status:             metav1.ConditionFalse,
-			Reason:             "ProvidersNotReady",
-			Message:            notReadyMsg,
-			LastTransitionTime: metav1.Now(),
-			ObservedGeneration: cgm.GetGeneration(),
-		})
-	}
+	setAggregatedReadyCondition(&cgm.Status.Conditions, cgm.GetGeneration(), r.ReadinessConfig, notificationmiloapiscomv1alpha1.ContactGroupMembershipReadyCondition, contactGroupMembershipProviderReadyConditionSuffix)
 }
@@ -39,6 +39,7 @@ var _ = ginkgo.Describe("ContactGroupMembershipController", func() {
 		ctx        context.Context
 		k8sClient  client.Client
 		controller *ContactGroupMembershipController
+		prov       *mockprovider.MockEmailProvider
 
 		contact      *notificationv1.Contact
 		contactGroup *notificationv1.ContactGroup
@@ -84,7 +85,7 @@ var _ = ginkgo.Describe("ContactGroupMembershipController", func() {
 			WithObjects(contact.DeepCopy(), contactGroup.DeepCopy(), membership.DeepCopy()).
 			Build()
 
-		prov := &mockprovider.MockEmailProvider{
+		prov = &mockprovider.MockEmailProvider{
 			CreateContactGroupOutput:           emailprovider.CreateContactGroupOutput{ContactGroupID: "cg-1"},
 			CreateContactGroupMembershipOutput: emailprovider.CreateContactGroupMembershipOutput{ContactGroupMembershipID: "cgm-1"},
 		}
@@ -110,6 +111,50 @@ var _ = ginkgo.Describe("ContactGroupMembershipController", func() {
 			gomega.Expect(resendCond.Reason).To(gomega.Equal(notificationv1.ContactGroupMembershipCreatedReason))
 
 		})
+
+		ginkgo.When("the contact group has a topic", func() {
+			ginkgo.BeforeEach(func() {
+				withProviderID := contact.DeepCopy()
+				withProviderID.Status.ProviderID = "c-1"
+				withTopic := contactGroup.DeepCopy()
+				withTopic.Annotations = map[string]string{ContactGroupTopicIDAnnotation: "topic-1"}
+				k8sClient = fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithStatusSubresource(&notificationv1.ContactGroupMembership{}).
+					WithObjects(withProviderID, withTopic, membership.DeepCopy()).
+					Build()
+				controller.Client = k8sClient
+			})
+
+			ginkgo.It("opts the contact in to the topic of a created membership", func() {
+				_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: membership.Name, Namespace: membership.Namespace}})
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				gomega.Expect(prov.UpdateContactTopicsInputs).To(gomega.HaveLen(1))
+
+				fetched := &notificationv1.ContactGroupMembership{}
+				gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: membership.Name, Namespace: membership.Namespace}, fetched)).To(gomega.Succeed())
+				gomega.Expect(fetched.Annotations).To(gomega.HaveKeyWithValue(ContactGroupMembershipTopicOptInAnnotation, "topic-1"))
+				gomega.Expect(meta.IsStatusConditionTrue(fetched.Status.Conditions, ResendContactGroupMembershipReadyCondition)).To(gomega.BeTrue())
+			})
+
+			ginkgo.It("opts the contact in to the topic of an adopted membership", func() {
+				adopted := &notificationv1.ContactGroupMembership{}
+				gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: membership.Name, Namespace: membership.Namespace}, adopted)).To(gomega.Succeed())
+				adopted.Annotations = map[string]string{AdoptProviderIDAnnotation: "cg-1"}
+				gomega.Expect(k8sClient.Update(ctx, adopted)).To(gomega.Succeed())
+
+				_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: membership.Name, Namespace: membership.Namespace}})
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				gomega.Expect(prov.CreateContactGroupMembershipCallCount).To(gomega.BeZero())
+				gomega.Expect(prov.UpdateContactTopicsInputs).To(gomega.HaveLen(1))
+
+				fetched := &notificationv1.ContactGroupMembership{}
+				gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: membership.Name, Namespace: membership.Namespace}, fetched)).To(gomega.Succeed())
+				gomega.Expect(fetched.Annotations).To(gomega.HaveKeyWithValue(ContactGroupMembershipTopicOptInAnnotation, "topic-1"))
+				resendCond := meta.FindStatusCondition(fetched.Status.Conditions, ResendContactGroupMembershipReadyCondition)
+				gomega.Expect(resendCond.Reason).To(gomega.Equal(ResendObjectAdoptedReason))
+			})
+		})
 	})
 
 	ginkgo.Describe("contactGroupMembershipFinalizer", func() {
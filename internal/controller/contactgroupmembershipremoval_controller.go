@@ -80,8 +80,8 @@ func (r *ContactGroupMembershipRemovalController) Reconcile(ctx context.Context,
 	}
 
 	// Set Status.Username based on Contact.Spec.SubjectRef.Kind
-	if contact.Spec.SubjectRef != nil && contact.Spec.SubjectRef.Kind == "User" {
-		cgmr.Status.Username = contact.Spec.SubjectRef.Name
+	if username, ok := contactSubjectUsername(contact); ok {
+		cgmr.Status.Username = username
 	}
 
 	// Get associated ContactGroupMemberships to contact group name and contact ref
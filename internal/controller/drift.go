@@ -0,0 +1,152 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// ResendDriftDetectedCondition reports whether the object was found missing on Resend during the last drift check
+	ResendDriftDetectedCondition = "ResendDriftDetected"
+	// ResendObjectInSyncReason is set when the object exists on Resend
+	ResendObjectInSyncReason = "ResendObjectInSync"
+	// ResendObjectRecreatedReason is set when the object was missing on Resend and has been recreated
+	ResendObjectRecreatedReason = "ResendObjectRecreated"
+)
+
+// Drift check results reported through the drift checks metric.
+const (
+	driftResultInSync  = "in_sync"
+	driftResultDrifted = "drifted"
+	driftResultError   = "error"
+)
+
+var (
+	providerDriftChecksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "resend_provider_drift_checks_total",
+			Help: "Number of checks verifying that a ready object still exists on Resend, by kind and result.",
+		},
+		[]string{"kind", "result"},
+	)
+	providerDriftRecreationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "resend_provider_drift_recreations_total",
+			Help: "Number of objects recreated on Resend after being found missing, by kind.",
+		},
+		[]string{"kind"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(providerDriftChecksTotal, providerDriftRecreationsTotal)
+}
+
+// driftTracker remembers when each object was last verified against the email provider, so objects are verified
+// at most once per resync interval regardless of how often they are reconciled.
+type driftTracker struct {
+	mu          sync.Mutex
+	lastChecked map[types.UID]time.Time
+}
+
+// due returns whether the object with the given uid has not been verified within the interval.
+// The tracker is not persisted, so an object seen for the first time, e.g. after a restart, is first verified after
+// a delay derived from its uid, spreading the checks of all objects over one interval instead of running them at once.
+func (t *driftTracker) due(uid types.UID, interval time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	last, ok := t.lastChecked[uid]
+	if !ok {
+		if t.lastChecked == nil {
+			t.lastChecked = map[types.UID]time.Time{}
+		}
+		last = time.Now().Add(firstDriftCheckDelay(uid, interval) - interval)
+		t.lastChecked[uid] = last
+	}
+	return time.Since(last) >= interval
+}
+
+// requeueAfter returns how long to wait before the object with the given uid is next due, at most the interval.
+func (t *driftTracker) requeueAfter(uid types.UID, interval time.Duration) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	last, ok := t.lastChecked[uid]
+	if !ok {
+		return interval
+	}
+	return min(max(interval-time.Since(last), time.Second), interval)
+}
+
+// firstDriftCheckDelay returns a delay within the interval that is stable for the given uid.
+func firstDriftCheckDelay(uid types.UID, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(uid))
+	return time.Duration(h.Sum64() % uint64(interval))
+}
+
+// record marks the object with the given uid as verified now.
+func (t *driftTracker) record(uid types.UID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.lastChecked == nil {
+		t.lastChecked = map[types.UID]time.Time{}
+	}
+	t.lastChecked[uid] = time.Now()
+}
+
+// forget drops the object with the given uid from the tracker.
+func (t *driftTracker) forget(uid types.UID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.lastChecked, uid)
+}
+
+// setDriftCondition records the result of a drift check in the ResendDriftDetected condition.
+func setDriftCondition(conditions *[]metav1.Condition, generation int64, kind string, drifted bool) {
+	if drifted {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               ResendDriftDetectedCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             ResendObjectRecreatedReason,
+			Message:            fmt.Sprintf("%s was missing on Resend and has been recreated", kind),
+			LastTransitionTime: metav1.Now(),
+			ObservedGeneration: generation,
+		})
+		return
+	}
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               ResendDriftDetectedCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             ResendObjectInSyncReason,
+		Message:            fmt.Sprintf("%s exists on Resend", kind),
+		LastTransitionTime: metav1.Now(),
+		ObservedGeneration: generation,
+	})
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// expire marks the object with the given uid as verified long ago, so it is due on its next reconcile.
+func (t *driftTracker) expire(uid types.UID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.lastChecked == nil {
+		t.lastChecked = map[types.UID]time.Time{}
+	}
+	t.lastChecked[uid] = time.Time{}
+}
+
+var _ = ginkgo.Describe("driftTracker", func() {
+	ginkgo.It("spreads the first checks of the objects over the interval", func() {
+		tracker := &driftTracker{}
+		due := 0
+		var longest time.Duration
+		for i := range 100 {
+			uid := types.UID(fmt.Sprintf("uid-%d", i))
+			if tracker.due(uid, time.Hour) {
+				due++
+			}
+			wait := tracker.requeueAfter(uid, time.Hour)
+			gomega.Expect(wait).To(gomega.BeNumerically("<=", time.Hour))
+			longest = max(longest, wait)
+		}
+		gomega.Expect(due).To(gomega.BeNumerically("<", 5))
+		gomega.Expect(longest).To(gomega.BeNumerically(">", 30*time.Minute))
+	})
+
+	ginkgo.It("delays the first check of an object by the same amount every time", func() {
+		gomega.Expect(firstDriftCheckDelay("uid-1", time.Hour)).To(gomega.Equal(firstDriftCheckDelay("uid-1", time.Hour)))
+		gomega.Expect(firstDriftCheckDelay("uid-1", time.Hour)).To(gomega.BeNumerically("<", time.Hour))
+	})
+
+	ginkgo.It("requeues after the interval once the object is verified", func() {
+		tracker := &driftTracker{}
+		tracker.record("uid-1")
+		gomega.Expect(tracker.due("uid-1", time.Hour)).To(gomega.BeFalse())
+		gomega.Expect(tracker.requeueAfter("uid-1", time.Hour)).To(gomega.BeNumerically("~", time.Hour, time.Second))
+
+		tracker.expire("uid-1")
+		gomega.Expect(tracker.due("uid-1", time.Hour)).To(gomega.BeTrue())
+	})
+})
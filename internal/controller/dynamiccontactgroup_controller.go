@@ -0,0 +1,331 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// ContactGroupContactSelectorAnnotation turns the ContactGroup into a dynamic contact group. Its value is a label
+	// selector, e.g. "team=platform,tier in (gold,silver)", over the Contacts in the namespace of the ContactGroup.
+	// Every matching Contact gets a ContactGroupMembership, unless it opted out with a ContactGroupMembershipRemoval.
+	ContactGroupContactSelectorAnnotation = "notification.miloapis.com/contact-selector"
+
+	// ContactGroupSelectorLabel marks the ContactGroupMemberships managed by the dynamic contact group named by its
+	// value. Memberships without the label are never deleted by the DynamicContactGroupController.
+	ContactGroupSelectorLabel = "notification.miloapis.com/selector-contactgroup"
+
+	// ContactSelectorSyncedCondition reports the member counts of a dynamic contact group
+	ContactSelectorSyncedCondition = "ContactSelectorSynced"
+
+	ContactSelectorSyncedReason  = "MembersSynced"
+	ContactSelectorInvalidReason = "InvalidSelector"
+)
+
+// DynamicContactGroupController keeps the ContactGroupMemberships of dynamic contact groups in sync with the Contacts
+// matching their label selector. It relies on the field indexes registered by the ContactGroupController.
+type DynamicContactGroupController struct {
+	Client client.Client
+}
+
+// contactGroupContactSelector returns the label selector of the dynamic contact group, if any.
+// An empty selector is reported as invalid.
+func contactGroupContactSelector(contactGroup *notificationmiloapiscomv1alpha1.ContactGroup) (labels.Selector, bool, error) {
+	value, ok := contactGroup.Annotations[ContactGroupContactSelectorAnnotation]
+	if !ok {
+		return nil, false, nil
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		// An empty selector would match every Contact in the namespace.
+		return nil, true, fmt.Errorf("contact selector is empty")
+	}
+	selector, err := labels.Parse(value)
+	if err != nil {
+		return nil, true, err
+	}
+	return selector, true, nil
+}
+
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contacts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmembershipremovals,verbs=get;list;watch
+
+// Reconcile is the main function that reconciles the memberships of a dynamic ContactGroup
+func (r *DynamicContactGroupController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx).WithValues("controller", "DynamicContactGroupController", "trigger", req.NamespacedName)
+	log.Info("Starting reconciliation", "namespacedName", req.String(), "name", req.Name, "namespace", req.Namespace)
+
+	// Get ContactGroup
+	contactGroup := &notificationmiloapiscomv1alpha1.ContactGroup{}
+	if err := r.Client.Get(ctx, req.NamespacedName, contactGroup); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("ContactGroup not found. Probably deleted.")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get ContactGroup")
+		return ctrl.Result{}, fmt.Errorf("failed to get ContactGroup: %w", err)
+	}
+	// The contact group finalizer deletes every membership, they must not be created again
+	if !contactGroup.GetDeletionTimestamp().IsZero() {
+		log.Info("ContactGroup is being deleted. Skipping reconciliation.")
+		return ctrl.Result{}, nil
+	}
+
+	managedMemberships := &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}
+	if err := r.Client.List(ctx, managedMemberships, client.InNamespace(contactGroup.Namespace),
+		client.MatchingLabels{ContactGroupSelectorLabel: contactGroup.Name}); err != nil {
+		log.Error(err, "Failed to list managed ContactGroupMemberships")
+		return ctrl.Result{}, fmt.Errorf("failed to list managed ContactGroupMemberships: %w", err)
+	}
+
+	oldStatus := contactGroup.Status.DeepCopy()
+	selector, ok, err := contactGroupContactSelector(contactGroup)
+	switch {
+	// Static contact group – memberships created while it was dynamic are removed
+	case !ok:
+		if err := r.deleteManagedMemberships(ctx, managedMemberships.Items, nil); err != nil {
+			log.Error(err, "Failed to delete managed ContactGroupMemberships")
+			return ctrl.Result{}, err
+		}
+		meta.RemoveStatusCondition(&contactGroup.Status.Conditions, ContactSelectorSyncedCondition)
+
+	// Invalid selector – memberships are kept until the selector is fixed
+	case err != nil:
+		log.Info("Invalid contact selector", "error", err.Error())
+		meta.SetStatusCondition(&contactGroup.Status.Conditions, metav1.Condition{
+			Type:               ContactSelectorSyncedCondition,
+			Status:             metav1.ConditionFalse,
+			Reason:             ContactSelectorInvalidReason,
+			Message:            fmt.Sprintf("Invalid contact selector: %s", err.Error()),
+			LastTransitionTime: metav1.Now(),
+			ObservedGeneration: contactGroup.GetGeneration(),
+		})
+
+	default:
+		members, optedOut, err := r.syncMemberships(ctx, contactGroup, selector, managedMemberships.Items)
+		if err != nil {
+			log.Error(err, "Failed to sync ContactGroupMemberships")
+			return ctrl.Result{}, err
+		}
+		meta.SetStatusCondition(&contactGroup.Status.Conditions, metav1.Condition{
+			Type:               ContactSelectorSyncedCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             ContactSelectorSyncedReason,
+			Message:            fmt.Sprintf("%d contacts match the selector. Members: %d, opted out: %d", members+optedOut, members, optedOut),
+			LastTransitionTime: metav1.Now(),
+			ObservedGeneration: contactGroup.GetGeneration(),
+		})
+	}
+
+	// Update status if it changed. The status is also written by the ContactGroupController, so the patch is rejected
+	// if the contact group changed since it was read.
+	if !equality.Semantic.DeepEqual(oldStatus, &contactGroup.Status) {
+		original := contactGroup.DeepCopy()
+		original.Status = *oldStatus
+		if err := r.Client.Status().Patch(ctx, contactGroup, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})); err != nil {
+			log.Error(err, "Failed to update contact group status")
+			return ctrl.Result{}, fmt.Errorf("failed to update contact group status: %w", err)
+		}
+	}
+
+	log.Info("Dynamic contact group reconciled")
+
+	return ctrl.Result{}, nil
+}
+
+// syncMemberships creates a ContactGroupMembership for every Contact matching the selector, and deletes the managed
+// memberships of the Contacts that no longer match. Contacts with a ContactGroupMembershipRemoval are skipped.
+// It returns the number of members and the number of matching contacts that opted out.
+func (r *DynamicContactGroupController) syncMemberships(ctx context.Context,
+	contactGroup *notificationmiloapiscomv1alpha1.ContactGroup,
+	selector labels.Selector,
+	managedMemberships []notificationmiloapiscomv1alpha1.ContactGroupMembership,
+) (int, int, error) {
+	log := logf.FromContext(ctx).WithValues("controller", "DynamicContactGroupController", "trigger", contactGroup.Name)
+
+	contacts := &notificationmiloapiscomv1alpha1.ContactList{}
+	if err := r.Client.List(ctx, contacts, client.InNamespace(contactGroup.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return 0, 0, fmt.Errorf("failed to list Contacts: %w", err)
+	}
+
+	indexKey := buildContactGroupNamespacedIndexKey(contactGroup.Name, contactGroup.Namespace)
+	memberships := &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}
+	if err := r.Client.List(ctx, memberships, client.MatchingFields{contactGroupNamespacedIndexKey: indexKey}); err != nil {
+		return 0, 0, fmt.Errorf("failed to list ContactGroupMemberships: %w", err)
+	}
+	removals := &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList{}
+	if err := r.Client.List(ctx, removals, client.MatchingFields{contactGroupToCgmrNamespacedIndexKey: indexKey}); err != nil {
+		return 0, 0, fmt.Errorf("failed to list ContactGroupMembershipRemovals: %w", err)
+	}
+
+	isMember := map[string]bool{}
+	for _, membership := range memberships.Items {
+		isMember[buildContactNamespacedIndexKey(membership.Spec.ContactRef.Name, membership.Spec.ContactRef.Namespace)] = true
+	}
+	hasOptedOut := map[string]bool{}
+	for _, removal := range removals.Items {
+		hasOptedOut[buildContactNamespacedIndexKey(removal.Spec.ContactRef.Name, removal.Spec.ContactRef.Namespace)] = true
+	}
+
+	matching := map[string]bool{}
+	members, optedOut := 0, 0
+	for _, contact := range contacts.Items {
+		if !contact.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+		contactKey := buildContactNamespacedIndexKey(contact.Name, contact.Namespace)
+		matching[contactKey] = true
+
+		switch {
+		case hasOptedOut[contactKey]:
+			optedOut++
+		case isMember[contactKey]:
+			members++
+		default:
+			log.Info("Contact matches the selector. Creating ContactGroupMembership.", "contact", contact.Name)
+			contactRef := notificationmiloapiscomv1alpha1.ContactReference{Name: contact.Name, Namespace: contact.Namespace}
+			contactGroupRef := notificationmiloapiscomv1alpha1.ContactGroupReference{Name: contactGroup.Name, Namespace: contactGroup.Namespace}
+			membership := &notificationmiloapiscomv1alpha1.ContactGroupMembership{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ContactGroupMembershipName(contactRef, contactGroupRef),
+					Namespace: contact.Namespace,
+					Labels:    map[string]string{ContactGroupSelectorLabel: contactGroup.Name},
+				},
+				Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
+					ContactRef:      contactRef,
+					ContactGroupRef: contactGroupRef,
+				},
+			}
+			err := r.Client.Create(ctx, membership)
+			if errors.IsAlreadyExists(err) {
+				err = r.verifyExistingMembership(ctx, membership)
+			}
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to create ContactGroupMembership: %w", err)
+			}
+			members++
+		}
+	}
+
+	if err := r.deleteManagedMemberships(ctx, managedMemberships, matching); err != nil {
+		return 0, 0, err
+	}
+
+	return members, optedOut, nil
+}
+
+// verifyExistingMembership verifies that the existing ContactGroupMembership with the name of the desired one, e.g.
+// created after the memberships were listed, references the same contact and contact group.
+func (r *DynamicContactGroupController) verifyExistingMembership(ctx context.Context, desired *notificationmiloapiscomv1alpha1.ContactGroupMembership) error {
+	existing := &notificationmiloapiscomv1alpha1.ContactGroupMembership{}
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(desired), existing); err != nil {
+		return fmt.Errorf("failed to get existing ContactGroupMembership: %w", err)
+	}
+	if existing.Spec.ContactRef != desired.Spec.ContactRef || existing.Spec.ContactGroupRef != desired.Spec.ContactGroupRef {
+		return fmt.Errorf("ContactGroupMembership %s/%s already exists for contact %s/%s and contact group %s/%s",
+			existing.Namespace, existing.Name,
+			existing.Spec.ContactRef.Namespace, existing.Spec.ContactRef.Name,
+			existing.Spec.ContactGroupRef.Namespace, existing.Spec.ContactGroupRef.Name)
+	}
+	return nil
+}
+
+// deleteManagedMemberships deletes the managed memberships whose contact is not in keep.
+func (r *DynamicContactGroupController) deleteManagedMemberships(ctx context.Context, managedMemberships []notificationmiloapiscomv1alpha1.ContactGroupMembership, keep map[string]bool) error {
+	log := logf.FromContext(ctx).WithValues("controller", "DynamicContactGroupController")
+
+	for _, membership := range managedMemberships {
+		if !membership.GetDeletionTimestamp().IsZero() ||
+			keep[buildContactNamespacedIndexKey(membership.Spec.ContactRef.Name, membership.Spec.ContactRef.Namespace)] {
+			continue
+		}
+		log.Info("Contact no longer matches the selector. Deleting ContactGroupMembership.", "contactGroupMembership", membership.Name)
+		if err := r.Client.Delete(ctx, &membership); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete ContactGroupMembership: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DynamicContactGroupController) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&notificationmiloapiscomv1alpha1.ContactGroup{}).
+		Watches(
+			&notificationmiloapiscomv1alpha1.Contact{},
+			handler.EnqueueRequestsFromMapFunc(r.enqueueDynamicContactGroupsForContact),
+		).
+		Watches(
+			&notificationmiloapiscomv1alpha1.ContactGroupMembership{},
+			handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+				cgm := obj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
+				return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: cgm.Spec.ContactGroupRef.Name, Namespace: cgm.Spec.ContactGroupRef.Namespace}}}
+			}),
+		).
+		Watches(
+			&notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{},
+			handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+				cgmr := obj.(*notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval)
+				return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: cgmr.Spec.ContactGroupRef.Name, Namespace: cgmr.Spec.ContactGroupRef.Namespace}}}
+			}),
+		).
+		Named("dynamiccontactgroup").
+		Complete(r)
+}
+
+// enqueueDynamicContactGroupsForContact enqueues every dynamic contact group in the namespace of the contact, as its
+// labels may have started or stopped matching their selectors.
+func (r *DynamicContactGroupController) enqueueDynamicContactGroupsForContact(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx).WithValues("controller", "enqueueDynamicContactGroupsForContact", "trigger", obj.GetName())
+
+	contactGroups := &notificationmiloapiscomv1alpha1.ContactGroupList{}
+	if err := r.Client.List(ctx, contactGroups, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.Error(err, "Failed to list ContactGroups")
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, contactGroup := range contactGroups.Items {
+		if _, ok := contactGroup.Annotations[ContactGroupContactSelectorAnnotation]; !ok {
+			continue
+		}
+		reqs = append(reqs, ctrl.Request{
+			NamespacedName: client.ObjectKey{Name: contactGroup.Name, Namespace: contactGroup.Namespace},
+		})
+	}
+
+	return reqs
+}
@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+	notificationv1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = ginkgo.Describe("DynamicContactGroupController", func() {
+	var (
+		ctx        context.Context
+		k8sClient  client.Client
+		controller *DynamicContactGroupController
+		group      *notificationv1.ContactGroup
+	)
+
+	newContact := func(name string, labels map[string]string) *notificationv1.Contact {
+		return &notificationv1.Contact{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+			Spec:       notificationv1.ContactSpec{Email: name + "@example.com"},
+		}
+	}
+
+	ginkgo.BeforeEach(func() {
+		ctx = context.Background()
+
+		group = &notificationv1.ContactGroup{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        "platform",
+				Annotations: map[string]string{ContactGroupContactSelectorAnnotation: "team=platform"},
+			},
+			Spec: notificationv1.ContactGroupSpec{DisplayName: "Platform"},
+		}
+
+		sch := scheme.Scheme
+		gomega.Expect(notificationv1.AddToScheme(sch)).To(gomega.Succeed())
+
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(sch).
+			WithStatusSubresource(&notificationv1.ContactGroup{}).
+			WithObjects(group.DeepCopy(),
+				newContact("alice", map[string]string{"team": "platform"}),
+				newContact("bob", map[string]string{"team": "platform"}),
+				newContact("carol", map[string]string{"team": "platform"}),
+				newContact("dave", map[string]string{"team": "sales"}),
+				// carol opted out of the contact group
+				&notificationv1.ContactGroupMembershipRemoval{
+					ObjectMeta: metav1.ObjectMeta{Name: "carol-platform", Namespace: "default"},
+					Spec: notificationv1.ContactGroupMembershipRemovalSpec{
+						ContactRef:      notificationv1.ContactReference{Name: "carol", Namespace: "default"},
+						ContactGroupRef: notificationv1.ContactGroupReference{Name: "platform", Namespace: "default"},
+					},
+				},
+				// bob was added by hand
+				&notificationv1.ContactGroupMembership{
+					ObjectMeta: metav1.ObjectMeta{Name: "bob-manual", Namespace: "default"},
+					Spec: notificationv1.ContactGroupMembershipSpec{
+						ContactRef:      notificationv1.ContactReference{Name: "bob", Namespace: "default"},
+						ContactGroupRef: notificationv1.ContactGroupReference{Name: "platform", Namespace: "default"},
+					},
+				},
+			).
+			WithIndex(&notificationv1.ContactGroupMembership{}, contactGroupNamespacedIndexKey, func(raw client.Object) []string {
+				c := raw.(*notificationv1.ContactGroupMembership)
+				return []string{buildContactGroupNamespacedIndexKey(c.Spec.ContactGroupRef.Name, c.Spec.ContactGroupRef.Namespace)}
+			}).
+			WithIndex(&notificationv1.ContactGroupMembershipRemoval{}, contactGroupToCgmrNamespacedIndexKey, func(raw client.Object) []string {
+				c := raw.(*notificationv1.ContactGroupMembershipRemoval)
+				return []string{buildContactGroupNamespacedIndexKey(c.Spec.ContactGroupRef.Name, c.Spec.ContactGroupRef.Namespace)}
+			}).
+			Build()
+
+		controller = &DynamicContactGroupController{Client: k8sClient}
+	})
+
+	reconcileAndFetch := func() *notificationv1.ContactGroup {
+		_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: group.Name, Namespace: group.Namespace}})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		fetched := &notificationv1.ContactGroup{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: group.Name, Namespace: group.Namespace}, fetched)).To(gomega.Succeed())
+		return fetched
+	}
+
+	aliceMembership := ContactGroupMembershipName(
+		notificationv1.ContactReference{Name: "alice", Namespace: "default"},
+		notificationv1.ContactGroupReference{Name: "platform", Namespace: "default"},
+	)
+
+	// interceptCreate replaces the controller client with one where creating a ContactGroupMembership first creates
+	// the given membership, as another client would, and then fails with AlreadyExists
+	interceptCreate := func(existing func(*notificationv1.ContactGroupMembership) *notificationv1.ContactGroupMembership) {
+		controller.Client = interceptor.NewClient(k8sClient.(client.WithWatch), interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				cgm, ok := obj.(*notificationv1.ContactGroupMembership)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				if err := c.Create(ctx, existing(cgm.DeepCopy()), opts...); err != nil {
+					return err
+				}
+				return apierrors.NewAlreadyExists(schema.GroupResource{Group: notificationv1.SchemeGroupVersion.Group, Resource: "contactgroupmemberships"}, cgm.Name)
+			},
+		})
+	}
+
+	membershipNames := func() []string {
+		list := &notificationv1.ContactGroupMembershipList{}
+		gomega.Expect(k8sClient.List(ctx, list)).To(gomega.Succeed())
+		names := []string{}
+		for _, cgm := range list.Items {
+			names = append(names, cgm.Name)
+		}
+		return names
+	}
+
+	ginkgo.It("creates memberships for matching contacts that did not opt out and reports the counts", func() {
+		fetched := reconcileAndFetch()
+
+		gomega.Expect(membershipNames()).To(gomega.ConsistOf(aliceMembership, "bob-manual"))
+
+		managed := &notificationv1.ContactGroupMembership{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: aliceMembership, Namespace: "default"}, managed)).To(gomega.Succeed())
+		gomega.Expect(managed.Labels).To(gomega.HaveKeyWithValue(ContactGroupSelectorLabel, "platform"))
+
+		cond := meta.FindStatusCondition(fetched.Status.Conditions, ContactSelectorSyncedCondition)
+		gomega.Expect(cond).NotTo(gomega.BeNil())
+		gomega.Expect(cond.Status).To(gomega.Equal(metav1.ConditionTrue))
+		gomega.Expect(cond.Message).To(gomega.Equal("3 contacts match the selector. Members: 2, opted out: 1"))
+	})
+
+	ginkgo.It("counts a membership created concurrently for the same contact as a member", func() {
+		interceptCreate(func(cgm *notificationv1.ContactGroupMembership) *notificationv1.ContactGroupMembership {
+			return cgm
+		})
+
+		fetched := reconcileAndFetch()
+		gomega.Expect(membershipNames()).To(gomega.ConsistOf(aliceMembership, "bob-manual"))
+		gomega.Expect(meta.FindStatusCondition(fetched.Status.Conditions, ContactSelectorSyncedCondition).Message).
+			To(gomega.Equal("3 contacts match the selector. Members: 2, opted out: 1"))
+	})
+
+	ginkgo.It("fails when a membership with the same name references another contact group", func() {
+		interceptCreate(func(cgm *notificationv1.ContactGroupMembership) *notificationv1.ContactGroupMembership {
+			cgm.Spec.ContactGroupRef.Namespace = "other"
+			return cgm
+		})
+
+		_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: group.Name, Namespace: group.Namespace}})
+		gomega.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("already exists for contact default/alice and contact group other/platform")))
+	})
+
+	ginkgo.It("deletes the managed memberships of contacts that no longer match", func() {
+		reconcileAndFetch()
+
+		alice := &notificationv1.Contact{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "alice", Namespace: "default"}, alice)).To(gomega.Succeed())
+		alice.Labels = map[string]string{"team": "sales"}
+		gomega.Expect(k8sClient.Update(ctx, alice)).To(gomega.Succeed())
+
+		fetched := reconcileAndFetch()
+		gomega.Expect(membershipNames()).To(gomega.ConsistOf("bob-manual"))
+		gomega.Expect(meta.FindStatusCondition(fetched.Status.Conditions, ContactSelectorSyncedCondition).Message).
+			To(gomega.Equal("2 contacts match the selector. Members: 1, opted out: 1"))
+	})
+
+	ginkgo.It("removes the managed memberships when the selector is removed", func() {
+		reconcileAndFetch()
+
+		current := &notificationv1.ContactGroup{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: group.Name, Namespace: group.Namespace}, current)).To(gomega.Succeed())
+		current.Annotations = nil
+		gomega.Expect(k8sClient.Update(ctx, current)).To(gomega.Succeed())
+
+		fetched := reconcileAndFetch()
+		gomega.Expect(membershipNames()).To(gomega.ConsistOf("bob-manual"))
+		gomega.Expect(meta.FindStatusCondition(fetched.Status.Conditions, ContactSelectorSyncedCondition)).To(gomega.BeNil())
+	})
+
+	ginkgo.It("reports an invalid selector", func() {
+		current := &notificationv1.ContactGroup{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: group.Name, Namespace: group.Namespace}, current)).To(gomega.Succeed())
+		current.Annotations[ContactGroupContactSelectorAnnotation] = "team in platform"
+		gomega.Expect(k8sClient.Update(ctx, current)).To(gomega.Succeed())
+
+		fetched := reconcileAndFetch()
+		cond := meta.FindStatusCondition(fetched.Status.Conditions, ContactSelectorSyncedCondition)
+		gomega.Expect(cond).NotTo(gomega.BeNil())
+		gomega.Expect(cond.Status).To(gomega.Equal(metav1.ConditionFalse))
+		gomega.Expect(cond.Reason).To(gomega.Equal(ContactSelectorInvalidReason))
+		gomega.Expect(membershipNames()).To(gomega.ConsistOf("bob-manual"))
+	})
+
+	ginkgo.It("reports an empty selector as invalid", func() {
+		current := &notificationv1.ContactGroup{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: group.Name, Namespace: group.Namespace}, current)).To(gomega.Succeed())
+		current.Annotations[ContactGroupContactSelectorAnnotation] = "  "
+		gomega.Expect(k8sClient.Update(ctx, current)).To(gomega.Succeed())
+
+		fetched := reconcileAndFetch()
+		cond := meta.FindStatusCondition(fetched.Status.Conditions, ContactSelectorSyncedCondition)
+		gomega.Expect(cond).NotTo(gomega.BeNil())
+		gomega.Expect(cond.Status).To(gomega.Equal(metav1.ConditionFalse))
+		gomega.Expect(cond.Reason).To(gomega.Equal(ContactSelectorInvalidReason))
+		gomega.Expect(membershipNames()).To(gomega.ConsistOf("bob-manual"))
+	})
+})
@@ -0,0 +1,212 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+	emailtemplating "go.miloapis.com/email-provider-resend/internal/emailtemplanting"
+)
+
+const (
+	// EmailRecipientContactGroupAnnotation sends the Email to every member of a ContactGroup through a Resend
+	// broadcast instead of to the Spec.Recipient. The value is either "<name>", for a ContactGroup in the Email
+	// namespace, or "<namespace>/<name>".
+	EmailRecipientContactGroupAnnotation = "notification.miloapis.com/recipient-contactgroup"
+	// EmailBroadcastIDAnnotation records the id of the broadcast created for the Email before it is recorded in the
+	// Email status, so a failed status update finds the broadcast again without scanning every broadcast.
+	EmailBroadcastIDAnnotation = "notification.miloapis.com/broadcast-id"
+
+	// BroadcastCreatedReason is set when the broadcast was created on Resend but has not been sent yet
+	BroadcastCreatedReason = "BroadcastCreated"
+)
+
+// recipientContactGroupKey returns the key of the ContactGroup the Email is broadcast to, if any.
+func recipientContactGroupKey(email *notificationmiloapiscomv1alpha1.Email) (client.ObjectKey, bool) {
+	return emailContactGroupKey(email, EmailRecipientContactGroupAnnotation)
+}
+
+// emailContactGroupKey returns the key of the ContactGroup referenced by the annotation of the Email, if any.
+// The value is either "<name>", for a ContactGroup in the Email namespace, or "<namespace>/<name>".
+func emailContactGroupKey(email *notificationmiloapiscomv1alpha1.Email, annotation string) (client.ObjectKey, bool) {
+	value := strings.TrimSpace(email.Annotations[annotation])
+	if value == "" {
+		return client.ObjectKey{}, false
+	}
+	if namespace, name, found := strings.Cut(value, "/"); found {
+		return client.ObjectKey{Namespace: namespace, Name: name}, true
+	}
+	return client.ObjectKey{Namespace: email.Namespace, Name: value}, true
+}
+
+// reconcileBroadcast sends the Email to every member of the ContactGroup through a Resend broadcast.
+// The broadcast is created first and its ID recorded in the Email status before sending it, so a failed status
+// update never leads to the broadcast being sent twice; the provider reuses the draft left behind by such a failure
+// instead of creating another one. Afterwards the broadcast is polled until it is sent or fails.
+func (r *EmailController) reconcileBroadcast(ctx context.Context,
+	email *notificationmiloapiscomv1alpha1.Email,
+	contactGroupKey client.ObjectKey,
+) (ctrl.Result, error) {
+	log := logf.FromContext(ctx).WithName("email-reconciler").WithValues("contactGroup", contactGroupKey.String())
+
+	retryAfter := r.Config.GetWaitTimeBeforeRetry(email.Spec.Priority)
+	if email.Status.ProviderID == "" {
+		contactGroup := &notificationmiloapiscomv1alpha1.ContactGroup{}
+		if err := r.Client.Get(ctx, contactGroupKey, contactGroup); err != nil {
+			log.Error(err, "Failed to get recipient ContactGroup")
+			return ctrl.Result{}, fmt.Errorf("failed to get recipient ContactGroup: %w", err)
+		}
+		if contactGroup.Status.ProviderID == "" {
+			log.Info("Recipient ContactGroup is not ready yet. Requeuing.")
+			return ctrl.Result{RequeueAfter: retryAfter}, nil
+		}
+
+		rendered, err := r.pinnedContent(ctx, email, "")
+		if isPinnedTemplateUnavailable(err) {
+			log.Error(err, "Failed to resolve pinned broadcast content")
+			return r.failPinnedTemplate(ctx, email, err)
+		}
+		if emailtemplating.IsTerminalError(err) {
+			log.Error(err, "Failed to render broadcast")
+			return r.failRendering(ctx, email, err)
+		}
+		if err != nil {
+			log.Error(err, "Failed to render broadcast")
+			return ctrl.Result{}, fmt.Errorf("failed to render broadcast: %w", err)
+		}
+
+		log.Info("Creating broadcast")
+		output, err := r.EmailProvider.CreateBroadcast(ctx, email.DeepCopy(), rendered, contactGroup.DeepCopy(), email.Annotations[EmailBroadcastIDAnnotation])
+		if err != nil {
+			log.Error(err, "Failed to create broadcast")
+			if err := r.updateEmailStatus(ctx, email, metav1.Condition{
+				Type:               notificationmiloapiscomv1alpha1.EmailDeliveredCondition,
+				Status:             metav1.ConditionFalse,
+				Reason:             notificationmiloapiscomv1alpha1.EmailDeliveryFailedReason,
+				Message:            fmt.Sprintf("Broadcast creation failed: %s", err.Error()),
+				LastTransitionTime: metav1.Now(),
+			}); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to update Email status: %w", err)
+			}
+			return ctrl.Result{RequeueAfter: retryAfter}, nil
+		}
+		log.Info("Broadcast created", "broadcastID", output.BroadcastID)
+		if err := r.recordBroadcastID(ctx, email, output.BroadcastID); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		email.Status.ProviderID = output.BroadcastID
+		email.Status.HTMLBody = output.HTMLBody
+		email.Status.TextBody = output.TextBody
+		email.Status.Subject = output.Subject
+		if err := r.updateEmailStatus(ctx, email, metav1.Condition{
+			Type:               notificationmiloapiscomv1alpha1.EmailDeliveredCondition,
+			Status:             metav1.ConditionUnknown,
+			Reason:             BroadcastCreatedReason,
+			Message:            fmt.Sprintf("Broadcast created. Provider ID: %s", output.BroadcastID),
+			LastTransitionTime: metav1.Now(),
+		}); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update Email status: %w", err)
+		}
+		// Sending happens on the next reconciliation, triggered by the status update
+		return ctrl.Result{}, nil
+	}
+
+	delivered := meta.FindStatusCondition(email.Status.Conditions, notificationmiloapiscomv1alpha1.EmailDeliveredCondition)
+	if delivered != nil && delivered.Status != metav1.ConditionUnknown {
+		log.Info("Broadcast already finished", "status", delivered.Status, "reason", delivered.Reason)
+		return ctrl.Result{}, nil
+	}
+
+	broadcast, err := r.EmailProvider.GetBroadcast(ctx, email)
+	if err != nil {
+		log.Error(err, "Failed to get broadcast", "broadcastID", email.Status.ProviderID)
+		return ctrl.Result{}, fmt.Errorf("failed to get broadcast: %w", err)
+	}
+
+	if broadcast.Status == emailprovider.BroadcastStatusDraft {
+		log.Info("Sending broadcast", "broadcastID", email.Status.ProviderID)
+		if _, err := r.EmailProvider.SendBroadcast(ctx, email); err != nil {
+			log.Error(err, "Failed to send broadcast", "broadcastID", email.Status.ProviderID)
+			return ctrl.Result{}, fmt.Errorf("failed to send broadcast: %w", err)
+		}
+		broadcast.Status = emailprovider.BroadcastStatusQueued
+	}
+
+	condition := metav1.Condition{
+		Type:               notificationmiloapiscomv1alpha1.EmailDeliveredCondition,
+		Status:             metav1.ConditionUnknown,
+		Reason:             notificationmiloapiscomv1alpha1.EmailDeliveryPendingReason,
+		Message:            fmt.Sprintf("Broadcast %s. Provider ID: %s", broadcast.Status, email.Status.ProviderID),
+		LastTransitionTime: metav1.Now(),
+	}
+	switch broadcast.Status {
+	case emailprovider.BroadcastStatusSent:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = notificationmiloapiscomv1alpha1.EmailDeliveredReason
+	case emailprovider.BroadcastStatusFailed:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = notificationmiloapiscomv1alpha1.EmailDeliveryFailedReason
+	}
+
+	if delivered == nil || delivered.Reason != condition.Reason || delivered.Message != condition.Message {
+		if err := r.updateEmailStatus(ctx, email, condition); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update Email status: %w", err)
+		}
+	}
+
+	if condition.Status == metav1.ConditionUnknown {
+		return ctrl.Result{RequeueAfter: retryAfter}, nil
+	}
+	log.Info("Broadcast finished", "broadcastID", email.Status.ProviderID, "status", broadcast.Status)
+	return ctrl.Result{}, nil
+}
+
+// recordBroadcastID records the id of the broadcast in the Email annotations.
+// The in-memory status is kept, as it may hold changes not yet persisted.
+func (r *EmailController) recordBroadcastID(ctx context.Context, email *notificationmiloapiscomv1alpha1.Email, broadcastID string) error {
+	if email.Annotations[EmailBroadcastIDAnnotation] == broadcastID {
+		return nil
+	}
+
+	status := email.Status.DeepCopy()
+	patch := client.MergeFrom(email.DeepCopy())
+
+	annotations := email.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[EmailBroadcastIDAnnotation] = broadcastID
+	email.SetAnnotations(annotations)
+
+	if err := r.Client.Patch(ctx, email, patch); err != nil {
+		return fmt.Errorf("failed to update Email broadcast annotation: %w", err)
+	}
+	email.Status = *status
+
+	return nil
+}
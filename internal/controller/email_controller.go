@@ -31,18 +31,30 @@ import (
 
 	"go.miloapis.com/email-provider-resend/internal/config"
 	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+	emailtemplating "go.miloapis.com/email-provider-resend/internal/emailtemplanting"
 )
 
+// EmailRenderFailedReason is set when the EmailTemplate cannot be rendered with the variables of the Email, e.g. in
+// strict variables mode. Rendering is not retried, as it would fail again.
+const EmailRenderFailedReason = "EmailRenderFailed"
+
 // EmailReconciler reconciles a Email object
 type EmailController struct {
 	Client        client.Client
 	EmailProvider emailprovider.Service
 	Config        config.EmailControllerConfig
+	// UnsubscribeConfig enables the unsubscribe links of the Emails tied to a ContactGroup
+	UnsubscribeConfig config.UnsubscribeConfig
+	// TemplateConfig configures the rendering of the EmailTemplates
+	TemplateConfig config.TemplateConfig
 }
 
-// +kubebuilder:rbac:groups=notification.miloapis.com,resources=emails,verbs=get
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=emails,verbs=get;patch
 // +kubebuilder:rbac:groups=notification.miloapis.com,resources=emails/status,verbs=get;update
-// +kubebuilder:rbac:groups=notification.miloapis.com,resources=emailtemplates,verbs=get
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=emailtemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups,verbs=get
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships,verbs=list;watch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contacts,verbs=get
 // +kubebuilder:rbac:groups=iam.miloapis.com,resources=users,verbs=get
 
 // Reconcile is the main function that reconciles the Email object.
@@ -62,15 +74,16 @@ func (r *EmailController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		return ctrl.Result{}, fmt.Errorf("failed to get Email: %w", err)
 	}
 
+	if isEmailRenderFailed(email) {
+		log.Info("Email template could not be rendered. Not retrying.")
+		return ctrl.Result{}, nil
+	}
+
 	log.Info("Reconciling Email", "email", email.Name, "template", email.Spec.TemplateRef.Name, "recipient", email.Spec.Recipient)
 
-	// Get EmailTemplate
-	emailTemplate := &notificationmiloapiscomv1alpha1.EmailTemplate{} // Cluster scoped resource
-	err = r.Client.Get(ctx, client.ObjectKey{Name: email.Spec.TemplateRef.Name}, emailTemplate)
-	if err != nil {
-		// emailTemplate is warranty to exist. As it is checked on a webhook on Milo.
-		log.Error(err, "Failed to get EmailTemplate", "email", email.Spec.TemplateRef.Name)
-		return ctrl.Result{}, fmt.Errorf("failed to get EmailTemplate: %w", err)
+	// Emails to a ContactGroup are sent as a broadcast to every member instead of to the Spec.Recipient
+	if contactGroupKey, ok := recipientContactGroupKey(email); ok {
+		return r.reconcileBroadcast(ctx, email, contactGroupKey)
 	}
 
 	// Get EmailRecipient
@@ -83,8 +96,29 @@ func (r *EmailController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	if !isEmailAlreadySent(email) {
 		log.Info("Sending email")
 
+		unsubscribeURL, err := r.getUnsubscribeURL(ctx, email, recipientEmailAddress)
+		if err != nil {
+			log.Error(err, "Failed to get unsubscribe URL", "email", email.Name)
+			return ctrl.Result{}, fmt.Errorf("failed to get unsubscribe URL: %w", err)
+		}
+
+		// The EmailTemplate is only read when the Email has no content snapshot yet
+		rendered, err := r.pinnedContent(ctx, email, unsubscribeURL)
+		if isPinnedTemplateUnavailable(err) {
+			log.Error(err, "Failed to resolve pinned email content", "email", email.Name)
+			return r.failPinnedTemplate(ctx, email, err)
+		}
+		if emailtemplating.IsTerminalError(err) {
+			log.Error(err, "Failed to render email", "email", email.Name)
+			return r.failRendering(ctx, email, err)
+		}
+		if err != nil {
+			log.Error(err, "Failed to render email", "email", email.Name)
+			return ctrl.Result{}, fmt.Errorf("failed to render email: %w", err)
+		}
+
 		// Send email
-		output, err := r.EmailProvider.Send(ctx, email.DeepCopy(), emailTemplate.DeepCopy(), recipientEmailAddress)
+		output, err := r.EmailProvider.Send(ctx, email.DeepCopy(), rendered, recipientEmailAddress, unsubscribeURL)
 		if err != nil {
 			log.Error(err, "Failed to send email", "email", email.Name)
 			if err := r.updateEmailStatus(ctx, email, metav1.Condition{
@@ -146,6 +180,57 @@ func isEmailAlreadySent(email *notificationmiloapiscomv1alpha1.Email) bool {
 	return false
 }
 
+// isEmailRenderFailed checks if the template of the email could not be rendered, which is a terminal failure
+func isEmailRenderFailed(email *notificationmiloapiscomv1alpha1.Email) bool {
+	delivered := meta.FindStatusCondition(email.Status.Conditions, notificationmiloapiscomv1alpha1.EmailDeliveredCondition)
+	return delivered != nil && delivered.Reason == EmailRenderFailedReason
+}
+
+// renderOptions returns the options the EmailTemplate is rendered with, along with the partials it can invoke.
+func (r *EmailController) renderOptions(ctx context.Context, emailTemplate *notificationmiloapiscomv1alpha1.EmailTemplate) (emailtemplating.RenderOptions, error) {
+	partials, err := listPartials(ctx, r.Client)
+	if err != nil {
+		return emailtemplating.RenderOptions{}, err
+	}
+	return emailtemplating.RenderOptions{
+		Strict:   emailtemplating.IsStrict(emailTemplate, r.TemplateConfig.IsStrictVariables()),
+		Partials: partials,
+	}, nil
+}
+
+// listPartials returns the EmailTemplates labeled as partials by partial name. If several EmailTemplates have the same
+// partial name, the first one by name is used.
+func listPartials(ctx context.Context, c client.Client) (map[string]*notificationmiloapiscomv1alpha1.EmailTemplate, error) {
+	templates := &notificationmiloapiscomv1alpha1.EmailTemplateList{}
+	if err := c.List(ctx, templates, client.HasLabels{emailtemplating.PartialLabel}); err != nil {
+		return nil, fmt.Errorf("failed to list partial EmailTemplates: %w", err)
+	}
+
+	partials := make(map[string]*notificationmiloapiscomv1alpha1.EmailTemplate, len(templates.Items))
+	for i := range templates.Items {
+		template := &templates.Items[i]
+		name := template.Labels[emailtemplating.PartialLabel]
+		if existing, ok := partials[name]; !ok || template.Name < existing.Name {
+			partials[name] = template
+		}
+	}
+	return partials, nil
+}
+
+// failRendering marks the Email as failed because its template cannot be rendered. The Email is not requeued.
+func (r *EmailController) failRendering(ctx context.Context, email *notificationmiloapiscomv1alpha1.Email, renderErr error) (ctrl.Result, error) {
+	if err := r.updateEmailStatus(ctx, email, metav1.Condition{
+		Type:               notificationmiloapiscomv1alpha1.EmailDeliveredCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             EmailRenderFailedReason,
+		Message:            fmt.Sprintf("Email rendering failed: %s", renderErr.Error()),
+		LastTransitionTime: metav1.Now(),
+	}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update Email status: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
 // updateEmailStatus updates the status of the email with the given condition.
 func (r *EmailController) updateEmailStatus(ctx context.Context, email *notificationmiloapiscomv1alpha1.Email, condition metav1.Condition) error {
 	log := logf.FromContext(ctx).WithName("email-reconciler")
@@ -177,6 +262,11 @@ func (r *EmailController) SetupWithManager(mgr ctrl.Manager) error {
 		return fmt.Errorf("failed to index Email objects by .status.providerID: %w", err)
 	}
 
+	// Index Contacts by email address, to find the unsubscribe link of the recipient
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &notificationmiloapiscomv1alpha1.Contact{}, contactEmailIndexKey, contactEmailIndex); err != nil {
+		return fmt.Errorf("failed to index Contact objects by email address: %w", err)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&notificationmiloapiscomv1alpha1.Email{}).
 		Named("email").
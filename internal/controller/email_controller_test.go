@@ -25,6 +25,7 @@ import (
 	gomega "github.com/onsi/gomega"
 	iammiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -35,6 +36,7 @@ import (
 	"go.miloapis.com/email-provider-resend/internal/config"
 	"go.miloapis.com/email-provider-resend/internal/emailprovider"
 	"go.miloapis.com/email-provider-resend/internal/emailprovider/mockprovider"
+	emailtemplating "go.miloapis.com/email-provider-resend/internal/emailtemplanting"
 )
 
 var _ = ginko.Describe("EmailController.Reconcile", func() {
@@ -173,6 +175,261 @@ var _ = ginko.Describe("EmailController.Reconcile", func() {
 		})
 	})
 
+	ginko.Context("when the template is edited while the email is being retried", func() {
+		editTemplate := func(subject string) {
+			template := &notificationmiloapiscomv1alpha1.EmailTemplate{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "welcome-template"}, template)).To(gomega.Succeed())
+			template.Spec.Subject = subject
+			// The fake client does not bump the generation on spec changes
+			template.Generation++
+			gomega.Expect(k8sClient.Update(ctx, template)).To(gomega.Succeed())
+		}
+		deleteTemplate := func() {
+			template := &notificationmiloapiscomv1alpha1.EmailTemplate{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "welcome-template"}, template)).To(gomega.Succeed())
+			gomega.Expect(k8sClient.Delete(ctx, template)).To(gomega.Succeed())
+		}
+		loseSnapshot := func() {
+			email := &notificationmiloapiscomv1alpha1.Email{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}, email)).To(gomega.Succeed())
+			email.Status.Subject = ""
+			gomega.Expect(k8sClient.Status().Update(ctx, email)).To(gomega.Succeed())
+		}
+		expectPinUnavailable := func() {
+			fetched := &notificationmiloapiscomv1alpha1.Email{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}, fetched)).To(gomega.Succeed())
+			pinned := meta.FindStatusCondition(fetched.Status.Conditions, EmailTemplatePinnedCondition)
+			gomega.Expect(pinned).NotTo(gomega.BeNil())
+			gomega.Expect(pinned.Status).To(gomega.Equal(metav1.ConditionFalse))
+			gomega.Expect(pinned.Reason).To(gomega.Equal(PinnedTemplateUnavailableReason))
+			delivered := meta.FindStatusCondition(fetched.Status.Conditions, notificationmiloapiscomv1alpha1.EmailDeliveredCondition)
+			gomega.Expect(delivered.Reason).To(gomega.Equal(EmailRenderFailedReason))
+		}
+
+		ginko.It("retries with the content snapshotted before the first send attempt", func() {
+			fakeProv.SendEmailErr = fmt.Errorf("provider failure")
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			editTemplate("Welcome, edited")
+			fakeProv.SendEmailErr = nil
+			_, err = controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(fakeProv.SendEmailCallCount).To(gomega.Equal(2))
+			gomega.Expect(fakeProv.LastSendEmailInput.Subject).To(gomega.Equal("Welcome"))
+
+			fetched := &notificationmiloapiscomv1alpha1.Email{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}, fetched)).To(gomega.Succeed())
+			gomega.Expect(fetched.Status.Subject).To(gomega.Equal("Welcome"))
+			pinned := meta.FindStatusCondition(fetched.Status.Conditions, EmailTemplatePinnedCondition)
+			gomega.Expect(pinned).NotTo(gomega.BeNil())
+			gomega.Expect(pinned.Status).To(gomega.Equal(metav1.ConditionTrue))
+			gomega.Expect(pinned.Reason).To(gomega.Equal(TemplateSnapshottedReason))
+			gomega.Expect(pinned.Message).To(gomega.ContainSubstring("EmailTemplate welcome-template"))
+			gomega.Expect(fetched.Annotations).To(gomega.HaveKeyWithValue(PinnedTemplateAnnotation, "welcome-template"))
+			gomega.Expect(fetched.Annotations).To(gomega.HaveKeyWithValue(PinnedTemplateGenerationAnnotation, "0"))
+			gomega.Expect(fetched.Annotations).To(gomega.HaveKey(PinnedTemplateResourceVersionAnnotation))
+			gomega.Expect(fetched.Annotations).To(gomega.HaveKeyWithValue(PinnedContentHashAnnotation, gomega.HavePrefix("sha256:")))
+		})
+
+		ginko.It("retries with the snapshotted content after the template is deleted", func() {
+			fakeProv.SendEmailErr = fmt.Errorf("provider failure")
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			deleteTemplate()
+			fakeProv.SendEmailErr = nil
+			res, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(res).To(gomega.Equal(ctrl.Result{}))
+			gomega.Expect(fakeProv.SendEmailCallCount).To(gomega.Equal(2))
+			gomega.Expect(fakeProv.LastSendEmailInput.Subject).To(gomega.Equal("Welcome"))
+
+			fetched := &notificationmiloapiscomv1alpha1.Email{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}, fetched)).To(gomega.Succeed())
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("delivery-123"))
+		})
+
+		ginko.It("fails the email without retrying it when the snapshot was lost and the template deleted", func() {
+			fakeProv.SendEmailErr = fmt.Errorf("provider failure")
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			loseSnapshot()
+			deleteTemplate()
+			fakeProv.SendEmailErr = nil
+			res, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(res).To(gomega.Equal(ctrl.Result{}))
+			gomega.Expect(fakeProv.SendEmailCallCount).To(gomega.Equal(1))
+			expectPinUnavailable()
+		})
+
+		ginko.It("fails the email without retrying it when the snapshot was lost", func() {
+			fakeProv.SendEmailErr = fmt.Errorf("provider failure")
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			loseSnapshot()
+			editTemplate("Welcome, edited")
+
+			fakeProv.SendEmailErr = nil
+			res, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(res).To(gomega.Equal(ctrl.Result{}))
+			gomega.Expect(fakeProv.SendEmailCallCount).To(gomega.Equal(1))
+			expectPinUnavailable()
+		})
+
+		ginko.It("restores the lost snapshot from the unchanged template", func() {
+			fakeProv.SendEmailErr = fmt.Errorf("provider failure")
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			loseSnapshot()
+
+			fakeProv.SendEmailErr = nil
+			_, err = controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(fakeProv.SendEmailCallCount).To(gomega.Equal(2))
+			gomega.Expect(fakeProv.LastSendEmailInput.Subject).To(gomega.Equal("Welcome"))
+		})
+	})
+
+	ginko.Context("when the variables do not match the template in strict mode", func() {
+		ginko.It("fails the email without sending nor retrying it", func() {
+			template := &notificationmiloapiscomv1alpha1.EmailTemplate{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "welcome-template"}, template)).To(gomega.Succeed())
+			template.Annotations = map[string]string{emailtemplating.StrictVariablesAnnotation: "true"}
+			template.Spec.Subject = "Welcome {{ .Name }}"
+			gomega.Expect(k8sClient.Update(ctx, template)).To(gomega.Succeed())
+
+			for range 2 {
+				res, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				gomega.Expect(res).To(gomega.Equal(ctrl.Result{}))
+			}
+			gomega.Expect(fakeProv.SendEmailCallCount).To(gomega.Equal(0))
+
+			fetched := &notificationmiloapiscomv1alpha1.Email{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}, fetched)).To(gomega.Succeed())
+			delivered := meta.FindStatusCondition(fetched.Status.Conditions, notificationmiloapiscomv1alpha1.EmailDeliveredCondition)
+			gomega.Expect(delivered).NotTo(gomega.BeNil())
+			gomega.Expect(delivered.Status).To(gomega.Equal(metav1.ConditionFalse))
+			gomega.Expect(delivered.Reason).To(gomega.Equal(EmailRenderFailedReason))
+			gomega.Expect(delivered.Message).To(gomega.ContainSubstring("missing variables: Name"))
+		})
+	})
+
+	ginko.Context("when a strict template tests the unsubscribe URL of an email without unsubscribe link", func() {
+		ginko.It("renders the template with an empty unsubscribe URL", func() {
+			template := &notificationmiloapiscomv1alpha1.EmailTemplate{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "welcome-template"}, template)).To(gomega.Succeed())
+			template.Annotations = map[string]string{emailtemplating.StrictVariablesAnnotation: "true"}
+			template.Spec.Subject = `Welcome{{ if .UnsubscribeURL }} (unsubscribe: {{ .UnsubscribeURL }}){{ end }}`
+			gomega.Expect(k8sClient.Update(ctx, template)).To(gomega.Succeed())
+
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(fakeProv.SendEmailCallCount).To(gomega.Equal(1))
+			gomega.Expect(fakeProv.LastSendEmailInput.Subject).To(gomega.Equal("Welcome"))
+		})
+	})
+
+	ginko.Context("when the template has locale variants", func() {
+		ginko.BeforeEach(func() {
+			gomega.Expect(k8sClient.Create(ctx, &notificationmiloapiscomv1alpha1.EmailTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "welcome-template-pt", Labels: map[string]string{
+					emailtemplating.VariantOfLabel: "welcome-template",
+					emailtemplating.LocaleLabel:    "pt",
+				}},
+				Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{Subject: "Bem-vindo"},
+			})).To(gomega.Succeed())
+		})
+
+		ginko.It("renders the variant of the user locale, falling back to its language", func() {
+			user := &iammiloapiscomv1alpha1.User{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "user-1"}, user)).To(gomega.Succeed())
+			user.Annotations = map[string]string{UserLocaleAnnotation: "pt-BR"}
+			gomega.Expect(k8sClient.Update(ctx, user)).To(gomega.Succeed())
+
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(fakeProv.LastSendEmailInput.Subject).To(gomega.Equal("Bem-vindo"))
+
+			fetched := &notificationmiloapiscomv1alpha1.Email{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}, fetched)).To(gomega.Succeed())
+			localized := meta.FindStatusCondition(fetched.Status.Conditions, EmailLocalizedCondition)
+			gomega.Expect(localized).NotTo(gomega.BeNil())
+			gomega.Expect(localized.Reason).To(gomega.Equal(LocaleFallbackReason))
+			gomega.Expect(localized.Message).To(gomega.ContainSubstring(`locale "pt" with EmailTemplate welcome-template-pt`))
+		})
+
+		ginko.It("prefers the locale variable of the email over the user locale", func() {
+			email := &notificationmiloapiscomv1alpha1.Email{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}, email)).To(gomega.Succeed())
+			email.Spec.Variables = []notificationmiloapiscomv1alpha1.EmailVariable{{Name: emailtemplating.LocaleVariable, Value: "pt"}}
+			gomega.Expect(k8sClient.Update(ctx, email)).To(gomega.Succeed())
+
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(fakeProv.LastSendEmailInput.Subject).To(gomega.Equal("Bem-vindo"))
+
+			fetched := &notificationmiloapiscomv1alpha1.Email{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}, fetched)).To(gomega.Succeed())
+			localized := meta.FindStatusCondition(fetched.Status.Conditions, EmailLocalizedCondition)
+			gomega.Expect(localized).NotTo(gomega.BeNil())
+			gomega.Expect(localized.Reason).To(gomega.Equal(LocaleSelectedReason))
+		})
+
+		ginko.It("renders the base template when no variant matches", func() {
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(fakeProv.LastSendEmailInput.Subject).To(gomega.Equal("Welcome"))
+		})
+	})
+
+	ginko.Context("when the template is wrapped in a layout", func() {
+		ginko.It("renders the template with the layout and partials", func() {
+			gomega.Expect(k8sClient.Create(ctx, &notificationmiloapiscomv1alpha1.EmailTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "brand-layout", Labels: map[string]string{emailtemplating.PartialLabel: "brand"}},
+				Spec:       notificationmiloapiscomv1alpha1.EmailTemplateSpec{HTMLBody: `<div>{{ template "content" . }}</div>`},
+			})).To(gomega.Succeed())
+			template := &notificationmiloapiscomv1alpha1.EmailTemplate{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "welcome-template"}, template)).To(gomega.Succeed())
+			template.Annotations = map[string]string{emailtemplating.LayoutAnnotation: "brand"}
+			template.Spec.HTMLBody = "<p>Welcome</p>"
+			gomega.Expect(k8sClient.Update(ctx, template)).To(gomega.Succeed())
+
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			gomega.Expect(fakeProv.SendEmailCallCount).To(gomega.Equal(1))
+
+			fetched := &notificationmiloapiscomv1alpha1.Email{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}, fetched)).To(gomega.Succeed())
+			gomega.Expect(fetched.Status.HTMLBody).To(gomega.Equal("<div><p>Welcome</p></div>"))
+		})
+	})
+
+	ginko.Context("when the template inlines its CSS", func() {
+		ginko.It("records the inlined HTML body that was sent", func() {
+			template := &notificationmiloapiscomv1alpha1.EmailTemplate{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "welcome-template"}, template)).To(gomega.Succeed())
+			template.Annotations = map[string]string{emailtemplating.InlineCSSAnnotation: "true"}
+			template.Spec.HTMLBody = "<style>p { color: red }</style><p>Welcome</p>"
+			gomega.Expect(k8sClient.Update(ctx, template)).To(gomega.Succeed())
+
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			fetched := &notificationmiloapiscomv1alpha1.Email{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}, fetched)).To(gomega.Succeed())
+			gomega.Expect(fetched.Status.HTMLBody).To(gomega.ContainSubstring(`<p style="color: red">Welcome</p>`))
+			gomega.Expect(fetched.Status.HTMLBody).To(gomega.Equal(fakeProv.LastSendEmailInput.HtmlBody))
+			gomega.Expect(fetched.Status.TextBody).To(gomega.Equal("Welcome"))
+		})
+	})
+
 	ginko.Context("when the recipient is specified by email address", func() {
 		ginko.BeforeEach(func() {
 			// Override the recipient to use EmailAddress instead of UserRef
@@ -220,4 +477,165 @@ var _ = ginko.Describe("EmailController.Reconcile", func() {
 			gomega.Expect(fetched.Status.EmailAddress).To(gomega.Equal("recipient@example.com"))
 		})
 	})
+
+	ginko.Context("when the recipient is a contact group", func() {
+		ginko.BeforeEach(func() {
+			emailObj.Annotations = map[string]string{EmailRecipientContactGroupAnnotation: "developers"}
+
+			sch := scheme.Scheme
+			gomega.Expect(notificationmiloapiscomv1alpha1.AddToScheme(sch)).To(gomega.Succeed())
+
+			k8sClient = fake.NewClientBuilder().
+				WithScheme(sch).
+				WithStatusSubresource(&notificationmiloapiscomv1alpha1.Email{}).
+				WithObjects(emailObj.DeepCopy(), &notificationmiloapiscomv1alpha1.EmailTemplate{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "notification.miloapis.com/v1alpha1", Kind: "EmailTemplate"},
+					ObjectMeta: metav1.ObjectMeta{Name: "welcome-template"},
+					Spec:       notificationmiloapiscomv1alpha1.EmailTemplateSpec{Subject: "Welcome"},
+				}, &notificationmiloapiscomv1alpha1.ContactGroup{
+					ObjectMeta: metav1.ObjectMeta{Name: "developers", Namespace: "default"},
+					Spec:       notificationmiloapiscomv1alpha1.ContactGroupSpec{DisplayName: "Developers", Visibility: notificationmiloapiscomv1alpha1.ContactGroupVisibilityPublic},
+					Status:     notificationmiloapiscomv1alpha1.ContactGroupStatus{ProviderID: "segment-123"},
+				}).
+				Build()
+
+			fakeProv.CreateBroadcastOutput = emailprovider.CreateBroadcastOutput{BroadcastID: "broadcast-123"}
+			service := emailprovider.NewService(fakeProv, "from@example.com", "reply@example.com")
+			conf, err := config.NewEmailControllerConfig(time.Second, time.Second, time.Second)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			controller = &EmailController{Client: k8sClient, EmailProvider: *service, Config: *conf}
+		})
+
+		reconcileAndFetch := func() (ctrl.Result, *notificationmiloapiscomv1alpha1.Email) {
+			res, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			fetched := &notificationmiloapiscomv1alpha1.Email{}
+			gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}, fetched)).To(gomega.Succeed())
+			return res, fetched
+		}
+
+		ginko.It("creates, sends and tracks a broadcast to the contact group segment", func() {
+			// First reconciliation creates the broadcast and records its id
+			_, fetched := reconcileAndFetch()
+			gomega.Expect(fakeProv.SendEmailCallCount).To(gomega.BeZero())
+			gomega.Expect(fakeProv.CreateBroadcastCallCount).To(gomega.Equal(1))
+			gomega.Expect(fakeProv.LastCreateBroadcastInput.ContactGroupID).To(gomega.Equal("segment-123"))
+			gomega.Expect(fakeProv.LastCreateBroadcastInput.Subject).To(gomega.Equal("Welcome"))
+			gomega.Expect(fakeProv.SendBroadcastCallCount).To(gomega.BeZero())
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("broadcast-123"))
+			gomega.Expect(meta.FindStatusCondition(fetched.Status.Conditions, notificationmiloapiscomv1alpha1.EmailDeliveredCondition).Reason).To(gomega.Equal(BroadcastCreatedReason))
+
+			// Second reconciliation sends the draft broadcast
+			res, fetched := reconcileAndFetch()
+			gomega.Expect(fakeProv.SendBroadcastCallCount).To(gomega.Equal(1))
+			gomega.Expect(res.RequeueAfter).To(gomega.Equal(time.Second))
+			gomega.Expect(meta.FindStatusCondition(fetched.Status.Conditions, notificationmiloapiscomv1alpha1.EmailDeliveredCondition).Reason).To(gomega.Equal(notificationmiloapiscomv1alpha1.EmailDeliveryPendingReason))
+
+			// Once sent, the Email is marked as delivered and no longer polled
+			fakeProv.GetBroadcastOutput = emailprovider.GetBroadcastOutput{BroadcastID: "broadcast-123", Status: emailprovider.BroadcastStatusSent}
+			res, fetched = reconcileAndFetch()
+			gomega.Expect(res).To(gomega.Equal(ctrl.Result{}))
+			gomega.Expect(fakeProv.SendBroadcastCallCount).To(gomega.Equal(1))
+			gomega.Expect(meta.IsStatusConditionTrue(fetched.Status.Conditions, notificationmiloapiscomv1alpha1.EmailDeliveredCondition)).To(gomega.BeTrue())
+			gomega.Expect(fakeProv.CreateBroadcastCallCount).To(gomega.Equal(1))
+		})
+
+		ginko.It("looks up the recorded broadcast again when its id was not recorded in the status", func() {
+			_, fetched := reconcileAndFetch()
+			gomega.Expect(fetched.Annotations).To(gomega.HaveKeyWithValue(EmailBroadcastIDAnnotation, "broadcast-123"))
+			gomega.Expect(fakeProv.LastCreateBroadcastInput.BroadcastID).To(gomega.BeEmpty())
+
+			// Simulate a failed status update after the broadcast was created
+			fetched.Status.ProviderID = ""
+			gomega.Expect(k8sClient.Status().Update(ctx, fetched)).To(gomega.Succeed())
+
+			_, fetched = reconcileAndFetch()
+			gomega.Expect(fakeProv.CreateBroadcastCallCount).To(gomega.Equal(2))
+			gomega.Expect(fakeProv.LastCreateBroadcastInput.BroadcastID).To(gomega.Equal("broadcast-123"))
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("broadcast-123"))
+		})
+
+		ginko.It("retries the broadcast creation after a provider error", func() {
+			fakeProv.CreateBroadcastErr = fmt.Errorf("provider failure")
+			res, fetched := reconcileAndFetch()
+			gomega.Expect(res.RequeueAfter).To(gomega.Equal(time.Second))
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.BeEmpty())
+
+			fakeProv.CreateBroadcastErr = nil
+			_, fetched = reconcileAndFetch()
+			gomega.Expect(fakeProv.CreateBroadcastCallCount).To(gomega.Equal(2))
+			gomega.Expect(fetched.Status.ProviderID).To(gomega.Equal("broadcast-123"))
+		})
+	})
+
+	ginko.Context("when the email is tied to a contact group for unsubscription", func() {
+		ginko.BeforeEach(func() {
+			emailObj.Annotations = map[string]string{EmailUnsubscribeContactGroupAnnotation: "newsletter"}
+
+			sch := scheme.Scheme
+			gomega.Expect(iammiloapiscomv1alpha1.AddToScheme(sch)).To(gomega.Succeed())
+			gomega.Expect(notificationmiloapiscomv1alpha1.AddToScheme(sch)).To(gomega.Succeed())
+
+			k8sClient = fake.NewClientBuilder().
+				WithScheme(sch).
+				WithStatusSubresource(&notificationmiloapiscomv1alpha1.Email{}).
+				WithIndex(&notificationmiloapiscomv1alpha1.Contact{}, contactEmailIndexKey, contactEmailIndex).
+				WithIndex(&notificationmiloapiscomv1alpha1.ContactGroupMembership{}, contactAndContactGroupTupleIndexKey, func(obj client.Object) []string {
+					cgm := obj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
+					return []string{buildContactAndContactGroupTupleIndexKey(cgm.Spec.ContactRef, cgm.Spec.ContactGroupRef)}
+				}).
+				WithObjects(emailObj.DeepCopy(), &notificationmiloapiscomv1alpha1.EmailTemplate{
+					ObjectMeta: metav1.ObjectMeta{Name: "welcome-template"},
+					Spec:       notificationmiloapiscomv1alpha1.EmailTemplateSpec{Subject: "Welcome", TextBody: "Bye: {{ .UnsubscribeURL }}"},
+				}, &iammiloapiscomv1alpha1.User{
+					ObjectMeta: metav1.ObjectMeta{Name: "user-1"},
+					Spec:       iammiloapiscomv1alpha1.UserSpec{Email: "Recipient@example.com"},
+				}, &notificationmiloapiscomv1alpha1.Contact{
+					ObjectMeta: metav1.ObjectMeta{Name: "contact-1", Namespace: "default"},
+					Spec:       notificationmiloapiscomv1alpha1.ContactSpec{Email: "recipient@example.com"},
+				}, &notificationmiloapiscomv1alpha1.ContactGroupMembership{
+					ObjectMeta: metav1.ObjectMeta{Name: "contact-1-newsletter", Namespace: "default"},
+					Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
+						ContactRef:      notificationmiloapiscomv1alpha1.ContactReference{Name: "contact-1", Namespace: "default"},
+						ContactGroupRef: notificationmiloapiscomv1alpha1.ContactGroupReference{Name: "newsletter", Namespace: "default"},
+					},
+				}).
+				Build()
+
+			service := emailprovider.NewService(fakeProv, "from@example.com", "reply@example.com")
+			conf, err := config.NewEmailControllerConfig(time.Second, time.Second, time.Second)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			controller = &EmailController{Client: k8sClient, EmailProvider: *service, Config: *conf}
+		})
+
+		reconcile := func() {
+			_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: emailObj.Name, Namespace: emailObj.Namespace}})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		}
+
+		ginko.It("adds the unsubscribe link of the recipient contact", func() {
+			unsubscribeConf, err := config.NewUnsubscribeConfig("https://hooks.example.com/unsubscribe", "secret")
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			controller.UnsubscribeConfig = *unsubscribeConf
+
+			reconcile()
+
+			gomega.Expect(fakeProv.SendEmailCallCount).To(gomega.Equal(1))
+			headers := fakeProv.LastSendEmailInput.Headers
+			gomega.Expect(headers).To(gomega.HaveKeyWithValue(emailprovider.ListUnsubscribePostHeader, emailprovider.ListUnsubscribePostValue))
+			gomega.Expect(headers[emailprovider.ListUnsubscribeHeader]).To(gomega.HavePrefix("<https://hooks.example.com/unsubscribe?token="))
+
+			unsubscribeURL := headers[emailprovider.ListUnsubscribeHeader]
+			unsubscribeURL = unsubscribeURL[1 : len(unsubscribeURL)-1]
+			gomega.Expect(fakeProv.LastSendEmailInput.TextBody).To(gomega.Equal("Bye: " + unsubscribeURL))
+		})
+
+		ginko.It("sends without unsubscribe link when unsubscribe links are disabled", func() {
+			reconcile()
+
+			gomega.Expect(fakeProv.SendEmailCallCount).To(gomega.Equal(1))
+			gomega.Expect(fakeProv.LastSendEmailInput.Headers).To(gomega.BeEmpty())
+			gomega.Expect(fakeProv.LastSendEmailInput.TextBody).To(gomega.Equal("Bye: "))
+		})
+	})
 })
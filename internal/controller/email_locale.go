@@ -0,0 +1,108 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	iammiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	emailtemplating "go.miloapis.com/email-provider-resend/internal/emailtemplanting"
+)
+
+const (
+	// UserLocaleAnnotation is the preferred locale of a User, e.g. "pt-BR"
+	UserLocaleAnnotation = "notification.miloapis.com/locale"
+
+	// EmailLocalizedCondition records the locale the Email was rendered in, when its EmailTemplate has locale variants
+	EmailLocalizedCondition = "Localized"
+
+	// LocaleSelectedReason is set when the EmailTemplate of the recipient locale was rendered
+	LocaleSelectedReason = "LocaleSelected"
+
+	// LocaleFallbackReason is set when there is no EmailTemplate for the recipient locale and a fallback was rendered
+	LocaleFallbackReason = "LocaleFallback"
+)
+
+// localizeTemplate returns the locale variant of the EmailTemplate to render for the recipient of the Email, and
+// records the chosen locale in the Email status conditions. The condition is persisted with the next status update.
+// EmailTemplates without locale variants are returned as is.
+func (r *EmailController) localizeTemplate(ctx context.Context,
+	email *notificationmiloapiscomv1alpha1.Email,
+	emailTemplate *notificationmiloapiscomv1alpha1.EmailTemplate,
+) (*notificationmiloapiscomv1alpha1.EmailTemplate, error) {
+	log := logf.FromContext(ctx).WithName("email-reconciler")
+
+	variants := &notificationmiloapiscomv1alpha1.EmailTemplateList{}
+	if err := r.Client.List(ctx, variants, client.MatchingLabels{emailtemplating.VariantOfLabel: emailTemplate.Name}); err != nil {
+		return nil, fmt.Errorf("failed to list EmailTemplate locale variants: %w", err)
+	}
+	if len(variants.Items) == 0 {
+		return emailTemplate, nil
+	}
+
+	locale, err := r.getRecipientLocale(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	localized, chosenLocale := emailtemplating.SelectLocaleVariant(emailTemplate, variants.Items, locale, r.TemplateConfig.GetDefaultLocale())
+	log.Info("Selected EmailTemplate locale variant", "locale", locale, "chosenLocale", chosenLocale, "template", localized.Name)
+
+	condition := metav1.Condition{
+		Type:               EmailLocalizedCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             LocaleSelectedReason,
+		Message:            fmt.Sprintf("Rendered in locale %q with EmailTemplate %s", chosenLocale, localized.Name),
+		LastTransitionTime: metav1.Now(),
+	}
+	if chosenLocale != emailtemplating.CanonicalLocale(locale) {
+		condition.Reason = LocaleFallbackReason
+		condition.Message = fmt.Sprintf("No EmailTemplate for locale %q. Rendered in locale %q with EmailTemplate %s",
+			locale, chosenLocale, localized.Name)
+	}
+	meta.SetStatusCondition(&email.Status.Conditions, condition)
+
+	return localized, nil
+}
+
+// getRecipientLocale returns the preferred locale of the recipient of the Email: the Locale variable of the Email, or
+// else the locale annotation of the recipient User. An empty locale means the default locale.
+func (r *EmailController) getRecipientLocale(ctx context.Context, email *notificationmiloapiscomv1alpha1.Email) (string, error) {
+	for _, variable := range email.Spec.Variables {
+		if variable.Name == emailtemplating.LocaleVariable && variable.Value != "" {
+			return variable.Value, nil
+		}
+	}
+
+	// Broadcasts are sent to every member of a ContactGroup, so the User of the recipient does not apply
+	if _, ok := recipientContactGroupKey(email); ok || !r.hasUserRef(email) {
+		return "", nil
+	}
+
+	user := &iammiloapiscomv1alpha1.User{} // Cluster scoped resource
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: email.Spec.Recipient.UserRef.Name}, user); err != nil {
+		return "", fmt.Errorf("failed to get recipient User: %w", err)
+	}
+	return user.Annotations[UserLocaleAnnotation], nil
+}
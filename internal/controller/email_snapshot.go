@@ -0,0 +1,261 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+	emailtemplating "go.miloapis.com/email-provider-resend/internal/emailtemplanting"
+)
+
+const (
+	// EmailTemplatePinnedCondition records the version of the EmailTemplate the Email was rendered from. The rendered
+	// content is snapshotted in the Email status before the first send attempt and reused on every retry, so editing
+	// the EmailTemplate never changes the content of an Email that is already being delivered.
+	EmailTemplatePinnedCondition = "TemplatePinned"
+
+	// TemplateSnapshottedReason is set when the rendered content was snapshotted in the Email status
+	TemplateSnapshottedReason = "TemplateSnapshotted"
+
+	// PinnedTemplateUnavailableReason is set when the snapshot was lost and the pinned version of the EmailTemplate
+	// no longer exists. The Email is not retried, as its original content cannot be resolved anymore.
+	PinnedTemplateUnavailableReason = "PinnedTemplateUnavailable"
+
+	// PinnedTemplateAnnotation is the name of the EmailTemplate the Email was rendered from. It is the locale variant
+	// that was rendered, if any.
+	PinnedTemplateAnnotation = "notification.miloapis.com/pinned-template"
+
+	// PinnedTemplateGenerationAnnotation is the generation of the EmailTemplate the Email was rendered from
+	PinnedTemplateGenerationAnnotation = "notification.miloapis.com/pinned-template-generation"
+
+	// PinnedTemplateResourceVersionAnnotation is the resourceVersion of the EmailTemplate the Email was rendered from
+	PinnedTemplateResourceVersionAnnotation = "notification.miloapis.com/pinned-template-resource-version"
+
+	// PinnedContentHashAnnotation is the hash of the content snapshotted in the Email status. Its presence marks the
+	// Email as pinned.
+	PinnedContentHashAnnotation = "notification.miloapis.com/pinned-content-hash"
+)
+
+// pinnedTemplateUnavailableError is returned when the pinned content of an Email can no longer be resolved.
+type pinnedTemplateUnavailableError struct {
+	reason string
+}
+
+func (e *pinnedTemplateUnavailableError) Error() string {
+	return fmt.Sprintf("pinned EmailTemplate content can no longer be resolved: %s", e.reason)
+}
+
+// isPinnedTemplateUnavailable returns whether the error is a pinnedTemplateUnavailableError.
+func isPinnedTemplateUnavailable(err error) bool {
+	var unavailableErr *pinnedTemplateUnavailableError
+	return errors.As(err, &unavailableErr)
+}
+
+// pinnedContent returns the content to send for the Email. The first time, the EmailTemplate is rendered and the
+// Email is pinned to it: the version of the EmailTemplate is recorded in the Email annotations and the result is
+// snapshotted in the Email status before it is sent. Afterwards the snapshot is reused without reading the
+// EmailTemplate. If the snapshot was lost, the pinned EmailTemplate is rendered again as long as its generation did
+// not change; otherwise a *pinnedTemplateUnavailableError is returned.
+func (r *EmailController) pinnedContent(ctx context.Context,
+	email *notificationmiloapiscomv1alpha1.Email,
+	unsubscribeURL string,
+) (emailprovider.RenderedEmail, error) {
+	log := logf.FromContext(ctx).WithName("email-reconciler")
+
+	pinnedHash := email.Annotations[PinnedContentHashAnnotation]
+	if pinnedHash == "" {
+		emailTemplate := &notificationmiloapiscomv1alpha1.EmailTemplate{} // Cluster scoped resource
+		if err := r.Client.Get(ctx, client.ObjectKey{Name: email.Spec.TemplateRef.Name}, emailTemplate); err != nil {
+			// emailTemplate is warranty to exist. As it is checked on a webhook on Milo.
+			return emailprovider.RenderedEmail{}, fmt.Errorf("failed to get EmailTemplate: %w", err)
+		}
+
+		rendered, localized, err := r.renderEmail(ctx, email, emailTemplate, unsubscribeURL)
+		if err != nil {
+			return emailprovider.RenderedEmail{}, err
+		}
+		if err := r.pinTemplate(ctx, email, localized, rendered); err != nil {
+			return emailprovider.RenderedEmail{}, err
+		}
+		log.Info("Email content snapshotted", "template", localized.Name, "generation", localized.Generation)
+		return rendered, nil
+	}
+
+	snapshot := emailprovider.RenderedEmail{
+		Subject:  email.Status.Subject,
+		HTMLBody: email.Status.HTMLBody,
+		TextBody: email.Status.TextBody,
+	}
+	if contentHash(snapshot) == pinnedHash {
+		log.Info("Reusing the snapshotted Email content")
+		return snapshot, nil
+	}
+
+	// The snapshot was lost, e.g. the status was overwritten. The pinned EmailTemplate can still be used as long as it
+	// was not edited since.
+	templateName := email.Annotations[PinnedTemplateAnnotation]
+	pinnedGeneration, err := strconv.ParseInt(email.Annotations[PinnedTemplateGenerationAnnotation], 10, 64)
+	if templateName == "" || err != nil {
+		return emailprovider.RenderedEmail{}, &pinnedTemplateUnavailableError{
+			reason: "the snapshot was lost and the Email does not record the pinned EmailTemplate version",
+		}
+	}
+
+	emailTemplate := &notificationmiloapiscomv1alpha1.EmailTemplate{} // Cluster scoped resource
+	err = r.Client.Get(ctx, client.ObjectKey{Name: templateName}, emailTemplate)
+	if apierrors.IsNotFound(err) {
+		return emailprovider.RenderedEmail{}, &pinnedTemplateUnavailableError{
+			reason: fmt.Sprintf("the snapshot was lost and EmailTemplate %s was deleted", templateName),
+		}
+	}
+	if err != nil {
+		return emailprovider.RenderedEmail{}, fmt.Errorf("failed to get pinned EmailTemplate: %w", err)
+	}
+	if emailTemplate.Generation != pinnedGeneration {
+		return emailprovider.RenderedEmail{}, &pinnedTemplateUnavailableError{
+			reason: fmt.Sprintf("the snapshot was lost and EmailTemplate %s changed since it was pinned (generation %d, pinned generation %d)",
+				templateName, emailTemplate.Generation, pinnedGeneration),
+		}
+	}
+
+	rendered, err := r.renderTemplate(ctx, email, emailTemplate, unsubscribeURL)
+	if emailtemplating.IsTerminalError(err) {
+		return emailprovider.RenderedEmail{}, &pinnedTemplateUnavailableError{
+			reason: fmt.Sprintf("the snapshot was lost and the EmailTemplate cannot be rendered: %s", err.Error()),
+		}
+	}
+	if err != nil {
+		return emailprovider.RenderedEmail{}, err
+	}
+	if err := r.pinTemplate(ctx, email, emailTemplate, rendered); err != nil {
+		return emailprovider.RenderedEmail{}, err
+	}
+	log.Info("Restored the Email content snapshot from the unchanged EmailTemplate", "template", templateName)
+	return rendered, nil
+}
+
+// pinTemplate records the version of the EmailTemplate in the Email annotations, then snapshots the rendered content
+// in the Email status along with the TemplatePinned condition. The annotations are written first, so a failed status
+// update is recovered from the pinned EmailTemplate on the next attempt.
+func (r *EmailController) pinTemplate(ctx context.Context,
+	email *notificationmiloapiscomv1alpha1.Email,
+	emailTemplate *notificationmiloapiscomv1alpha1.EmailTemplate,
+	rendered emailprovider.RenderedEmail,
+) error {
+	// The in-memory status is kept, as it may hold changes not yet persisted, e.g. the Localized condition
+	status := email.Status.DeepCopy()
+	patch := client.MergeFrom(email.DeepCopy())
+
+	annotations := email.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[PinnedTemplateAnnotation] = emailTemplate.Name
+	annotations[PinnedTemplateGenerationAnnotation] = strconv.FormatInt(emailTemplate.Generation, 10)
+	annotations[PinnedTemplateResourceVersionAnnotation] = emailTemplate.ResourceVersion
+	annotations[PinnedContentHashAnnotation] = contentHash(rendered)
+	email.SetAnnotations(annotations)
+
+	if err := r.Client.Patch(ctx, email, patch); err != nil {
+		return fmt.Errorf("failed to update Email pinned template annotations: %w", err)
+	}
+	email.Status = *status
+
+	setSnapshot(email, rendered)
+	return r.updateEmailStatus(ctx, email, metav1.Condition{
+		Type:   EmailTemplatePinnedCondition,
+		Status: metav1.ConditionTrue,
+		Reason: TemplateSnapshottedReason,
+		Message: fmt.Sprintf("Rendered content of EmailTemplate %s (generation %d) snapshotted before the first send attempt",
+			emailTemplate.Name, emailTemplate.Generation),
+		LastTransitionTime: metav1.Now(),
+	})
+}
+
+// renderEmail renders the locale variant of the EmailTemplate for the recipient of the Email. It returns the
+// EmailTemplate that was rendered.
+func (r *EmailController) renderEmail(ctx context.Context,
+	email *notificationmiloapiscomv1alpha1.Email,
+	emailTemplate *notificationmiloapiscomv1alpha1.EmailTemplate,
+	unsubscribeURL string,
+) (emailprovider.RenderedEmail, *notificationmiloapiscomv1alpha1.EmailTemplate, error) {
+	localized, err := r.localizeTemplate(ctx, email, emailTemplate)
+	if err != nil {
+		return emailprovider.RenderedEmail{}, nil, fmt.Errorf("failed to get EmailTemplate locale variant: %w", err)
+	}
+
+	rendered, err := r.renderTemplate(ctx, email, localized, unsubscribeURL)
+	return rendered, localized, err
+}
+
+// renderTemplate renders the EmailTemplate, as is, with the variables of the Email.
+func (r *EmailController) renderTemplate(ctx context.Context,
+	email *notificationmiloapiscomv1alpha1.Email,
+	emailTemplate *notificationmiloapiscomv1alpha1.EmailTemplate,
+	unsubscribeURL string,
+) (emailprovider.RenderedEmail, error) {
+	renderOptions, err := r.renderOptions(ctx, emailTemplate)
+	if err != nil {
+		return emailprovider.RenderedEmail{}, fmt.Errorf("failed to get render options: %w", err)
+	}
+
+	return r.EmailProvider.Render(email.DeepCopy(), emailTemplate.DeepCopy(), unsubscribeURL, renderOptions)
+}
+
+// failPinnedTemplate marks the Email as failed because its pinned content can no longer be resolved. The Email is not
+// requeued.
+func (r *EmailController) failPinnedTemplate(ctx context.Context, email *notificationmiloapiscomv1alpha1.Email, pinErr error) (ctrl.Result, error) {
+	meta.SetStatusCondition(&email.Status.Conditions, metav1.Condition{
+		Type:               EmailTemplatePinnedCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             PinnedTemplateUnavailableReason,
+		Message:            pinErr.Error(),
+		LastTransitionTime: metav1.Now(),
+	})
+	return r.failRendering(ctx, email, pinErr)
+}
+
+// setSnapshot records the rendered content in the Email status.
+func setSnapshot(email *notificationmiloapiscomv1alpha1.Email, rendered emailprovider.RenderedEmail) {
+	email.Status.Subject = rendered.Subject
+	email.Status.HTMLBody = rendered.HTMLBody
+	email.Status.TextBody = rendered.TextBody
+}
+
+// contentHash returns the hash of the rendered content of an Email.
+func contentHash(rendered emailprovider.RenderedEmail) string {
+	hash := sha256.New()
+	for _, part := range []string{rendered.Subject, rendered.HTMLBody, rendered.TextBody} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"go.miloapis.com/email-provider-resend/internal/unsubscribe"
+)
+
+// EmailUnsubscribeContactGroupAnnotation ties the Email to a ContactGroup the recipient is a member of. The Email gets
+// an unsubscribe link that removes the recipient from the ContactGroup. The value is either "<name>", for a
+// ContactGroup in the Email namespace, or "<namespace>/<name>".
+const EmailUnsubscribeContactGroupAnnotation = "notification.miloapis.com/unsubscribe-contactgroup"
+
+// contactEmailIndexKey indexes Contacts by their lowercased email address
+const contactEmailIndexKey = "contact-email-index"
+
+// contactEmailIndex returns the contactEmailIndexKey values of a Contact.
+func contactEmailIndex(rawObj client.Object) []string {
+	contact := rawObj.(*notificationmiloapiscomv1alpha1.Contact)
+	if contact.Spec.Email == "" {
+		return nil
+	}
+	return []string{strings.ToLower(contact.Spec.Email)}
+}
+
+// getUnsubscribeURL returns the unsubscribe URL of the recipient of the Email.
+// It is empty if unsubscribe links are disabled, the Email is not tied to a ContactGroup or the recipient is not a
+// member of the ContactGroup.
+func (r *EmailController) getUnsubscribeURL(ctx context.Context, email *notificationmiloapiscomv1alpha1.Email, recipientEmailAddress string) (string, error) {
+	log := logf.FromContext(ctx).WithName("email-reconciler")
+
+	contactGroupKey, ok := emailContactGroupKey(email, EmailUnsubscribeContactGroupAnnotation)
+	if !ok || !r.UnsubscribeConfig.Enabled() {
+		return "", nil
+	}
+
+	contacts := &notificationmiloapiscomv1alpha1.ContactList{}
+	if err := r.Client.List(ctx, contacts, client.MatchingFields{contactEmailIndexKey: strings.ToLower(recipientEmailAddress)}); err != nil {
+		return "", fmt.Errorf("failed to list Contacts: %w", err)
+	}
+
+	contactGroupRef := notificationmiloapiscomv1alpha1.ContactGroupReference{Name: contactGroupKey.Name, Namespace: contactGroupKey.Namespace}
+	for _, contact := range contacts.Items {
+		contactRef := notificationmiloapiscomv1alpha1.ContactReference{Name: contact.Name, Namespace: contact.Namespace}
+		cgms := &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}
+		if err := r.Client.List(ctx, cgms,
+			client.MatchingFields{contactAndContactGroupTupleIndexKey: buildContactAndContactGroupTupleIndexKey(contactRef, contactGroupRef)}); err != nil {
+			return "", fmt.Errorf("failed to list ContactGroupMemberships: %w", err)
+		}
+
+		for _, cgm := range cgms.Items {
+			if !cgm.DeletionTimestamp.IsZero() {
+				continue
+			}
+			return unsubscribe.NewSigner(r.UnsubscribeConfig.GetSigningKey()).URL(r.UnsubscribeConfig.GetBaseURL(), unsubscribe.Token{
+				ContactRef:      cgm.Spec.ContactRef,
+				ContactGroupRef: cgm.Spec.ContactGroupRef,
+			})
+		}
+	}
+
+	log.Info("Recipient is not a member of the unsubscribe ContactGroup. Sending without unsubscribe link.", "contactGroup", contactGroupKey.String())
+	return "", nil
+}
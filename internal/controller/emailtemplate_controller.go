@@ -0,0 +1,178 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"go.miloapis.com/email-provider-resend/internal/config"
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+	emailtemplating "go.miloapis.com/email-provider-resend/internal/emailtemplanting"
+)
+
+const (
+	// EmailTemplateValidCondition reports whether the EmailTemplate can be rendered. Its message lists the problems
+	// found, located by line and column, e.g. "spec.htmlBody:3:14: function "shout" not defined".
+	EmailTemplateValidCondition = "Valid"
+
+	EmailTemplateValidReason   = "TemplateValid"
+	EmailTemplateInvalidReason = "TemplateInvalid"
+
+	// maxConditionMessageLength is the maximum length of the message of a condition accepted by the API server
+	maxConditionMessageLength = 32768
+)
+
+// EmailTemplateController validates the EmailTemplates as soon as they are created or updated, so syntax and variable
+// errors surface on the EmailTemplate instead of as failed deliveries of the Emails using it. Templates are parsed and
+// test rendered with the same engines as when sending Emails.
+type EmailTemplateController struct {
+	Client client.Client
+	// TemplateConfig configures the rendering of the EmailTemplates
+	TemplateConfig config.TemplateConfig
+}
+
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=emailtemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=emailtemplates/status,verbs=get;update
+
+// Reconcile validates the EmailTemplate and records the result in its Valid condition.
+func (r *EmailTemplateController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx).WithName("emailtemplate-reconciler")
+
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{}
+	err := r.Client.Get(ctx, req.NamespacedName, template)
+	if errors.IsNotFound(err) {
+		log.Info("EmailTemplate not found. Probably deleted.", "name", req.Name)
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		log.Error(err, "Failed to get EmailTemplate", "name", req.Name)
+		return ctrl.Result{}, fmt.Errorf("failed to get EmailTemplate: %w", err)
+	}
+
+	var templateErrs []*emailtemplating.TemplateError
+	if _, isPartial := template.Labels[emailtemplating.PartialLabel]; isPartial {
+		templateErrs = emailtemplating.ValidatePartial(template)
+	} else {
+		partials, err := listPartials(ctx, r.Client)
+		if err != nil {
+			log.Error(err, "Failed to list partials")
+			return ctrl.Result{}, err
+		}
+		templateErrs = emailtemplating.ValidateTemplate(template, emailtemplating.RenderOptions{
+			Strict:   emailtemplating.IsStrict(template, r.TemplateConfig.IsStrictVariables()),
+			Partials: partials,
+		}, emailprovider.UnsubscribeURLVariable, emailtemplating.LocaleVariable)
+	}
+
+	condition := metav1.Condition{
+		Type:               EmailTemplateValidCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             EmailTemplateValidReason,
+		Message:            "EmailTemplate is valid",
+		ObservedGeneration: template.Generation,
+		LastTransitionTime: metav1.Now(),
+	}
+	if len(templateErrs) > 0 {
+		problems := make([]string, 0, len(templateErrs))
+		for _, templateErr := range templateErrs {
+			problems = append(problems, templateErr.Error())
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = EmailTemplateInvalidReason
+		condition.Message = truncateConditionMessage("EmailTemplate is invalid: " + strings.Join(problems, "; "))
+	}
+	log.Info("EmailTemplate validated", "valid", condition.Status, "problems", len(templateErrs))
+
+	if !meta.SetStatusCondition(&template.Status.Conditions, condition) {
+		return ctrl.Result{}, nil
+	}
+	if err := r.Client.Status().Update(ctx, template); err != nil {
+		log.Error(err, "Failed to update EmailTemplate status")
+		return ctrl.Result{}, fmt.Errorf("failed to update EmailTemplate status: %w", err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// truncateConditionMessage truncates the message to the maximum length of the message of a condition.
+func truncateConditionMessage(message string) string {
+	if len(message) <= maxConditionMessageLength {
+		return message
+	}
+	const ellipsis = "…"
+	message = message[:maxConditionMessageLength-len(ellipsis)]
+	return strings.ToValidUTF8(message, "") + ellipsis
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *EmailTemplateController) SetupWithManager(mgr ctrl.Manager) error {
+	// The status updates of the controller do not trigger a new validation. Annotations and labels are watched, as
+	// they configure the rendering, e.g. the layout or strict variables mode.
+	specChanged := builder.WithPredicates(predicate.Or(
+		predicate.GenerationChangedPredicate{},
+		predicate.AnnotationChangedPredicate{},
+		predicate.LabelChangedPredicate{},
+	))
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&notificationmiloapiscomv1alpha1.EmailTemplate{}, specChanged).
+		Watches(
+			&notificationmiloapiscomv1alpha1.EmailTemplate{},
+			handler.EnqueueRequestsFromMapFunc(r.enqueueEmailTemplatesForPartial),
+			specChanged,
+		).
+		Named("emailtemplate").
+		Complete(r)
+}
+
+// enqueueEmailTemplatesForPartial enqueues every EmailTemplate that is not a partial when a partial changes, as they may
+// invoke it or be wrapped in it.
+func (r *EmailTemplateController) enqueueEmailTemplatesForPartial(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx).WithValues("controller", "enqueueEmailTemplatesForPartial", "trigger", obj.GetName())
+
+	if _, isPartial := obj.GetLabels()[emailtemplating.PartialLabel]; !isPartial {
+		return nil
+	}
+
+	templates := &notificationmiloapiscomv1alpha1.EmailTemplateList{}
+	if err := r.Client.List(ctx, templates); err != nil {
+		log.Error(err, "Failed to list EmailTemplates")
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, template := range templates.Items {
+		if _, isPartial := template.Labels[emailtemplating.PartialLabel]; isPartial {
+			continue
+		}
+		reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKey{Name: template.Name}})
+	}
+	return reqs
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	emailtemplating "go.miloapis.com/email-provider-resend/internal/emailtemplanting"
+)
+
+var _ = ginkgo.Describe("EmailTemplateController", func() {
+	var (
+		ctx        context.Context
+		k8sClient  client.Client
+		controller *EmailTemplateController
+	)
+
+	newTemplate := func(name string, spec notificationmiloapiscomv1alpha1.EmailTemplateSpec) *notificationmiloapiscomv1alpha1.EmailTemplate {
+		return &notificationmiloapiscomv1alpha1.EmailTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 1},
+			Spec:       spec,
+		}
+	}
+
+	validCondition := func(name string) *metav1.Condition {
+		template := &notificationmiloapiscomv1alpha1.EmailTemplate{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name}, template)).To(gomega.Succeed())
+		return meta.FindStatusCondition(template.Status.Conditions, EmailTemplateValidCondition)
+	}
+
+	reconcile := func(name string) {
+		_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: name}})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+
+	ginkgo.BeforeEach(func() {
+		ctx = context.Background()
+
+		footer := newTemplate("footer", notificationmiloapiscomv1alpha1.EmailTemplateSpec{HTMLBody: "<footer>{{ .Team }}</footer>"})
+		footer.Labels = map[string]string{emailtemplating.PartialLabel: "footer"}
+
+		sch := scheme.Scheme
+		gomega.Expect(notificationmiloapiscomv1alpha1.AddToScheme(sch)).To(gomega.Succeed())
+
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(sch).
+			WithStatusSubresource(&notificationmiloapiscomv1alpha1.EmailTemplate{}).
+			WithObjects(
+				newTemplate("welcome", notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+					Subject:  "Welcome {{ .Name }}",
+					HTMLBody: `<p>Hello {{ .Name }}</p>{{ template "footer" . }}`,
+					Variables: []notificationmiloapiscomv1alpha1.TemplateVariable{
+						{Name: "Name", Required: true, Type: "string"},
+						{Name: "Team", Required: true, Type: "string"},
+					},
+				}),
+				newTemplate("broken", notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+					Subject:  "Welcome {{ .Name }}",
+					HTMLBody: "<p>\n  Hello {{ .Nmae | shout }}\n</p>",
+					Variables: []notificationmiloapiscomv1alpha1.TemplateVariable{
+						{Name: "Name", Required: true, Type: "string"},
+					},
+				}),
+				footer,
+			).
+			Build()
+
+		controller = &EmailTemplateController{Client: k8sClient}
+	})
+
+	ginkgo.It("marks a valid template as valid", func() {
+		reconcile("welcome")
+
+		valid := validCondition("welcome")
+		gomega.Expect(valid).NotTo(gomega.BeNil())
+		gomega.Expect(valid.Status).To(gomega.Equal(metav1.ConditionTrue))
+		gomega.Expect(valid.Reason).To(gomega.Equal(EmailTemplateValidReason))
+		gomega.Expect(valid.ObservedGeneration).To(gomega.Equal(int64(1)))
+	})
+
+	ginkgo.It("marks a template with errors as invalid, locating them by line and column", func() {
+		reconcile("broken")
+
+		valid := validCondition("broken")
+		gomega.Expect(valid).NotTo(gomega.BeNil())
+		gomega.Expect(valid.Status).To(gomega.Equal(metav1.ConditionFalse))
+		gomega.Expect(valid.Reason).To(gomega.Equal(EmailTemplateInvalidReason))
+		gomega.Expect(valid.Message).To(gomega.Equal(`EmailTemplate is invalid: spec.htmlBody:2:9: function "shout" not defined`))
+	})
+
+	ginkgo.It("validates partials on their own", func() {
+		reconcile("footer")
+
+		valid := validCondition("footer")
+		gomega.Expect(valid).NotTo(gomega.BeNil())
+		gomega.Expect(valid.Status).To(gomega.Equal(metav1.ConditionTrue))
+	})
+
+	ginkgo.It("revalidates the templates when a partial changes", func() {
+		footer := &notificationmiloapiscomv1alpha1.EmailTemplate{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "footer"}, footer)).To(gomega.Succeed())
+		reqs := controller.enqueueEmailTemplatesForPartial(ctx, footer)
+		gomega.Expect(reqs).To(gomega.ConsistOf(
+			ctrl.Request{NamespacedName: types.NamespacedName{Name: "welcome"}},
+			ctrl.Request{NamespacedName: types.NamespacedName{Name: "broken"}},
+		))
+
+		welcome := &notificationmiloapiscomv1alpha1.EmailTemplate{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "welcome"}, welcome)).To(gomega.Succeed())
+		gomega.Expect(controller.enqueueEmailTemplatesForPartial(ctx, welcome)).To(gomega.BeEmpty())
+
+		footer.Spec.HTMLBody = "<footer>{{ .Team }} {{ .Company }}</footer>"
+		gomega.Expect(k8sClient.Update(ctx, footer)).To(gomega.Succeed())
+		reconcile("welcome")
+
+		valid := validCondition("welcome")
+		gomega.Expect(valid.Status).To(gomega.Equal(metav1.ConditionFalse))
+		gomega.Expect(valid.Message).To(gomega.ContainSubstring(`partial "footer":1:24: variable "Company" is not declared in spec.variables`))
+	})
+})
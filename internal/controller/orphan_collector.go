@@ -0,0 +1,212 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.miloapis.com/email-provider-resend/internal/config"
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Orphan actions reported through the orphans metric.
+const (
+	orphanActionReported = "reported"
+	orphanActionDeleted  = "deleted"
+	orphanActionFailed   = "failed"
+)
+
+var providerOrphansTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "resend_provider_orphans_total",
+		Help: "Number of Resend objects found without an owning resource, by kind and action.",
+	},
+	[]string{"kind", "action"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(providerOrphansTotal)
+}
+
+// OrphanCollector periodically deletes email provider contacts and contact groups that were created by this operator
+// but are not owned by any Contact or ContactGroup, e.g. because the operator crashed between the provider create and
+// the status update.
+type OrphanCollector struct {
+	Client        client.Client
+	EmailProvider emailprovider.Service
+	Config        config.OrphanGCConfig
+}
+
+var _ manager.LeaderElectionRunnable = &OrphanCollector{}
+
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contacts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups,verbs=get;list;watch
+
+// Start runs the garbage collection every interval until the context is cancelled.
+func (c *OrphanCollector) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithValues("runnable", "OrphanCollector")
+	log.Info("Starting orphan garbage collection", "interval", c.Config.GetInterval(), "gracePeriod", c.Config.GetGracePeriod(), "dryRun", c.Config.DryRun())
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.Collect(ctx); err != nil {
+			log.Error(err, "Failed to collect orphaned email provider objects")
+		}
+	}, c.Config.GetInterval())
+
+	return nil
+}
+
+// NeedLeaderElection ensures only the leader deletes email provider objects.
+func (c *OrphanCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Collect runs a single garbage collection pass over the email provider contact groups and contacts.
+func (c *OrphanCollector) Collect(ctx context.Context) error {
+	if err := c.collectContactGroups(ctx); err != nil {
+		return err
+	}
+	return c.collectContacts(ctx)
+}
+
+// collectContactGroups deletes contact groups that are managed by this operator, as recognised by their
+// deterministic display name, but not owned by any ContactGroup.
+func (c *OrphanCollector) collectContactGroups(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithValues("runnable", "OrphanCollector", "kind", "ContactGroup")
+
+	contactGroups := &notificationmiloapiscomv1alpha1.ContactGroupList{}
+	if err := c.Client.List(ctx, contactGroups); err != nil {
+		return fmt.Errorf("failed to list ContactGroups: %w", err)
+	}
+	ownedIDs := map[string]struct{}{}
+	ownedNames := map[string]struct{}{}
+	for _, cg := range contactGroups.Items {
+		if cg.Status.ProviderID != "" {
+			ownedIDs[cg.Status.ProviderID] = struct{}{}
+		}
+		// A ContactGroup being created has no provider id yet, but will adopt the contact group by its name
+		ownedNames[emailprovider.GetDeterministicContactGroupDisplayName(&cg)] = struct{}{}
+		ownedNames[emailprovider.GetLegacyContactGroupDisplayName(&cg)] = struct{}{}
+	}
+
+	for providerContactGroup, err := range c.EmailProvider.ListContactGroups(ctx) {
+		if err != nil {
+			return fmt.Errorf("failed to list contact groups from email provider: %w", err)
+		}
+		if !emailprovider.IsDeterministicContactGroupDisplayName(providerContactGroup.DisplayName) {
+			continue
+		}
+		if _, ok := ownedIDs[providerContactGroup.ContactGroupID]; ok {
+			continue
+		}
+		if _, ok := ownedNames[providerContactGroup.DisplayName]; ok {
+			continue
+		}
+		if !c.pastGracePeriod(providerContactGroup.CreatedAt) {
+			continue
+		}
+
+		log := log.WithValues("providerID", providerContactGroup.ContactGroupID, "displayName", providerContactGroup.DisplayName, "createdAt", providerContactGroup.CreatedAt)
+		if c.Config.DryRun() {
+			log.Info("Orphaned contact group found on email provider. Dry run, not deleting it.")
+			providerOrphansTotal.WithLabelValues("ContactGroup", orphanActionReported).Inc()
+			continue
+		}
+
+		log.Info("Orphaned contact group found on email provider. Deleting it.")
+		if _, err := c.EmailProvider.DeleteContactGroupByID(ctx, providerContactGroup.ContactGroupID); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete orphaned contact group from email provider")
+			providerOrphansTotal.WithLabelValues("ContactGroup", orphanActionFailed).Inc()
+			continue
+		}
+		providerOrphansTotal.WithLabelValues("ContactGroup", orphanActionDeleted).Inc()
+	}
+
+	return nil
+}
+
+// collectContacts deletes contacts that were created by this operator but are not owned by any Contact. Contacts not
+// created by this operator, e.g. created before it was deployed or by other integrations, are never deleted.
+func (c *OrphanCollector) collectContacts(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithValues("runnable", "OrphanCollector", "kind", "Contact")
+
+	contacts := &notificationmiloapiscomv1alpha1.ContactList{}
+	if err := c.Client.List(ctx, contacts); err != nil {
+		return fmt.Errorf("failed to list Contacts: %w", err)
+	}
+	ownedIDs := map[string]struct{}{}
+	ownedEmails := map[string]struct{}{}
+	for _, contact := range contacts.Items {
+		if contact.Status.ProviderID != "" {
+			ownedIDs[contact.Status.ProviderID] = struct{}{}
+		}
+		// A Contact being created has no provider id yet, but will adopt the contact by its email address
+		ownedEmails[strings.ToLower(contact.Spec.Email)] = struct{}{}
+	}
+
+	for providerContact, err := range c.EmailProvider.ListContacts(ctx) {
+		if err != nil {
+			return fmt.Errorf("failed to list contacts from email provider: %w", err)
+		}
+		if !providerContact.Managed {
+			continue
+		}
+		if _, ok := ownedIDs[providerContact.ContactId]; ok {
+			continue
+		}
+		if _, ok := ownedEmails[strings.ToLower(providerContact.Email)]; ok {
+			continue
+		}
+		if !c.pastGracePeriod(providerContact.CreatedAt) {
+			continue
+		}
+
+		log := log.WithValues("providerID", providerContact.ContactId, "createdAt", providerContact.CreatedAt)
+		if c.Config.DryRun() {
+			log.Info("Orphaned contact found on email provider. Dry run, not deleting it.")
+			providerOrphansTotal.WithLabelValues("Contact", orphanActionReported).Inc()
+			continue
+		}
+
+		log.Info("Orphaned contact found on email provider. Deleting it.")
+		if _, err := c.EmailProvider.DeleteContactByID(ctx, providerContact.ContactId); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete orphaned contact from email provider")
+			providerOrphansTotal.WithLabelValues("Contact", orphanActionFailed).Inc()
+			continue
+		}
+		providerOrphansTotal.WithLabelValues("Contact", orphanActionDeleted).Inc()
+	}
+
+	return nil
+}
+
+// pastGracePeriod returns whether an email provider object created at the given time is old enough to be collected.
+// Objects without a creation time are never collected.
+func (c *OrphanCollector) pastGracePeriod(createdAt time.Time) bool {
+	return !createdAt.IsZero() && time.Since(createdAt) >= c.Config.GetGracePeriod()
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+	notificationv1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"go.miloapis.com/email-provider-resend/internal/config"
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+	"go.miloapis.com/email-provider-resend/internal/emailprovider/mockprovider"
+)
+
+var _ = ginkgo.Describe("OrphanCollector", func() {
+	var (
+		ctx       context.Context
+		provider  *mockprovider.MockEmailProvider
+		collector *OrphanCollector
+		old       time.Time
+	)
+
+	newCollector := func(dryRun bool) *OrphanCollector {
+		sch := scheme.Scheme
+		gomega.Expect(notificationv1.AddToScheme(sch)).To(gomega.Succeed())
+
+		k8sClient := fake.NewClientBuilder().
+			WithScheme(sch).
+			WithObjects(
+				&notificationv1.ContactGroup{
+					ObjectMeta: metav1.ObjectMeta{Name: "owned", Namespace: "default"},
+					Spec:       notificationv1.ContactGroupSpec{DisplayName: "Owned", Visibility: notificationv1.ContactGroupVisibilityPublic},
+					Status:     notificationv1.ContactGroupStatus{ProviderID: "cg-owned"},
+				},
+				&notificationv1.ContactGroup{
+					ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"},
+					Spec:       notificationv1.ContactGroupSpec{DisplayName: "Pending", Visibility: notificationv1.ContactGroupVisibilityPublic},
+				},
+				&notificationv1.Contact{
+					ObjectMeta: metav1.ObjectMeta{Name: "owned", Namespace: "default"},
+					Spec:       notificationv1.ContactSpec{Email: "owned@example.com"},
+					Status:     notificationv1.ContactStatus{ProviderID: "c-owned"},
+				},
+				&notificationv1.Contact{
+					ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"},
+					Spec:       notificationv1.ContactSpec{Email: "Pending@example.com"},
+				},
+			).
+			Build()
+
+		gcConfig, err := config.NewOrphanGCConfig(time.Hour, time.Hour, dryRun)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+		return &OrphanCollector{
+			Client:        k8sClient,
+			EmailProvider: *emailprovider.NewService(provider, "from@example.com", "reply@example.com"),
+			Config:        *gcConfig,
+		}
+	}
+
+	ginkgo.BeforeEach(func() {
+		ctx = context.Background()
+		old = time.Now().Add(-2 * time.Hour)
+		provider = &mockprovider.MockEmailProvider{
+			ListContactGroupsOutput: []emailprovider.GetContactGroupOutput{
+				{ContactGroupID: "cg-owned", DisplayName: "Owned-public-default", CreatedAt: old},
+				{ContactGroupID: "cg-pending", DisplayName: "Pending-public-default", CreatedAt: old},
+				{ContactGroupID: "cg-recent", DisplayName: "Recent-public-default", CreatedAt: time.Now()},
+				{ContactGroupID: "cg-unmanaged", DisplayName: "Newsletter", CreatedAt: old},
+				{ContactGroupID: "cg-foreign", DisplayName: "Weekly--digest", CreatedAt: old},
+				{ContactGroupID: "cg-orphan", DisplayName: "Orphan-private-default", CreatedAt: old},
+			},
+			ListContactsOutput: []emailprovider.GetContactOutput{
+				{ContactId: "c-owned", Email: "owned@example.com", CreatedAt: old, Managed: true},
+				{ContactId: "c-pending", Email: "pending@example.com", CreatedAt: old, Managed: true},
+				{ContactId: "c-recent", Email: "recent@example.com", CreatedAt: time.Now(), Managed: true},
+				{ContactId: "c-unmanaged", Email: "legacy@example.com", CreatedAt: old},
+				{ContactId: "c-orphan", Email: "orphan@example.com", CreatedAt: old, Managed: true},
+			},
+		}
+	})
+
+	ginkgo.It("deletes only orphans older than the grace period", func() {
+		collector = newCollector(false)
+
+		gomega.Expect(collector.Collect(ctx)).To(gomega.Succeed())
+
+		gomega.Expect(provider.DeletedGroupID).To(gomega.Equal("cg-orphan"))
+		gomega.Expect(provider.DeleteContactCallCount).To(gomega.Equal(1))
+		gomega.Expect(provider.LastDeleteContactInput.ContactId).To(gomega.Equal("c-orphan"))
+	})
+
+	ginkgo.It("does not delete anything in dry-run mode", func() {
+		collector = newCollector(true)
+
+		gomega.Expect(collector.Collect(ctx)).To(gomega.Succeed())
+
+		gomega.Expect(provider.DeletedGroupID).To(gomega.BeEmpty())
+		gomega.Expect(provider.DeleteContactCallCount).To(gomega.BeZero())
+	})
+})
@@ -0,0 +1,74 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"go.miloapis.com/email-provider-resend/internal/config"
+)
+
+const (
+	// AllProvidersReadyReason is set when every required provider is ready
+	AllProvidersReadyReason = "AllProvidersReady"
+	// AnyProviderReadyReason is set when at least one of the required providers is ready
+	AnyProviderReadyReason = "AnyProviderReady"
+	// ProvidersNotReadyReason is set when the required providers are not ready
+	ProvidersNotReadyReason = "ProvidersNotReady"
+)
+
+// setAggregatedReadyCondition sets the readyType condition from the ready conditions of the providers, named
+// "<provider><providerConditionSuffix>", e.g. "ResendContactReady". The message lists the state of each provider.
+func setAggregatedReadyCondition(conditions *[]metav1.Condition, generation int64, readiness config.ProviderReadinessConfig, readyType, providerConditionSuffix string) {
+	providers := readiness.GetProviders()
+	states := make([]string, 0, len(providers))
+	readyCount := 0
+	for _, provider := range providers {
+		cond := meta.FindStatusCondition(*conditions, provider+providerConditionSuffix)
+		switch {
+		case cond == nil:
+			states = append(states, fmt.Sprintf("%s: condition missing", provider))
+		case cond.Status == metav1.ConditionTrue:
+			states = append(states, fmt.Sprintf("%s: ready", provider))
+			readyCount++
+		default:
+			states = append(states, fmt.Sprintf("%s: %s (%s)", provider, cond.Reason, cond.Message))
+		}
+	}
+
+	readyCond := metav1.Condition{
+		Type:               readyType,
+		Status:             metav1.ConditionFalse,
+		Reason:             ProvidersNotReadyReason,
+		Message:            fmt.Sprintf("Requires %s %s. %s", readiness.GetMode(), strings.Join(providers, ", "), strings.Join(states, "; ")),
+		LastTransitionTime: metav1.Now(),
+		ObservedGeneration: generation,
+	}
+	switch {
+	case readiness.GetMode() == config.ReadinessModeAnyOf && readyCount > 0:
+		readyCond.Status = metav1.ConditionTrue
+		readyCond.Reason = AnyProviderReadyReason
+	case readyCount == len(providers):
+		readyCond.Status = metav1.ConditionTrue
+		readyCond.Reason = AllProvidersReadyReason
+	}
+	meta.SetStatusCondition(conditions, readyCond)
+}
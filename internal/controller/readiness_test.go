@@ -0,0 +1,68 @@
+package controller
+
+import (
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+	notificationv1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"go.miloapis.com/email-provider-resend/internal/config"
+)
+
+var _ = ginkgo.Describe("setAggregatedReadyCondition", func() {
+	var conditions []metav1.Condition
+
+	ginkgo.BeforeEach(func() {
+		conditions = []metav1.Condition{
+			{Type: ResendContactReadyCondition, Status: metav1.ConditionTrue, Reason: ResendContactCreatedReason},
+			{Type: LoopsContactReadyCondition, Status: metav1.ConditionFalse, Reason: "LoopsContactPending", Message: "Waiting"},
+		}
+	})
+
+	aggregate := func(readiness config.ProviderReadinessConfig) *metav1.Condition {
+		setAggregatedReadyCondition(&conditions, 1, readiness, notificationv1.ContactReadyCondition, contactProviderReadyConditionSuffix)
+		return meta.FindStatusCondition(conditions, notificationv1.ContactReadyCondition)
+	}
+
+	ginkgo.It("requires every default provider when not configured", func() {
+		cond := aggregate(config.ProviderReadinessConfig{})
+		gomega.Expect(cond.Status).To(gomega.Equal(metav1.ConditionFalse))
+		gomega.Expect(cond.Reason).To(gomega.Equal(ProvidersNotReadyReason))
+		gomega.Expect(cond.Message).To(gomega.Equal("Requires all-of Loops, Resend. Loops: LoopsContactPending (Waiting); Resend: ready"))
+	})
+
+	ginkgo.It("is ready when the only required provider is ready", func() {
+		readiness, err := config.NewProviderReadinessConfig([]string{"Resend"}, "all-of")
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		cond := aggregate(*readiness)
+		gomega.Expect(cond.Status).To(gomega.Equal(metav1.ConditionTrue))
+		gomega.Expect(cond.Reason).To(gomega.Equal(AllProvidersReadyReason))
+		gomega.Expect(cond.Message).To(gomega.Equal("Requires all-of Resend. Resend: ready"))
+	})
+
+	ginkgo.It("is ready when any of the providers is ready", func() {
+		readiness, err := config.NewProviderReadinessConfig([]string{"Loops", "Resend"}, "any-of")
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		cond := aggregate(*readiness)
+		gomega.Expect(cond.Status).To(gomega.Equal(metav1.ConditionTrue))
+		gomega.Expect(cond.Reason).To(gomega.Equal(AnyProviderReadyReason))
+	})
+
+	ginkgo.It("reports providers without condition", func() {
+		readiness, err := config.NewProviderReadinessConfig([]string{"Mailgun"}, "any-of")
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		cond := aggregate(*readiness)
+		gomega.Expect(cond.Status).To(gomega.Equal(metav1.ConditionFalse))
+		gomega.Expect(cond.Message).To(gomega.Equal("Requires any-of Mailgun. Mailgun: condition missing"))
+	})
+
+	ginkgo.It("rejects invalid configurations", func() {
+		_, err := config.NewProviderReadinessConfig(nil, "all-of")
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		_, err = config.NewProviderReadinessConfig([]string{"Resend", "Resend"}, "all-of")
+		gomega.Expect(err).To(gomega.HaveOccurred())
+		_, err = config.NewProviderReadinessConfig([]string{"Resend"}, "some-of")
+		gomega.Expect(err).To(gomega.HaveOccurred())
+	})
+})
@@ -0,0 +1,243 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	iammiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"go.miloapis.com/email-provider-resend/internal/config"
+)
+
+const (
+	contactBySubjectUserIndexKey = "contact-by-subject-user-index"
+
+	// UserContactLabel marks the Contacts created by the UserContactController. Its value is the name of the User.
+	UserContactLabel = "notification.miloapis.com/user"
+
+	// UserContactConflictReason is the reason of the event emitted when the Contact of a User cannot be created
+	// because an unrelated Contact already has its name.
+	UserContactConflictReason = "UserContactConflict"
+
+	// userContactConflictRetryInterval is the interval at which the creation of a conflicting Contact is retried.
+	userContactConflictRetryInterval = 10 * time.Minute
+)
+
+// contactSubjectUsername returns the name of the User referenced by the SubjectRef of the contact, if any.
+func contactSubjectUsername(contact *notificationmiloapiscomv1alpha1.Contact) (string, bool) {
+	if contact.Spec.SubjectRef == nil || contact.Spec.SubjectRef.Kind != "User" {
+		return "", false
+	}
+	return contact.Spec.SubjectRef.Name, true
+}
+
+// UserContactController ensures a Contact exists for each IAM User in the configured namespace. The Contact
+// references the User through its SubjectRef, is kept in sync with the User, and is deleted along with the User.
+// Contacts created beforehand with a SubjectRef to the User are adopted: they are kept in sync, but only the
+// Contacts created by the controller, marked with the UserContactLabel, are deleted.
+type UserContactController struct {
+	Client client.Client
+	// APIReader reads the User from the API server before its Contacts are deleted, so a User not in the cache yet
+	// is not mistaken for a deleted one. Defaults to Client.
+	APIReader client.Reader
+	Config    config.UserContactConfig
+}
+
+// +kubebuilder:rbac:groups=iam.miloapis.com,resources=users,verbs=get;list;watch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contacts,verbs=get;list;watch;create;update;delete
+
+// Reconcile is the main function that reconciles the Contact of a User
+func (r *UserContactController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx).WithValues("controller", "UserContactController", "trigger", req.Name)
+	log.Info("Starting reconciliation", "name", req.Name)
+
+	contacts := &notificationmiloapiscomv1alpha1.ContactList{}
+	if err := r.Client.List(ctx, contacts, client.InNamespace(r.Config.GetNamespace()), client.MatchingFields{contactBySubjectUserIndexKey: req.Name}); err != nil {
+		log.Error(err, "Failed to list Contacts of User")
+		return ctrl.Result{}, fmt.Errorf("failed to list Contacts of User: %w", err)
+	}
+
+	// Get User
+	user := &iammiloapiscomv1alpha1.User{} // Cluster scoped resource
+	err := r.Client.Get(ctx, client.ObjectKey{Name: req.Name}, user)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get User")
+		return ctrl.Result{}, fmt.Errorf("failed to get User: %w", err)
+	}
+
+	// The cache may lag behind the API server, so the User is confirmed deleted before deleting its contacts
+	if errors.IsNotFound(err) {
+		reader := r.APIReader
+		if reader == nil {
+			reader = r.Client
+		}
+		err = reader.Get(ctx, client.ObjectKey{Name: req.Name}, user)
+		if err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to get User from API server")
+			return ctrl.Result{}, fmt.Errorf("failed to get User from API server: %w", err)
+		}
+	}
+
+	// User deleted – the contacts created for it are deleted as well
+	if errors.IsNotFound(err) || !user.GetDeletionTimestamp().IsZero() {
+		for _, contact := range contacts.Items {
+			if !contact.GetDeletionTimestamp().IsZero() || contact.Labels[UserContactLabel] != req.Name {
+				continue
+			}
+			log.Info("User deleted. Deleting Contact.", "contact", contact.Name)
+			if err := r.Client.Delete(ctx, &contact); err != nil && !errors.IsNotFound(err) {
+				log.Error(err, "Failed to delete Contact")
+				return ctrl.Result{}, fmt.Errorf("failed to delete Contact: %w", err)
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	spec := notificationmiloapiscomv1alpha1.ContactSpec{
+		SubjectRef: &notificationmiloapiscomv1alpha1.SubjectReference{
+			APIGroup: iammiloapiscomv1alpha1.SchemeGroupVersion.Group,
+			Kind:     "User",
+			Name:     user.Name,
+		},
+		Email:      user.Spec.Email,
+		GivenName:  user.Spec.GivenName,
+		FamilyName: user.Spec.FamilyName,
+	}
+
+	// First creation – no contact references the user yet
+	if len(contacts.Items) == 0 {
+		log.Info("Creating Contact for User")
+		err := r.Client.Create(ctx, &notificationmiloapiscomv1alpha1.Contact{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      user.Name,
+				Namespace: r.Config.GetNamespace(),
+				Labels:    map[string]string{UserContactLabel: user.Name},
+			},
+			Spec: spec,
+		})
+		// The Contact does not reference the User, otherwise it would have been listed, so it is not overwritten
+		if errors.IsAlreadyExists(err) {
+			log.Info("An unrelated Contact already has the name of the User. Retrying later.", "contact", user.Name)
+			if err := r.createContactConflictEvent(ctx, user); err != nil {
+				log.Error(err, "Failed to create Contact conflict event")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: userContactConflictRetryInterval}, nil
+		}
+		if err != nil {
+			log.Error(err, "Failed to create Contact for User")
+			return ctrl.Result{}, fmt.Errorf("failed to create Contact for User: %w", err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Update – the user changed since the contacts were synced
+	for _, contact := range contacts.Items {
+		if !contact.GetDeletionTimestamp().IsZero() || equality.Semantic.DeepEqual(contact.Spec, spec) {
+			continue
+		}
+		log.Info("User changed. Updating Contact.", "contact", contact.Name)
+		contact.Spec = spec
+		if err := r.Client.Update(ctx, &contact); err != nil {
+			log.Error(err, "Failed to update Contact")
+			return ctrl.Result{}, fmt.Errorf("failed to update Contact: %w", err)
+		}
+	}
+
+	log.Info("User contact reconciled")
+
+	return ctrl.Result{}, nil
+}
+
+// createContactConflictEvent emits a warning event on the Contact that prevents the Contact of the User from being created.
+func (r *UserContactController) createContactConflictEvent(ctx context.Context, user *iammiloapiscomv1alpha1.User) error {
+	event := &eventsv1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-", user.Name),
+			Namespace:    r.Config.GetNamespace(),
+		},
+		Action:              "CreateUserContact",
+		Reason:              UserContactConflictReason,
+		Note:                fmt.Sprintf("Contact for User %s cannot be created: a Contact with the same name does not reference the User", user.Name),
+		Type:                corev1.EventTypeWarning,
+		EventTime:           metav1.MicroTime{Time: time.Now()},
+		ReportingController: "email-provider-resend-controller",
+		ReportingInstance:   "email-provider-resend-controller-1",
+		Regarding: corev1.ObjectReference{
+			Kind:       "Contact",
+			Namespace:  r.Config.GetNamespace(),
+			Name:       user.Name,
+			APIVersion: notificationmiloapiscomv1alpha1.SchemeGroupVersion.String(),
+		},
+	}
+	if err := r.Client.Create(ctx, event); err != nil {
+		return fmt.Errorf("failed to create Contact conflict event: %w", err)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *UserContactController) SetupWithManager(mgr ctrl.Manager) error {
+	// Index by subject user name for efficient lookup of the contacts of a user
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &notificationmiloapiscomv1alpha1.Contact{}, contactBySubjectUserIndexKey, func(rawObj client.Object) []string {
+		if username, ok := contactSubjectUsername(rawObj.(*notificationmiloapiscomv1alpha1.Contact)); ok {
+			return []string{username}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to index contact by subject user: %w", err)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&iammiloapiscomv1alpha1.User{}).
+		// Contacts are watched so that contacts of users deleted while the controller was down are cleaned up, and
+		// manual changes to the contacts are reverted
+		Watches(
+			&notificationmiloapiscomv1alpha1.Contact{},
+			handler.EnqueueRequestsFromMapFunc(r.enqueueUserForContact),
+		).
+		Named("usercontact").
+		Complete(r)
+}
+
+func (r *UserContactController) enqueueUserForContact(ctx context.Context, obj client.Object) []reconcile.Request {
+	contact, ok := obj.(*notificationmiloapiscomv1alpha1.Contact)
+	if !ok || contact.Namespace != r.Config.GetNamespace() {
+		return nil
+	}
+	username, ok := contactSubjectUsername(contact)
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: username}}}
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"context"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	gomega "github.com/onsi/gomega"
+	iamv1 "go.miloapis.com/milo/pkg/apis/iam/v1alpha1"
+	notificationv1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"go.miloapis.com/email-provider-resend/internal/config"
+)
+
+var _ = ginkgo.Describe("UserContactController", func() {
+	var (
+		ctx        context.Context
+		k8sClient  client.Client
+		controller *UserContactController
+		user       *iamv1.User
+	)
+
+	ginkgo.BeforeEach(func() {
+		ctx = context.Background()
+
+		user = &iamv1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: "user-1"},
+			Spec:       iamv1.UserSpec{Email: "user@example.com", GivenName: "Ada", FamilyName: "Lovelace"},
+		}
+	})
+
+	buildController := func(objs ...client.Object) {
+		sch := scheme.Scheme
+		gomega.Expect(iamv1.AddToScheme(sch)).To(gomega.Succeed())
+		gomega.Expect(notificationv1.AddToScheme(sch)).To(gomega.Succeed())
+
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(sch).
+			WithObjects(objs...).
+			WithIndex(&notificationv1.Contact{}, contactBySubjectUserIndexKey, func(raw client.Object) []string {
+				if username, ok := contactSubjectUsername(raw.(*notificationv1.Contact)); ok {
+					return []string{username}
+				}
+				return nil
+			}).
+			Build()
+
+		conf, err := config.NewUserContactConfig("users")
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		controller = &UserContactController{Client: k8sClient, Config: *conf}
+	}
+
+	reconcile := func() {
+		_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: user.Name}})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+
+	listContacts := func() []notificationv1.Contact {
+		list := &notificationv1.ContactList{}
+		gomega.Expect(k8sClient.List(ctx, list, client.InNamespace("users"))).To(gomega.Succeed())
+		return list.Items
+	}
+
+	ginkgo.It("creates a contact referencing the user", func() {
+		buildController(user.DeepCopy())
+		reconcile()
+
+		contacts := listContacts()
+		gomega.Expect(contacts).To(gomega.HaveLen(1))
+		gomega.Expect(contacts[0].Name).To(gomega.Equal("user-1"))
+		gomega.Expect(contacts[0].Labels).To(gomega.HaveKeyWithValue(UserContactLabel, "user-1"))
+		gomega.Expect(contacts[0].Spec.Email).To(gomega.Equal("user@example.com"))
+		gomega.Expect(contacts[0].Spec.GivenName).To(gomega.Equal("Ada"))
+		gomega.Expect(contacts[0].Spec.FamilyName).To(gomega.Equal("Lovelace"))
+		username, ok := contactSubjectUsername(&contacts[0])
+		gomega.Expect(ok).To(gomega.BeTrue())
+		gomega.Expect(username).To(gomega.Equal("user-1"))
+	})
+
+	ginkgo.It("keeps an adopted contact in sync with the user", func() {
+		buildController(user.DeepCopy(), &notificationv1.Contact{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy-contact", Namespace: "users"},
+			Spec: notificationv1.ContactSpec{
+				SubjectRef: &notificationv1.SubjectReference{Kind: "User", Name: "user-1"},
+				Email:      "old@example.com",
+			},
+		})
+
+		fetched := &iamv1.User{}
+		gomega.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: user.Name}, fetched)).To(gomega.Succeed())
+		fetched.Spec.Email = "new@example.com"
+		gomega.Expect(k8sClient.Update(ctx, fetched)).To(gomega.Succeed())
+		reconcile()
+
+		contacts := listContacts()
+		gomega.Expect(contacts).To(gomega.HaveLen(1))
+		gomega.Expect(contacts[0].Name).To(gomega.Equal("legacy-contact"))
+		gomega.Expect(contacts[0].Spec.Email).To(gomega.Equal("new@example.com"))
+		gomega.Expect(contacts[0].Spec.GivenName).To(gomega.Equal("Ada"))
+	})
+
+	ginkgo.It("deletes the contact when the user is deleted", func() {
+		buildController(user.DeepCopy())
+		reconcile()
+		gomega.Expect(listContacts()).To(gomega.HaveLen(1))
+
+		gomega.Expect(k8sClient.Delete(ctx, user.DeepCopy())).To(gomega.Succeed())
+		reconcile()
+		gomega.Expect(listContacts()).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("keeps contacts it did not create when the user is deleted", func() {
+		buildController(&notificationv1.Contact{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy-contact", Namespace: "users"},
+			Spec: notificationv1.ContactSpec{
+				SubjectRef: &notificationv1.SubjectReference{Kind: "User", Name: "user-1"},
+				Email:      "user@example.com",
+			},
+		})
+		reconcile()
+
+		contacts := listContacts()
+		gomega.Expect(contacts).To(gomega.HaveLen(1))
+		gomega.Expect(contacts[0].Name).To(gomega.Equal("legacy-contact"))
+	})
+
+	ginkgo.It("keeps the contact when the user is missing from the cache only", func() {
+		buildController(&notificationv1.Contact{
+			ObjectMeta: metav1.ObjectMeta{Name: "user-1", Namespace: "users", Labels: map[string]string{UserContactLabel: "user-1"}},
+			Spec: notificationv1.ContactSpec{
+				SubjectRef: &notificationv1.SubjectReference{Kind: "User", Name: "user-1"},
+				Email:      "user@example.com",
+			},
+		})
+		controller.APIReader = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(user.DeepCopy()).Build()
+		reconcile()
+
+		gomega.Expect(listContacts()).To(gomega.HaveLen(1))
+	})
+
+	ginkgo.It("reports a conflict instead of failing when an unrelated contact has the name of the user", func() {
+		buildController(user.DeepCopy(), &notificationv1.Contact{
+			ObjectMeta: metav1.ObjectMeta{Name: "user-1", Namespace: "users"},
+			Spec:       notificationv1.ContactSpec{Email: "someone-else@example.com"},
+		})
+
+		res, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: user.Name}})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(res.RequeueAfter).To(gomega.Equal(userContactConflictRetryInterval))
+
+		contacts := listContacts()
+		gomega.Expect(contacts).To(gomega.HaveLen(1))
+		gomega.Expect(contacts[0].Spec.Email).To(gomega.Equal("someone-else@example.com"))
+
+		events := &eventsv1.EventList{}
+		gomega.Expect(k8sClient.List(ctx, events, client.InNamespace("users"))).To(gomega.Succeed())
+		gomega.Expect(events.Items).To(gomega.HaveLen(1))
+		gomega.Expect(events.Items[0].Reason).To(gomega.Equal(UserContactConflictReason))
+		gomega.Expect(events.Items[0].Regarding.Name).To(gomega.Equal("user-1"))
+	})
+})
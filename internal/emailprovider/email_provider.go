@@ -2,6 +2,7 @@ package emailprovider
 
 import (
 	"context"
+	"iter"
 	"time"
 )
 
@@ -16,6 +17,7 @@ type SendEmailInput struct {
 	Subject        string
 	HtmlBody       string
 	TextBody       string
+	Headers        map[string]string // Custom headers, e.g. List-Unsubscribe
 }
 
 // SendEmailOutput contains the output of the email provider
@@ -23,6 +25,62 @@ type SendEmailOutput struct {
 	DeliveryID string
 }
 
+// CreateBroadcastInput contains all the data required to create a broadcast to a contact group regardless of the underlying provider.
+type CreateBroadcastInput struct {
+	// BroadcastID is the id of a broadcast previously created for the email, if recorded. It is reused while it
+	// is still a draft with the same name.
+	BroadcastID    string
+	Name           string
+	ContactGroupID string
+	From           string
+	ReplyTo        string
+	Subject        string
+	HtmlBody       string
+	TextBody       string
+}
+
+// CreateBroadcastOutput contains the output of the email provider
+type CreateBroadcastOutput struct {
+	BroadcastID string
+}
+
+// SendBroadcastInput contains the input of the email provider
+type SendBroadcastInput struct {
+	BroadcastID string
+}
+
+// SendBroadcastOutput contains the output of the email provider
+type SendBroadcastOutput struct {
+	BroadcastID string
+}
+
+// BroadcastStatus is the delivery status of a broadcast on the email provider.
+type BroadcastStatus string
+
+const (
+	// BroadcastStatusDraft is the status of a broadcast that has been created but not sent yet.
+	BroadcastStatusDraft BroadcastStatus = "draft"
+	// BroadcastStatusQueued is the status of a broadcast that is waiting to be sent.
+	BroadcastStatusQueued BroadcastStatus = "queued"
+	// BroadcastStatusSending is the status of a broadcast that is being sent.
+	BroadcastStatusSending BroadcastStatus = "sending"
+	// BroadcastStatusSent is the status of a broadcast that has been sent to every contact of the contact group.
+	BroadcastStatusSent BroadcastStatus = "sent"
+	// BroadcastStatusFailed is the status of a broadcast that could not be sent.
+	BroadcastStatusFailed BroadcastStatus = "failed"
+)
+
+// GetBroadcastInput contains the input of the email provider
+type GetBroadcastInput struct {
+	BroadcastID string
+}
+
+// GetBroadcastOutput contains the output of the email provider
+type GetBroadcastOutput struct {
+	BroadcastID string
+	Status      BroadcastStatus
+}
+
 // CreateContactGroupInput contains the input of the email provider
 type CreateContactGroupInput struct {
 	DisplayName string
@@ -56,11 +114,6 @@ type DeleteContactGroupOutput struct {
 	Deleted        bool
 }
 
-// ListContactGroupsOutput contains the output of the email provider
-type ListContactGroupsOutput struct {
-	ContactGroups []GetContactGroupOutput
-}
-
 // CreateContactGroupMembershipInput contains the input of the email provider
 type CreateContactGroupMembershipInput struct {
 	ContactId      string
@@ -72,6 +125,18 @@ type CreateContactGroupMembershipOutput struct {
 	ContactGroupMembershipID string
 }
 
+// GetContactGroupMembershipInput contains the input of the email provider
+type GetContactGroupMembershipInput struct {
+	ContactId      string
+	ContactGroupId string
+}
+
+// GetContactGroupMembershipOutput contains the output of the email provider
+type GetContactGroupMembershipOutput struct {
+	ContactId      string
+	ContactGroupId string
+}
+
 // DeleteContactGroupMembershipInput contains the input of the email provider
 type DeleteContactGroupMembershipInput struct {
 	ContactId      string
@@ -96,6 +161,44 @@ type CreateContactOutput struct {
 	ContactId string
 }
 
+// GetContactInput contains the input of the email provider.
+// The contact is looked up by ContactId if set, otherwise by Email.
+type GetContactInput struct {
+	ContactId string
+	Email     string
+}
+
+// GetContactOutput contains the output of the email provider
+type GetContactOutput struct {
+	ContactId  string
+	Email      string
+	GivenName  string
+	FamilyName string
+	CreatedAt  time.Time
+	// Unsubscribed is whether the contact unsubscribed from every broadcast
+	Unsubscribed bool
+	// Managed is whether the contact was created by this operator, as opposed to e.g. contacts created before the
+	// operator was deployed or by other integrations
+	Managed bool
+}
+
+// ListSegmentContactsInput contains the input of the email provider
+type ListSegmentContactsInput struct {
+	ContactGroupID string
+}
+
+// UpdateContactInput contains the input of the email provider
+type UpdateContactInput struct {
+	ContactId  string
+	GivenName  string
+	FamilyName string
+}
+
+// UpdateContactOutput contains the output of the email provider
+type UpdateContactOutput struct {
+	ContactId string
+}
+
 // DeleteContactInput contains the input of the email provider
 type DeleteContactInput struct {
 	ContactId string
@@ -106,15 +209,110 @@ type DeleteContactOutput struct {
 	Deleted bool
 }
 
+// TopicSubscription is the subscription preference of a contact for a topic.
+type TopicSubscription string
+
+const (
+	// TopicSubscriptionOptIn means the contact receives the emails of the topic.
+	TopicSubscriptionOptIn TopicSubscription = "opt_in"
+	// TopicSubscriptionOptOut means the contact does not receive the emails of the topic.
+	TopicSubscriptionOptOut TopicSubscription = "opt_out"
+)
+
+// CreateTopicInput contains the input of the email provider
+type CreateTopicInput struct {
+	DisplayName         string
+	Description         string
+	DefaultSubscription TopicSubscription
+}
+
+// CreateTopicOutput contains the output of the email provider
+type CreateTopicOutput struct {
+	TopicID string
+}
+
+// GetTopicInput contains the input of the email provider
+type GetTopicInput struct {
+	TopicID string
+}
+
+// GetTopicOutput contains the output of the email provider
+type GetTopicOutput struct {
+	TopicID     string
+	DisplayName string
+}
+
+// UpdateTopicInput contains the input of the email provider
+type UpdateTopicInput struct {
+	TopicID     string
+	DisplayName string
+	Description string
+}
+
+// UpdateTopicOutput contains the output of the email provider
+type UpdateTopicOutput struct {
+	TopicID string
+}
+
+// DeleteTopicInput contains the input of the email provider
+type DeleteTopicInput struct {
+	TopicID string
+}
+
+// DeleteTopicOutput contains the output of the email provider
+type DeleteTopicOutput struct {
+	TopicID string
+	Deleted bool
+}
+
+// ListContactTopicsInput contains the input of the email provider
+type ListContactTopicsInput struct {
+	ContactId string
+}
+
+// ContactTopicSubscription is the subscription preference of a contact for a single topic
+type ContactTopicSubscription struct {
+	TopicID      string
+	Subscription TopicSubscription
+}
+
+// UpdateContactTopicsInput contains the input of the email provider
+type UpdateContactTopicsInput struct {
+	ContactId     string
+	Subscriptions []ContactTopicSubscription
+}
+
+// UpdateContactTopicsOutput contains the output of the email provider
+type UpdateContactTopicsOutput struct {
+	ContactId string
+}
+
 // EmailProvider defines the contract every e-mail provider (Resend, SES, Mailgun, …) must fulfil.
+//
+// List methods return iterators that walk every page of the provider state. Iteration stops at the first error,
+// which is yielded together with a zero value.
 type EmailProvider interface {
 	SendEmail(ctx context.Context, input SendEmailInput) (SendEmailOutput, error)
+	CreateBroadcast(ctx context.Context, input CreateBroadcastInput) (CreateBroadcastOutput, error)
+	SendBroadcast(ctx context.Context, input SendBroadcastInput) (SendBroadcastOutput, error)
+	GetBroadcast(ctx context.Context, input GetBroadcastInput) (GetBroadcastOutput, error)
 	CreateContactGroup(ctx context.Context, input CreateContactGroupInput) (CreateContactGroupOutput, error)
 	GetContactGroup(ctx context.Context, input GetContactGroupInput) (GetContactGroupOutput, error)
 	DeleteContactGroup(ctx context.Context, input DeleteContactGroupInput) (DeleteContactGroupOutput, error)
-	ListContactGroups(ctx context.Context) (ListContactGroupsOutput, error)
+	ListContactGroups(ctx context.Context) iter.Seq2[GetContactGroupOutput, error]
 	CreateContactGroupMembership(ctx context.Context, input CreateContactGroupMembershipInput) (CreateContactGroupMembershipOutput, error)
+	GetContactGroupMembership(ctx context.Context, input GetContactGroupMembershipInput) (GetContactGroupMembershipOutput, error)
 	DeleteContactGroupMembership(ctx context.Context, input DeleteContactGroupMembershipInput) (DeleteContactGroupMembershipOutput, error)
 	CreateContact(ctx context.Context, input CreateContactInput) (CreateContactOutput, error)
+	GetContact(ctx context.Context, input GetContactInput) (GetContactOutput, error)
+	UpdateContact(ctx context.Context, input UpdateContactInput) (UpdateContactOutput, error)
+	ListContacts(ctx context.Context) iter.Seq2[GetContactOutput, error]
+	ListSegmentContacts(ctx context.Context, input ListSegmentContactsInput) iter.Seq2[GetContactOutput, error]
 	DeleteContact(ctx context.Context, input DeleteContactInput) (DeleteContactOutput, error)
+	CreateTopic(ctx context.Context, input CreateTopicInput) (CreateTopicOutput, error)
+	GetTopic(ctx context.Context, input GetTopicInput) (GetTopicOutput, error)
+	UpdateTopic(ctx context.Context, input UpdateTopicInput) (UpdateTopicOutput, error)
+	DeleteTopic(ctx context.Context, input DeleteTopicInput) (DeleteTopicOutput, error)
+	ListContactTopics(ctx context.Context, input ListContactTopicsInput) iter.Seq2[ContactTopicSubscription, error]
+	UpdateContactTopics(ctx context.Context, input UpdateContactTopicsInput) (UpdateContactTopicsOutput, error)
 }
@@ -2,6 +2,7 @@ package mockprovider
 
 import (
 	"context"
+	"iter"
 
 	"go.miloapis.com/email-provider-resend/internal/emailprovider"
 )
@@ -15,6 +16,20 @@ type MockEmailProvider struct {
 	SendEmailCallCount int
 	LastSendEmailInput emailprovider.SendEmailInput
 
+	// CreateBroadcast
+	CreateBroadcastOutput    emailprovider.CreateBroadcastOutput
+	CreateBroadcastErr       error
+	CreateBroadcastCallCount int
+	LastCreateBroadcastInput emailprovider.CreateBroadcastInput
+
+	// SendBroadcast
+	SendBroadcastErr       error
+	SendBroadcastCallCount int
+
+	// GetBroadcast
+	GetBroadcastOutput emailprovider.GetBroadcastOutput
+	GetBroadcastErr    error
+
 	// CreateContactGroup
 	CreateContactGroupOutput emailprovider.CreateContactGroupOutput
 	CreateContactGroupErr    error
@@ -30,7 +45,7 @@ type MockEmailProvider struct {
 	DeletedGroupID           string
 
 	// ListContactGroups
-	ListContactGroupsOutput emailprovider.ListContactGroupsOutput
+	ListContactGroupsOutput []emailprovider.GetContactGroupOutput
 	ListContactGroupsErr    error
 
 	// CreateContactGroupMembership
@@ -39,6 +54,10 @@ type MockEmailProvider struct {
 	CreatedMembershipInputs               []emailprovider.CreateContactGroupMembershipInput
 	CreateContactGroupMembershipCallCount int
 
+	// GetContactGroupMembership
+	GetContactGroupMembershipErr       error
+	GetContactGroupMembershipCallCount int
+
 	// DeleteContactGroupMembership
 	DeleteContactGroupMembershipOutput emailprovider.DeleteContactGroupMembershipOutput
 	DeleteContactGroupMembershipErr    error
@@ -50,11 +69,57 @@ type MockEmailProvider struct {
 	CreateContactCallCount int
 	LastCreateContactInput emailprovider.CreateContactInput
 
+	// GetContact tracking
+	GetContactOutput    emailprovider.GetContactOutput
+	GetContactErr       error
+	GetContactCallCount int
+	LastGetContactInput emailprovider.GetContactInput
+
+	// UpdateContact tracking
+	UpdateContactOutput    emailprovider.UpdateContactOutput
+	UpdateContactErr       error
+	UpdateContactCallCount int
+	LastUpdateContactInput emailprovider.UpdateContactInput
+
+	// ListContacts tracking
+	ListContactsOutput []emailprovider.GetContactOutput
+	ListContactsErr    error
+
+	// ListSegmentContacts tracking
+	ListSegmentContactsOutput    []emailprovider.GetContactOutput
+	ListSegmentContactsErr       error
+	LastListSegmentContactsInput emailprovider.ListSegmentContactsInput
+
 	// DeleteContact tracking
 	DeleteContactOutput    emailprovider.DeleteContactOutput
 	DeleteContactErr       error
 	DeleteContactCallCount int
 	LastDeleteContactInput emailprovider.DeleteContactInput
+
+	// CreateTopic tracking
+	CreateTopicOutput emailprovider.CreateTopicOutput
+	CreateTopicErr    error
+	CreatedTopics     []emailprovider.CreateTopicInput
+
+	// GetTopic tracking
+	GetTopicOutput emailprovider.GetTopicOutput
+	GetTopicErr    error
+
+	// UpdateTopic tracking
+	UpdateTopicErr error
+	UpdatedTopics  []emailprovider.UpdateTopicInput
+
+	// DeleteTopic tracking
+	DeleteTopicErr  error
+	DeletedTopicIDs []string
+
+	// ListContactTopics tracking
+	ListContactTopicsOutput []emailprovider.ContactTopicSubscription
+	ListContactTopicsErr    error
+
+	// UpdateContactTopics tracking
+	UpdateContactTopicsErr    error
+	UpdateContactTopicsInputs []emailprovider.UpdateContactTopicsInput
 }
 
 func (m *MockEmailProvider) SendEmail(ctx context.Context, input emailprovider.SendEmailInput) (emailprovider.SendEmailOutput, error) {
@@ -63,6 +128,25 @@ func (m *MockEmailProvider) SendEmail(ctx context.Context, input emailprovider.S
 	return m.SendEmailOutput, m.SendEmailErr
 }
 
+func (m *MockEmailProvider) CreateBroadcast(ctx context.Context, input emailprovider.CreateBroadcastInput) (emailprovider.CreateBroadcastOutput, error) {
+	m.CreateBroadcastCallCount++
+	m.LastCreateBroadcastInput = input
+	return m.CreateBroadcastOutput, m.CreateBroadcastErr
+}
+
+func (m *MockEmailProvider) SendBroadcast(ctx context.Context, input emailprovider.SendBroadcastInput) (emailprovider.SendBroadcastOutput, error) {
+	m.SendBroadcastCallCount++
+	return emailprovider.SendBroadcastOutput{BroadcastID: input.BroadcastID}, m.SendBroadcastErr
+}
+
+func (m *MockEmailProvider) GetBroadcast(ctx context.Context, input emailprovider.GetBroadcastInput) (emailprovider.GetBroadcastOutput, error) {
+	// Default to a draft broadcast echoing back the ID if not explicitly set
+	if (m.GetBroadcastOutput == emailprovider.GetBroadcastOutput{}) {
+		return emailprovider.GetBroadcastOutput{BroadcastID: input.BroadcastID, Status: emailprovider.BroadcastStatusDraft}, m.GetBroadcastErr
+	}
+	return m.GetBroadcastOutput, m.GetBroadcastErr
+}
+
 func (m *MockEmailProvider) CreateContactGroup(ctx context.Context, input emailprovider.CreateContactGroupInput) (emailprovider.CreateContactGroupOutput, error) {
 	m.CreatedGroups = append(m.CreatedGroups, input)
 	return m.CreateContactGroupOutput, m.CreateContactGroupErr
@@ -84,8 +168,8 @@ func (m *MockEmailProvider) DeleteContactGroup(ctx context.Context, input emailp
 	return m.DeleteContactGroupOutput, m.DeleteContactGroupErr
 }
 
-func (m *MockEmailProvider) ListContactGroups(ctx context.Context) (emailprovider.ListContactGroupsOutput, error) {
-	return m.ListContactGroupsOutput, m.ListContactGroupsErr
+func (m *MockEmailProvider) ListContactGroups(ctx context.Context) iter.Seq2[emailprovider.GetContactGroupOutput, error] {
+	return seq(m.ListContactGroupsOutput, m.ListContactGroupsErr)
 }
 
 func (m *MockEmailProvider) CreateContactGroupMembership(ctx context.Context, input emailprovider.CreateContactGroupMembershipInput) (emailprovider.CreateContactGroupMembershipOutput, error) {
@@ -94,6 +178,14 @@ func (m *MockEmailProvider) CreateContactGroupMembership(ctx context.Context, in
 	return m.CreateContactGroupMembershipOutput, m.CreateContactGroupMembershipErr
 }
 
+func (m *MockEmailProvider) GetContactGroupMembership(ctx context.Context, input emailprovider.GetContactGroupMembershipInput) (emailprovider.GetContactGroupMembershipOutput, error) {
+	m.GetContactGroupMembershipCallCount++
+	if m.GetContactGroupMembershipErr != nil {
+		return emailprovider.GetContactGroupMembershipOutput{}, m.GetContactGroupMembershipErr
+	}
+	return emailprovider.GetContactGroupMembershipOutput{ContactId: input.ContactId, ContactGroupId: input.ContactGroupId}, nil
+}
+
 func (m *MockEmailProvider) DeleteContactGroupMembership(ctx context.Context, input emailprovider.DeleteContactGroupMembershipInput) (emailprovider.DeleteContactGroupMembershipOutput, error) {
 	m.DeleteContactGroupMembershipInputs = append(m.DeleteContactGroupMembershipInputs, input)
 	if (m.DeleteContactGroupMembershipOutput == emailprovider.DeleteContactGroupMembershipOutput{}) {
@@ -108,8 +200,83 @@ func (m *MockEmailProvider) CreateContact(ctx context.Context, input emailprovid
 	return m.CreateContactOutput, m.CreateContactErr
 }
 
+func (m *MockEmailProvider) GetContact(ctx context.Context, input emailprovider.GetContactInput) (emailprovider.GetContactOutput, error) {
+	m.GetContactCallCount++
+	m.LastGetContactInput = input
+	// Default to echoing back ID if not explicitly set
+	if (m.GetContactOutput == emailprovider.GetContactOutput{}) {
+		return emailprovider.GetContactOutput{ContactId: input.ContactId, Email: input.Email}, m.GetContactErr
+	}
+	return m.GetContactOutput, m.GetContactErr
+}
+
+func (m *MockEmailProvider) UpdateContact(ctx context.Context, input emailprovider.UpdateContactInput) (emailprovider.UpdateContactOutput, error) {
+	m.UpdateContactCallCount++
+	m.LastUpdateContactInput = input
+	if (m.UpdateContactOutput == emailprovider.UpdateContactOutput{}) {
+		return emailprovider.UpdateContactOutput{ContactId: input.ContactId}, m.UpdateContactErr
+	}
+	return m.UpdateContactOutput, m.UpdateContactErr
+}
+
+func (m *MockEmailProvider) ListContacts(ctx context.Context) iter.Seq2[emailprovider.GetContactOutput, error] {
+	return seq(m.ListContactsOutput, m.ListContactsErr)
+}
+
+func (m *MockEmailProvider) ListSegmentContacts(ctx context.Context, input emailprovider.ListSegmentContactsInput) iter.Seq2[emailprovider.GetContactOutput, error] {
+	m.LastListSegmentContactsInput = input
+	return seq(m.ListSegmentContactsOutput, m.ListSegmentContactsErr)
+}
+
 func (m *MockEmailProvider) DeleteContact(ctx context.Context, input emailprovider.DeleteContactInput) (emailprovider.DeleteContactOutput, error) {
 	m.DeleteContactCallCount++
 	m.LastDeleteContactInput = input
 	return m.DeleteContactOutput, m.DeleteContactErr
 }
+
+// seq returns an iterator over the given items. If err is set, it is yielded after the items.
+func (m *MockEmailProvider) CreateTopic(ctx context.Context, input emailprovider.CreateTopicInput) (emailprovider.CreateTopicOutput, error) {
+	m.CreatedTopics = append(m.CreatedTopics, input)
+	return m.CreateTopicOutput, m.CreateTopicErr
+}
+
+func (m *MockEmailProvider) GetTopic(ctx context.Context, input emailprovider.GetTopicInput) (emailprovider.GetTopicOutput, error) {
+	// Default to echoing back the ID if not explicitly set
+	if m.GetTopicOutput.TopicID == "" {
+		return emailprovider.GetTopicOutput{TopicID: input.TopicID}, m.GetTopicErr
+	}
+	return m.GetTopicOutput, m.GetTopicErr
+}
+
+func (m *MockEmailProvider) UpdateTopic(ctx context.Context, input emailprovider.UpdateTopicInput) (emailprovider.UpdateTopicOutput, error) {
+	m.UpdatedTopics = append(m.UpdatedTopics, input)
+	return emailprovider.UpdateTopicOutput{TopicID: input.TopicID}, m.UpdateTopicErr
+}
+
+func (m *MockEmailProvider) DeleteTopic(ctx context.Context, input emailprovider.DeleteTopicInput) (emailprovider.DeleteTopicOutput, error) {
+	m.DeletedTopicIDs = append(m.DeletedTopicIDs, input.TopicID)
+	return emailprovider.DeleteTopicOutput{TopicID: input.TopicID, Deleted: m.DeleteTopicErr == nil}, m.DeleteTopicErr
+}
+
+func (m *MockEmailProvider) ListContactTopics(ctx context.Context, input emailprovider.ListContactTopicsInput) iter.Seq2[emailprovider.ContactTopicSubscription, error] {
+	return seq(m.ListContactTopicsOutput, m.ListContactTopicsErr)
+}
+
+func (m *MockEmailProvider) UpdateContactTopics(ctx context.Context, input emailprovider.UpdateContactTopicsInput) (emailprovider.UpdateContactTopicsOutput, error) {
+	m.UpdateContactTopicsInputs = append(m.UpdateContactTopicsInputs, input)
+	return emailprovider.UpdateContactTopicsOutput{ContactId: input.ContactId}, m.UpdateContactTopicsErr
+}
+
+func seq[T any](items []T, err error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
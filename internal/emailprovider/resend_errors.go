@@ -8,8 +8,9 @@ import (
 )
 
 // TranslateResendError inspects the error returned by the Resend SDK.
-// If it corresponds to a "not found" situation it converts it into a
-// Kubernetes NotFound error (so callers can rely on apierrors.IsNotFound).
+// If it corresponds to a "not found" or "already exists" situation it converts
+// it into a Kubernetes NotFound or AlreadyExists error (so callers can rely on
+// apierrors.IsNotFound and apierrors.IsAlreadyExists).
 //
 // The caller must specify which GroupResource and name were being requested
 // so the generated error contains correct metadata.
@@ -24,6 +25,11 @@ func TranslateResendError(err error, gr schema.GroupResource, name string) error
 	if strings.Contains(strings.ToLower(err.Error()), "not found") {
 		return apierrors.NewNotFound(gr, name)
 	}
+	// Creating a resource that already exists, e.g. a contact with the same email address, fails with an
+	// "already exists" message.
+	if strings.Contains(strings.ToLower(err.Error()), "already exist") {
+		return apierrors.NewAlreadyExists(gr, name)
+	}
 
 	return err
 }
@@ -3,16 +3,33 @@ package emailprovider
 import (
 	"context"
 	"fmt"
+	"iter"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/resend/resend-go/v3"
 	rtime "go.miloapis.com/email-provider-resend/internal/resend"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+const (
+	// managedByContactProperty is the Resend contact property marking the contacts created by this operator.
+	// Only contacts carrying it are ever garbage collected as orphans.
+	managedByContactProperty = "managed_by"
+	// managedByContactPropertyValue is the value of managedByContactProperty on the contacts created by this operator
+	managedByContactPropertyValue = "email-provider-resend"
+)
+
 // ResendEmailProvider is an implementation of EmailProvider that delivers e-mails
 // using Resend (https://resend.com/).
 type ResendEmailProvider struct {
 	client *resend.Client
+
+	// managedByPropertyMu guards managedByPropertyExists, set once the managedByContactProperty exists on Resend
+	managedByPropertyMu     sync.Mutex
+	managedByPropertyExists bool
 }
 
 // NewResendEmailProviderFromAPIKey instantiates the provider from the given
@@ -27,6 +44,12 @@ func (r *ResendEmailProvider) SendEmail(ctx context.Context, input SendEmailInpu
 		DeliveryID: "",
 	}
 
+	headers := map[string]string{}
+	for name, value := range input.Headers {
+		headers[name] = value
+	}
+	headers["IdempotencyKey"] = input.IdempotencyKey
+
 	resp, err := r.client.Emails.Send(&resend.SendEmailRequest{
 		From:    input.From,
 		ReplyTo: input.ReplyTo,
@@ -36,9 +59,7 @@ func (r *ResendEmailProvider) SendEmail(ctx context.Context, input SendEmailInpu
 		Subject: input.Subject,
 		Html:    input.HtmlBody,
 		Text:    input.TextBody,
-		Headers: map[string]string{
-			"IdempotencyKey": input.IdempotencyKey,
-		},
+		Headers: headers,
 	})
 	if err != nil {
 		return output, fmt.Errorf("failed to send email using resend: %w", err)
@@ -49,6 +70,120 @@ func (r *ResendEmailProvider) SendEmail(ctx context.Context, input SendEmailInpu
 	return output, nil
 }
 
+// CreateBroadcast satisfies the EmailProvider interface. It returns the resend broadcast id of the broadcast.
+// The broadcast is created as a draft, and must be sent with SendBroadcast. Broadcast names are unique per Email,
+// so a draft with the same name left behind by a previous attempt is reused instead of creating a duplicate. The
+// broadcast recorded in the input is looked up first; every broadcast is only scanned when none was recorded.
+func (r *ResendEmailProvider) CreateBroadcast(ctx context.Context, input CreateBroadcastInput) (CreateBroadcastOutput, error) {
+	output := CreateBroadcastOutput{}
+
+	draftID, err := r.findDraftBroadcast(ctx, input.BroadcastID, input.Name, input.ContactGroupID)
+	if err != nil {
+		return output, err
+	}
+	if draftID != "" {
+		output.BroadcastID = draftID
+		return output, nil
+	}
+
+	var replyTo []string
+	if input.ReplyTo != "" {
+		replyTo = []string{input.ReplyTo}
+	}
+
+	resp, err := r.client.Broadcasts.CreateWithContext(ctx, &resend.CreateBroadcastRequest{
+		Name:      input.Name,
+		SegmentId: input.ContactGroupID,
+		From:      input.From,
+		ReplyTo:   replyTo,
+		Subject:   input.Subject,
+		Html:      input.HtmlBody,
+		Text:      input.TextBody,
+	})
+	if err != nil {
+		return output, TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "segments"}, input.ContactGroupID)
+	}
+
+	output.BroadcastID = resp.Id
+
+	return output, nil
+}
+
+// findDraftBroadcast returns the id of the draft broadcast with the given name to the given segment, if any.
+// The recorded broadcast, if any, is checked first, and every broadcast is scanned when it is not such a draft.
+func (r *ResendEmailProvider) findDraftBroadcast(ctx context.Context, recordedID string, name string, segmentID string) (string, error) {
+	isDraft := func(broadcast resend.Broadcast) bool {
+		return broadcast.Name == name && broadcast.Status == string(BroadcastStatusDraft) &&
+			(broadcast.SegmentId == segmentID || broadcast.AudienceId == segmentID)
+	}
+
+	if recordedID != "" {
+		broadcast, err := r.client.Broadcasts.GetWithContext(ctx, recordedID)
+		err = TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "broadcasts"}, recordedID)
+		if err != nil && !errors.IsNotFound(err) {
+			return "", fmt.Errorf("failed to get broadcast using resend: %w", err)
+		}
+		if err == nil && isDraft(broadcast) {
+			return broadcast.Id, nil
+		}
+	}
+
+	broadcasts := paginate(
+		func(options *resend.ListOptions) ([]resend.Broadcast, bool, error) {
+			resp, err := r.client.Broadcasts.ListWithOptions(ctx, options)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to list broadcasts using resend: %w", err)
+			}
+			return resp.Data, resp.HasMore, nil
+		},
+		func(broadcast resend.Broadcast) string { return broadcast.Id },
+		func(broadcast resend.Broadcast) (resend.Broadcast, error) { return broadcast, nil },
+	)
+	for broadcast, err := range broadcasts {
+		if err != nil {
+			return "", err
+		}
+		if isDraft(broadcast) {
+			return broadcast.Id, nil
+		}
+	}
+	return "", nil
+}
+
+// SendBroadcast satisfies the EmailProvider interface. It returns the resend broadcast id of the broadcast.
+func (r *ResendEmailProvider) SendBroadcast(ctx context.Context, input SendBroadcastInput) (SendBroadcastOutput, error) {
+	output := SendBroadcastOutput{}
+
+	resp, err := r.client.Broadcasts.SendWithContext(ctx, &resend.SendBroadcastRequest{
+		BroadcastId: input.BroadcastID,
+	})
+	if err != nil {
+		return output, TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "broadcasts"}, input.BroadcastID)
+	}
+
+	output.BroadcastID = resp.Id
+	if output.BroadcastID == "" {
+		output.BroadcastID = input.BroadcastID
+	}
+
+	return output, nil
+}
+
+// GetBroadcast satisfies the EmailProvider interface. It returns the resend broadcast status of the broadcast.
+func (r *ResendEmailProvider) GetBroadcast(ctx context.Context, input GetBroadcastInput) (GetBroadcastOutput, error) {
+	output := GetBroadcastOutput{}
+
+	resp, err := r.client.Broadcasts.GetWithContext(ctx, input.BroadcastID)
+	if err != nil {
+		return output, TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "broadcasts"}, input.BroadcastID)
+	}
+
+	output.BroadcastID = resp.Id
+	output.Status = BroadcastStatus(resp.Status)
+
+	return output, nil
+}
+
 // CreateContactGroup satisfies the EmailProvider interface. It returns the resend contact group id of the contact group.
 func (r *ResendEmailProvider) CreateContactGroup(ctx context.Context, input CreateContactGroupInput) (CreateContactGroupOutput, error) {
 	output := CreateContactGroupOutput{
@@ -79,12 +214,11 @@ func (r *ResendEmailProvider) GetContactGroup(ctx context.Context, input GetCont
 	output.ContactGroupID = resp.Id
 	output.DisplayName = resp.Name
 
-	// Parse the timestamp returned by Resend using the resilient ResendTime helper.
-	var rt rtime.ResendTime
-	if err := rt.UnmarshalJSON([]byte(fmt.Sprintf("%q", resp.CreatedAt))); err != nil {
+	createdAt, err := parseResendTime(resp.CreatedAt)
+	if err != nil {
 		return output, fmt.Errorf("failed to parse created at get contact group using resend: %w", err)
 	}
-	output.CreatedAt = rt.Time
+	output.CreatedAt = createdAt
 
 	return output, nil
 }
@@ -104,23 +238,29 @@ func (r *ResendEmailProvider) DeleteContactGroup(ctx context.Context, input Dele
 	return output, nil
 }
 
-// ListContactGroups satisfies the EmailProvider interface. It returns the list of contact groups.
-func (r *ResendEmailProvider) ListContactGroups(ctx context.Context) (ListContactGroupsOutput, error) {
-	output := ListContactGroupsOutput{}
-
-	resp, err := r.client.Segments.List()
-	if err != nil {
-		return output, fmt.Errorf("failed to list contact groups using resend: %w", err)
-	}
-
-	for _, segment := range resp.Data {
-		output.ContactGroups = append(output.ContactGroups, GetContactGroupOutput{
-			ContactGroupID: segment.Id,
-			DisplayName:    segment.Name,
-		})
-	}
-
-	return output, nil
+// ListContactGroups satisfies the EmailProvider interface. It walks every page of contact groups.
+func (r *ResendEmailProvider) ListContactGroups(ctx context.Context) iter.Seq2[GetContactGroupOutput, error] {
+	return paginate(
+		func(options *resend.ListOptions) ([]resend.Segment, bool, error) {
+			resp, err := r.client.Segments.ListWithOptions(ctx, options)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to list contact groups using resend: %w", err)
+			}
+			return resp.Data, resp.HasMore, nil
+		},
+		func(segment resend.Segment) string { return segment.Id },
+		func(segment resend.Segment) (GetContactGroupOutput, error) {
+			createdAt, err := parseResendTime(segment.CreatedAt)
+			if err != nil {
+				return GetContactGroupOutput{}, fmt.Errorf("failed to parse created at list contact groups using resend: %w", err)
+			}
+			return GetContactGroupOutput{
+				ContactGroupID: segment.Id,
+				DisplayName:    segment.Name,
+				CreatedAt:      createdAt,
+			}, nil
+		},
+	)
 }
 
 // CreateContactGroupMembership satisfies the EmailProvider interface. It returns the resend contact group membership id of the contact group membership.
@@ -142,6 +282,37 @@ func (r *ResendEmailProvider) CreateContactGroupMembership(ctx context.Context,
 	return output, nil
 }
 
+// GetContactGroupMembership satisfies the EmailProvider interface. It returns a NotFound error if the contact is not
+// part of the contact group.
+func (r *ResendEmailProvider) GetContactGroupMembership(ctx context.Context, input GetContactGroupMembershipInput) (GetContactGroupMembershipOutput, error) {
+	segments := paginate(
+		func(options *resend.ListOptions) ([]resend.Segment, bool, error) {
+			resp, err := r.client.Contacts.Segments.ListWithOptions(ctx, &resend.ListContactSegmentsRequest{
+				ContactId: input.ContactId,
+			}, options)
+			if err != nil {
+				return nil, false, TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "contacts"}, input.ContactId)
+			}
+			return resp.Data, resp.HasMore, nil
+		},
+		func(segment resend.Segment) string { return segment.Id },
+		func(segment resend.Segment) (string, error) { return segment.Id, nil },
+	)
+	for segmentID, err := range segments {
+		if err != nil {
+			return GetContactGroupMembershipOutput{}, err
+		}
+		if segmentID == input.ContactGroupId {
+			return GetContactGroupMembershipOutput{
+				ContactId:      input.ContactId,
+				ContactGroupId: input.ContactGroupId,
+			}, nil
+		}
+	}
+
+	return GetContactGroupMembershipOutput{}, errors.NewNotFound(schema.GroupResource{Group: "resend", Resource: "contactsegments"}, fmt.Sprintf("%s/%s", input.ContactGroupId, input.ContactId))
+}
+
 // DeleteContactGroupMembership satisfies the EmailProvider interface. It returns the resend contact group membership id of the contact group membership.
 // This is an idempotent operation.
 func (r *ResendEmailProvider) DeleteContactGroupMembership(ctx context.Context, input DeleteContactGroupMembershipInput) (DeleteContactGroupMembershipOutput, error) {
@@ -166,14 +337,20 @@ func (r *ResendEmailProvider) DeleteContactGroupMembership(ctx context.Context,
 func (r *ResendEmailProvider) CreateContact(ctx context.Context, input CreateContactInput) (CreateContactOutput, error) {
 	output := CreateContactOutput{}
 
+	if err := r.ensureManagedByContactProperty(ctx); err != nil {
+		return output, err
+	}
+
 	resp, err := r.client.Contacts.Create(&resend.CreateContactRequest{
 		Email:        input.Email,
 		FirstName:    input.GivenName,
 		LastName:     input.FamilyName,
 		Unsubscribed: false,
+		Properties:   map[string]interface{}{managedByContactProperty: managedByContactPropertyValue},
 	})
 	if err != nil {
-		return output, fmt.Errorf("failed to create contact using resend: %w", err)
+		return output, fmt.Errorf("failed to create contact using resend: %w",
+			TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "contacts"}, input.Email))
 	}
 
 	output.ContactId = resp.Id
@@ -181,6 +358,159 @@ func (r *ResendEmailProvider) CreateContact(ctx context.Context, input CreateCon
 	return output, nil
 }
 
+// ensureManagedByContactProperty creates the managedByContactProperty on Resend, if missing, as Resend only accepts
+// the contact properties that were created beforehand.
+func (r *ResendEmailProvider) ensureManagedByContactProperty(ctx context.Context) error {
+	r.managedByPropertyMu.Lock()
+	defer r.managedByPropertyMu.Unlock()
+	if r.managedByPropertyExists {
+		return nil
+	}
+
+	properties := paginate(
+		func(options *resend.ListOptions) ([]resend.ContactProperty, bool, error) {
+			resp, err := r.client.ContactProperties.ListWithOptions(ctx, options)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to list contact properties using resend: %w", err)
+			}
+			return resp.Data, resp.HasMore, nil
+		},
+		func(property resend.ContactProperty) string { return property.Id },
+		func(property resend.ContactProperty) (string, error) { return property.Key, nil },
+	)
+	for key, err := range properties {
+		if err != nil {
+			return err
+		}
+		if key == managedByContactProperty {
+			r.managedByPropertyExists = true
+			return nil
+		}
+	}
+
+	if _, err := r.client.ContactProperties.CreateWithContext(ctx, &resend.CreateContactPropertyRequest{
+		Key:           managedByContactProperty,
+		Type:          "string",
+		FallbackValue: "",
+	}); err != nil {
+		return fmt.Errorf("failed to create contact property using resend: %w", err)
+	}
+	r.managedByPropertyExists = true
+	return nil
+}
+
+// isManagedContact returns whether the Resend contact was created by this operator.
+func isManagedContact(contact resend.Contact) bool {
+	value, ok := contact.Properties[managedByContactProperty].(string)
+	return ok && value == managedByContactPropertyValue
+}
+
+// GetContact satisfies the EmailProvider interface. It returns the resend contact of the given id or email address.
+func (r *ResendEmailProvider) GetContact(ctx context.Context, input GetContactInput) (GetContactOutput, error) {
+	output := GetContactOutput{}
+
+	// Resend accepts either the contact id or the contact email address
+	id := input.ContactId
+	if id == "" {
+		id = input.Email
+	}
+
+	resp, err := r.client.Contacts.Get(&resend.GetContactOptions{
+		Id: id,
+	})
+	if err != nil {
+		return output, TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "contacts"}, id)
+	}
+
+	output.ContactId = resp.Id
+	output.Email = resp.Email
+	output.GivenName = resp.FirstName
+	output.FamilyName = resp.LastName
+	output.Unsubscribed = resp.Unsubscribed
+	output.Managed = isManagedContact(resp)
+
+	return output, nil
+}
+
+// updateContactRequest is the body of a contact update. Unlike resend.UpdateContactRequest, empty names are kept.
+type updateContactRequest struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// UpdateContact satisfies the EmailProvider interface. It updates the contact names in place.
+// The email address is not updated, as Resend does not support updating the email address of a contact.
+func (r *ResendEmailProvider) UpdateContact(ctx context.Context, input UpdateContactInput) (UpdateContactOutput, error) {
+	output := UpdateContactOutput{}
+
+	// The SDK request omits empty names, which would never clear them on Resend, so the names are always sent.
+	req, err := r.client.NewRequest(ctx, http.MethodPatch, "contacts/"+input.ContactId, &updateContactRequest{
+		FirstName: input.GivenName,
+		LastName:  input.FamilyName,
+	})
+	if err != nil {
+		return output, fmt.Errorf("failed to create contact update request: %w", err)
+	}
+	resp := resend.UpdateContactResponse{}
+	if _, err := r.client.Perform(req, &resp); err != nil {
+		return output, TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "contacts"}, input.ContactId)
+	}
+
+	output.ContactId = resp.Data.Id
+	if output.ContactId == "" {
+		output.ContactId = input.ContactId
+	}
+
+	return output, nil
+}
+
+// ListContacts satisfies the EmailProvider interface. It walks every page of contacts.
+func (r *ResendEmailProvider) ListContacts(ctx context.Context) iter.Seq2[GetContactOutput, error] {
+	return r.listContacts(ctx, "")
+}
+
+// ListSegmentContacts satisfies the EmailProvider interface. It walks every page of contacts in the given contact group.
+func (r *ResendEmailProvider) ListSegmentContacts(ctx context.Context, input ListSegmentContactsInput) iter.Seq2[GetContactOutput, error] {
+	return r.listContacts(ctx, input.ContactGroupID)
+}
+
+// listContacts lists the contacts of the account, or of the given segment if segmentID is set.
+// Resend kept audience ids when migrating audiences to segments, so the audience scoped contact list is used for segments.
+func (r *ResendEmailProvider) listContacts(ctx context.Context, segmentID string) iter.Seq2[GetContactOutput, error] {
+	return paginate(
+		func(options *resend.ListOptions) ([]resend.Contact, bool, error) {
+			resp, err := r.client.Contacts.ListWithContext(ctx, &resend.ListContactsOptions{
+				AudienceId: segmentID,
+				Limit:      options.Limit,
+				After:      options.After,
+			})
+			if err != nil {
+				if segmentID != "" {
+					return nil, false, TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "segments"}, segmentID)
+				}
+				return nil, false, fmt.Errorf("failed to list contacts using resend: %w", err)
+			}
+			return resp.Data, resp.HasMore, nil
+		},
+		func(contact resend.Contact) string { return contact.Id },
+		func(contact resend.Contact) (GetContactOutput, error) {
+			createdAt, err := parseResendTime(contact.CreatedAt)
+			if err != nil {
+				return GetContactOutput{}, fmt.Errorf("failed to parse created at list contacts using resend: %w", err)
+			}
+			return GetContactOutput{
+				ContactId:    contact.Id,
+				Email:        contact.Email,
+				GivenName:    contact.FirstName,
+				FamilyName:   contact.LastName,
+				CreatedAt:    createdAt,
+				Unsubscribed: contact.Unsubscribed,
+				Managed:      isManagedContact(contact),
+			}, nil
+		},
+	)
+}
+
 // DeleteContact satisfies the EmailProvider interface. It returns the resend contact id of the contact.
 func (r *ResendEmailProvider) DeleteContact(ctx context.Context, input DeleteContactInput) (DeleteContactOutput, error) {
 	output := DeleteContactOutput{}
@@ -195,3 +525,166 @@ func (r *ResendEmailProvider) DeleteContact(ctx context.Context, input DeleteCon
 	output.Deleted = resp.Deleted
 	return output, nil
 }
+
+// CreateTopic satisfies the EmailProvider interface. It returns the resend topic id of the topic.
+func (r *ResendEmailProvider) CreateTopic(ctx context.Context, input CreateTopicInput) (CreateTopicOutput, error) {
+	output := CreateTopicOutput{}
+
+	resp, err := r.client.Topics.CreateWithContext(ctx, &resend.CreateTopicRequest{
+		Name:                input.DisplayName,
+		Description:         input.Description,
+		DefaultSubscription: resend.DefaultSubscription(input.DefaultSubscription),
+	})
+	if err != nil {
+		return output, fmt.Errorf("failed to create topic using resend: %w", err)
+	}
+
+	output.TopicID = resp.Id
+
+	return output, nil
+}
+
+// GetTopic satisfies the EmailProvider interface. It returns the resend topic id and name of the topic.
+func (r *ResendEmailProvider) GetTopic(ctx context.Context, input GetTopicInput) (GetTopicOutput, error) {
+	output := GetTopicOutput{}
+
+	resp, err := r.client.Topics.GetWithContext(ctx, input.TopicID)
+	if err != nil {
+		return output, TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "topics"}, input.TopicID)
+	}
+
+	output.TopicID = resp.Id
+	output.DisplayName = resp.Name
+
+	return output, nil
+}
+
+// UpdateTopic satisfies the EmailProvider interface. The default subscription of a topic can not be updated.
+func (r *ResendEmailProvider) UpdateTopic(ctx context.Context, input UpdateTopicInput) (UpdateTopicOutput, error) {
+	output := UpdateTopicOutput{}
+
+	resp, err := r.client.Topics.UpdateWithContext(ctx, input.TopicID, &resend.UpdateTopicRequest{
+		Name:        input.DisplayName,
+		Description: input.Description,
+	})
+	if err != nil {
+		return output, TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "topics"}, input.TopicID)
+	}
+
+	output.TopicID = resp.Id
+
+	return output, nil
+}
+
+// DeleteTopic satisfies the EmailProvider interface.
+func (r *ResendEmailProvider) DeleteTopic(ctx context.Context, input DeleteTopicInput) (DeleteTopicOutput, error) {
+	output := DeleteTopicOutput{}
+
+	resp, err := r.client.Topics.RemoveWithContext(ctx, input.TopicID)
+	if err != nil {
+		return output, TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "topics"}, input.TopicID)
+	}
+
+	output.TopicID = input.TopicID
+	output.Deleted = resp.Deleted
+
+	return output, nil
+}
+
+// ListContactTopics satisfies the EmailProvider interface. It walks every page of topic subscriptions of the contact.
+func (r *ResendEmailProvider) ListContactTopics(ctx context.Context, input ListContactTopicsInput) iter.Seq2[ContactTopicSubscription, error] {
+	return paginate(
+		func(options *resend.ListOptions) ([]resend.ContactTopic, bool, error) {
+			resp, err := r.client.Contacts.Topics.ListWithOptions(ctx, input.ContactId, options)
+			if err != nil {
+				return nil, false, TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "contacts"}, input.ContactId)
+			}
+			return resp.Data, resp.HasMore, nil
+		},
+		func(topic resend.ContactTopic) string { return topic.Id },
+		func(topic resend.ContactTopic) (ContactTopicSubscription, error) {
+			return ContactTopicSubscription{
+				TopicID:      topic.Id,
+				Subscription: TopicSubscription(topic.Subscription),
+			}, nil
+		},
+	)
+}
+
+// UpdateContactTopics satisfies the EmailProvider interface. Topics not part of the input keep their subscription.
+func (r *ResendEmailProvider) UpdateContactTopics(ctx context.Context, input UpdateContactTopicsInput) (UpdateContactTopicsOutput, error) {
+	output := UpdateContactTopicsOutput{}
+
+	topics := make([]resend.TopicSubscriptionUpdate, 0, len(input.Subscriptions))
+	for _, subscription := range input.Subscriptions {
+		topics = append(topics, resend.TopicSubscriptionUpdate{
+			Id:           subscription.TopicID,
+			Subscription: string(subscription.Subscription),
+		})
+	}
+
+	resp, err := r.client.Contacts.Topics.UpdateWithContext(ctx, &resend.UpdateContactTopicsRequest{
+		Id:     input.ContactId,
+		Topics: topics,
+	})
+	if err != nil {
+		return output, TranslateResendError(err, schema.GroupResource{Group: "resend", Resource: "contacts"}, input.ContactId)
+	}
+
+	output.ContactId = resp.Id
+
+	return output, nil
+}
+
+// listPageSize is the number of items requested per page on list operations. Resend allows up to 100.
+const listPageSize = 100
+
+// paginate returns an iterator that walks every page of a Resend cursor based list endpoint.
+// fetch returns one page of items and whether more pages are available, cursor returns the id used as the
+// "after" cursor for the next page, and convert maps each item to the provider agnostic output.
+func paginate[T any, O any](
+	fetch func(options *resend.ListOptions) ([]T, bool, error),
+	cursor func(T) string,
+	convert func(T) (O, error),
+) iter.Seq2[O, error] {
+	return func(yield func(O, error) bool) {
+		var zero O
+		limit := listPageSize
+		options := &resend.ListOptions{Limit: &limit}
+		for {
+			items, hasMore, err := fetch(options)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				out, err := convert(item)
+				if err != nil {
+					yield(zero, err)
+					return
+				}
+				if !yield(out, nil) {
+					return
+				}
+			}
+			if !hasMore || len(items) == 0 {
+				return
+			}
+			after := cursor(items[len(items)-1])
+			options = &resend.ListOptions{Limit: &limit, After: &after}
+		}
+	}
+}
+
+// parseResendTime parses a timestamp returned by Resend using the resilient ResendTime helper.
+// Empty timestamps are returned as the zero time.
+func parseResendTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	var rt rtime.ResendTime
+	if err := rt.UnmarshalJSON([]byte(fmt.Sprintf("%q", value))); err != nil {
+		return time.Time{}, err
+	}
+	return rt.Time, nil
+}
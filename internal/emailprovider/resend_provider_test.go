@@ -0,0 +1,337 @@
+package emailprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/resend/resend-go/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// newTestResendProvider returns a resend provider that talks to the given test server.
+func newTestResendProvider(t *testing.T, server *httptest.Server) *ResendEmailProvider {
+	t.Helper()
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatalf("failed to parse test server url: %v", err)
+	}
+	client := resend.NewClient("re_test")
+	client.BaseURL = baseURL
+	return &ResendEmailProvider{client: client}
+}
+
+func TestResendEmailProvider_ListContacts_WalksEveryPage(t *testing.T) {
+	var afters []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/contacts" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		after := r.URL.Query().Get("after")
+		afters = append(afters, after)
+		w.Header().Set("Content-Type", "application/json")
+		switch after {
+		case "":
+			fmt.Fprint(w, `{"object":"list","has_more":true,"data":[
+				{"id":"c1","email":"one@example.com","created_at":"2025-10-01T17:38:42.986Z"},
+				{"id":"c2","email":"two@example.com","created_at":"2025-10-01 17:38:42.986+00"}]}`)
+		case "c2":
+			fmt.Fprint(w, `{"object":"list","has_more":false,"data":[
+				{"id":"c3","email":"three@example.com","first_name":"Three","created_at":"2025-10-01T17:38:42.986Z",
+				 "properties":{"managed_by":"email-provider-resend"}}]}`)
+		default:
+			t.Errorf("unexpected cursor %q", after)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestResendProvider(t, server)
+
+	var ids, managed []string
+	for contact, err := range provider.ListContacts(context.Background()) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if contact.CreatedAt.IsZero() {
+			t.Errorf("expected created at to be parsed for contact %q", contact.ContactId)
+		}
+		ids = append(ids, contact.ContactId)
+		if contact.Managed {
+			managed = append(managed, contact.ContactId)
+		}
+	}
+
+	if fmt.Sprint(ids) != "[c1 c2 c3]" {
+		t.Fatalf("expected contacts [c1 c2 c3], got %v", ids)
+	}
+	if fmt.Sprint(managed) != "[c3]" {
+		t.Fatalf("expected managed contacts [c3], got %v", managed)
+	}
+	if fmt.Sprint(afters) != "[ c2]" {
+		t.Fatalf("expected cursors [ c2], got %v", afters)
+	}
+}
+
+func TestResendEmailProvider_ListSegmentContacts_StopsOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audiences/seg-1/contacts" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"statusCode":404,"name":"not_found","message":"Segment not found"}`)
+	}))
+	defer server.Close()
+
+	provider := newTestResendProvider(t, server)
+
+	var seen int
+	var lastErr error
+	for _, err := range provider.ListSegmentContacts(context.Background(), ListSegmentContactsInput{ContactGroupID: "seg-1"}) {
+		seen++
+		lastErr = err
+	}
+
+	if seen != 1 {
+		t.Fatalf("expected a single yielded error, got %d items", seen)
+	}
+	if lastErr == nil {
+		t.Fatalf("expected an error to be yielded")
+	}
+}
+
+func TestResendEmailProvider_SendEmail_SendsCustomHeaders(t *testing.T) {
+	var body struct {
+		Headers map[string]string `json:"headers"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/emails" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"email-1"}`)
+	}))
+	defer server.Close()
+
+	provider := newTestResendProvider(t, server)
+
+	output, err := provider.SendEmail(context.Background(), SendEmailInput{
+		To:             []string{"to@example.com"},
+		IdempotencyKey: "uid-1",
+		Headers:        map[string]string{ListUnsubscribeHeader: "<https://example.com/unsubscribe>"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.DeliveryID != "email-1" {
+		t.Fatalf("expected delivery id email-1, got %q", output.DeliveryID)
+	}
+	if body.Headers[ListUnsubscribeHeader] != "<https://example.com/unsubscribe>" || body.Headers["IdempotencyKey"] != "uid-1" {
+		t.Fatalf("unexpected headers %v", body.Headers)
+	}
+}
+
+func TestResendEmailProvider_CreateContact_MarksManagedContacts(t *testing.T) {
+	var createdProperties []string
+	var contactBodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/contact-properties" && r.Method == http.MethodGet:
+			fmt.Fprint(w, `{"object":"list","has_more":false,"data":[{"id":"p1","key":"company_name","type":"string"}]}`)
+		case r.URL.Path == "/contact-properties" && r.Method == http.MethodPost:
+			var body struct {
+				Key string `json:"key"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode request body: %v", err)
+			}
+			createdProperties = append(createdProperties, body.Key)
+			fmt.Fprint(w, `{"object":"contact_property","id":"p2"}`)
+		case r.URL.Path == "/contacts" && r.Method == http.MethodPost:
+			var body map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode request body: %v", err)
+			}
+			contactBodies = append(contactBodies, body)
+			fmt.Fprintf(w, `{"object":"contact","id":"c%d"}`, len(contactBodies))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestResendProvider(t, server)
+
+	for _, email := range []string{"one@example.com", "two@example.com"} {
+		if _, err := provider.CreateContact(context.Background(), CreateContactInput{Email: email}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if fmt.Sprint(createdProperties) != "[managed_by]" {
+		t.Fatalf("expected the managed_by property to be created once, got %v", createdProperties)
+	}
+	for _, body := range contactBodies {
+		properties, _ := body["properties"].(map[string]any)
+		if properties["managed_by"] != "email-provider-resend" {
+			t.Fatalf("expected contact to be marked as managed, got properties %v", body["properties"])
+		}
+	}
+}
+
+func TestResendEmailProvider_CreateContact_ReportsExistingContacts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/contact-properties" && r.Method == http.MethodGet:
+			fmt.Fprint(w, `{"object":"list","has_more":false,"data":[{"id":"p1","key":"managed_by","type":"string"}]}`)
+		case r.URL.Path == "/contacts" && r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"statusCode":409,"name":"conflict","message":"Contact already exists"}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestResendProvider(t, server)
+
+	_, err := provider.CreateContact(context.Background(), CreateContactInput{Email: "one@example.com"})
+	if !apierrors.IsAlreadyExists(err) {
+		t.Fatalf("expected an AlreadyExists error, got %v", err)
+	}
+}
+
+func TestResendEmailProvider_UpdateContact_ClearsNames(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodPatch || r.URL.Path != "/contacts/c-1" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		fmt.Fprint(w, `{"object":"contact","id":"c-1"}`)
+	}))
+	defer server.Close()
+
+	provider := newTestResendProvider(t, server)
+
+	output, err := provider.UpdateContact(context.Background(), UpdateContactInput{ContactId: "c-1"})
+	if err != nil {
+		t.Fatalf("UpdateContact: %v", err)
+	}
+	if output.ContactId != "c-1" {
+		t.Errorf("expected contact id c-1, got %q", output.ContactId)
+	}
+	for _, field := range []string{"first_name", "last_name"} {
+		if value, ok := body[field]; !ok || value != "" {
+			t.Errorf("expected %s to be sent empty, got %v (present: %t)", field, value, ok)
+		}
+	}
+}
+
+func TestResendEmailProvider_CreateBroadcast_ReusesRecordedDraft(t *testing.T) {
+	var listed int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/broadcasts/b-draft":
+			fmt.Fprint(w, `{"id":"b-draft","name":"default/welcome (uid-1)","segment_id":"seg-1","status":"draft"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/broadcasts/b-sent":
+			fmt.Fprint(w, `{"id":"b-sent","name":"default/welcome (uid-1)","segment_id":"seg-1","status":"sent"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/broadcasts":
+			listed++
+			fmt.Fprint(w, `{"object":"list","has_more":false,"data":[
+				{"id":"b-sent","name":"default/welcome (uid-1)","segment_id":"seg-1","status":"sent"},
+				{"id":"b-draft","name":"default/welcome (uid-1)","segment_id":"seg-1","status":"draft"}]}`)
+		default:
+			t.Errorf("unexpected request %s %q", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestResendProvider(t, server)
+
+	output, err := provider.CreateBroadcast(context.Background(), CreateBroadcastInput{
+		BroadcastID:    "b-draft",
+		Name:           "default/welcome (uid-1)",
+		ContactGroupID: "seg-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.BroadcastID != "b-draft" || listed != 0 {
+		t.Fatalf("expected the recorded draft to be reused without listing broadcasts, got %q with %d lists", output.BroadcastID, listed)
+	}
+
+	// A recorded broadcast that is no longer a draft falls back to the scan
+	output, err = provider.CreateBroadcast(context.Background(), CreateBroadcastInput{
+		BroadcastID:    "b-sent",
+		Name:           "default/welcome (uid-1)",
+		ContactGroupID: "seg-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.BroadcastID != "b-draft" || listed != 1 {
+		t.Fatalf("expected the draft to be found by listing broadcasts, got %q with %d lists", output.BroadcastID, listed)
+	}
+}
+
+func TestResendEmailProvider_CreateBroadcast_ReusesLeakedDraft(t *testing.T) {
+	var created int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/broadcasts":
+			fmt.Fprint(w, `{"object":"list","has_more":false,"data":[
+				{"id":"b-sent","name":"default/welcome (uid-1)","segment_id":"seg-1","status":"sent"},
+				{"id":"b-other","name":"default/other (uid-2)","segment_id":"seg-1","status":"draft"},
+				{"id":"b-draft","name":"default/welcome (uid-1)","segment_id":"seg-1","status":"draft"}]}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/broadcasts":
+			created++
+			fmt.Fprint(w, `{"id":"b-new"}`)
+		default:
+			t.Errorf("unexpected request %s %q", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestResendProvider(t, server)
+
+	output, err := provider.CreateBroadcast(context.Background(), CreateBroadcastInput{
+		Name:           "default/welcome (uid-1)",
+		ContactGroupID: "seg-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.BroadcastID != "b-draft" || created != 0 {
+		t.Fatalf("expected the leaked draft b-draft to be reused, got %q with %d broadcasts created", output.BroadcastID, created)
+	}
+
+	output, err = provider.CreateBroadcast(context.Background(), CreateBroadcastInput{
+		Name:           "default/welcome (uid-3)",
+		ContactGroupID: "seg-1",
+		From:           "from@example.com",
+		Subject:        "Welcome",
+		HtmlBody:       "<p>Welcome</p>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.BroadcastID != "b-new" || created != 1 {
+		t.Fatalf("expected a new broadcast to be created, got %q with %d broadcasts created", output.BroadcastID, created)
+	}
+}
@@ -3,6 +3,9 @@ package emailprovider
 import (
 	"context"
 	"fmt"
+	"iter"
+	"slices"
+	"strings"
 
 	emailtemplating "go.miloapis.com/email-provider-resend/internal/emailtemplanting"
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
@@ -10,6 +13,16 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+const (
+	// UnsubscribeURLVariable is the template variable that holds the unsubscribe URL of the recipient
+	UnsubscribeURLVariable = "UnsubscribeURL"
+
+	// ListUnsubscribeHeader and ListUnsubscribePostHeader implement the RFC 8058 one-click unsubscription
+	ListUnsubscribeHeader     = "List-Unsubscribe"
+	ListUnsubscribePostHeader = "List-Unsubscribe-Post"
+	ListUnsubscribePostValue  = "List-Unsubscribe=One-Click"
+)
+
 // Service ties rendering logic with an underlying EmailProvider.
 type Service struct {
 	provider EmailProvider // Actual email provider
@@ -36,38 +49,47 @@ type SendEmailRenderedOutput struct {
 	RecipientEmailAddress string `json:"recipientEmailAddress,omitempty"`
 }
 
-// Send takes the CRD objects coming from Milo, renders the templates and finally
-// hands the result to the configured Provider.
-func (s *Service) Send(ctx context.Context,
+// Render renders the subject and bodies of the template with the variables of the email.
+// The unsubscribeURL is exposed to the templates as the UnsubscribeURLVariable variable, empty if the email has no
+// unsubscribe link, so templates can test it.
+// In strict mode, an *emailtemplating.VariablesError is returned if the variables do not match the template.
+func (s *Service) Render(
 	email *notificationmiloapiscomv1alpha1.Email,
 	template *notificationmiloapiscomv1alpha1.EmailTemplate,
-	recipientEmailAddress string,
-) (SendEmailRenderedOutput, error) {
+	unsubscribeURL string,
+	opts emailtemplating.RenderOptions,
+) (RenderedEmail, error) {
 	// variables are already validated by Milo webhooks
 	// to match the referenced template
-	vars := email.Spec.Variables
-
-	output := SendEmailRenderedOutput{
-		RecipientEmailAddress: recipientEmailAddress,
-	}
+	vars := append(slices.DeleteFunc(slices.Clone(email.Spec.Variables), func(v notificationmiloapiscomv1alpha1.EmailVariable) bool {
+		return v.Name == UnsubscribeURLVariable
+	}), notificationmiloapiscomv1alpha1.EmailVariable{Name: UnsubscribeURLVariable, Value: unsubscribeURL})
 
-	htmlBody, err := emailtemplating.RenderHTMLBodyTemplate(vars, template)
-	if err != nil {
-		return output, fmt.Errorf("render HTML body: %w", err)
-	}
-	output.HTMLBody = htmlBody
+	return renderEmail(vars, template, opts)
+}
 
-	textBody, err := emailtemplating.RenderTextBodyTemplate(vars, template)
-	if err != nil {
-		return output, fmt.Errorf("render text body: %w", err)
+// Send hands the email rendered by Render to the configured Provider.
+// If unsubscribeURL is set, RFC 8058 one-click List-Unsubscribe headers are added to the email.
+func (s *Service) Send(ctx context.Context,
+	email *notificationmiloapiscomv1alpha1.Email,
+	rendered RenderedEmail,
+	recipientEmailAddress string,
+	unsubscribeURL string,
+) (SendEmailRenderedOutput, error) {
+	var headers map[string]string
+	if unsubscribeURL != "" {
+		headers = map[string]string{
+			ListUnsubscribeHeader:     fmt.Sprintf("<%s>", unsubscribeURL),
+			ListUnsubscribePostHeader: ListUnsubscribePostValue,
+		}
 	}
-	output.TextBody = textBody
 
-	subject, err := emailtemplating.RenderSubjectTemplate(vars, template)
-	if err != nil {
-		return output, fmt.Errorf("render subject: %w", err)
+	output := SendEmailRenderedOutput{
+		HTMLBody:              rendered.HTMLBody,
+		TextBody:              rendered.TextBody,
+		Subject:               rendered.Subject,
+		RecipientEmailAddress: recipientEmailAddress,
 	}
-	output.Subject = subject
 
 	providerOutput, err := s.provider.SendEmail(ctx, SendEmailInput{
 		From:           s.from,
@@ -75,10 +97,11 @@ func (s *Service) Send(ctx context.Context,
 		To:             []string{recipientEmailAddress},
 		Cc:             email.Spec.CC,
 		Bcc:            email.Spec.BCC,
-		Subject:        subject,
-		HtmlBody:       htmlBody,
-		TextBody:       textBody,
+		Subject:        rendered.Subject,
+		HtmlBody:       rendered.HTMLBody,
+		TextBody:       rendered.TextBody,
 		IdempotencyKey: string(email.UID),
+		Headers:        headers,
 	})
 	if err != nil {
 		return output, fmt.Errorf("error sending email: %w", err)
@@ -88,13 +111,156 @@ func (s *Service) Send(ctx context.Context,
 	return output, nil
 }
 
+// CreateBroadcastRenderedOutput is the output of the CreateBroadcast function.
+// It contains the broadcast ID, the HTML body, the text body and the subject.
+type CreateBroadcastRenderedOutput struct {
+	BroadcastID string `json:"broadcastID"`
+	HTMLBody    string `json:"htmlBody"`
+	TextBody    string `json:"textBody,omitempty"`
+	Subject     string `json:"subject,omitempty"`
+}
+
+// CreateBroadcast creates a broadcast of the email rendered by Render to every contact of the contact group.
+// The broadcast is not sent until SendBroadcast is called, so the caller can record the broadcast ID first.
+// recordedBroadcastID is the broadcast previously created for the email, if the caller recorded one, which is reused
+// while it is still a draft.
+func (s *Service) CreateBroadcast(ctx context.Context,
+	email *notificationmiloapiscomv1alpha1.Email,
+	rendered RenderedEmail,
+	contactGroup *notificationmiloapiscomv1alpha1.ContactGroup,
+	recordedBroadcastID string,
+) (CreateBroadcastRenderedOutput, error) {
+	output := CreateBroadcastRenderedOutput{
+		HTMLBody: rendered.HTMLBody,
+		TextBody: rendered.TextBody,
+		Subject:  rendered.Subject,
+	}
+
+	providerOutput, err := s.provider.CreateBroadcast(ctx, CreateBroadcastInput{
+		BroadcastID:    recordedBroadcastID,
+		Name:           broadcastName(email),
+		ContactGroupID: contactGroup.Status.ProviderID,
+		From:           s.from,
+		ReplyTo:        s.replyTo,
+		Subject:        rendered.Subject,
+		HtmlBody:       rendered.HTMLBody,
+		TextBody:       rendered.TextBody,
+	})
+	if err != nil {
+		return output, fmt.Errorf("error creating broadcast: %w", err)
+	}
+	output.BroadcastID = providerOutput.BroadcastID
+
+	return output, nil
+}
+
+// broadcastName returns the provider name of the broadcast of the email. It includes the UID so that an Email
+// recreated with the same name never picks up the draft broadcast of its predecessor.
+func broadcastName(email *notificationmiloapiscomv1alpha1.Email) string {
+	return fmt.Sprintf("%s/%s (%s)", email.Namespace, email.Name, email.UID)
+}
+
+// SendBroadcast sends the broadcast previously created for the email.
+func (s *Service) SendBroadcast(ctx context.Context, email *notificationmiloapiscomv1alpha1.Email) (SendBroadcastOutput, error) {
+	return s.provider.SendBroadcast(ctx, SendBroadcastInput{
+		BroadcastID: email.Status.ProviderID,
+	})
+}
+
+// GetBroadcast returns the broadcast previously created for the email.
+func (s *Service) GetBroadcast(ctx context.Context, email *notificationmiloapiscomv1alpha1.Email) (GetBroadcastOutput, error) {
+	return s.provider.GetBroadcast(ctx, GetBroadcastInput{
+		BroadcastID: email.Status.ProviderID,
+	})
+}
+
+// RenderedEmail contains the rendered subject and bodies of an email.
+type RenderedEmail struct {
+	Subject  string `json:"subject,omitempty"`
+	HTMLBody string `json:"htmlBody"`
+	TextBody string `json:"textBody,omitempty"`
+}
+
+// builtinVariables are the variables set by the controller rather than by the email. They are always defined when
+// rendering, empty if unset, so templates can test them even in strict mode.
+var builtinVariables = []string{UnsubscribeURLVariable, emailtemplating.LocaleVariable}
+
+// renderEmail renders the subject and bodies of the template with the given variables.
+// In strict mode, an *emailtemplating.VariablesError is returned if the variables do not match the template.
+func renderEmail(vars []notificationmiloapiscomv1alpha1.EmailVariable, template *notificationmiloapiscomv1alpha1.EmailTemplate, opts emailtemplating.RenderOptions) (RenderedEmail, error) {
+	rendered := RenderedEmail{}
+
+	for _, name := range builtinVariables {
+		if !slices.ContainsFunc(vars, func(v notificationmiloapiscomv1alpha1.EmailVariable) bool { return v.Name == name }) {
+			vars = append(slices.Clip(vars), notificationmiloapiscomv1alpha1.EmailVariable{Name: name})
+		}
+	}
+
+	if opts.Strict {
+		if err := emailtemplating.CheckVariables(vars, template, opts, builtinVariables...); err != nil {
+			return rendered, fmt.Errorf("check variables: %w", err)
+		}
+	}
+
+	htmlBody, err := emailtemplating.RenderHTMLBodyTemplate(vars, template, opts)
+	if err != nil {
+		return rendered, fmt.Errorf("render HTML body: %w", err)
+	}
+	rendered.HTMLBody = htmlBody
+
+	textBody, err := emailtemplating.RenderTextBodyTemplate(vars, template, opts)
+	if err != nil {
+		return rendered, fmt.Errorf("render text body: %w", err)
+	}
+	rendered.TextBody = textBody
+
+	subject, err := emailtemplating.RenderSubjectTemplate(vars, template, opts)
+	if err != nil {
+		return rendered, fmt.Errorf("render subject: %w", err)
+	}
+	rendered.Subject = subject
+
+	return rendered, nil
+}
+
 // CreateContactGroup creates a contact group on the email provider.
+// If a contact group with the deterministic display name already exists (e.g. created before a crash, or imported manually),
+// it is adopted instead of creating a duplicate.
+// This is an idempotent operation.
 func (s *Service) CreateContactGroup(ctx context.Context, cg notificationmiloapiscomv1alpha1.ContactGroup) (CreateContactGroupOutput, error) {
 	displayName := GetDeterministicContactGroupDisplayName(&cg)
+
+	existing, err := s.GetContactGroupByDisplayName(ctx, displayName)
+	if err == nil {
+		return CreateContactGroupOutput{ContactGroupID: existing.ContactGroupID}, nil
+	}
+	if !errors.IsNotFound(err) {
+		return CreateContactGroupOutput{}, fmt.Errorf("failed to look up existing contact group: %w", err)
+	}
+
 	return s.provider.CreateContactGroup(ctx, CreateContactGroupInput{
 		DisplayName: displayName,
 	})
+}
 
+// RenameContactGroup moves the contact group to a contact group named after its current display name.
+// Resend does not support renaming segments, so a contact group with the new name is created (or adopted) and the
+// previous one is deleted if deletePrevious is set. The caller must keep the previous contact group while other
+// ContactGroups still use it. Contacts are not moved: the caller must add them again to the returned contact group.
+// This is an idempotent operation.
+func (s *Service) RenameContactGroup(ctx context.Context, cg notificationmiloapiscomv1alpha1.ContactGroup, deletePrevious bool) (CreateContactGroupOutput, error) {
+	renamed, err := s.CreateContactGroup(ctx, cg)
+	if err != nil {
+		return CreateContactGroupOutput{}, fmt.Errorf("failed to create renamed contact group: %w", err)
+	}
+
+	if deletePrevious && cg.Status.ProviderID != "" && cg.Status.ProviderID != renamed.ContactGroupID {
+		if _, err := s.DeleteContactGroupByID(ctx, cg.Status.ProviderID); err != nil && !errors.IsNotFound(err) {
+			return CreateContactGroupOutput{}, fmt.Errorf("failed to delete previous contact group: %w", err)
+		}
+	}
+
+	return renamed, nil
 }
 
 // GetContactGroup returns the email provider contact group id of the contact group.
@@ -111,13 +277,21 @@ func (s *Service) DeleteContactGroup(ctx context.Context, cg notificationmiloapi
 	})
 }
 
+// DeleteContactGroupByID deletes a contact group on the email provider by its email provider id.
+// It is used for contact groups that are not owned by any ContactGroup.
+func (s *Service) DeleteContactGroupByID(ctx context.Context, contactGroupID string) (DeleteContactGroupOutput, error) {
+	return s.provider.DeleteContactGroup(ctx, DeleteContactGroupInput{
+		ContactGroupID: contactGroupID,
+	})
+}
+
 // GetContactGroupByDisplayName returns the email provider contact group by display name.
+// It walks every page of contact groups on the email provider.
 func (s *Service) GetContactGroupByDisplayName(ctx context.Context, displayName string) (GetContactGroupOutput, error) {
-	contactGroups, err := s.provider.ListContactGroups(ctx)
-	if err != nil {
-		return GetContactGroupOutput{}, err
-	}
-	for _, contactGroup := range contactGroups.ContactGroups {
+	for contactGroup, err := range s.provider.ListContactGroups(ctx) {
+		if err != nil {
+			return GetContactGroupOutput{}, err
+		}
 		if contactGroup.DisplayName == displayName {
 			return contactGroup, nil
 		}
@@ -125,6 +299,74 @@ func (s *Service) GetContactGroupByDisplayName(ctx context.Context, displayName
 	return GetContactGroupOutput{}, errors.NewNotFound(schema.GroupResource{Group: "resend", Resource: "audiences"}, displayName)
 }
 
+// ListContactGroups returns an iterator over every contact group on the email provider.
+func (s *Service) ListContactGroups(ctx context.Context) iter.Seq2[GetContactGroupOutput, error] {
+	return s.provider.ListContactGroups(ctx)
+}
+
+// ListContacts returns an iterator over every contact on the email provider.
+func (s *Service) ListContacts(ctx context.Context) iter.Seq2[GetContactOutput, error] {
+	return s.provider.ListContacts(ctx)
+}
+
+// ListContactGroupContacts returns an iterator over every contact of the contact group on the email provider.
+func (s *Service) ListContactGroupContacts(ctx context.Context, cg notificationmiloapiscomv1alpha1.ContactGroup) iter.Seq2[GetContactOutput, error] {
+	return s.provider.ListSegmentContacts(ctx, ListSegmentContactsInput{
+		ContactGroupID: cg.Status.ProviderID,
+	})
+}
+
+// CreateContactGroupTopic creates the topic recipients use to manage their subscription to the contact group on the
+// email provider hosted preferences page. Contacts are opted out by default, members are opted in explicitly.
+func (s *Service) CreateContactGroupTopic(ctx context.Context, cg notificationmiloapiscomv1alpha1.ContactGroup) (CreateTopicOutput, error) {
+	return s.provider.CreateTopic(ctx, CreateTopicInput{
+		DisplayName:         cg.Spec.DisplayName,
+		DefaultSubscription: TopicSubscriptionOptOut,
+	})
+}
+
+// UpdateContactGroupTopic updates the name of the contact group topic to the contact group display name.
+func (s *Service) UpdateContactGroupTopic(ctx context.Context, cg notificationmiloapiscomv1alpha1.ContactGroup, topicID string) (UpdateTopicOutput, error) {
+	return s.provider.UpdateTopic(ctx, UpdateTopicInput{
+		TopicID:     topicID,
+		DisplayName: cg.Spec.DisplayName,
+	})
+}
+
+// GetTopic returns the topic on the email provider.
+func (s *Service) GetTopic(ctx context.Context, topicID string) (GetTopicOutput, error) {
+	return s.provider.GetTopic(ctx, GetTopicInput{
+		TopicID: topicID,
+	})
+}
+
+// DeleteTopic deletes the topic on the email provider.
+func (s *Service) DeleteTopic(ctx context.Context, topicID string) (DeleteTopicOutput, error) {
+	return s.provider.DeleteTopic(ctx, DeleteTopicInput{
+		TopicID: topicID,
+	})
+}
+
+// SetContactTopicSubscription sets the subscription preference of the contact for the topic.
+// This is an idempotent operation.
+func (s *Service) SetContactTopicSubscription(ctx context.Context, contact notificationmiloapiscomv1alpha1.Contact, topicID string, subscription TopicSubscription) (UpdateContactTopicsOutput, error) {
+	return s.provider.UpdateContactTopics(ctx, UpdateContactTopicsInput{
+		ContactId: contact.Status.ProviderID,
+		Subscriptions: []ContactTopicSubscription{{
+			TopicID:      topicID,
+			Subscription: subscription,
+		}},
+	})
+}
+
+// ListContactTopicSubscriptions returns an iterator over the topic subscription preferences of the contact with the
+// given email provider id.
+func (s *Service) ListContactTopicSubscriptions(ctx context.Context, contactID string) iter.Seq2[ContactTopicSubscription, error] {
+	return s.provider.ListContactTopics(ctx, ListContactTopicsInput{
+		ContactId: contactID,
+	})
+}
+
 // DeleteContactGroupMembership deletes a contact group membership on the email provider.
 // This is an idempotent operation.
 func (s *Service) DeleteContactGroupMembershipIdempotent(
@@ -137,6 +379,15 @@ func (s *Service) DeleteContactGroupMembershipIdempotent(
 	})
 }
 
+// GetContactGroupMembership returns the contact group membership on the email provider.
+// It returns a NotFound error if the contact is not part of the contact group.
+func (s *Service) GetContactGroupMembership(ctx context.Context, contactGroup notificationmiloapiscomv1alpha1.ContactGroup, contact notificationmiloapiscomv1alpha1.Contact) (GetContactGroupMembershipOutput, error) {
+	return s.provider.GetContactGroupMembership(ctx, GetContactGroupMembershipInput{
+		ContactGroupId: contactGroup.Status.ProviderID,
+		ContactId:      contact.Status.ProviderID,
+	})
+}
+
 // CreateContactGroupMembershipIdempotent creates a contact group membership on the email provider.
 // This is an idempotent operation.
 func (s *Service) CreateContactGroupMembershipIdempotent(ctx context.Context, contactGroup notificationmiloapiscomv1alpha1.ContactGroup, contact notificationmiloapiscomv1alpha1.Contact) (CreateContactGroupMembershipOutput, error) {
@@ -147,13 +398,51 @@ func (s *Service) CreateContactGroupMembershipIdempotent(ctx context.Context, co
 }
 
 // CreateContact creates a contact on the email provider.
+// If the creation fails because a contact with the same email address already exists (e.g. created before a crash,
+// or imported manually), the existing contact is adopted and its names are updated in place.
 // This is an idempotent operation.
 func (s *Service) CreateContactIdempotent(ctx context.Context, contact notificationmiloapiscomv1alpha1.Contact) (CreateContactOutput, error) {
-	return s.provider.CreateContact(ctx, CreateContactInput{
+	created, createErr := s.provider.CreateContact(ctx, CreateContactInput{
 		Email:      contact.Spec.Email,
 		GivenName:  contact.Spec.GivenName,
 		FamilyName: contact.Spec.FamilyName,
 	})
+	if createErr == nil {
+		return created, nil
+	}
+	// Only a conflict means the contact exists, other errors must not adopt a contact of the same email address
+	if !errors.IsAlreadyExists(createErr) && !errors.IsConflict(createErr) {
+		return CreateContactOutput{}, createErr
+	}
+
+	existing, err := s.provider.GetContact(ctx, GetContactInput{
+		Email: contact.Spec.Email,
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return CreateContactOutput{}, createErr
+		}
+		return CreateContactOutput{}, fmt.Errorf("failed to look up existing contact after create error (%w): %w", createErr, err)
+	}
+
+	if existing.GivenName != contact.Spec.GivenName || existing.FamilyName != contact.Spec.FamilyName {
+		if _, err := s.provider.UpdateContact(ctx, UpdateContactInput{
+			ContactId:  existing.ContactId,
+			GivenName:  contact.Spec.GivenName,
+			FamilyName: contact.Spec.FamilyName,
+		}); err != nil {
+			return CreateContactOutput{}, fmt.Errorf("failed to update adopted contact: %w", err)
+		}
+	}
+
+	return CreateContactOutput{ContactId: existing.ContactId}, nil
+}
+
+// GetContact returns the contact on the email provider.
+func (s *Service) GetContact(ctx context.Context, contact notificationmiloapiscomv1alpha1.Contact) (GetContactOutput, error) {
+	return s.provider.GetContact(ctx, GetContactInput{
+		ContactId: contact.Status.ProviderID,
+	})
 }
 
 // DeleteContact deletes a contact on the email provider.
@@ -163,20 +452,66 @@ func (s *Service) DeleteContact(ctx context.Context, contact notificationmiloapi
 	})
 }
 
+// DeleteContactByID deletes a contact on the email provider by its email provider id.
+// It is used for contacts that are not owned by any Contact.
+func (s *Service) DeleteContactByID(ctx context.Context, contactID string) (DeleteContactOutput, error) {
+	return s.provider.DeleteContact(ctx, DeleteContactInput{
+		ContactId: contactID,
+	})
+}
+
+// UpdateContactIdempotentOutput is the output of the UpdateContactIdempotent function.
+type UpdateContactIdempotentOutput struct {
+	ContactId string
+	// Recreated is true when the contact was deleted and created again on the email provider.
+	// In that case the contact was removed from every contact group on the email provider side.
+	Recreated bool
+}
+
 // UpdateContactIdempotent updates a contact on the email provider.
-// It deletes the existing contact and creates a new one, as Resend does not support updating the email address.
+// Names are updated in place. If the email address changed, it deletes the existing contact and creates a new one,
+// as Resend does not support updating the email address.
 // This is an idempotent operation.
-func (s *Service) UpdateContactIdempotent(ctx context.Context, contact notificationmiloapiscomv1alpha1.Contact) (CreateContactOutput, error) {
-	deleted, err := s.DeleteContact(ctx, contact)
+func (s *Service) UpdateContactIdempotent(ctx context.Context, contact notificationmiloapiscomv1alpha1.Contact) (UpdateContactIdempotentOutput, error) {
+	providerContact, err := s.provider.GetContact(ctx, GetContactInput{
+		ContactId: contact.Status.ProviderID,
+	})
 	if err != nil {
 		if !errors.IsNotFound(err) {
-			return CreateContactOutput{}, fmt.Errorf("failed to delete contact during update: %w", err)
+			return UpdateContactIdempotentOutput{}, fmt.Errorf("failed to get contact during update: %w", err)
 		}
-		return s.CreateContactIdempotent(ctx, contact)
+		// Contact does not exist on the email provider anymore, create it again
+		created, err := s.CreateContactIdempotent(ctx, contact)
+		if err != nil {
+			return UpdateContactIdempotentOutput{}, err
+		}
+		return UpdateContactIdempotentOutput{ContactId: created.ContactId, Recreated: true}, nil
 	}
 
-	if !deleted.Deleted {
-		return CreateContactOutput{}, fmt.Errorf("failed to delete contact during update")
+	if strings.EqualFold(providerContact.Email, contact.Spec.Email) {
+		updated, err := s.provider.UpdateContact(ctx, UpdateContactInput{
+			ContactId:  contact.Status.ProviderID,
+			GivenName:  contact.Spec.GivenName,
+			FamilyName: contact.Spec.FamilyName,
+		})
+		if err != nil {
+			return UpdateContactIdempotentOutput{}, fmt.Errorf("failed to update contact: %w", err)
+		}
+		return UpdateContactIdempotentOutput{ContactId: updated.ContactId}, nil
+	}
+
+	// Email address changed. Delete the contact and create it again.
+	deleted, err := s.DeleteContact(ctx, contact)
+	if err != nil && !errors.IsNotFound(err) {
+		return UpdateContactIdempotentOutput{}, fmt.Errorf("failed to delete contact during update: %w", err)
+	}
+	if err == nil && !deleted.Deleted {
+		return UpdateContactIdempotentOutput{}, fmt.Errorf("failed to delete contact during update")
+	}
+
+	created, err := s.CreateContactIdempotent(ctx, contact)
+	if err != nil {
+		return UpdateContactIdempotentOutput{}, err
 	}
-	return s.CreateContactIdempotent(ctx, contact)
+	return UpdateContactIdempotentOutput{ContactId: created.ContactId, Recreated: true}, nil
 }
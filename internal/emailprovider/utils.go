@@ -2,13 +2,34 @@ package emailprovider
 
 import (
 	"fmt"
+	"regexp"
 
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 )
 
-// getDeterministicContactGroupName returns a deterministic contact group displayname for the contact group.
-// As the email provider does not support namespaces or custom identifiers, we need to use a deterministic name for the contact group
-// stored in the email provider.
+// GetDeterministicContactGroupDisplayName returns a deterministic contact group display name for the contact group.
+// As the email provider does not support namespaces or custom identifiers, the contact group UID is used to keep the
+// name unique, while the display name keeps it readable on the email provider dashboard.
 func GetDeterministicContactGroupDisplayName(contactGroup *notificationmiloapiscomv1alpha1.ContactGroup) string {
+	return fmt.Sprintf("%s-%s", contactGroup.Spec.DisplayName, contactGroup.UID)
+}
+
+// GetLegacyContactGroupDisplayName returns the display name used for contact groups before names were derived from the UID.
+// Two contact groups in the same namespace with the same display name and visibility share this name.
+func GetLegacyContactGroupDisplayName(contactGroup *notificationmiloapiscomv1alpha1.ContactGroup) string {
 	return fmt.Sprintf("%s-%s-%s", contactGroup.Spec.DisplayName, contactGroup.Spec.Visibility, contactGroup.Namespace)
 }
+
+var (
+	// deterministicContactGroupDisplayNameRegexp matches display names generated by GetDeterministicContactGroupDisplayName.
+	deterministicContactGroupDisplayNameRegexp = regexp.MustCompile(`^.*-[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	// legacyContactGroupDisplayNameRegexp matches display names generated by GetLegacyContactGroupDisplayName.
+	// The namespace is a DNS-1123 label, and the visibility one of the ContactGroup visibilities.
+	legacyContactGroupDisplayNameRegexp = regexp.MustCompile(`^.+-(public|private)-[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+)
+
+// IsDeterministicContactGroupDisplayName returns whether the display name was generated by GetDeterministicContactGroupDisplayName
+// or GetLegacyContactGroupDisplayName, i.e. whether the contact group on the email provider is managed by this operator.
+func IsDeterministicContactGroupDisplayName(displayName string) bool {
+	return deterministicContactGroupDisplayNameRegexp.MatchString(displayName) || legacyContactGroupDisplayNameRegexp.MatchString(displayName)
+}
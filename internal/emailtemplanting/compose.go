@@ -0,0 +1,237 @@
+package emailtemplating
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+const (
+	// PartialLabel marks the cluster-scoped EmailTemplates that are partials. Its value is the name other templates
+	// invoke the partial with, e.g. {{ template "footer" . }} for "footer". The HTML body of the partial is used by the
+	// HTML bodies, and its text body by the text bodies and subjects.
+	PartialLabel = "notification.miloapis.com/partial"
+
+	// LayoutAnnotation is the name of the partial an EmailTemplate is wrapped in. The layout renders the body of the
+	// EmailTemplate with {{ template "content" . }}. Layouts without a text body do not wrap the text body.
+	LayoutAnnotation = "notification.miloapis.com/layout"
+
+	// layoutContentTemplate is the name of the template holding the body wrapped in a layout.
+	layoutContentTemplate = "content"
+)
+
+// MissingPartialError is returned when a template invokes a template that it does not define, and that no partial
+// provides.
+type MissingPartialError struct {
+	Name string
+}
+
+func (e *MissingPartialError) Error() string {
+	return fmt.Sprintf("template %q is not defined and no EmailTemplate with label %s=%s exists", e.Name, PartialLabel, e.Name)
+}
+
+// partialSource returns the source of the partial used by the HTML or text templates.
+func partialSource(partial *notificationmiloapiscomv1alpha1.EmailTemplate, html bool) string {
+	if html {
+		return partial.Spec.HTMLBody
+	}
+	return partial.Spec.TextBody
+}
+
+// parseHTMLTemplate parses the HTML body of the template, wrapped in its layout, along with the partials it invokes.
+// The funcs are available to the body on top of the FuncMap.
+func parseHTMLTemplate(body string, template *notificationmiloapiscomv1alpha1.EmailTemplate, opts RenderOptions, funcs map[string]any) (*htmltemplate.Template, error) {
+	tmpl := htmltemplate.New("html").Funcs(FuncMap()).Funcs(funcs).Option(opts.missingKeyOption())
+	err := composeTemplate(body, template, opts, true,
+		func(name, text string) error {
+			t := tmpl
+			if name != tmpl.Name() {
+				t = tmpl.New(name)
+			}
+			_, err := t.Parse(text)
+			return err
+		},
+		func() map[string]*parse.Tree { return htmlTrees(tmpl) },
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// parseTextTemplate parses the text body or subject of the template, along with the partials it invokes. Only the
+// text body is wrapped in the layout of the template.
+func parseTextTemplate(textTemplate string, template *notificationmiloapiscomv1alpha1.EmailTemplate, wrapInLayout bool, opts RenderOptions) (*texttemplate.Template, error) {
+	tmpl := texttemplate.New("text").Funcs(FuncMap()).Option(opts.missingKeyOption())
+	if !wrapInLayout {
+		template = template.DeepCopy()
+		delete(template.Annotations, LayoutAnnotation)
+	}
+	err := composeTemplate(textTemplate, template, opts, false,
+		func(name, text string) error {
+			t := tmpl
+			if name != tmpl.Name() {
+				t = tmpl.New(name)
+			}
+			_, err := t.Parse(text)
+			return err
+		},
+		func() map[string]*parse.Tree { return textTrees(tmpl) },
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// composeTemplate parses the body of the template as the root template, or as the content of its layout, then the
+// partials invoked by the parsed templates until every invoked template is defined. Templates invoking each other in
+// a cycle are rejected, as executing them would never end.
+func composeTemplate(body string,
+	template *notificationmiloapiscomv1alpha1.EmailTemplate,
+	opts RenderOptions,
+	html bool,
+	parseTemplate func(name, text string) error,
+	trees func() map[string]*parse.Tree,
+) error {
+	root := "text"
+	if html {
+		root = "html"
+	}
+
+	layoutName := strings.TrimSpace(template.Annotations[LayoutAnnotation])
+	if layoutName != "" {
+		layout, ok := opts.Partials[layoutName]
+		if !ok {
+			return fmt.Errorf("failed to resolve layout: %w", &MissingPartialError{Name: layoutName})
+		}
+		if layoutSource := partialSource(layout, html); layoutSource != "" {
+			if err := parseTemplate(root, layoutSource); err != nil {
+				return fmt.Errorf("failed to parse layout %q: %w", layoutName, err)
+			}
+			if err := parseTemplate(layoutContentTemplate, body); err != nil {
+				return err
+			}
+			return resolvePartials(root, opts, html, parseTemplate, trees)
+		}
+	}
+
+	if err := parseTemplate(root, body); err != nil {
+		return err
+	}
+	return resolvePartials(root, opts, html, parseTemplate, trees)
+}
+
+// resolvePartials parses the partials invoked by the templates, then checks the templates do not invoke each other in
+// a cycle.
+func resolvePartials(root string,
+	opts RenderOptions,
+	html bool,
+	parseTemplate func(name, text string) error,
+	trees func() map[string]*parse.Tree,
+) error {
+	// Partials with an empty body define no template, they are only parsed once
+	resolved := map[string]bool{}
+	for {
+		defined := trees()
+		var missing []string
+		for _, tree := range defined {
+			for _, name := range invokedTemplates(tree) {
+				if _, ok := defined[name]; !ok && !resolved[name] && !slices.Contains(missing, name) {
+					missing = append(missing, name)
+				}
+			}
+		}
+		if len(missing) == 0 {
+			break
+		}
+		slices.Sort(missing)
+
+		for _, name := range missing {
+			partial, ok := opts.Partials[name]
+			if !ok {
+				return &MissingPartialError{Name: name}
+			}
+			if err := parseTemplate(name, partialSource(partial, html)); err != nil {
+				return fmt.Errorf("failed to parse partial %q: %w", name, err)
+			}
+			resolved[name] = true
+		}
+	}
+
+	if cycle := findTemplateCycle(trees(), root); cycle != nil {
+		return fmt.Errorf("templates invoke each other in a cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// invokedTemplates returns the names of the templates invoked by the tree with {{ template "name" }}.
+func invokedTemplates(tree *parse.Tree) []string {
+	if tree == nil || tree.Root == nil {
+		return nil
+	}
+	var names []string
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			names = append(names, n.Name)
+		}
+	}
+	walk(tree.Root)
+	return names
+}
+
+// findTemplateCycle returns the templates invoking each other in a cycle reachable from the root template, if any,
+// e.g. ["header", "logo", "header"].
+func findTemplateCycle(trees map[string]*parse.Tree, root string) []string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			return append(slices.Clone(path[slices.Index(path, name):]), name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, invoked := range invokedTemplates(trees[name]) {
+			if cycle := visit(invoked); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	return visit(root)
+}
@@ -0,0 +1,121 @@
+package emailtemplating
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func partial(name, htmlBody, textBody string) *notificationmiloapiscomv1alpha1.EmailTemplate {
+	return &notificationmiloapiscomv1alpha1.EmailTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{PartialLabel: name}},
+		Spec:       notificationmiloapiscomv1alpha1.EmailTemplateSpec{HTMLBody: htmlBody, TextBody: textBody},
+	}
+}
+
+func partialsOptions(partials ...*notificationmiloapiscomv1alpha1.EmailTemplate) RenderOptions {
+	opts := RenderOptions{Partials: map[string]*notificationmiloapiscomv1alpha1.EmailTemplate{}}
+	for _, p := range partials {
+		opts.Partials[p.Labels[PartialLabel]] = p
+	}
+	return opts
+}
+
+func TestRender_LayoutAndPartials(t *testing.T) {
+	opts := partialsOptions(
+		partial("brand",
+			`<html>{{ block "title" . }}Default{{ end }}<body>{{ template "content" . }}{{ template "footer" . }}</body></html>`,
+			`{{ template "content" . }}{{ template "footer" . }}`),
+		partial("footer", `<footer>{{ template "signature" . }}</footer>`, "\n-- {{ template \"signature\" . }}"),
+		partial("signature", `The {{ .Team }} team`, `The {{ .Team }} team`),
+	)
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{LayoutAnnotation: "brand"}},
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+			Subject:  `Hello {{ .Name }} from {{ template "signature" . }}`,
+			HTMLBody: `{{ define "title" }}<title>Welcome</title>{{ end }}<p>Hello {{ .Name }}</p>`,
+			TextBody: `Hello {{ .Name }}`,
+		},
+	}
+	variables := []notificationmiloapiscomv1alpha1.EmailVariable{{Name: "Name", Value: "Ada"}, {Name: "Team", Value: "Acme"}}
+
+	html, err := RenderHTMLBodyTemplate(variables, template, opts)
+	if err != nil {
+		t.Fatalf("unexpected HTML error: %v", err)
+	}
+	if want := `<html><title>Welcome</title><body><p>Hello Ada</p><footer>The Acme team</footer></body></html>`; html != want {
+		t.Errorf("unexpected HTML body: got %q want %q", html, want)
+	}
+
+	text, err := RenderTextBodyTemplate(variables, template, opts)
+	if err != nil {
+		t.Fatalf("unexpected text error: %v", err)
+	}
+	if want := "Hello Ada\n-- The Acme team"; text != want {
+		t.Errorf("unexpected text body: got %q want %q", text, want)
+	}
+
+	subject, err := RenderSubjectTemplate(variables, template, opts)
+	if err != nil {
+		t.Fatalf("unexpected subject error: %v", err)
+	}
+	if want := "Hello Ada from The Acme team"; subject != want {
+		t.Errorf("unexpected subject: got %q want %q", subject, want)
+	}
+
+	if err := CheckVariables(variables, template, opts); err != nil {
+		t.Errorf("unexpected variables error: %v", err)
+	}
+}
+
+func TestRender_LayoutWithoutTextBodyDoesNotWrapTextBody(t *testing.T) {
+	opts := partialsOptions(partial("brand", `<div>{{ template "content" . }}</div>`, ""))
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{LayoutAnnotation: "brand"}},
+		Spec:       notificationmiloapiscomv1alpha1.EmailTemplateSpec{TextBody: "Hello"},
+	}
+
+	text, err := RenderTextBodyTemplate(nil, template, opts)
+	if err != nil || text != "Hello" {
+		t.Fatalf("unexpected text body: %q, %v", text, err)
+	}
+}
+
+func TestRender_MissingPartial(t *testing.T) {
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{HTMLBody: `<p>{{ template "footer" . }}</p>`},
+	}
+
+	_, err := RenderHTMLBodyTemplate(nil, template, partialsOptions())
+
+	var missingErr *MissingPartialError
+	if !errors.As(err, &missingErr) || missingErr.Name != "footer" {
+		t.Fatalf("expected a MissingPartialError for footer, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `template "footer" is not defined and no EmailTemplate with label notification.miloapis.com/partial=footer exists`) {
+		t.Errorf("unexpected error message: %v", err)
+	}
+
+	template.Annotations = map[string]string{LayoutAnnotation: "brand"}
+	if _, err := RenderHTMLBodyTemplate(nil, template, partialsOptions()); !errors.As(err, &missingErr) || missingErr.Name != "brand" {
+		t.Fatalf("expected a MissingPartialError for the layout, got %v", err)
+	}
+}
+
+func TestRender_PartialCycle(t *testing.T) {
+	opts := partialsOptions(
+		partial("header", `{{ template "logo" . }}`, ""),
+		partial("logo", `{{ if .Logo }}{{ template "header" . }}{{ end }}`, ""),
+	)
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{HTMLBody: `{{ template "header" . }}`},
+	}
+
+	_, err := RenderHTMLBodyTemplate(nil, template, opts)
+	if err == nil || !strings.Contains(err.Error(), "templates invoke each other in a cycle: header -> logo -> header") {
+		t.Fatalf("expected a cycle error, got %v", err)
+	}
+}
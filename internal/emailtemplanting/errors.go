@@ -0,0 +1,49 @@
+package emailtemplating
+
+import (
+	"errors"
+	"fmt"
+)
+
+// InvalidVariableError is returned when the value of a variable cannot be decoded, e.g. a JSON variable that is not
+// valid JSON. Rendering the same template with the same variables always fails again.
+type InvalidVariableError struct {
+	Name string
+	Err  error
+}
+
+func (e *InvalidVariableError) Error() string {
+	return fmt.Sprintf("invalid JSON value of variable %q: %s", e.Name, e.Err.Error())
+}
+
+func (e *InvalidVariableError) Unwrap() error {
+	return e.Err
+}
+
+// ExecutionError is returned when a parsed template fails to execute with the variables of the email, e.g. a missing
+// nested key of a JSON variable in strict mode, or a function rejecting its argument, such as safeURL rejecting the
+// scheme of a URL. Templates execute deterministically, so rendering the same template with the same variables always
+// fails again.
+type ExecutionError struct {
+	// Body is the rendered part of the email, e.g. "HTML" or "text"
+	Body string
+	Err  error
+}
+
+func (e *ExecutionError) Error() string {
+	return fmt.Sprintf("failed to execute %s template: %s", e.Body, e.Err.Error())
+}
+
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// IsTerminalError returns whether the error, or an error it wraps, is caused by the variables of the email, e.g. a
+// *VariablesError, an *InvalidVariableError or an *ExecutionError, so rendering the same template with the same
+// variables fails again.
+func IsTerminalError(err error) bool {
+	var varsErr *VariablesError
+	var invalidErr *InvalidVariableError
+	var execErr *ExecutionError
+	return errors.As(err, &varsErr) || errors.As(err, &invalidErr) || errors.As(err, &execErr)
+}
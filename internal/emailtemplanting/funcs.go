@@ -0,0 +1,275 @@
+package emailtemplating
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// FuncMap returns the functions available to the subject, text and HTML templates. Every function is free of side
+// effects and returns the same output in the text and HTML engines, except for the HTML escaping of the output.
+//
+// Functions taking the formatted value as their last argument, so they can be used in pipelines, e.g.
+// {{ .Name | default "there" | title }}:
+//
+//   - default DEFAULT VALUE: VALUE, or DEFAULT if VALUE is empty
+//   - upper, lower, title, trim STRING
+//   - replace OLD NEW STRING, contains SUBSTR STRING, hasPrefix PREFIX STRING, hasSuffix SUFFIX STRING
+//   - truncate LENGTH STRING: STRING cut to LENGTH characters, ending with "…" if cut
+//   - pluralize SINGULAR PLURAL COUNT: SINGULAR if COUNT is 1, PLURAL otherwise
+//   - formatDate LAYOUT LOCALE DATE: DATE, a time or an RFC 3339 or YYYY-MM-DD string, formatted with the "short",
+//     "long" or "full" LOCALE layout, or with a Go time layout
+//   - formatNumber LOCALE NUMBER: NUMBER with the LOCALE digit grouping and decimal separator
+//   - urlEncode STRING: STRING escaped for a URL query
+//   - safeURL STRING: STRING as a trusted URL, which must be an http, https or mailto URL
+func FuncMap() map[string]any {
+	return map[string]any{
+		"default":      defaultValue,
+		"upper":        func(s any) string { return strings.ToUpper(toString(s)) },
+		"lower":        func(s any) string { return strings.ToLower(toString(s)) },
+		"title":        title,
+		"trim":         func(s any) string { return strings.TrimSpace(toString(s)) },
+		"replace":      func(old, new string, s any) string { return strings.ReplaceAll(toString(s), old, new) },
+		"contains":     func(substr string, s any) bool { return strings.Contains(toString(s), substr) },
+		"hasPrefix":    func(prefix string, s any) bool { return strings.HasPrefix(toString(s), prefix) },
+		"hasSuffix":    func(suffix string, s any) bool { return strings.HasSuffix(toString(s), suffix) },
+		"truncate":     truncate,
+		"pluralize":    pluralize,
+		"formatDate":   formatDate,
+		"formatNumber": formatNumber,
+		"urlEncode":    func(s any) string { return url.QueryEscape(toString(s)) },
+		"safeURL":      safeURL,
+	}
+}
+
+// toString returns the string representation of a template value.
+func toString(v any) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case fmt.Stringer:
+		return s.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// isEmpty returns whether the template value is nil, a zero number, or an empty string, slice or map.
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return value.IsNil()
+	default:
+		return value.IsZero()
+	}
+}
+
+func defaultValue(def, v any) any {
+	if isEmpty(v) {
+		return def
+	}
+	return v
+}
+
+func title(s any) string {
+	runes := []rune(toString(s))
+	for i, r := range runes {
+		if i == 0 || unicode.IsSpace(runes[i-1]) {
+			runes[i] = unicode.ToTitle(r)
+		}
+	}
+	return string(runes)
+}
+
+func truncate(length int, s any) string {
+	str := toString(s)
+	if length < 0 || utf8.RuneCountInString(str) <= length {
+		return str
+	}
+	if length == 0 {
+		return ""
+	}
+	return string([]rune(str)[:length-1]) + "…"
+}
+
+func pluralize(singular, plural string, count any) (string, error) {
+	n, err := toFloat(count)
+	if err != nil {
+		return "", fmt.Errorf("pluralize: %w", err)
+	}
+	if n == 1 {
+		return singular, nil
+	}
+	return plural, nil
+}
+
+// toFloat converts a number, or a string holding a number, to a float64.
+func toFloat(v any) (float64, error) {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	case reflect.String:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value.String()), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", value.String())
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("%v is not a number", v)
+	}
+}
+
+func formatNumber(locale string, v any) (string, error) {
+	n, err := toFloat(v)
+	if err != nil {
+		return "", fmt.Errorf("formatNumber: %w", err)
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		tag = language.English
+	}
+	return message.NewPrinter(tag).Sprint(number.Decimal(n)), nil
+}
+
+func safeURL(s any) (htmltemplate.URL, error) {
+	str := strings.TrimSpace(toString(s))
+	u, err := url.Parse(str)
+	if err != nil {
+		return "", fmt.Errorf("safeURL: invalid URL %q: %w", str, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return htmltemplate.URL(str), nil
+	default:
+		return "", fmt.Errorf("safeURL: unsupported scheme in URL %q, must be http, https or mailto", str)
+	}
+}
+
+// dateLocale holds the names and layouts used to format dates in a language. Layouts replace {d} with the day,
+// {m} with the month number, {month} with the month name, {weekday} with the weekday name and {yyyy} with the year.
+type dateLocale struct {
+	months   [12]string
+	weekdays [7]string // Starting on Sunday
+	short    string
+	long     string
+	full     string
+}
+
+var dateLocales = map[string]dateLocale{
+	"en": {
+		months:   [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		short:    "{m}/{d}/{yyyy}",
+		long:     "{month} {d}, {yyyy}",
+		full:     "{weekday}, {month} {d}, {yyyy}",
+	},
+	"es": {
+		months:   [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		weekdays: [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		short:    "{d}/{m}/{yyyy}",
+		long:     "{d} de {month} de {yyyy}",
+		full:     "{weekday}, {d} de {month} de {yyyy}",
+	},
+	"pt": {
+		months:   [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		weekdays: [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		short:    "{d}/{m}/{yyyy}",
+		long:     "{d} de {month} de {yyyy}",
+		full:     "{weekday}, {d} de {month} de {yyyy}",
+	},
+	"fr": {
+		months:   [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		weekdays: [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		short:    "{d}/{m}/{yyyy}",
+		long:     "{d} {month} {yyyy}",
+		full:     "{weekday} {d} {month} {yyyy}",
+	},
+	"de": {
+		months:   [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		weekdays: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		short:    "{d}.{m}.{yyyy}",
+		long:     "{d}. {month} {yyyy}",
+		full:     "{weekday}, {d}. {month} {yyyy}",
+	},
+	"it": {
+		months:   [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		weekdays: [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		short:    "{d}/{m}/{yyyy}",
+		long:     "{d} {month} {yyyy}",
+		full:     "{weekday} {d} {month} {yyyy}",
+	},
+}
+
+// getDateLocale returns the date locale of the language of the locale, e.g. "pt" for "pt-BR", defaulting to English.
+func getDateLocale(locale string) dateLocale {
+	base, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(locale, "_", "-")), "-")
+	if l, ok := dateLocales[base]; ok {
+		return l
+	}
+	return dateLocales["en"]
+}
+
+func formatDate(layout, locale string, v any) (string, error) {
+	var t time.Time
+	switch date := v.(type) {
+	case time.Time:
+		t = date
+	case *time.Time:
+		if date == nil {
+			return "", fmt.Errorf("formatDate: nil date")
+		}
+		t = *date
+	default:
+		str := strings.TrimSpace(toString(v))
+		var err error
+		if t, err = time.Parse(time.RFC3339, str); err != nil {
+			if t, err = time.Parse(time.DateOnly, str); err != nil {
+				return "", fmt.Errorf("formatDate: %q is not an RFC 3339 or YYYY-MM-DD date", str)
+			}
+		}
+	}
+
+	l := getDateLocale(locale)
+	var pattern string
+	switch layout {
+	case "short":
+		pattern = l.short
+	case "long":
+		pattern = l.long
+	case "full":
+		pattern = l.full
+	default:
+		return t.Format(layout), nil
+	}
+
+	return strings.NewReplacer(
+		"{d}", strconv.Itoa(t.Day()),
+		"{m}", strconv.Itoa(int(t.Month())),
+		"{month}", l.months[t.Month()-1],
+		"{weekday}", l.weekdays[t.Weekday()],
+		"{yyyy}", strconv.Itoa(t.Year()),
+	).Replace(pattern), nil
+}
@@ -0,0 +1,117 @@
+package emailtemplating
+
+import (
+	"strings"
+	"testing"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+func renderBoth(t *testing.T, tmpl string, vars map[string]string) (string, string, error, error) {
+	t.Helper()
+	variables := make([]notificationmiloapiscomv1alpha1.EmailVariable, 0, len(vars))
+	for name, value := range vars {
+		variables = append(variables, notificationmiloapiscomv1alpha1.EmailVariable{Name: name, Value: value})
+	}
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{HTMLBody: tmpl, TextBody: tmpl},
+	}
+	text, textErr := RenderTextBodyTemplate(variables, template, RenderOptions{})
+	html, htmlErr := RenderHTMLBodyTemplate(variables, template, RenderOptions{})
+	return text, html, textErr, htmlErr
+}
+
+func TestFuncMap_SameOutputInTextAndHTML(t *testing.T) {
+	vars := map[string]string{
+		"Name":    "ada lovelace",
+		"Empty":   "",
+		"Date":    "2025-03-09T10:00:00Z",
+		"Day":     "2025-12-25",
+		"Total":   "1234567.5",
+		"Count":   "1",
+		"Items":   "3",
+		"Long":    "The quick brown fox jumps",
+		"Query":   "a/b&c",
+		"Website": "https://example.com/path",
+	}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{`{{ .Empty | default "there" }}`, "there"},
+		{`{{ .Name | default "there" }}`, "ada lovelace"},
+		{`{{ .Missing | default "there" }}`, "there"},
+		{`{{ .Name | upper }}`, "ADA LOVELACE"},
+		{`{{ .Name | title }}`, "Ada Lovelace"},
+		{`{{ "  Ada  " | trim | lower }}`, "ada"},
+		{`{{ .Name | replace "ada" "grace" }}`, "grace lovelace"},
+		{`{{ if .Name | contains "love" }}yes{{ end }}`, "yes"},
+		{`{{ if .Name | hasPrefix "ada" }}yes{{ end }}`, "yes"},
+		{`{{ if .Name | hasSuffix "ada" }}yes{{ else }}no{{ end }}`, "no"},
+		{`{{ .Long | truncate 9 }}`, "The quic…"},
+		{`{{ .Long | truncate 100 }}`, "The quick brown fox jumps"},
+		{`{{ .Count | pluralize "item" "items" }}`, "item"},
+		{`{{ .Items | pluralize "item" "items" }}`, "items"},
+		{`{{ .Date | formatDate "long" "en" }}`, "March 9, 2025"},
+		{`{{ .Date | formatDate "full" "en-US" }}`, "Sunday, March 9, 2025"},
+		{`{{ .Date | formatDate "long" "pt-BR" }}`, "9 de março de 2025"},
+		{`{{ .Day | formatDate "full" "de" }}`, "Donnerstag, 25. Dezember 2025"},
+		{`{{ .Day | formatDate "short" "fr" }}`, "25/12/2025"},
+		{`{{ .Day | formatDate "short" "xx" }}`, "12/25/2025"},
+		{`{{ .Day | formatDate "2006" "" }}`, "2025"},
+		{`{{ .Total | formatNumber "en" }}`, "1,234,567.5"},
+		{`{{ .Total | formatNumber "de" }}`, "1.234.567,5"},
+		{`{{ .Query | urlEncode }}`, "a%2Fb%26c"},
+		{`{{ .Website | safeURL }}`, "https://example.com/path"},
+	}
+
+	for _, tt := range tests {
+		text, html, textErr, htmlErr := renderBoth(t, tt.template, vars)
+		if textErr != nil || htmlErr != nil {
+			t.Fatalf("%s: unexpected errors: text %v, HTML %v", tt.template, textErr, htmlErr)
+		}
+		if text != tt.want {
+			t.Errorf("%s: unexpected text output: got %q want %q", tt.template, text, tt.want)
+		}
+		if html != text {
+			t.Errorf("%s: HTML output %q differs from text output %q", tt.template, html, text)
+		}
+	}
+}
+
+func TestFuncMap_ErrorsInTextAndHTML(t *testing.T) {
+	vars := map[string]string{
+		"Website": "javascript:alert(1)",
+		"Total":   "many",
+		"Date":    "yesterday",
+	}
+
+	for _, tmpl := range []string{
+		`{{ .Website | safeURL }}`,
+		`{{ .Total | formatNumber "en" }}`,
+		`{{ .Total | pluralize "item" "items" }}`,
+		`{{ .Date | formatDate "long" "en" }}`,
+	} {
+		_, _, textErr, htmlErr := renderBoth(t, tmpl, vars)
+		if textErr == nil || htmlErr == nil {
+			t.Fatalf("%s: expected errors in both engines: text %v, HTML %v", tmpl, textErr, htmlErr)
+		}
+		if !IsTerminalError(textErr) || !IsTerminalError(htmlErr) {
+			t.Fatalf("%s: expected terminal errors: text %v, HTML %v", tmpl, textErr, htmlErr)
+		}
+	}
+}
+
+func TestFuncMap_SafeURLInHTMLAttribute(t *testing.T) {
+	vars := []notificationmiloapiscomv1alpha1.EmailVariable{{Name: "Website", Value: "https://example.com/?a=1&b=2"}}
+	html, err := RenderHTMLBodyTemplate(vars, &notificationmiloapiscomv1alpha1.EmailTemplate{
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{HTMLBody: `<a href="{{ .Website | safeURL }}">link</a>`},
+	}, RenderOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, `href="https://example.com/?a=1&amp;b=2"`) {
+		t.Fatalf("unexpected HTML: %s", html)
+	}
+}
@@ -1,9 +1,21 @@
 package emailtemplating
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 )
 
+// JSONVariablesAnnotation lists the comma separated names of the variables of an EmailTemplate whose values are JSON,
+// e.g. "Items,Totals". Their values are decoded so templates can use them as lists, objects, numbers and booleans,
+// e.g. {{ range .Items }}{{ .Name }}{{ end }} or {{ if gt .Totals.Count 1 }}.
+const JSONVariablesAnnotation = "notification.miloapis.com/json-variables"
+
 // convertVariables transforms a slice of EmailVariable into a map[string]string
 // so templates can reference variables directly via {{ .MyVar }} rather than
 // iterating over a slice. If duplicate names exist, the last one wins.
@@ -14,3 +26,83 @@ func convertVariables(vars []notificationmiloapiscomv1alpha1.EmailVariable) map[
 	}
 	return m
 }
+
+// templateData returns the data the templates are executed with: the variables by name, with the values of the JSON
+// variables of the template decoded. It returns an *InvalidVariableError if a JSON variable is not valid JSON.
+func templateData(vars []notificationmiloapiscomv1alpha1.EmailVariable, template *notificationmiloapiscomv1alpha1.EmailTemplate) (map[string]any, error) {
+	jsonVariables := jsonVariableNames(template)
+
+	data := make(map[string]any, len(vars))
+	for name, value := range convertVariables(vars) {
+		if !jsonVariables[name] {
+			data[name] = value
+			continue
+		}
+		decoded, err := decodeJSON(value)
+		if err != nil {
+			return nil, &InvalidVariableError{Name: name, Err: err}
+		}
+		data[name] = decoded
+	}
+	return data, nil
+}
+
+// jsonVariableNames returns the names of the JSON variables of the template, listed by its JSONVariablesAnnotation.
+func jsonVariableNames(template *notificationmiloapiscomv1alpha1.EmailTemplate) map[string]bool {
+	names := map[string]bool{}
+	for _, name := range strings.Split(template.Annotations[JSONVariablesAnnotation], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// decodeJSON decodes a single JSON value. Integers are decoded as int64, and other numbers as float64, so they can be
+// compared with the integer and float constants of the templates.
+func decodeJSON(value string) (any, error) {
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("%s, at offset %d", syntaxErr.Error(), syntaxErr.Offset)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("empty value")
+		}
+		return nil, err
+	}
+	offset := decoder.InputOffset()
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unexpected data after the JSON value, at offset %d", offset)
+	}
+
+	return convertNumbers(decoded), nil
+}
+
+// convertNumbers replaces the json.Number of the decoded value by int64 or float64.
+func convertNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil && !bytes.ContainsAny([]byte(v), ".eE") {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, child := range v {
+			v[key] = convertNumbers(child)
+		}
+		return v
+	case []any:
+		for i, child := range v {
+			v[i] = convertNumbers(child)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,90 @@
+package emailtemplating
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func jsonVariablesTemplate(body string) *notificationmiloapiscomv1alpha1.EmailTemplate {
+	return &notificationmiloapiscomv1alpha1.EmailTemplate{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{JSONVariablesAnnotation: "Items, Totals"}},
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+			Subject:  body,
+			TextBody: body,
+			HTMLBody: body,
+		},
+	}
+}
+
+func TestRender_JSONVariables(t *testing.T) {
+	template := jsonVariablesTemplate(
+		`{{ range .Items }}{{ .Name }}: {{ .Price | formatNumber "en" }};{{ end }}` +
+			`{{ if gt .Totals.Count 1 }} {{ .Totals.Count }} items{{ end }}` +
+			`{{ if .Totals.Paid }} paid{{ end }} {{ .Count }}`)
+	variables := []notificationmiloapiscomv1alpha1.EmailVariable{
+		{Name: "Items", Value: `[{"Name": "Book", "Price": 1200.5}, {"Name": "Pen", "Price": 2}]`},
+		{Name: "Totals", Value: `{"Count": 2, "Paid": true}`},
+		// Not a JSON variable, so rendered as is
+		{Name: "Count", Value: `[2]`},
+	}
+
+	want := "Book: 1,200.5;Pen: 2; 2 items paid [2]"
+	for name, render := range map[string]func([]notificationmiloapiscomv1alpha1.EmailVariable, *notificationmiloapiscomv1alpha1.EmailTemplate, RenderOptions) (string, error){
+		"subject": RenderSubjectTemplate,
+		"text":    RenderTextBodyTemplate,
+		"HTML":    RenderHTMLBodyTemplate,
+	} {
+		got, err := render(variables, template, RenderOptions{})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("%s: got %q want %q", name, got, want)
+		}
+	}
+}
+
+func TestRender_InvalidJSONVariable(t *testing.T) {
+	template := jsonVariablesTemplate(`{{ range .Items }}{{ . }}{{ end }}`)
+
+	for value, message := range map[string]string{
+		`[1, 2`:   `invalid JSON value of variable "Items": unexpected EOF`,
+		`[1, 2,]`: `invalid JSON value of variable "Items": invalid character ']' looking for beginning of value, at offset 7`,
+		`[1] [2]`: `invalid JSON value of variable "Items": unexpected data after the JSON value, at offset 3`,
+		``:        `invalid JSON value of variable "Items": empty value`,
+	} {
+		_, err := RenderTextBodyTemplate([]notificationmiloapiscomv1alpha1.EmailVariable{{Name: "Items", Value: value}}, template, RenderOptions{})
+
+		var invalidErr *InvalidVariableError
+		if !errors.As(err, &invalidErr) || !IsTerminalError(err) {
+			t.Fatalf("%q: expected an InvalidVariableError, got %v", value, err)
+		}
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("%q: unexpected error message: got %q want %q", value, err.Error(), message)
+		}
+	}
+}
+
+func TestRender_MissingNestedJSONKeyIsTerminal(t *testing.T) {
+	template := jsonVariablesTemplate(`{{ .Totals.Count }} {{ .Totals.Missing }} {{ .Items }}`)
+	variables := []notificationmiloapiscomv1alpha1.EmailVariable{
+		{Name: "Items", Value: `[]`},
+		{Name: "Totals", Value: `{"Count": 2}`},
+	}
+
+	for name, render := range map[string]func([]notificationmiloapiscomv1alpha1.EmailVariable, *notificationmiloapiscomv1alpha1.EmailTemplate, RenderOptions) (string, error){
+		"subject": RenderSubjectTemplate,
+		"text":    RenderTextBodyTemplate,
+		"HTML":    RenderHTMLBodyTemplate,
+	} {
+		_, err := render(variables, template, RenderOptions{Strict: true})
+		var execErr *ExecutionError
+		if !errors.As(err, &execErr) || !IsTerminalError(err) {
+			t.Fatalf("%s: expected a terminal ExecutionError, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package emailtemplating
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLToText returns a readable plain text alternative of the HTML email. Links are kept as numbered footnotes,
+// headings are underlined, list items are bulleted or numbered, and the head, styles and scripts are dropped.
+func HTMLToText(htmlBody string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(htmlBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	w := &textWriter{footnotes: map[string]int{}}
+	w.walk(doc)
+
+	text := strings.TrimSpace(w.buf.String())
+	if len(w.links) > 0 {
+		var footnotes strings.Builder
+		for i, link := range w.links {
+			fmt.Fprintf(&footnotes, "\n[%d] %s", i+1, link)
+		}
+		text += "\n" + footnotes.String()
+	}
+	return text, nil
+}
+
+// textWriter writes the text of the HTML nodes, collapsing whitespace as browsers do.
+type textWriter struct {
+	buf strings.Builder
+	// newlines are written before the next text, 1 for a line break and 2 for a paragraph break
+	newlines int
+	// lineStart is whether nothing was written on the current line yet
+	lineStart bool
+	// space is whether a space must be written before the next text
+	space bool
+	// afterMarker is whether a list item marker was just written, so no space is needed
+	afterMarker bool
+	// indent prefixes every line, e.g. for nested lists and quotes
+	indent string
+	pre    int
+
+	lists     []listState
+	links     []string
+	footnotes map[string]int
+}
+
+type listState struct {
+	ordered bool
+	items   int
+}
+
+// blockElements are separated from the surrounding text by a paragraph break.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Header: true, atom.Footer: true,
+	atom.Main: true, atom.Aside: true, atom.Nav: true, atom.Table: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Ul: true, atom.Ol: true, atom.Dl: true, atom.Form: true, atom.Fieldset: true, atom.Address: true,
+	atom.Figure: true, atom.Center: true,
+}
+
+// lineElements are separated from the surrounding text by a line break.
+var lineElements = map[atom.Atom]bool{
+	atom.Tr: true, atom.Dt: true, atom.Dd: true, atom.Figcaption: true,
+}
+
+// skippedElements have no text content.
+var skippedElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Style: true, atom.Script: true, atom.Noscript: true, atom.Template: true, atom.Title: true,
+	atom.Svg: true, atom.Iframe: true, atom.Object: true,
+}
+
+func (w *textWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.writeText(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		w.walkChildren(n)
+		return
+	}
+
+	if skippedElements[n.DataAtom] {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		w.lineBreak()
+	case atom.Hr:
+		w.breakLine(2)
+		w.writeRaw("--------")
+		w.breakLine(2)
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			w.writeText(alt)
+		}
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		w.writeHeading(n)
+	case atom.Ul, atom.Ol:
+		// Nested lists are not separated from their parent item by an empty line
+		newlines := 2
+		if len(w.lists) > 0 {
+			newlines = 1
+		}
+		w.breakLine(newlines)
+		w.lists = append(w.lists, listState{ordered: n.DataAtom == atom.Ol})
+		w.walkChildren(n)
+		w.lists = w.lists[:len(w.lists)-1]
+		w.breakLine(newlines)
+	case atom.Li:
+		w.writeListItem(n)
+	case atom.A:
+		w.writeLink(n)
+	case atom.Blockquote:
+		w.breakLine(2)
+		indent := w.indent
+		w.indent += "> "
+		w.walkChildren(n)
+		w.indent = indent
+		w.breakLine(2)
+	case atom.Pre:
+		w.breakLine(2)
+		w.pre++
+		w.walkChildren(n)
+		w.pre--
+		w.breakLine(2)
+	case atom.Td, atom.Th:
+		if !w.lineStart {
+			w.space = true
+		}
+		w.walkChildren(n)
+		w.space = true
+	default:
+		switch {
+		case blockElements[n.DataAtom]:
+			w.breakLine(2)
+			w.walkChildren(n)
+			w.breakLine(2)
+		case lineElements[n.DataAtom]:
+			w.breakLine(1)
+			w.walkChildren(n)
+			w.breakLine(1)
+		default:
+			w.walkChildren(n)
+		}
+	}
+}
+
+func (w *textWriter) walkChildren(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		w.walk(child)
+	}
+}
+
+// writeHeading writes the heading on its own paragraph, underlined with "=" for h1 and "-" for h2, or in upper case
+// for the other levels.
+func (w *textWriter) writeHeading(n *html.Node) {
+	heading := &textWriter{footnotes: w.footnotes, links: w.links}
+	heading.walkChildren(n)
+	w.links = heading.links
+	text := strings.Join(strings.Fields(heading.buf.String()), " ")
+	if text == "" {
+		return
+	}
+
+	w.breakLine(2)
+	switch n.DataAtom {
+	case atom.H1:
+		w.writeRaw(text)
+		w.breakLine(1)
+		w.writeRaw(strings.Repeat("=", len([]rune(text))))
+	case atom.H2:
+		w.writeRaw(text)
+		w.breakLine(1)
+		w.writeRaw(strings.Repeat("-", len([]rune(text))))
+	default:
+		w.writeRaw(strings.ToUpper(text))
+	}
+	w.breakLine(2)
+}
+
+// writeListItem writes the list item on its own line, prefixed with "- " or its number. The following lines of the
+// item, including nested lists, are indented under its text.
+func (w *textWriter) writeListItem(n *html.Node) {
+	w.breakLine(1)
+	marker := "- "
+	if len(w.lists) > 0 {
+		list := &w.lists[len(w.lists)-1]
+		list.items++
+		if list.ordered {
+			marker = strconv.Itoa(list.items) + ". "
+		}
+	}
+
+	indent := w.indent
+	w.writeRaw(marker)
+	w.afterMarker = true
+	w.indent += strings.Repeat(" ", len(marker))
+	w.walkChildren(n)
+	w.indent = indent
+	w.breakLine(1)
+}
+
+// writeLink writes the text of the link followed by the number of its footnote, unless the text is the URL itself.
+func (w *textWriter) writeLink(n *html.Node) {
+	href := strings.TrimSpace(attr(n, "href"))
+	before := w.buf.Len()
+	w.walkChildren(n)
+	text := strings.TrimSpace(w.buf.String()[before:])
+
+	if href == "" || strings.HasPrefix(href, "#") || text == href || "mailto:"+text == href {
+		return
+	}
+	if text == "" {
+		w.writeText(href)
+		return
+	}
+	number, ok := w.footnotes[href]
+	if !ok {
+		w.links = append(w.links, href)
+		number = len(w.links)
+		w.footnotes[href] = number
+	}
+	w.writeRaw(fmt.Sprintf(" [%d]", number))
+}
+
+// writeText writes the text, collapsing its whitespace unless in a pre element.
+func (w *textWriter) writeText(text string) {
+	if w.pre > 0 {
+		for i, line := range strings.Split(text, "\n") {
+			if i > 0 {
+				w.lineBreak()
+			}
+			if line != "" {
+				w.writeRaw(line)
+			}
+		}
+		return
+	}
+
+	for i, word := range strings.FieldsFunc(text, unicode.IsSpace) {
+		if i > 0 || (text != "" && unicode.IsSpace([]rune(text)[0])) {
+			w.space = true
+		}
+		w.writeRaw(word)
+	}
+	if text != "" && unicode.IsSpace([]rune(text)[len([]rune(text))-1]) {
+		w.space = true
+	}
+}
+
+// writeRaw writes the text as is, after the pending line breaks or space.
+func (w *textWriter) writeRaw(text string) {
+	if w.buf.Len() > 0 && w.newlines > 0 {
+		w.buf.WriteString(strings.Repeat("\n", w.newlines))
+		w.lineStart = true
+	}
+	if w.buf.Len() == 0 {
+		w.lineStart = true
+	}
+	w.newlines = 0
+
+	if w.lineStart {
+		w.buf.WriteString(w.indent)
+	} else if w.space && !w.afterMarker {
+		w.buf.WriteString(" ")
+	}
+	w.buf.WriteString(text)
+	w.lineStart = false
+	w.space = false
+	w.afterMarker = false
+}
+
+// lineBreak ends the current line, and adds an empty line if the line is already ended.
+func (w *textWriter) lineBreak() {
+	w.newlines = min(w.newlines+1, 2)
+	w.space = false
+}
+
+// breakLine ends the current line, with an empty line after it if newlines is 2.
+func (w *textWriter) breakLine(newlines int) {
+	w.newlines = max(w.newlines, newlines)
+	w.space = false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package emailtemplating
+
+import (
+	"testing"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+func TestHTMLToText(t *testing.T) {
+	htmlBody := `<html>
+<head><title>Welcome</title><style>p { color: red; }</style></head>
+<body>
+  <h1>Welcome, Ada!</h1>
+  <p>Thanks for   signing up.<br>Your <b>account</b> is <a href="https://example.com/account">ready</a>.</p>
+  <h3>Next steps</h3>
+  <ul>
+    <li>Verify your <a href="https://example.com/verify">email</a></li>
+    <li>Invite your team
+      <ol><li>Open settings</li><li>Add members</li></ol>
+    </li>
+  </ul>
+  <script>alert("hidden")</script>
+  <p>Visit <a href="https://example.com">https://example.com</a> or <a href="https://example.com/account">your account</a>.</p>
+  <table><tr><td>Book</td><td>10</td></tr><tr><td>Pen</td><td>2</td></tr></table>
+  <blockquote>Quoted</blockquote>
+  <hr>
+  <p><img src="logo.png" alt="Acme"></p>
+</body>
+</html>`
+
+	want := `Welcome, Ada!
+=============
+
+Thanks for signing up.
+Your account is ready [1].
+
+NEXT STEPS
+
+- Verify your email [2]
+- Invite your team
+  1. Open settings
+  2. Add members
+
+Visit https://example.com or your account [1].
+
+Book 10
+Pen 2
+
+> Quoted
+
+--------
+
+Acme
+
+[1] https://example.com/account
+[2] https://example.com/verify`
+
+	got, err := HTMLToText(htmlBody)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("unexpected text:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderTextBodyTemplate_DerivedFromHTMLBody(t *testing.T) {
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+			HTMLBody: `<h2>Hello {{ .Name }}</h2><p><a href="{{ .URL }}">Sign in</a></p>`,
+		},
+	}
+	variables := []notificationmiloapiscomv1alpha1.EmailVariable{{Name: "Name", Value: "Ada"}, {Name: "URL", Value: "https://example.com/?a=1&b=2"}}
+
+	got, err := RenderTextBodyTemplate(variables, template, RenderOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Hello Ada\n---------\n\nSign in [1]\n\n[1] https://example.com/?a=1&b=2"; got != want {
+		t.Errorf("unexpected text: got %q want %q", got, want)
+	}
+
+	template.Spec.TextBody = "Hello {{ .Name }}"
+	if got, err := RenderTextBodyTemplate(variables, template, RenderOptions{}); err != nil || got != "Hello Ada" {
+		t.Errorf("expected the text body template to be used: %q, %v", got, err)
+	}
+}
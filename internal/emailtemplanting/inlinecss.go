@@ -0,0 +1,379 @@
+package emailtemplating
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// InlineCSSAnnotation enables ("true") the inlining of the CSS rules of the <style> blocks of the rendered HTML body
+// into the style attributes of the elements, as many email clients drop <style> blocks.
+const InlineCSSAnnotation = "notification.miloapis.com/inline-css"
+
+// IsInlineCSS returns whether the CSS of the rendered HTML body of the template is inlined.
+func IsInlineCSS(template *notificationmiloapiscomv1alpha1.EmailTemplate) bool {
+	inline, _ := strconv.ParseBool(strings.TrimSpace(template.Annotations[InlineCSSAnnotation]))
+	return inline
+}
+
+// InlineCSS moves the CSS rules of the <style> blocks of the HTML document into the style attributes of the elements
+// they match. Declarations are applied by specificity, then order, and !important declarations and the existing
+// style attributes take precedence as in browsers.
+//
+// Rules that cannot be inlined stay in their <style> block: at-rules such as media queries and font faces, and rules
+// whose selectors are not type, class, id or universal selectors combined with descendant or child combinators, e.g.
+// pseudo-classes. <style> blocks left empty are removed.
+func InlineCSS(htmlBody string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(htmlBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	var styles []*html.Node
+	var elements []*html.Node
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if n.DataAtom == atom.Style {
+				styles = append(styles, n)
+				return
+			}
+			elements = append(elements, n)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(doc)
+
+	var rules []cssRule
+	for _, style := range styles {
+		var css strings.Builder
+		for child := style.FirstChild; child != nil; child = child.NextSibling {
+			css.WriteString(child.Data)
+		}
+		inlined, kept := parseCSS(css.String(), len(rules))
+		rules = append(rules, inlined...)
+
+		for style.FirstChild != nil {
+			style.RemoveChild(style.FirstChild)
+		}
+		if kept == "" {
+			style.Parent.RemoveChild(style)
+			continue
+		}
+		style.AppendChild(&html.Node{Type: html.TextNode, Data: kept})
+	}
+
+	for _, element := range elements {
+		var declarations []cssDeclaration
+		for _, rule := range rules {
+			if rule.selector.matches(element) {
+				for _, declaration := range rule.declarations {
+					declaration.specificity = rule.selector.specificity
+					declaration.order = rule.order
+					declarations = append(declarations, declaration)
+				}
+			}
+		}
+		if len(declarations) == 0 {
+			continue
+		}
+
+		// The style attribute is more specific than any selector
+		for i, declaration := range parseDeclarations(attr(element, "style")) {
+			declaration.specificity = [3]int{1 << 16}
+			declaration.order = i
+			declarations = append(declarations, declaration)
+		}
+		setAttr(element, "style", formatDeclarations(declarations))
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return "", fmt.Errorf("failed to render HTML: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// cssRule is a style rule with a single selector.
+type cssRule struct {
+	selector     cssSelector
+	declarations []cssDeclaration
+	order        int
+}
+
+type cssDeclaration struct {
+	property    string
+	value       string
+	important   bool
+	specificity [3]int
+	order       int
+}
+
+// parseCSS returns the rules of the style sheet that can be inlined, numbered from order, and the CSS of the rules
+// that cannot be inlined.
+func parseCSS(css string, order int) ([]cssRule, string) {
+	css = cssCommentRegexp.ReplaceAllString(css, "")
+
+	var rules []cssRule
+	var kept []string
+	for css = strings.TrimSpace(css); css != ""; css = strings.TrimSpace(css) {
+		open := strings.IndexByte(css, '{')
+		if strings.HasPrefix(css, "@") {
+			// Statement at-rules, e.g. @import url(...); or block at-rules, e.g. @media (...) { ... }
+			end := len(css)
+			if semicolon := strings.IndexByte(css, ';'); semicolon >= 0 && (open < 0 || semicolon < open) {
+				end = semicolon + 1
+			} else if open >= 0 {
+				end = matchingBrace(css, open)
+			}
+			kept = append(kept, strings.TrimSpace(css[:end]))
+			css = css[end:]
+			continue
+		}
+		if open < 0 {
+			break
+		}
+		prelude := strings.TrimSpace(css[:open])
+
+		end := matchingBrace(css, open)
+		body := strings.TrimSuffix(css[open+1:end], "}")
+		css = css[end:]
+		declarations := parseDeclarations(body)
+
+		var unsupported []string
+		for _, raw := range strings.Split(prelude, ",") {
+			selector, ok := parseSelector(strings.TrimSpace(raw))
+			if !ok {
+				unsupported = append(unsupported, strings.TrimSpace(raw))
+				continue
+			}
+			rules = append(rules, cssRule{selector: selector, declarations: declarations, order: order})
+			order++
+		}
+		if len(unsupported) > 0 {
+			kept = append(kept, strings.Join(unsupported, ", ")+" { "+strings.TrimSpace(body)+" }")
+		}
+	}
+
+	return rules, strings.Join(kept, "\n")
+}
+
+// matchingBrace returns the index after the brace closing the brace at open.
+func matchingBrace(css string, open int) int {
+	depth := 0
+	for i := open; i < len(css); i++ {
+		switch css[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(css)
+}
+
+// parseDeclarations parses "property: value; ..." declarations, ignoring the semicolons in quotes and parentheses,
+// e.g. in url("data:image/png;base64,...").
+func parseDeclarations(css string) []cssDeclaration {
+	var parts []string
+	var quote rune
+	depth, start := 0, 0
+	for i, r := range css {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ';' && depth == 0:
+			parts = append(parts, css[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, css[start:])
+
+	var declarations []cssDeclaration
+	for _, part := range parts {
+		property, value, found := strings.Cut(part, ":")
+		property = strings.ToLower(strings.TrimSpace(property))
+		value = strings.TrimSpace(value)
+		if !found || property == "" || value == "" {
+			continue
+		}
+		declaration := cssDeclaration{property: property, value: value}
+		if cssImportantRegexp.MatchString(value) {
+			declaration.important = true
+			declaration.value = cssImportantRegexp.ReplaceAllString(value, "")
+		}
+		declarations = append(declarations, declaration)
+	}
+	return declarations
+}
+
+// formatDeclarations returns the style attribute applying the declarations in the order of the cascade: important
+// declarations first, then specificity, then order. Each property is written once, with its winning value.
+func formatDeclarations(declarations []cssDeclaration) string {
+	slices.SortStableFunc(declarations, func(a, b cssDeclaration) int {
+		switch {
+		case a.important != b.important:
+			if a.important {
+				return 1
+			}
+			return -1
+		case a.specificity != b.specificity:
+			return slices.Compare(a.specificity[:], b.specificity[:])
+		default:
+			return a.order - b.order
+		}
+	})
+
+	var properties []string
+	values := map[string]cssDeclaration{}
+	for _, declaration := range declarations {
+		if _, ok := values[declaration.property]; !ok {
+			properties = append(properties, declaration.property)
+		}
+		values[declaration.property] = declaration
+	}
+
+	style := make([]string, 0, len(properties))
+	for _, property := range properties {
+		declaration := values[property]
+		value := declaration.value
+		if declaration.important {
+			value += " !important"
+		}
+		style = append(style, property+": "+value)
+	}
+	return strings.Join(style, "; ")
+}
+
+// cssSelector is a complex selector, made of compound selectors joined by combinators.
+type cssSelector struct {
+	// compounds in document order, with the combinator before each of them: ' ' for descendant, '>' for child
+	compounds   []cssCompound
+	specificity [3]int
+}
+
+type cssCompound struct {
+	combinator byte
+	tag        string
+	id         string
+	classes    []string
+}
+
+var (
+	cssCommentRegexp   = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssImportantRegexp = regexp.MustCompile(`(?i)\s*!\s*important$`)
+	// compoundSelectorRegexp matches a type or universal selector followed by class and id selectors, e.g. a.button#cta
+	compoundSelectorRegexp = regexp.MustCompile(`^(\*|[a-zA-Z][a-zA-Z0-9-]*)?((?:[.#][-_a-zA-Z0-9]+)*)$`)
+	idOrClassRegexp        = regexp.MustCompile(`[.#][-_a-zA-Z0-9]+`)
+)
+
+// parseSelector parses the selector, returning false if it is not supported.
+func parseSelector(raw string) (cssSelector, bool) {
+	selector := cssSelector{}
+	combinator := byte(' ')
+	for _, token := range strings.Fields(strings.ReplaceAll(raw, ">", " > ")) {
+		if token == ">" {
+			if len(selector.compounds) == 0 || combinator == '>' {
+				return cssSelector{}, false
+			}
+			combinator = '>'
+			continue
+		}
+
+		match := compoundSelectorRegexp.FindStringSubmatch(token)
+		if match == nil {
+			return cssSelector{}, false
+		}
+		compound := cssCompound{combinator: combinator, tag: strings.ToLower(match[1])}
+		if compound.tag == "*" {
+			compound.tag = ""
+		} else if compound.tag != "" {
+			selector.specificity[2]++
+		}
+		for _, part := range idOrClassRegexp.FindAllString(match[2], -1) {
+			if part[0] == '#' {
+				compound.id = part[1:]
+				selector.specificity[0]++
+				continue
+			}
+			compound.classes = append(compound.classes, part[1:])
+			selector.specificity[1]++
+		}
+		selector.compounds = append(selector.compounds, compound)
+		combinator = ' '
+	}
+	if len(selector.compounds) == 0 || combinator == '>' {
+		return cssSelector{}, false
+	}
+	return selector, true
+}
+
+// matches returns whether the element matches the selector, matching its compounds from right to left.
+func (s cssSelector) matches(element *html.Node) bool {
+	return s.matchesFrom(element, len(s.compounds)-1)
+}
+
+func (s cssSelector) matchesFrom(element *html.Node, i int) bool {
+	compound := s.compounds[i]
+	if !compound.matches(element) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	for parent := element.Parent; parent != nil && parent.Type == html.ElementNode; parent = parent.Parent {
+		if s.matchesFrom(parent, i-1) {
+			return true
+		}
+		if compound.combinator == '>' {
+			return false
+		}
+	}
+	return false
+}
+
+func (c cssCompound) matches(element *html.Node) bool {
+	if c.tag != "" && element.Data != c.tag {
+		return false
+	}
+	if c.id != "" && attr(element, "id") != c.id {
+		return false
+	}
+	classes := strings.Fields(attr(element, "class"))
+	for _, class := range c.classes {
+		if !slices.Contains(classes, class) {
+			return false
+		}
+	}
+	return true
+}
+
+func setAttr(n *html.Node, key, value string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
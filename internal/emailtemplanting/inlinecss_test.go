@@ -0,0 +1,67 @@
+package emailtemplating
+
+import (
+	"testing"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInlineCSS(t *testing.T) {
+	htmlBody := `<html><head><style>
+/* Brand */
+p { color: #333; margin: 0 }
+.button { color: white; background: url("data:image/png;base64,AA==") }
+a.button#cta { font-weight: bold }
+table td > p, p:hover { color: red }
+.important { color: blue !important }
+@media (max-width: 600px) { p { font-size: 18px } }
+</style></head><body>
+<p style="margin: 4px">Hello</p>
+<a class="button" id="cta" href="https://example.com">Go</a>
+<table><tr><td><p class="important">Cell</p></td></tr></table>
+</body></html>`
+
+	want := `<html><head><style>p:hover { color: red }
+@media (max-width: 600px) { p { font-size: 18px } }</style></head><body>
+<p style="color: #333; margin: 4px">Hello</p>
+<a class="button" id="cta" href="https://example.com" style="color: white; background: url(&#34;data:image/png;base64,AA==&#34;); font-weight: bold">Go</a>
+<table><tbody><tr><td><p class="important" style="color: blue !important; margin: 0">Cell</p></td></tr></tbody></table>
+</body></html>`
+
+	got, err := InlineCSS(htmlBody)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("unexpected HTML:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestInlineCSS_RemovesEmptyStyleBlocks(t *testing.T) {
+	got, err := InlineCSS(`<style>p { color: red }</style><p>Hello</p>`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `<html><head></head><body><p style="color: red">Hello</p></body></html>`; got != want {
+		t.Errorf("unexpected HTML: got %q want %q", got, want)
+	}
+}
+
+func TestRenderHTMLBodyTemplate_InlinesCSSWhenEnabled(t *testing.T) {
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{HTMLBody: `<style>p { color: red }</style><p>{{ .Name }}</p>`},
+	}
+	variables := []notificationmiloapiscomv1alpha1.EmailVariable{{Name: "Name", Value: "Ada"}}
+
+	got, err := RenderHTMLBodyTemplate(variables, template, RenderOptions{})
+	if err != nil || got != `<style>p { color: red }</style><p>Ada</p>` {
+		t.Fatalf("expected the CSS not to be inlined: %q, %v", got, err)
+	}
+
+	template.ObjectMeta = metav1.ObjectMeta{Annotations: map[string]string{InlineCSSAnnotation: "true"}}
+	got, err = RenderHTMLBodyTemplate(variables, template, RenderOptions{})
+	if err != nil || got != `<html><head></head><body><p style="color: red">Ada</p></body></html>` {
+		t.Fatalf("expected the CSS to be inlined: %q, %v", got, err)
+	}
+}
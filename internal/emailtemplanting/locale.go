@@ -0,0 +1,100 @@
+package emailtemplating
+
+import (
+	"sort"
+	"strings"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	"golang.org/x/text/language"
+)
+
+const (
+	// LocaleLabel is the locale of an EmailTemplate, e.g. "pt-BR". EmailTemplates without it are written in the
+	// default locale.
+	LocaleLabel = "notification.miloapis.com/locale"
+
+	// VariantOfLabel marks an EmailTemplate as the locale variant of the EmailTemplate with the given name.
+	// Emails keep referencing the base EmailTemplate, and the variant matching the recipient locale is rendered instead.
+	VariantOfLabel = "notification.miloapis.com/variant-of"
+
+	// LocaleVariable is the Email variable that sets the locale of the recipient. It takes precedence over the
+	// locale of the User, and is not required to be referenced by the EmailTemplate in strict variables mode.
+	LocaleVariable = "Locale"
+)
+
+// CanonicalLocale returns the canonical form of the locale, e.g. "pt-BR" for "pt_br". Locales that are not valid BCP 47
+// tags are returned trimmed.
+func CanonicalLocale(locale string) string {
+	locale = strings.TrimSpace(locale)
+	if locale == "" {
+		return ""
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return locale
+	}
+	return tag.String()
+}
+
+// LocaleFallbacks returns the locales to look for, in order, when rendering for the given locale: the locale itself,
+// its less specific parents and finally the default locale and its parents, e.g. pt-BR, pt, en.
+func LocaleFallbacks(locale, defaultLocale string) []string {
+	var fallbacks []string
+	seen := map[string]bool{}
+	for _, l := range []string{CanonicalLocale(locale), CanonicalLocale(defaultLocale)} {
+		for l != "" {
+			if !seen[l] {
+				seen[l] = true
+				fallbacks = append(fallbacks, l)
+			}
+			i := strings.LastIndex(l, "-")
+			if i < 0 {
+				break
+			}
+			l = l[:i]
+		}
+	}
+	return fallbacks
+}
+
+// TemplateLocale returns the locale the EmailTemplate is written in.
+func TemplateLocale(template *notificationmiloapiscomv1alpha1.EmailTemplate, defaultLocale string) string {
+	if locale := CanonicalLocale(template.Labels[LocaleLabel]); locale != "" {
+		return locale
+	}
+	return CanonicalLocale(defaultLocale)
+}
+
+// SelectLocaleVariant returns the EmailTemplate to render for the locale, among the base EmailTemplate and its locale
+// variants, along with its locale. The first locale of the fallback chain with an EmailTemplate wins; the base
+// EmailTemplate is returned when none matches. If several variants have the same locale, the first one by name is
+// used.
+func SelectLocaleVariant(
+	base *notificationmiloapiscomv1alpha1.EmailTemplate,
+	variants []notificationmiloapiscomv1alpha1.EmailTemplate,
+	locale, defaultLocale string,
+) (*notificationmiloapiscomv1alpha1.EmailTemplate, string) {
+	sorted := make([]*notificationmiloapiscomv1alpha1.EmailTemplate, 0, len(variants))
+	for i := range variants {
+		sorted = append(sorted, &variants[i])
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	byLocale := map[string]*notificationmiloapiscomv1alpha1.EmailTemplate{}
+	if baseLocale := TemplateLocale(base, defaultLocale); baseLocale != "" {
+		byLocale[baseLocale] = base
+	}
+	for _, variant := range sorted {
+		variantLocale := CanonicalLocale(variant.Labels[LocaleLabel])
+		if _, ok := byLocale[variantLocale]; !ok && variantLocale != "" {
+			byLocale[variantLocale] = variant
+		}
+	}
+
+	for _, l := range LocaleFallbacks(locale, defaultLocale) {
+		if template, ok := byLocale[l]; ok {
+			return template, l
+		}
+	}
+	return base, TemplateLocale(base, defaultLocale)
+}
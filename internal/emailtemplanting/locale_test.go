@@ -0,0 +1,75 @@
+package emailtemplating
+
+import (
+	"reflect"
+	"testing"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLocaleFallbacks(t *testing.T) {
+	tests := []struct {
+		locale, defaultLocale string
+		want                  []string
+	}{
+		{"pt-BR", "en", []string{"pt-BR", "pt", "en"}},
+		{"pt_br", "en", []string{"pt-BR", "pt", "en"}},
+		{"zh-Hant-TW", "en-US", []string{"zh-Hant-TW", "zh-Hant", "zh", "en-US", "en"}},
+		{"en-GB", "en", []string{"en-GB", "en"}},
+		{"", "en", []string{"en"}},
+		{"", "", nil},
+	}
+
+	for _, tt := range tests {
+		if got := LocaleFallbacks(tt.locale, tt.defaultLocale); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LocaleFallbacks(%q, %q) = %v, want %v", tt.locale, tt.defaultLocale, got, tt.want)
+		}
+	}
+}
+
+func TestSelectLocaleVariant(t *testing.T) {
+	localized := func(name, locale string) notificationmiloapiscomv1alpha1.EmailTemplate {
+		return notificationmiloapiscomv1alpha1.EmailTemplate{ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{VariantOfLabel: "welcome", LocaleLabel: locale},
+		}}
+	}
+	base := &notificationmiloapiscomv1alpha1.EmailTemplate{ObjectMeta: metav1.ObjectMeta{Name: "welcome"}}
+	variants := []notificationmiloapiscomv1alpha1.EmailTemplate{
+		localized("welcome-pt-z", "pt"),
+		localized("welcome-pt", "pt"),
+		localized("welcome-es-mx", "es-MX"),
+	}
+
+	tests := []struct {
+		locale       string
+		wantTemplate string
+		wantLocale   string
+	}{
+		{"pt-BR", "welcome-pt", "pt"},
+		{"pt", "welcome-pt", "pt"},
+		{"es-MX", "welcome-es-mx", "es-MX"},
+		{"es", "welcome", "en"},
+		{"fr", "welcome", "en"},
+		{"", "welcome", "en"},
+	}
+
+	for _, tt := range tests {
+		template, locale := SelectLocaleVariant(base, variants, tt.locale, "en")
+		if template.Name != tt.wantTemplate || locale != tt.wantLocale {
+			t.Errorf("%q: got %s (%s), want %s (%s)", tt.locale, template.Name, locale, tt.wantTemplate, tt.wantLocale)
+		}
+	}
+
+	// A base EmailTemplate labeled with a locale is not the default locale variant
+	base.Labels = map[string]string{LocaleLabel: "de"}
+	template, locale := SelectLocaleVariant(base, variants, "fr", "pt")
+	if template.Name != "welcome-pt" || locale != "pt" {
+		t.Errorf("got %s (%s), want welcome-pt (pt)", template.Name, locale)
+	}
+	template, locale = SelectLocaleVariant(base, variants, "fr", "it")
+	if template.Name != "welcome" || locale != "de" {
+		t.Errorf("got %s (%s), want welcome (de)", template.Name, locale)
+	}
+}
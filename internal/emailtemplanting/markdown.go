@@ -0,0 +1,385 @@
+package emailtemplating
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// MarkdownToHTML renders the Markdown document to HTML. It supports headings, paragraphs, hard line breaks, emphasis,
+// strong emphasis, inline code, links, images, bulleted and numbered lists, block quotes, fenced code blocks and
+// thematic breaks. Raw HTML is not supported and is escaped, as is all the text. Links and images are only kept for
+// http, https and mailto URLs.
+func MarkdownToHTML(markdown string) string {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	var out strings.Builder
+	renderMarkdownBlocks(&out, lines)
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+var (
+	markdownHeadingRegexp     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	markdownBreakRegexp       = regexp.MustCompile(`^ {0,3}((?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	markdownFenceRegexp       = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	markdownListItemRegexp    = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])([ \t]+|$)`)
+	markdownBlockquoteRegexp  = regexp.MustCompile(`^ {0,3}> ?`)
+	markdownOrderedListRegexp = regexp.MustCompile(`^\d`)
+)
+
+// renderMarkdownBlocks renders the block elements of the lines.
+func renderMarkdownBlocks(out *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case markdownFenceRegexp.MatchString(line):
+			fence := strings.TrimSpace(markdownFenceRegexp.FindStringSubmatch(line)[1])
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			i++
+			out.WriteString("<pre><code>")
+			out.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			out.WriteString("</code></pre>\n")
+
+		case markdownHeadingRegexp.MatchString(line):
+			match := markdownHeadingRegexp.FindStringSubmatch(line)
+			fmt.Fprintf(out, "<h%d>%s</h%d>\n", len(match[1]), renderMarkdownInline(match[2]), len(match[1]))
+			i++
+
+		case markdownBreakRegexp.MatchString(line):
+			out.WriteString("<hr>\n")
+			i++
+
+		case markdownBlockquoteRegexp.MatchString(line):
+			var quote []string
+			for ; i < len(lines) && markdownBlockquoteRegexp.MatchString(lines[i]); i++ {
+				quote = append(quote, markdownBlockquoteRegexp.ReplaceAllString(lines[i], ""))
+			}
+			out.WriteString("<blockquote>\n")
+			renderMarkdownBlocks(out, quote)
+			out.WriteString("</blockquote>\n")
+
+		case markdownListItemRegexp.MatchString(line):
+			i = renderMarkdownList(out, lines, i)
+
+		default:
+			var paragraph []string
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && (len(paragraph) == 0 || !startsMarkdownBlock(lines[i])); i++ {
+				paragraph = append(paragraph, lines[i])
+			}
+			out.WriteString("<p>")
+			out.WriteString(renderMarkdownLines(paragraph))
+			out.WriteString("</p>\n")
+		}
+	}
+}
+
+// startsMarkdownBlock returns whether the line interrupts a paragraph.
+func startsMarkdownBlock(line string) bool {
+	return markdownFenceRegexp.MatchString(line) || markdownHeadingRegexp.MatchString(line) ||
+		markdownBreakRegexp.MatchString(line) || markdownBlockquoteRegexp.MatchString(line) ||
+		markdownListItemRegexp.MatchString(line)
+}
+
+// renderMarkdownList renders the list starting at lines[start] and returns the index of the line after it. The lines
+// of an item are the lines indented under its text, and the lines continuing its first paragraph.
+func renderMarkdownList(out *strings.Builder, lines []string, start int) int {
+	first := markdownListItemRegexp.FindStringSubmatch(lines[start])
+	ordered := markdownOrderedListRegexp.MatchString(first[2])
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	out.WriteString("<" + tag + ">\n")
+
+	i := start
+	for i < len(lines) {
+		match := markdownListItemRegexp.FindStringSubmatch(lines[i])
+		if match == nil || markdownOrderedListRegexp.MatchString(match[2]) != ordered {
+			break
+		}
+		contentIndent := len(match[0])
+		item := []string{lines[i][contentIndent:]}
+		loose := false
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// Blank lines are part of the item only if it continues after them
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) >= contentIndent {
+					item = append(item, "")
+					loose = true
+					continue
+				}
+				break
+			}
+			if leadingSpaces(line) >= contentIndent {
+				item = append(item, line[contentIndent:])
+				continue
+			}
+			if startsMarkdownBlock(line) || item[len(item)-1] == "" {
+				break
+			}
+			item = append(item, strings.TrimLeft(line, " \t"))
+		}
+
+		out.WriteString("<li>")
+		var content strings.Builder
+		renderMarkdownBlocks(&content, item)
+		html := content.String()
+		// Tight items are not wrapped in paragraphs
+		if !loose {
+			html = strings.Replace(html, "<p>", "", 1)
+			html = strings.Replace(html, "</p>\n", "", 1)
+		}
+		out.WriteString(strings.TrimSuffix(html, "\n"))
+		out.WriteString("</li>\n")
+
+		// Skip the blank line between items
+		if i < len(lines) && strings.TrimSpace(lines[i]) == "" && i+1 < len(lines) && markdownListItemRegexp.MatchString(lines[i+1]) {
+			i++
+		}
+	}
+
+	out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// renderMarkdownLines renders the lines of a paragraph. Lines ending with two spaces or a backslash end with a line
+// break.
+func renderMarkdownLines(lines []string) string {
+	var out strings.Builder
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " \t")
+		hardBreak := false
+		if strings.HasSuffix(line, "  ") {
+			hardBreak = true
+		} else if strings.HasSuffix(line, "\\") && !strings.HasSuffix(line, "\\\\") {
+			hardBreak = true
+			line = strings.TrimSuffix(line, "\\")
+		}
+		out.WriteString(renderMarkdownInline(strings.TrimRight(line, " \t")))
+		if i < len(lines)-1 {
+			if hardBreak {
+				out.WriteString("<br>")
+			}
+			out.WriteString("\n")
+		}
+	}
+	return out.String()
+}
+
+// markdownEscapable are the characters that can be escaped with a backslash.
+const markdownEscapable = "\\`*_{}[]<>()#+-.!|~"
+
+// renderMarkdownInline renders the inline elements of the text.
+func renderMarkdownInline(text string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && strings.IndexByte(markdownEscapable, text[i+1]) >= 0:
+			out.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+
+		case c == '`':
+			run := len(text[i:]) - len(strings.TrimLeft(text[i:], "`"))
+			delimiter := text[i : i+run]
+			end := strings.Index(text[i+run:], delimiter)
+			if end < 0 {
+				out.WriteString(html.EscapeString(delimiter))
+				i += run
+				continue
+			}
+			code := strings.TrimSpace(text[i+run : i+run+end])
+			out.WriteString("<code>" + html.EscapeString(code) + "</code>")
+			i += run + end + run
+
+		case c == '!' && strings.HasPrefix(text[i+1:], "["):
+			if label, destination, n, ok := parseMarkdownLink(text[i+1:]); ok {
+				if safe, ok := safeMarkdownURL(destination); ok {
+					fmt.Fprintf(&out, `<img src="%s" alt="%s">`, html.EscapeString(safe), html.EscapeString(stripMarkdownEscapes(label)))
+				} else {
+					out.WriteString(renderMarkdownInline(label))
+				}
+				i += 1 + n
+				continue
+			}
+			out.WriteString("!")
+			i++
+
+		case c == '[':
+			if label, destination, n, ok := parseMarkdownLink(text[i:]); ok {
+				if safe, ok := safeMarkdownURL(destination); ok {
+					fmt.Fprintf(&out, `<a href="%s">%s</a>`, html.EscapeString(safe), renderMarkdownInline(label))
+				} else {
+					out.WriteString(renderMarkdownInline(label))
+				}
+				i += n
+				continue
+			}
+			out.WriteString("[")
+			i++
+
+		case c == '*' || c == '_':
+			run := 1
+			if i+1 < len(text) && text[i+1] == c {
+				run = 2
+			}
+			delimiter := text[i : i+run]
+			end := findMarkdownDelimiter(text, i+run, delimiter)
+			// Intraword underscores, e.g. in snake_case, are not emphasis
+			intraword := c == '_' && i > 0 && isMarkdownWordByte(text[i-1])
+			if end < 0 || intraword || end == i+run || text[i+run] == ' ' {
+				out.WriteString(html.EscapeString(delimiter))
+				i += run
+				continue
+			}
+			tag := "em"
+			if run == 2 {
+				tag = "strong"
+			}
+			out.WriteString("<" + tag + ">" + renderMarkdownInline(text[i+run:end]) + "</" + tag + ">")
+			i = end + run
+
+		default:
+			next := strings.IndexAny(text[i+1:], "\\`![*_")
+			if next < 0 {
+				next = len(text) - i - 1
+			}
+			out.WriteString(html.EscapeString(text[i : i+1+next]))
+			i += 1 + next
+		}
+	}
+	return out.String()
+}
+
+// findMarkdownDelimiter returns the index of the unescaped closing delimiter of an emphasis, or -1. The closing
+// delimiter must follow a non-space character.
+func findMarkdownDelimiter(text string, from int, delimiter string) int {
+	for i := from; i+len(delimiter) <= len(text); i++ {
+		switch {
+		case text[i] == '\\':
+			i++
+		case text[i] == '`':
+			if end := strings.IndexByte(text[i+1:], '`'); end >= 0 {
+				i += end + 1
+			}
+		case strings.HasPrefix(text[i:], delimiter) && text[i-1] != ' ':
+			// A single delimiter does not close on a double one, e.g. *a **b** c*
+			if len(delimiter) == 1 && i+1 < len(text) && text[i+1] == delimiter[0] {
+				i++
+				continue
+			}
+			if delimiter[0] == '_' && i+len(delimiter) < len(text) && isMarkdownWordByte(text[i+len(delimiter)]) {
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// parseMarkdownLink parses a [label](destination) link at the start of the text, returning its length.
+func parseMarkdownLink(text string) (label, destination string, n int, ok bool) {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if i+1 >= len(text) || text[i+1] != '(' {
+				return "", "", 0, false
+			}
+			end := closingParenthesis(text[i+2:])
+			if end < 0 {
+				return "", "", 0, false
+			}
+			destination = strings.TrimSpace(text[i+2 : i+2+end])
+			// Titles, e.g. [label](url "title"), are dropped
+			if space := strings.IndexAny(destination, " \t"); space >= 0 {
+				destination = destination[:space]
+			}
+			return text[1:i], strings.Trim(destination, "<>"), i + 3 + end, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// closingParenthesis returns the index of the parenthesis closing the link destination, skipping balanced
+// parentheses, or -1.
+func closingParenthesis(text string) int {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// safeMarkdownURL returns the unescaped URL if it is an http, https or mailto URL.
+func safeMarkdownURL(destination string) (string, bool) {
+	destination = stripMarkdownEscapes(destination)
+	u, err := url.Parse(destination)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return destination, true
+	default:
+		return "", false
+	}
+}
+
+// stripMarkdownEscapes removes the backslashes escaping characters.
+func stripMarkdownEscapes(text string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) && strings.IndexByte(markdownEscapable, text[i+1]) >= 0 {
+			i++
+		}
+		out.WriteByte(text[i])
+	}
+	return out.String()
+}
+
+// EscapeMarkdown escapes the text so it is rendered as is by MarkdownToHTML.
+func EscapeMarkdown(text string) string {
+	var out strings.Builder
+	for _, r := range text {
+		if r < 128 && strings.IndexByte(markdownEscapable, byte(r)) >= 0 {
+			out.WriteByte('\\')
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+func isMarkdownWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
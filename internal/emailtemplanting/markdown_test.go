@@ -0,0 +1,40 @@
+package emailtemplating
+
+import (
+	"testing"
+)
+
+func TestMarkdownToHTML(t *testing.T) {
+	tests := []struct {
+		markdown string
+		want     string
+	}{
+		{"# Hello *Ada*", "<h1>Hello <em>Ada</em></h1>"},
+		{"### Title ###", "<h3>Title</h3>"},
+		{"Thanks for **signing up** to `acme`.  \nSee you", "<p>Thanks for <strong>signing up</strong> to <code>acme</code>.<br>\nSee you</p>"},
+		{"First\nparagraph\n\nSecond", "<p>First\nparagraph</p>\n<p>Second</p>"},
+		{"[docs](https://example.com/?a=1&b=2 \"Docs\")", `<p><a href="https://example.com/?a=1&amp;b=2">docs</a></p>`},
+		{"[bad](javascript:alert(1))", "<p>bad</p>"},
+		{"![logo](https://example.com/logo.png)", `<p><img src="https://example.com/logo.png" alt="logo"></p>`},
+		{"- one\n- two\n  - nested\n- snake_case_name", "<ul>\n<li>one</li>\n<li>two<ul>\n<li>nested</li>\n</ul></li>\n<li>snake_case_name</li>\n</ul>"},
+		{"1. first\n2. second", "<ol>\n<li>first</li>\n<li>second</li>\n</ol>"},
+		{"> quoted\n> text", "<blockquote>\n<p>quoted\ntext</p>\n</blockquote>"},
+		{"---", "<hr>"},
+		{"```\n<b> & *x*\n```", "<pre><code>&lt;b&gt; &amp; *x*</code></pre>"},
+		{"<b>raw</b> 2 * 3 \\*escaped\\*", "<p>&lt;b&gt;raw&lt;/b&gt; 2 * 3 *escaped*</p>"},
+	}
+
+	for _, tt := range tests {
+		if got := MarkdownToHTML(tt.markdown); got != tt.want {
+			t.Errorf("%q: got %q want %q", tt.markdown, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	text := "# not *a* heading [x](javascript:alert(1)) <script>\n1. not a list"
+	want := "<p># not *a* heading [x](javascript:alert(1)) &lt;script&gt;\n1. not a list</p>"
+	if got := MarkdownToHTML(EscapeMarkdown(text)); got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
@@ -3,26 +3,47 @@ package emailtemplating
 import (
 	"bytes"
 	"fmt"
-	htmltemplate "html/template"
 
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 )
 
 // renderHTMLTemplate renders an HTML template with the given variables
-func RenderHTMLBodyTemplate(variables []notificationmiloapiscomv1alpha1.EmailVariable, template *notificationmiloapiscomv1alpha1.EmailTemplate) (string, error) {
-	htmlTemplate := template.Spec.HTMLBody
-	if htmlTemplate == "" {
+// The CSS of the rendered HTML is inlined if the template has the InlineCSSAnnotation.
+// Markdown templates are rendered to HTML from their text body, see IsMarkdown.
+func RenderHTMLBodyTemplate(variables []notificationmiloapiscomv1alpha1.EmailVariable, template *notificationmiloapiscomv1alpha1.EmailTemplate, opts RenderOptions) (string, error) {
+	body, funcs := template.Spec.HTMLBody, map[string]any{}
+	if IsMarkdown(template) {
+		content, err := renderMarkdown(variables, template, opts)
+		if err != nil {
+			return "", err
+		}
+		body, funcs = markdownHTMLBody(template, content)
+	}
+	if body == "" {
 		return "", nil
 	}
 
-	tmpl, err := htmltemplate.New("html").Parse(htmlTemplate)
+	tmpl, err := parseHTMLTemplate(body, template, opts, funcs)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse HTML template: %w", err)
 	}
 
+	data, err := templateData(variables, template)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode variables: %w", err)
+	}
+
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, convertVariables(variables)); err != nil {
-		return "", fmt.Errorf("failed to execute HTML template: %w", err)
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", &ExecutionError{Body: "HTML", Err: err}
+	}
+
+	if IsInlineCSS(template) {
+		inlined, err := InlineCSS(buf.String())
+		if err != nil {
+			return "", fmt.Errorf("failed to inline CSS: %w", err)
+		}
+		return inlined, nil
 	}
 
 	return buf.String(), nil
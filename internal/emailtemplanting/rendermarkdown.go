@@ -0,0 +1,121 @@
+package emailtemplating
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+const (
+	// FormatAnnotation is the format the text body of an EmailTemplate is authored in. FormatMarkdown is the only
+	// format besides the default plain text.
+	FormatAnnotation = "notification.miloapis.com/format"
+	FormatMarkdown   = "markdown"
+
+	// MarkdownPrefix marks a text body authored in Markdown without the FormatAnnotation. It is removed before
+	// rendering.
+	MarkdownPrefix = "<!-- markdown -->"
+
+	// defaultMarkdownLayout wraps the HTML of the Markdown templates without layout.
+	defaultMarkdownLayout = "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"></head><body>\n{{ markdownContent }}\n</body></html>"
+)
+
+// IsMarkdown returns whether the text body of the template is authored in Markdown, according to its
+// FormatAnnotation or MarkdownPrefix. The HTML body of Markdown templates is ignored: the Markdown is rendered to both
+// the HTML body, wrapped in the layout of the template, and the text body.
+func IsMarkdown(template *notificationmiloapiscomv1alpha1.EmailTemplate) bool {
+	return strings.EqualFold(strings.TrimSpace(template.Annotations[FormatAnnotation]), FormatMarkdown) ||
+		strings.HasPrefix(strings.TrimSpace(template.Spec.TextBody), MarkdownPrefix)
+}
+
+// markdownSource returns the Markdown text body of the template, without the MarkdownPrefix.
+func markdownSource(template *notificationmiloapiscomv1alpha1.EmailTemplate) string {
+	source := strings.TrimSpace(template.Spec.TextBody)
+	if strings.HasPrefix(source, MarkdownPrefix) {
+		return strings.TrimSpace(strings.TrimPrefix(source, MarkdownPrefix))
+	}
+	return template.Spec.TextBody
+}
+
+// markdownHTMLBody returns the HTML body template wrapping the rendered Markdown content, along with the function
+// returning the content. The content is wrapped in the layout of the template, if any, or in a minimal HTML document.
+func markdownHTMLBody(template *notificationmiloapiscomv1alpha1.EmailTemplate, content string) (string, map[string]any) {
+	body := defaultMarkdownLayout
+	if strings.TrimSpace(template.Annotations[LayoutAnnotation]) != "" {
+		body = "{{ markdownContent }}"
+	}
+	return body, map[string]any{
+		"markdownContent": func() htmltemplate.HTML { return htmltemplate.HTML(content) },
+	}
+}
+
+// renderMarkdown substitutes the variables of the Markdown text body of the template, then renders it to HTML. The
+// output of every action is escaped, so the variables cannot inject Markdown nor HTML.
+func renderMarkdown(variables []notificationmiloapiscomv1alpha1.EmailVariable, template *notificationmiloapiscomv1alpha1.EmailTemplate, opts RenderOptions) (string, error) {
+	source := markdownSource(template)
+	if source == "" {
+		return "", nil
+	}
+
+	tmpl, err := parseTextTemplate(source, template, false, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Markdown template: %w", err)
+	}
+	escapeActions(tmpl, "escapeMarkdown")
+	tmpl.Funcs(texttemplate.FuncMap{"escapeMarkdown": func(v any) string { return EscapeMarkdown(toString(v)) }})
+
+	data, err := templateData(variables, template)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode variables: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", &ExecutionError{Body: "Markdown", Err: err}
+	}
+
+	return MarkdownToHTML(buf.String()), nil
+}
+
+// escapeActions pipes the output of every action of the templates to the escape function, as html/template does.
+func escapeActions(tmpl *texttemplate.Template, escape string) {
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		var walk func(node parse.Node)
+		walk = func(node parse.Node) {
+			switch n := node.(type) {
+			case *parse.ListNode:
+				if n == nil {
+					return
+				}
+				for _, child := range n.Nodes {
+					walk(child)
+				}
+			case *parse.ActionNode:
+				// Variable declarations, e.g. {{ $name := .Name }}, have no output
+				if len(n.Pipe.Decl) > 0 {
+					return
+				}
+				identifier := parse.NewIdentifier(escape).SetTree(t.Tree).SetPos(n.Pos)
+				n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{identifier}})
+			case *parse.IfNode:
+				walk(n.List)
+				walk(n.ElseList)
+			case *parse.RangeNode:
+				walk(n.List)
+				walk(n.ElseList)
+			case *parse.WithNode:
+				walk(n.List)
+				walk(n.ElseList)
+			}
+		}
+		walk(t.Tree.Root)
+	}
+}
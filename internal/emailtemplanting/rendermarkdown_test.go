@@ -0,0 +1,89 @@
+package emailtemplating
+
+import (
+	"strings"
+	"testing"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRender_MarkdownTemplate(t *testing.T) {
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+			Subject:  "Welcome {{ .Name }}",
+			TextBody: MarkdownPrefix + "\n# Welcome, {{ .Name }}!\n\nYour plan: **{{ .Plan | upper }}**. [Sign in]({{ .URL }}).",
+		},
+	}
+	variables := []notificationmiloapiscomv1alpha1.EmailVariable{
+		{Name: "Name", Value: "*Ada* <b>"},
+		{Name: "Plan", Value: "pro"},
+		{Name: "URL", Value: "https://example.com/login?next=/home"},
+	}
+
+	htmlBody, err := RenderHTMLBodyTemplate(variables, template, RenderOptions{})
+	if err != nil {
+		t.Fatalf("unexpected HTML error: %v", err)
+	}
+	want := "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"></head><body>\n" +
+		"<h1>Welcome, *Ada* &lt;b&gt;!</h1>\n" +
+		`<p>Your plan: <strong>PRO</strong>. <a href="https://example.com/login?next=/home">Sign in</a>.</p>` +
+		"\n</body></html>"
+	if htmlBody != want {
+		t.Errorf("unexpected HTML body:\n%s\nwant:\n%s", htmlBody, want)
+	}
+
+	textBody, err := RenderTextBodyTemplate(variables, template, RenderOptions{})
+	if err != nil {
+		t.Fatalf("unexpected text error: %v", err)
+	}
+	if want := "Welcome, *Ada* <b>!\n===================\n\nYour plan: PRO. Sign in [1].\n\n[1] https://example.com/login?next=/home"; textBody != want {
+		t.Errorf("unexpected text body:\n%s\nwant:\n%s", textBody, want)
+	}
+
+	if err := CheckVariables(variables, template, RenderOptions{}); err != nil {
+		t.Errorf("unexpected variables error: %v", err)
+	}
+}
+
+func TestRender_MarkdownTemplateWithLayout(t *testing.T) {
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{FormatAnnotation: FormatMarkdown, LayoutAnnotation: "brand"}},
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+			HTMLBody: "<p>ignored</p>",
+			TextBody: "Hello [{{ .Name }}](javascript:alert(1))",
+		},
+	}
+	opts := partialsOptions(partial("brand", `<div class="{{ .Theme }}">{{ template "content" . }}</div>`, ""))
+	variables := []notificationmiloapiscomv1alpha1.EmailVariable{{Name: "Name", Value: "Ada"}, {Name: "Theme", Value: "dark"}}
+
+	htmlBody, err := RenderHTMLBodyTemplate(variables, template, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `<div class="dark"><p>Hello Ada</p></div>`; htmlBody != want {
+		t.Errorf("unexpected HTML body: got %q want %q", htmlBody, want)
+	}
+
+	err = CheckVariables(variables[:1], template, RenderOptions{Partials: opts.Partials})
+	if err == nil || !strings.Contains(err.Error(), "missing variables: Theme") {
+		t.Errorf("expected the layout variables to be checked, got %v", err)
+	}
+}
+
+func TestRender_MarkdownInjection(t *testing.T) {
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{FormatAnnotation: FormatMarkdown, LayoutAnnotation: "bare"}},
+		Spec:       notificationmiloapiscomv1alpha1.EmailTemplateSpec{TextBody: "Hi {{ .Name }}"},
+	}
+	opts := partialsOptions(partial("bare", `{{ template "content" . }}`, ""))
+	variables := []notificationmiloapiscomv1alpha1.EmailVariable{{Name: "Name", Value: "x\n\n# [click](https://evil.example) <img src=x onerror=alert(1)>"}}
+
+	htmlBody, err := RenderHTMLBodyTemplate(variables, template, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(htmlBody, "<h1>") || strings.Contains(htmlBody, "<a ") || strings.Contains(htmlBody, "<img") {
+		t.Errorf("variable injected Markdown or HTML: %s", htmlBody)
+	}
+}
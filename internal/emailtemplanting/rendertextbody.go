@@ -3,35 +3,65 @@ package emailtemplating
 import (
 	"bytes"
 	"fmt"
-	texttemplate "text/template"
 
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 )
 
 // renderTextBodyTemplate renders a text body template with the given variables
-func RenderTextBodyTemplate(variables []notificationmiloapiscomv1alpha1.EmailVariable, template *notificationmiloapiscomv1alpha1.EmailTemplate) (string, error) {
-	return renderTextTemplate(variables, template.Spec.TextBody)
+// If the template has no text body, the text body is derived from the rendered HTML body. The text body of Markdown
+// templates is derived from the rendered Markdown.
+func RenderTextBodyTemplate(variables []notificationmiloapiscomv1alpha1.EmailVariable, template *notificationmiloapiscomv1alpha1.EmailTemplate, opts RenderOptions) (string, error) {
+	if IsMarkdown(template) {
+		content, err := renderMarkdown(variables, template, opts)
+		if err != nil {
+			return "", err
+		}
+		textBody, err := HTMLToText(content)
+		if err != nil {
+			return "", fmt.Errorf("failed to derive text body from Markdown: %w", err)
+		}
+		return textBody, nil
+	}
+
+	if template.Spec.TextBody == "" && template.Spec.HTMLBody != "" {
+		htmlBody, err := RenderHTMLBodyTemplate(variables, template, opts)
+		if err != nil {
+			return "", err
+		}
+		textBody, err := HTMLToText(htmlBody)
+		if err != nil {
+			return "", fmt.Errorf("failed to derive text body from HTML body: %w", err)
+		}
+		return textBody, nil
+	}
+
+	return renderTextTemplate(variables, template, template.Spec.TextBody, true, opts)
 }
 
 // renderSubjectTemplate renders a subject template with the given variables
-func RenderSubjectTemplate(variables []notificationmiloapiscomv1alpha1.EmailVariable, template *notificationmiloapiscomv1alpha1.EmailTemplate) (string, error) {
-	return renderTextTemplate(variables, template.Spec.Subject)
+func RenderSubjectTemplate(variables []notificationmiloapiscomv1alpha1.EmailVariable, template *notificationmiloapiscomv1alpha1.EmailTemplate, opts RenderOptions) (string, error) {
+	return renderTextTemplate(variables, template, template.Spec.Subject, false, opts)
 }
 
 // renderTextTemplate renders a text template with the given variables
-func renderTextTemplate(variables []notificationmiloapiscomv1alpha1.EmailVariable, textTemplate string) (string, error) {
+func renderTextTemplate(variables []notificationmiloapiscomv1alpha1.EmailVariable, template *notificationmiloapiscomv1alpha1.EmailTemplate, textTemplate string, wrapInLayout bool, opts RenderOptions) (string, error) {
 	if textTemplate == "" {
 		return "", nil
 	}
 
-	tmpl, err := texttemplate.New("text").Parse(textTemplate)
+	tmpl, err := parseTextTemplate(textTemplate, template, wrapInLayout, opts)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse text template: %w", err)
 	}
 
+	data, err := templateData(variables, template)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode variables: %w", err)
+	}
+
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, convertVariables(variables)); err != nil {
-		return "", fmt.Errorf("failed to execute text template: %w", err)
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", &ExecutionError{Body: "text", Err: err}
 	}
 
 	return buf.String(), nil
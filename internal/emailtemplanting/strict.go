@@ -0,0 +1,148 @@
+package emailtemplating
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"slices"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+// StrictVariablesAnnotation enables ("true") or disables ("false") the strict variables mode of an EmailTemplate,
+// overriding the default of the controller.
+const StrictVariablesAnnotation = "notification.miloapis.com/strict-variables"
+
+// RenderOptions configures the rendering of the templates.
+type RenderOptions struct {
+	// Strict fails the rendering when a variable referenced by the templates is missing, instead of rendering
+	// "<no value>" or an empty string, and when a variable is not referenced by any of the templates.
+	Strict bool
+	// Partials are the partials the templates can invoke, and be wrapped in, by name. See PartialLabel.
+	Partials map[string]*notificationmiloapiscomv1alpha1.EmailTemplate
+}
+
+// IsStrict returns whether the variables of the template must be rendered in strict mode, according to its
+// StrictVariablesAnnotation, or defaultStrict if the annotation is not set or invalid.
+func IsStrict(template *notificationmiloapiscomv1alpha1.EmailTemplate, defaultStrict bool) bool {
+	value, ok := template.Annotations[StrictVariablesAnnotation]
+	if !ok {
+		return defaultStrict
+	}
+	strict, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return defaultStrict
+	}
+	return strict
+}
+
+// VariablesError is returned in strict mode when the variables of an email do not match the variables referenced by
+// its template. Rendering the same template with the same variables always fails again.
+type VariablesError struct {
+	// Missing are the variables referenced by the templates, but not set
+	Missing []string
+	// Unused are the variables set, but not referenced by any of the templates
+	Unused []string
+}
+
+func (e *VariablesError) Error() string {
+	var problems []string
+	if len(e.Missing) > 0 {
+		problems = append(problems, "missing variables: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unused) > 0 {
+		problems = append(problems, "unused variables: "+strings.Join(e.Unused, ", "))
+	}
+	return "template variables do not match: " + strings.Join(problems, "; ")
+}
+
+// CheckVariables returns a VariablesError listing every variable referenced by the subject, text body or HTML body
+// of the template but missing from the variables, and every variable not referenced by any of them. The builtin
+// variables, e.g. the unsubscribe URL, are set by the controller, possibly empty, and never reported as missing nor
+// unused.
+// The templates are composed with their layout and partials, as when rendering them.
+func CheckVariables(variables []notificationmiloapiscomv1alpha1.EmailVariable,
+	template *notificationmiloapiscomv1alpha1.EmailTemplate,
+	opts RenderOptions,
+	builtins ...string,
+) error {
+	referenced := map[string]bool{}
+
+	htmlBody, funcs := template.Spec.HTMLBody, map[string]any{}
+	textBody, wrapInLayout := template.Spec.TextBody, true
+	if IsMarkdown(template) {
+		htmlBody, funcs = markdownHTMLBody(template, "")
+		textBody, wrapInLayout = markdownSource(template), false
+	}
+
+	if htmlBody != "" {
+		tmpl, err := parseHTMLTemplate(htmlBody, template, opts, funcs)
+		if err != nil {
+			return fmt.Errorf("failed to parse HTML template: %w", err)
+		}
+		for _, name := range referencedVariables(htmlTrees(tmpl), tmpl.Name()) {
+			referenced[name] = true
+		}
+	}
+	for _, text := range []struct {
+		template     string
+		wrapInLayout bool
+	}{{textBody, wrapInLayout}, {template.Spec.Subject, false}} {
+		if text.template == "" {
+			continue
+		}
+		tmpl, err := parseTextTemplate(text.template, template, text.wrapInLayout, opts)
+		if err != nil {
+			return fmt.Errorf("failed to parse text template: %w", err)
+		}
+		for _, name := range referencedVariables(textTrees(tmpl), tmpl.Name()) {
+			referenced[name] = true
+		}
+	}
+
+	set := convertVariables(variables)
+	varsErr := &VariablesError{}
+	for name := range referenced {
+		if _, ok := set[name]; !ok && !slices.Contains(builtins, name) {
+			varsErr.Missing = append(varsErr.Missing, name)
+		}
+	}
+	for name := range set {
+		if !referenced[name] && !slices.Contains(builtins, name) {
+			varsErr.Unused = append(varsErr.Unused, name)
+		}
+	}
+	if len(varsErr.Missing) == 0 && len(varsErr.Unused) == 0 {
+		return nil
+	}
+	slices.Sort(varsErr.Missing)
+	slices.Sort(varsErr.Unused)
+	return varsErr
+}
+
+// missingKeyOption returns the missingkey option of the templates rendered with the options.
+func (o RenderOptions) missingKeyOption() string {
+	if o.Strict {
+		return "missingkey=error"
+	}
+	return "missingkey=default"
+}
+
+func textTrees(tmpl *texttemplate.Template) map[string]*parse.Tree {
+	trees := map[string]*parse.Tree{}
+	for _, t := range tmpl.Templates() {
+		trees[t.Name()] = t.Tree
+	}
+	return trees
+}
+
+func htmlTrees(tmpl *htmltemplate.Template) map[string]*parse.Tree {
+	trees := map[string]*parse.Tree{}
+	for _, t := range tmpl.Templates() {
+		trees[t.Name()] = t.Tree
+	}
+	return trees
+}
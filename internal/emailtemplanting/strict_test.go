@@ -0,0 +1,103 @@
+package emailtemplating
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckVariables_ListsEveryMissingAndUnusedVariable(t *testing.T) {
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+			Subject:  "Hello {{ .GivenName }}",
+			TextBody: "{{ .GivenName }} {{ .FamilyName }}{{ with .Company }}{{ .Ignored }}{{ $.Plan }}{{ end }}",
+			HTMLBody: `{{ define "footer" }}{{ .Footer }}{{ end }}{{ range .Items }}{{ .Nested }}{{ end }}{{ template "footer" . }}`,
+		},
+	}
+	variables := []notificationmiloapiscomv1alpha1.EmailVariable{
+		{Name: "GivenName", Value: "Ada"},
+		{Name: "Company", Value: "Analytical Engines"},
+		{Name: "Unused", Value: "x"},
+		{Name: "UnsubscribeURL", Value: "https://example.com/unsubscribe"},
+	}
+
+	err := CheckVariables(variables, template, RenderOptions{}, "UnsubscribeURL")
+
+	var varsErr *VariablesError
+	if !errors.As(err, &varsErr) {
+		t.Fatalf("expected a VariablesError, got %v", err)
+	}
+	if want := []string{"FamilyName", "Footer", "Items", "Plan"}; !reflect.DeepEqual(varsErr.Missing, want) {
+		t.Errorf("unexpected missing variables: got %v want %v", varsErr.Missing, want)
+	}
+	if want := []string{"Unused"}; !reflect.DeepEqual(varsErr.Unused, want) {
+		t.Errorf("unexpected unused variables: got %v want %v", varsErr.Unused, want)
+	}
+	if want := "template variables do not match: missing variables: FamilyName, Footer, Items, Plan; unused variables: Unused"; err.Error() != want {
+		t.Errorf("unexpected error message: got %q want %q", err.Error(), want)
+	}
+}
+
+func TestCheckVariables_MatchingVariables(t *testing.T) {
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{Subject: "Hello {{ .GivenName | default \"there\" }}"},
+	}
+	if err := CheckVariables([]notificationmiloapiscomv1alpha1.EmailVariable{{Name: "GivenName", Value: "Ada"}}, template, RenderOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckVariables_BuiltinsAreNeverMissing(t *testing.T) {
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+			Subject:  "Hello {{ .GivenName }}",
+			TextBody: `{{ if .UnsubscribeURL }}Unsubscribe: {{ .UnsubscribeURL }}{{ end }} {{ .Locale }}`,
+		},
+	}
+	variables := []notificationmiloapiscomv1alpha1.EmailVariable{{Name: "GivenName", Value: "Ada"}}
+
+	if err := CheckVariables(variables, template, RenderOptions{Strict: true}, "UnsubscribeURL", "Locale"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRender_StrictModeFailsOnMissingVariable(t *testing.T) {
+	template := &notificationmiloapiscomv1alpha1.EmailTemplate{
+		Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{Subject: "Hello {{ .GivenName }}", HTMLBody: "<p>{{ .GivenName }}</p>"},
+	}
+
+	subject, err := RenderSubjectTemplate(nil, template, RenderOptions{})
+	if err != nil || subject != "Hello <no value>" {
+		t.Fatalf("unexpected lenient rendering: %q, %v", subject, err)
+	}
+	if _, err := RenderSubjectTemplate(nil, template, RenderOptions{Strict: true}); err == nil {
+		t.Fatal("expected the strict subject rendering to fail")
+	}
+	if _, err := RenderHTMLBodyTemplate(nil, template, RenderOptions{Strict: true}); err == nil {
+		t.Fatal("expected the strict HTML rendering to fail")
+	}
+}
+
+func TestIsStrict(t *testing.T) {
+	tests := []struct {
+		annotations   map[string]string
+		defaultStrict bool
+		want          bool
+	}{
+		{nil, false, false},
+		{nil, true, true},
+		{map[string]string{StrictVariablesAnnotation: "true"}, false, true},
+		{map[string]string{StrictVariablesAnnotation: "false"}, true, false},
+		{map[string]string{StrictVariablesAnnotation: "invalid"}, true, true},
+	}
+
+	for _, tt := range tests {
+		template := &notificationmiloapiscomv1alpha1.EmailTemplate{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+		if got := IsStrict(template, tt.defaultStrict); got != tt.want {
+			t.Errorf("IsStrict(%v, %v): got %v want %v", tt.annotations, tt.defaultStrict, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package emailtemplating
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+	"unicode/utf8"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+const (
+	// SampleVariablesAnnotation holds the values the variables of an EmailTemplate are test rendered with when
+	// validating it, as a JSON object of strings, e.g. {"Amount": "12.5", "Date": "2025-01-31"}. Variables without a
+	// sample value are rendered with their name, an example URL for variables of type url, or null for JSON variables.
+	SampleVariablesAnnotation = "notification.miloapis.com/sample-variables"
+
+	// sampleURL is the sample value of the variables of type url
+	sampleURL = "https://example.com"
+)
+
+// TemplateError is a problem found in an EmailTemplate by ValidateTemplate. Line and Column locate it in its source,
+// and are 0 when unknown.
+type TemplateError struct {
+	// Source is the field of the EmailTemplate, e.g. spec.htmlBody, or the layout or partial the problem is in
+	Source  string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *TemplateError) Error() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", e.Source, e.Line, e.Column, e.Message)
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", e.Source, e.Line, e.Message)
+	default:
+		return fmt.Sprintf("%s: %s", e.Source, e.Message)
+	}
+}
+
+// templateField is a field of an EmailTemplate holding a template.
+type templateField struct {
+	path         string
+	source       string
+	html         bool
+	wrapInLayout bool
+	// funcs are available to the template on top of the FuncMap
+	funcs map[string]any
+	// lineOffset and columnOffset locate the source in the field, when the source is only part of it
+	lineOffset, columnOffset int
+	render                   func([]notificationmiloapiscomv1alpha1.EmailVariable, *notificationmiloapiscomv1alpha1.EmailTemplate, RenderOptions) (string, error)
+}
+
+// templateFields returns the fields of the template parsed when rendering it, in the order they are validated.
+func templateFields(template *notificationmiloapiscomv1alpha1.EmailTemplate) []templateField {
+	subject := templateField{path: "spec.subject", source: template.Spec.Subject, render: RenderSubjectTemplate}
+	textBody := templateField{path: "spec.textBody", source: template.Spec.TextBody, wrapInLayout: true, render: RenderTextBodyTemplate}
+	htmlBody := templateField{path: "spec.htmlBody", source: template.Spec.HTMLBody, html: true, render: RenderHTMLBodyTemplate}
+
+	if IsMarkdown(template) {
+		textBody.source, textBody.wrapInLayout = markdownSource(template), false
+		textBody.lineOffset, textBody.columnOffset = sourceOffset(template.Spec.TextBody, textBody.source)
+		htmlBody.source, htmlBody.funcs = markdownHTMLBody(template, "")
+	}
+	return []templateField{subject, textBody, htmlBody}
+}
+
+// sourceOffset returns the lines and columns preceding the part in the full text.
+func sourceOffset(full, part string) (int, int) {
+	i := strings.Index(full, part)
+	if i <= 0 {
+		return 0, 0
+	}
+	before := full[:i]
+	lastLine := before[strings.LastIndex(before, "\n")+1:]
+	return strings.Count(before, "\n"), utf8.RuneCountInString(lastLine)
+}
+
+// ValidateTemplate parses the subject, text body and HTML body of the template, composed with its layout and partials,
+// as when rendering it, and returns every problem found:
+//   - syntax errors, and invocations of undefined partials
+//   - variables referenced by the templates but not declared in spec.variables, unless builtin
+//   - in strict mode, declared variables not referenced by any of the templates
+//   - errors test rendering the templates with sample values, see SampleVariablesAnnotation
+//
+// The problems are located by line and column when possible. The template is valid if none is returned.
+func ValidateTemplate(template *notificationmiloapiscomv1alpha1.EmailTemplate, opts RenderOptions, builtins ...string) []*TemplateError {
+	var errs []*TemplateError
+
+	declared := map[string]bool{}
+	for _, variable := range template.Spec.Variables {
+		declared[variable.Name] = true
+	}
+
+	fields := templateFields(template)
+	referenced := map[string]bool{}
+	for _, f := range fields {
+		if f.source == "" {
+			continue
+		}
+		trees, root, err := parseField(template, f, opts)
+		if err != nil {
+			errs = append(errs, locateError(template, f, opts, err))
+			continue
+		}
+
+		references := variableReferences(trees, root)
+		names := make([]string, 0, len(references))
+		for name := range references {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			referenced[name] = true
+			if declared[name] || slices.Contains(builtins, name) {
+				continue
+			}
+			templateErr := locateNode(template, f, opts, references[name])
+			templateErr.Message = fmt.Sprintf("variable %q is not declared in spec.variables", name)
+			errs = append(errs, templateErr)
+		}
+	}
+
+	if opts.Strict {
+		for _, variable := range template.Spec.Variables {
+			if !referenced[variable.Name] {
+				errs = append(errs, &TemplateError{
+					Source:  "spec.variables",
+					Message: fmt.Sprintf("variable %q is declared but not referenced by any template, as required in strict variables mode", variable.Name),
+				})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	variables, err := sampleVariables(template, referenced)
+	if err != nil {
+		return []*TemplateError{{
+			Source:  fmt.Sprintf("metadata.annotations[%s]", SampleVariablesAnnotation),
+			Message: err.Error(),
+		}}
+	}
+	for _, f := range fields {
+		if f.source == "" {
+			continue
+		}
+		if _, err := f.render(variables, template, opts); err != nil {
+			// The fields share their layout and partials, so the first failure is usually the failure of every field
+			templateErr := locateError(template, f, opts, err)
+			templateErr.Message = "test render with sample values failed: " + templateErr.Message
+			return []*TemplateError{templateErr}
+		}
+	}
+	return nil
+}
+
+// ValidatePartial parses the HTML and text bodies of the partial on their own, and returns their syntax errors. The
+// variables of partials are validated with the EmailTemplates invoking them.
+func ValidatePartial(partial *notificationmiloapiscomv1alpha1.EmailTemplate) []*TemplateError {
+	var errs []*TemplateError
+	for _, f := range []templateField{
+		{path: "spec.htmlBody", source: partial.Spec.HTMLBody},
+		{path: "spec.textBody", source: partial.Spec.TextBody},
+	} {
+		if f.source == "" {
+			continue
+		}
+		if _, err := texttemplate.New(f.path).Funcs(FuncMap()).Parse(f.source); err != nil {
+			templateErr := &TemplateError{Source: f.path, Message: err.Error()}
+			if _, line, column, message, ok := errorLocation(err); ok {
+				if column == 0 {
+					column = parseErrorColumn(f.source, line, message, nil)
+				}
+				templateErr.Line, templateErr.Column, templateErr.Message = line, column, message
+			}
+			errs = append(errs, templateErr)
+		}
+	}
+	return errs
+}
+
+// parseField parses the field as when rendering it, and returns the parsed trees along with the name of the root
+// template.
+func parseField(template *notificationmiloapiscomv1alpha1.EmailTemplate, f templateField, opts RenderOptions) (map[string]*parse.Tree, string, error) {
+	if f.html {
+		tmpl, err := parseHTMLTemplate(f.source, template, opts, f.funcs)
+		if err != nil {
+			return nil, "", err
+		}
+		return htmlTrees(tmpl), tmpl.Name(), nil
+	}
+	tmpl, err := parseTextTemplate(f.source, template, f.wrapInLayout, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	return textTrees(tmpl), tmpl.Name(), nil
+}
+
+// errorLocationRegexp matches the location of the errors of text/template and html/template, e.g.
+// "template: html:3: unexpected EOF" or "html/template:html:3:14: ... appears in an ambiguous context within a URL".
+var errorLocationRegexp = regexp.MustCompile(`(?:html/template:|template: )([^:\s]+):(\d+)(?::(\d+))?: (.*)$`)
+
+// errorLocation returns the template name, line, column (0 if unknown) and message of a template error.
+func errorLocation(err error) (string, int, int, string, bool) {
+	match := errorLocationRegexp.FindStringSubmatch(err.Error())
+	if match == nil {
+		return "", 0, 0, "", false
+	}
+	line, _ := strconv.Atoi(match[2])
+	column, _ := strconv.Atoi(match[3])
+	return match[1], line, column, match[4], true
+}
+
+// locateError returns the TemplateError of an error parsing or executing the field, located in its source.
+func locateError(template *notificationmiloapiscomv1alpha1.EmailTemplate, f templateField, opts RenderOptions, err error) *TemplateError {
+	name, line, column, message, ok := errorLocation(err)
+	if !ok {
+		return &TemplateError{Source: f.path, Message: err.Error()}
+	}
+	source, text, isField := templateSource(template, f, opts, name)
+	if column == 0 {
+		column = parseErrorColumn(text, line, message, f.funcs)
+	} else {
+		column = runeColumn(text, line, column)
+	}
+	return located(f, source, isField, line, column, message)
+}
+
+// locateNode returns a TemplateError located at the node of the field.
+func locateNode(template *notificationmiloapiscomv1alpha1.EmailTemplate, f templateField, opts RenderOptions, node parse.Node) *TemplateError {
+	location, _ := (*parse.Tree)(nil).ErrorContext(node)
+	parts := strings.Split(location, ":")
+	if len(parts) < 3 {
+		return &TemplateError{Source: f.path}
+	}
+	line, _ := strconv.Atoi(parts[len(parts)-2])
+	column, _ := strconv.Atoi(parts[len(parts)-1])
+	source, text, isField := templateSource(template, f, opts, strings.Join(parts[:len(parts)-2], ":"))
+	return located(f, source, isField, line, runeColumn(text, line, column), "")
+}
+
+// located returns a TemplateError at the line and column of the source, shifted by the offset of the field source
+// when the source is the field.
+func located(f templateField, source string, isField bool, line, column int, message string) *TemplateError {
+	if isField {
+		if line == 1 && column > 0 {
+			column += f.columnOffset
+		}
+		line += f.lineOffset
+	}
+	return &TemplateError{Source: source, Line: line, Column: column, Message: message}
+}
+
+// templateSource returns the description and text of the source of the template with the given name, parsed with the
+// field, and whether it is the field itself rather than its layout or a partial.
+func templateSource(template *notificationmiloapiscomv1alpha1.EmailTemplate, f templateField, opts RenderOptions, name string) (string, string, bool) {
+	root := "text"
+	if f.html {
+		root = "html"
+	}
+
+	layoutName := strings.TrimSpace(template.Annotations[LayoutAnnotation])
+	layout, hasLayout := opts.Partials[layoutName]
+	wrapped := f.wrapInLayout || f.html
+	layoutApplied := wrapped && layoutName != "" && hasLayout && partialSource(layout, f.html) != ""
+
+	switch {
+	case name == layoutContentTemplate && layoutApplied, name == root && !layoutApplied:
+		return f.path, f.source, true
+	case name == root:
+		return fmt.Sprintf("layout %q", layoutName), partialSource(layout, f.html), false
+	}
+	if partial, ok := opts.Partials[name]; ok {
+		return fmt.Sprintf("partial %q", name), partialSource(partial, f.html), false
+	}
+	return f.path, f.source, true
+}
+
+// runeColumn converts the 0-based byte column of text/template locations to the 1-based column, in characters, of the
+// line of the text.
+func runeColumn(text string, line, byteColumn int) int {
+	lines := strings.SplitAfter(text, "\n")
+	if line < 1 || line > len(lines) || byteColumn > len(lines[line-1]) {
+		return byteColumn + 1
+	}
+	return utf8.RuneCountInString(lines[line-1][:byteColumn]) + 1
+}
+
+// parseErrorColumn returns the column of the action causing the parse error on the line of the text, or 0 if it cannot
+// be found. Parse errors only report their line, so the text is parsed up to the end of every action of the line until
+// the same error is reproduced: the parser stops at the first error, so it is caused by the last parsed action.
+func parseErrorColumn(text string, line int, message string, funcs map[string]any) int {
+	lines := strings.SplitAfter(text, "\n")
+	if line < 1 || line > len(lines) {
+		return 0
+	}
+	lineStart := 0
+	for _, l := range lines[:line-1] {
+		lineStart += len(l)
+	}
+	lineText := lines[line-1]
+
+	for offset := 0; ; {
+		i := strings.Index(lineText[offset:], "{{")
+		if i < 0 {
+			return 0
+		}
+		actionStart := lineStart + offset + i
+		end := len(text)
+		if j := strings.Index(text[actionStart:], "}}"); j >= 0 {
+			end = actionStart + j + len("}}")
+		}
+
+		_, err := texttemplate.New("prefix").Funcs(FuncMap()).Funcs(funcs).Parse(text[:end])
+		if err != nil {
+			if _, errLine, _, errMessage, ok := errorLocation(err); ok && errLine == line && errMessage == message {
+				return utf8.RuneCountInString(lineText[:offset+i]) + 1
+			}
+		}
+		offset += i + len("{{")
+	}
+}
+
+// sampleVariables returns the variables to test render the template with: its declared variables and the referenced
+// builtin variables, with the values of its SampleVariablesAnnotation or else a default sample value.
+func sampleVariables(template *notificationmiloapiscomv1alpha1.EmailTemplate, referenced map[string]bool) ([]notificationmiloapiscomv1alpha1.EmailVariable, error) {
+	samples := map[string]string{}
+	if value := strings.TrimSpace(template.Annotations[SampleVariablesAnnotation]); value != "" {
+		if err := json.Unmarshal([]byte(value), &samples); err != nil {
+			return nil, fmt.Errorf("invalid sample variables, must be a JSON object of strings: %w", err)
+		}
+	}
+
+	types := map[string]notificationmiloapiscomv1alpha1.TemplateVariableType{}
+	for _, variable := range template.Spec.Variables {
+		types[variable.Name] = variable.Type
+	}
+	names := make([]string, 0, len(referenced)+len(types))
+	for name := range referenced {
+		names = append(names, name)
+	}
+	for name := range types {
+		if !referenced[name] {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	jsonVariables := jsonVariableNames(template)
+	variables := make([]notificationmiloapiscomv1alpha1.EmailVariable, 0, len(names))
+	for _, name := range names {
+		value, ok := samples[name]
+		switch {
+		case ok:
+		case jsonVariables[name]:
+			value = "null"
+		case strings.EqualFold(string(types[name]), "url"):
+			value = sampleURL
+		default:
+			value = name
+		}
+		variables = append(variables, notificationmiloapiscomv1alpha1.EmailVariable{Name: name, Value: value})
+	}
+	return variables, nil
+}
@@ -0,0 +1,168 @@
+package emailtemplating
+
+import (
+	"reflect"
+	"testing"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func validateErrors(template *notificationmiloapiscomv1alpha1.EmailTemplate, opts RenderOptions, builtins ...string) []string {
+	var errs []string
+	for _, err := range ValidateTemplate(template, opts, builtins...) {
+		errs = append(errs, err.Error())
+	}
+	return errs
+}
+
+func declared(names ...string) []notificationmiloapiscomv1alpha1.TemplateVariable {
+	var variables []notificationmiloapiscomv1alpha1.TemplateVariable
+	for _, name := range names {
+		variables = append(variables, notificationmiloapiscomv1alpha1.TemplateVariable{Name: name, Type: "string"})
+	}
+	return variables
+}
+
+func TestValidateTemplate(t *testing.T) {
+	opts := partialsOptions(
+		partial("brand", "<main>\n  {{ template \"content\" . }}\n  {{ .Team }}\n</main>", ""),
+		partial("broken", "{{ if .X }}", ""),
+	)
+
+	tests := []struct {
+		name     string
+		template notificationmiloapiscomv1alpha1.EmailTemplate
+		opts     RenderOptions
+		builtins []string
+		want     []string
+	}{
+		{
+			name: "valid",
+			template: notificationmiloapiscomv1alpha1.EmailTemplate{Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+				Subject:   "Hello {{ .Name }}",
+				HTMLBody:  `<a href="{{ .URL }}">{{ .Name | upper }}</a> {{ .UnsubscribeURL }}`,
+				Variables: declared("Name", "URL"),
+			}},
+			builtins: []string{"UnsubscribeURL"},
+		},
+		{
+			name: "syntax errors are located by line and column",
+			template: notificationmiloapiscomv1alpha1.EmailTemplate{Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+				Subject:   "Hello {{ .Name }",
+				TextBody:  "Hello\nDear {{ .Name }}, {{ .Name | shout }}",
+				HTMLBody:  "<p>\n  {{ .Name }} {{ end }}\n</p>",
+				Variables: declared("Name"),
+			}},
+			want: []string{
+				`spec.subject:1:7: unexpected "}" in operand`,
+				`spec.textBody:2:19: function "shout" not defined`,
+				`spec.htmlBody:2:15: unexpected {{end}}`,
+			},
+		},
+		{
+			name: "undeclared variables are located by line and column",
+			template: notificationmiloapiscomv1alpha1.EmailTemplate{Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+				Subject:   "Hello {{ .Name }}",
+				TextBody:  "Hi {{ .Name }}\n{{ range .Items }}{{ .Title }}{{ end }} {{ $.Plan }}",
+				Variables: declared("Name"),
+			}},
+			want: []string{
+				`spec.textBody:2:10: variable "Items" is not declared in spec.variables`,
+				`spec.textBody:2:45: variable "Plan" is not declared in spec.variables`,
+			},
+		},
+		{
+			name: "errors in the layout and partials are located in them",
+			template: notificationmiloapiscomv1alpha1.EmailTemplate{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{LayoutAnnotation: "brand"}},
+				Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+					HTMLBody:  `<p>{{ .Name }}</p>{{ template "broken" . }}`,
+					Variables: declared("Name"),
+				},
+			},
+			opts: opts,
+			want: []string{`partial "broken":1:1: unexpected EOF`},
+		},
+		{
+			name: "variables of the layout must be declared",
+			template: notificationmiloapiscomv1alpha1.EmailTemplate{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{LayoutAnnotation: "brand"}},
+				Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+					HTMLBody:  `<p>{{ .Name }}</p>`,
+					Variables: declared("Name"),
+				},
+			},
+			opts: opts,
+			want: []string{`layout "brand":3:6: variable "Team" is not declared in spec.variables`},
+		},
+		{
+			name: "missing partials",
+			template: notificationmiloapiscomv1alpha1.EmailTemplate{Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+				Subject: `{{ template "footer" . }}`,
+			}},
+			want: []string{`spec.subject: template "footer" is not defined and no EmailTemplate with label notification.miloapis.com/partial=footer exists`},
+		},
+		{
+			name: "unreferenced variables in strict mode",
+			template: notificationmiloapiscomv1alpha1.EmailTemplate{Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+				Subject:   "Hello {{ .Name }}",
+				Variables: declared("Name", "Plan"),
+			}},
+			opts: RenderOptions{Strict: true},
+			want: []string{`spec.variables: variable "Plan" is declared but not referenced by any template, as required in strict variables mode`},
+		},
+		{
+			name: "markdown text bodies are located in the field",
+			template: notificationmiloapiscomv1alpha1.EmailTemplate{Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+				TextBody:  MarkdownPrefix + "\n# Hi {{ .Nmae }}",
+				Variables: declared("Name"),
+			}},
+			want: []string{`spec.textBody:2:9: variable "Nmae" is not declared in spec.variables`},
+		},
+		{
+			name: "test render failures",
+			template: notificationmiloapiscomv1alpha1.EmailTemplate{Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+				Subject:   "Total: {{ formatNumber \"en\" .Amount }}",
+				Variables: declared("Amount"),
+			}},
+			want: []string{`spec.subject:1:11: test render with sample values failed: executing "text" at <formatNumber "en" .Amount>: error calling formatNumber: formatNumber: "Amount" is not a number`},
+		},
+		{
+			name: "test render with sample values",
+			template: notificationmiloapiscomv1alpha1.EmailTemplate{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+					SampleVariablesAnnotation: `{"Amount": "12.5"}`,
+					JSONVariablesAnnotation:   "Items",
+				}},
+				Spec: notificationmiloapiscomv1alpha1.EmailTemplateSpec{
+					Subject:   "Total: {{ formatNumber \"en\" .Amount }}{{ range .Items }}{{ .Name }}{{ end }}",
+					Variables: declared("Amount", "Items"),
+				},
+			},
+		},
+		{
+			name: "invalid sample values",
+			template: notificationmiloapiscomv1alpha1.EmailTemplate{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{SampleVariablesAnnotation: `["12.5"]`}},
+				Spec:       notificationmiloapiscomv1alpha1.EmailTemplateSpec{Subject: "{{ .Amount }}", Variables: declared("Amount")},
+			},
+			want: []string{`metadata.annotations[notification.miloapis.com/sample-variables]: invalid sample variables, must be a JSON object of strings: json: cannot unmarshal array into Go value of type map[string]string`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateErrors(&tt.template, tt.opts, tt.builtins...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got errors:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePartial(t *testing.T) {
+	errs := ValidatePartial(partial("footer", "<footer>{{ template \"content\" . }}</footer>", "--\n{{ .Team "))
+	if len(errs) != 1 || errs[0].Error() != "spec.textBody:2:1: unclosed action" {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
@@ -0,0 +1,124 @@
+package emailtemplating
+
+import (
+	"slices"
+	"text/template/parse"
+)
+
+// referencedVariables returns the sorted names of the variables the template named root references, e.g. "Name" for
+// {{ .Name }}, {{ .Name.First }} or {{ $.Name }}. The templates it invokes with {{ template "name" . }} are followed.
+// Fields accessed inside {{ range }} and {{ with }} blocks, where the dot is no longer the variables, are not variables.
+func referencedVariables(trees map[string]*parse.Tree, root string) []string {
+	references := variableReferences(trees, root)
+
+	variables := make([]string, 0, len(references))
+	for name := range references {
+		variables = append(variables, name)
+	}
+	slices.Sort(variables)
+	return variables
+}
+
+// variableReferences returns the first node referencing each of the variables the template named root references.
+// See referencedVariables.
+func variableReferences(trees map[string]*parse.Tree, root string) map[string]parse.Node {
+	w := &variablesWalker{trees: trees, variables: map[string]parse.Node{}, visited: map[templateVisit]bool{}}
+	w.walkTemplate(root, true)
+	return w.variables
+}
+
+type variablesWalker struct {
+	trees map[string]*parse.Tree
+	// variables holds the first node referencing each variable
+	variables map[string]parse.Node
+	// visited stops the walk on recursive templates
+	visited map[templateVisit]bool
+}
+
+// templateVisit is a template walked with the dot being the variables, or not.
+type templateVisit struct {
+	name      string
+	dotIsRoot bool
+}
+
+func (w *variablesWalker) walkTemplate(name string, dotIsRoot bool) {
+	key := templateVisit{name: name, dotIsRoot: dotIsRoot}
+	tree, ok := w.trees[name]
+	if !ok || tree == nil || tree.Root == nil || w.visited[key] {
+		return
+	}
+	w.visited[key] = true
+	w.walk(tree.Root, dotIsRoot)
+}
+
+func (w *variablesWalker) walk(node parse.Node, dotIsRoot bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			w.walk(child, dotIsRoot)
+		}
+	case *parse.ActionNode:
+		w.walk(n.Pipe, dotIsRoot)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			w.walk(cmd, dotIsRoot)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			w.walk(arg, dotIsRoot)
+		}
+	case *parse.ChainNode:
+		w.walk(n.Node, dotIsRoot)
+	case *parse.FieldNode:
+		if dotIsRoot && len(n.Ident) > 0 {
+			w.reference(n.Ident[0], n)
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			w.reference(n.Ident[1], n)
+		}
+	case *parse.IfNode:
+		w.walk(n.Pipe, dotIsRoot)
+		w.walk(n.List, dotIsRoot)
+		w.walk(n.ElseList, dotIsRoot)
+	case *parse.RangeNode:
+		w.walk(n.Pipe, dotIsRoot)
+		w.walk(n.List, false)
+		w.walk(n.ElseList, dotIsRoot)
+	case *parse.WithNode:
+		w.walk(n.Pipe, dotIsRoot)
+		w.walk(n.List, false)
+		w.walk(n.ElseList, dotIsRoot)
+	case *parse.TemplateNode:
+		w.walk(n.Pipe, dotIsRoot)
+		w.walkTemplate(n.Name, dotIsRoot && isDot(n.Pipe))
+	}
+}
+
+// reference records the node as referencing the variable, unless an earlier node already did.
+func (w *variablesWalker) reference(name string, node parse.Node) {
+	if _, ok := w.variables[name]; !ok {
+		w.variables[name] = node
+	}
+}
+
+// isDot returns whether the pipeline is the dot, or $, alone.
+func isDot(pipe *parse.PipeNode) bool {
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return true
+	case *parse.VariableNode:
+		return len(arg.Ident) == 1 && arg.Ident[0] == "$"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,105 @@
+// Package unsubscribe signs and verifies the tokens of the unsubscribe links added to the emails sent to the members of
+// a ContactGroup.
+package unsubscribe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+// TokenQueryParameter is the query parameter of the unsubscribe URL that carries the signed token.
+const TokenQueryParameter = "token"
+
+// ErrInvalidToken is returned when a token is malformed or its signature does not match.
+var ErrInvalidToken = errors.New("invalid unsubscribe token")
+
+// Token identifies the Contact that wants to leave the ContactGroup.
+type Token struct {
+	ContactRef      notificationmiloapiscomv1alpha1.ContactReference      `json:"contactRef"`
+	ContactGroupRef notificationmiloapiscomv1alpha1.ContactGroupReference `json:"contactGroupRef"`
+}
+
+// Signer signs and verifies tokens with HMAC-SHA256.
+// Tokens do not expire, as unsubscribe links must keep working for as long as the email is kept in the inbox.
+type Signer struct {
+	key []byte
+}
+
+// NewSigner creates a new Signer with the given signing key.
+func NewSigner(key string) *Signer {
+	return &Signer{key: []byte(key)}
+}
+
+// Sign returns the token encoded as "<payload>.<signature>", both base64url encoded.
+func (s *Signer) Sign(token Token) (string, error) {
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal unsubscribe token: %w", err)
+	}
+
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	return encodedPayload + "." + base64.RawURLEncoding.EncodeToString(s.signature(encodedPayload)), nil
+}
+
+// Verify checks the signature of the signed token and returns the token.
+func (s *Signer) Verify(signed string) (Token, error) {
+	encodedPayload, encodedSignature, found := strings.Cut(signed, ".")
+	if !found {
+		return Token{}, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return Token{}, ErrInvalidToken
+	}
+	if !hmac.Equal(signature, s.signature(encodedPayload)) {
+		return Token{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return Token{}, ErrInvalidToken
+	}
+	token := Token{}
+	if err := json.Unmarshal(payload, &token); err != nil {
+		return Token{}, ErrInvalidToken
+	}
+	if token.ContactRef.Name == "" || token.ContactRef.Namespace == "" ||
+		token.ContactGroupRef.Name == "" || token.ContactGroupRef.Namespace == "" {
+		return Token{}, ErrInvalidToken
+	}
+
+	return token, nil
+}
+
+// URL returns the unsubscribe URL of the token, served at baseURL.
+func (s *Signer) URL(baseURL string, token Token) (string, error) {
+	signed, err := s.Sign(token)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse unsubscribe base URL: %w", err)
+	}
+	query := u.Query()
+	query.Set(TokenQueryParameter, signed)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func (s *Signer) signature(encodedPayload string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(encodedPayload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,85 @@
+package unsubscribe
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+func testToken() Token {
+	return Token{
+		ContactRef:      notificationmiloapiscomv1alpha1.ContactReference{Name: "contact", Namespace: "default"},
+		ContactGroupRef: notificationmiloapiscomv1alpha1.ContactGroupReference{Name: "newsletter", Namespace: "default"},
+	}
+}
+
+func TestSigner_SignAndVerify(t *testing.T) {
+	signer := NewSigner("secret")
+
+	signed, err := signer.Sign(testToken())
+	if err != nil {
+		t.Fatalf("unexpected error signing token: %v", err)
+	}
+
+	token, err := signer.Verify(signed)
+	if err != nil {
+		t.Fatalf("unexpected error verifying token: %v", err)
+	}
+	if token != testToken() {
+		t.Fatalf("unexpected token: got %+v want %+v", token, testToken())
+	}
+}
+
+func TestSigner_VerifyRejectsInvalidTokens(t *testing.T) {
+	signer := NewSigner("secret")
+	signed, err := signer.Sign(testToken())
+	if err != nil {
+		t.Fatalf("unexpected error signing token: %v", err)
+	}
+	otherSigned, err := NewSigner("other-secret").Sign(testToken())
+	if err != nil {
+		t.Fatalf("unexpected error signing token: %v", err)
+	}
+	incomplete, err := signer.Sign(Token{})
+	if err != nil {
+		t.Fatalf("unexpected error signing token: %v", err)
+	}
+
+	tests := map[string]string{
+		"empty":              "",
+		"no signature":       "payload",
+		"tampered payload":   "x" + signed,
+		"tampered signature": signed + "x",
+		"other key":          otherSigned,
+		"incomplete token":   incomplete,
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := signer.Verify(token); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSigner_URL(t *testing.T) {
+	signer := NewSigner("secret")
+
+	unsubscribeURL, err := signer.URL("https://hooks.example.com/unsubscribe?lang=en", testToken())
+	if err != nil {
+		t.Fatalf("unexpected error building URL: %v", err)
+	}
+
+	u, err := url.Parse(unsubscribeURL)
+	if err != nil {
+		t.Fatalf("unexpected error parsing URL: %v", err)
+	}
+	if u.Host != "hooks.example.com" || u.Path != "/unsubscribe" || u.Query().Get("lang") != "en" {
+		t.Fatalf("unexpected URL: %s", unsubscribeURL)
+	}
+	if _, err := signer.Verify(u.Query().Get(TokenQueryParameter)); err != nil {
+		t.Fatalf("unexpected error verifying token of URL: %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"go.miloapis.com/email-provider-resend/internal/resend"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+const (
+	// broadcastStatsWindow is how long the events of a broadcast are collected before counting them in a single
+	// update of the Email, so the recipients of a broadcast do not contend on the Email with an update each.
+	broadcastStatsWindow = time.Second
+	// broadcastStatsTimeout bounds the update of the stats of a batch.
+	broadcastStatsTimeout = 30 * time.Second
+
+	// maxBroadcastSeenEvents bounds the number of events remembered per broadcast Email to keep the annotation
+	// small. They only detect the redeliveries arriving after a restart.
+	maxBroadcastSeenEvents = 1000
+	// maxRecentBroadcastEvents bounds the number of events remembered in memory across broadcasts. A redelivery
+	// arriving after that many newer events, and after maxBroadcastSeenEvents newer events of its broadcast, is
+	// counted again.
+	maxRecentBroadcastEvents = 100000
+)
+
+// broadcastEvent is an event waiting to be counted in the stats of its broadcast Email.
+type broadcastEvent struct {
+	key       string
+	eventType resend.EmailEventType
+	counted   chan error
+}
+
+// broadcastStatsBatcher counts broadcast events in the stats of their Email in batches, with a single update of the
+// Email per batch, and remembers the events counted recently so redeliveries are not counted twice.
+type broadcastStatsBatcher struct {
+	client client.Client
+	window time.Duration
+
+	mu sync.Mutex
+	// pending holds the events of the next batch of each Email. Emails are present while their batches are flushed.
+	pending map[types.NamespacedName][]broadcastEvent
+	// recent holds the keys of the events counted recently, by Email UID and event key, in recentOrder.
+	recent      map[string]struct{}
+	recentOrder []string
+}
+
+func newBroadcastStatsBatcher(k8sClient client.Client, window time.Duration) *broadcastStatsBatcher {
+	return &broadcastStatsBatcher{
+		client:  k8sClient,
+		window:  window,
+		pending: map[types.NamespacedName][]broadcastEvent{},
+		recent:  map[string]struct{}{},
+	}
+}
+
+// count adds the event to the next batch of the Email, and waits until the batch is counted.
+func (b *broadcastStatsBatcher) count(ctx context.Context, email types.NamespacedName, key string, eventType resend.EmailEventType) error {
+	counted := make(chan error, 1)
+
+	b.mu.Lock()
+	events, flushing := b.pending[email]
+	b.pending[email] = append(events, broadcastEvent{key: key, eventType: eventType, counted: counted})
+	b.mu.Unlock()
+	if !flushing {
+		go b.flush(email)
+	}
+
+	select {
+	case err := <-counted:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush counts the batches of the Email, one per window, until no event is left.
+func (b *broadcastStatsBatcher) flush(email types.NamespacedName) {
+	for {
+		time.Sleep(b.window)
+
+		b.mu.Lock()
+		events := b.pending[email]
+		if len(events) == 0 {
+			delete(b.pending, email)
+			b.mu.Unlock()
+			return
+		}
+		b.pending[email] = []broadcastEvent{}
+		b.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), broadcastStatsTimeout)
+		err := b.apply(ctx, email, events)
+		cancel()
+		for _, event := range events {
+			event.counted <- err
+		}
+	}
+}
+
+// apply counts the events not counted yet in the stats of the Email.
+func (b *broadcastStatsBatcher) apply(ctx context.Context, emailKey types.NamespacedName, events []broadcastEvent) error {
+	log := logf.FromContext(ctx).WithName("resend-webhook").WithValues("email", emailKey.Name)
+
+	var uid types.UID
+	var counted []string
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		email := &notificationmiloapiscomv1alpha1.Email{}
+		if err := b.client.Get(ctx, emailKey, email); err != nil {
+			return err
+		}
+		uid, counted = email.UID, nil
+
+		var seen []string
+		if raw := email.Annotations[EmailBroadcastSeenEventsAnnotation]; raw != "" {
+			if err := json.Unmarshal([]byte(raw), &seen); err != nil {
+				log.Error(err, "Failed to decode broadcast seen events annotation. Resetting it.")
+				seen = nil
+			}
+		}
+		stats := map[resend.EmailEventType]int{}
+		if raw := email.Annotations[EmailBroadcastStatsAnnotation]; raw != "" {
+			if err := json.Unmarshal([]byte(raw), &stats); err != nil {
+				// Start over rather than blocking every later event on a corrupted annotation
+				log.Error(err, "Failed to decode broadcast stats annotation. Resetting it.")
+				stats = map[resend.EmailEventType]int{}
+			}
+		}
+
+		known := map[string]struct{}{}
+		for _, key := range seen {
+			known[key] = struct{}{}
+		}
+		b.mu.Lock()
+		for _, event := range events {
+			if _, ok := b.recent[recentBroadcastEventKey(uid, event.key)]; ok {
+				known[event.key] = struct{}{}
+			}
+		}
+		b.mu.Unlock()
+
+		for _, event := range events {
+			if _, ok := known[event.key]; ok {
+				continue
+			}
+			known[event.key] = struct{}{}
+			stats[event.eventType]++
+			seen = append(seen, event.key)
+			counted = append(counted, event.key)
+		}
+		if len(counted) == 0 {
+			log.Info("Broadcast events already counted. Ignoring redeliveries.", "events", len(events))
+			return nil
+		}
+		if len(seen) > maxBroadcastSeenEvents {
+			seen = seen[len(seen)-maxBroadcastSeenEvents:]
+		}
+
+		encodedSeen, err := json.Marshal(seen)
+		if err != nil {
+			return fmt.Errorf("failed to encode broadcast seen events: %w", err)
+		}
+		encoded, err := json.Marshal(stats)
+		if err != nil {
+			return fmt.Errorf("failed to encode broadcast stats: %w", err)
+		}
+
+		// The optimistic lock makes concurrent updates of the stats fail with a conflict, which is retried
+		patch := client.MergeFromWithOptions(email.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		if email.Annotations == nil {
+			email.Annotations = map[string]string{}
+		}
+		email.Annotations[EmailBroadcastStatsAnnotation] = string(encoded)
+		email.Annotations[EmailBroadcastSeenEventsAnnotation] = string(encodedSeen)
+		return b.client.Patch(ctx, email, patch)
+	})
+	if apierrors.IsNotFound(err) {
+		log.Info("Broadcast Email deleted. Dropping its stats.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update broadcast stats: %w", err)
+	}
+
+	b.mu.Lock()
+	for _, key := range counted {
+		b.remember(recentBroadcastEventKey(uid, key))
+	}
+	b.mu.Unlock()
+
+	if len(counted) > 0 {
+		log.Info("Updated broadcast stats from webhook", "events", len(counted))
+	}
+	return nil
+}
+
+// remember adds the event key to the recent events, forgetting the oldest ones beyond maxRecentBroadcastEvents.
+// It must be called with the lock held.
+func (b *broadcastStatsBatcher) remember(key string) {
+	b.recent[key] = struct{}{}
+	b.recentOrder = append(b.recentOrder, key)
+	if len(b.recentOrder) > maxRecentBroadcastEvents {
+		delete(b.recent, b.recentOrder[0])
+		b.recentOrder = b.recentOrder[1:]
+	}
+}
+
+func recentBroadcastEventKey(uid types.UID, key string) string {
+	return fmt.Sprintf("%s/%s", uid, key)
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	contactcontroller "go.miloapis.com/email-provider-resend/internal/controller"
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups,verbs=list;watch
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmemberships,verbs=list;watch;create
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmembershipremovals,verbs=list;watch;create;delete
+
+// buildContactAndContactGroupIndexKey returns "<contact-ns>|<contact-name>|<contactgroup-ns>|<contactgroup-name>"
+func buildContactAndContactGroupIndexKey(contactRef notificationmiloapiscomv1alpha1.ContactReference, contactGroupRef notificationmiloapiscomv1alpha1.ContactGroupReference) string {
+	return fmt.Sprintf("%s|%s|%s|%s", contactRef.Namespace, contactRef.Name, contactGroupRef.Namespace, contactGroupRef.Name)
+}
+
+// syncContactTopicSubscriptions mirrors the topic preferences of the contact on the email provider, as changed on the
+// hosted preferences page, into ContactGroupMemberships and ContactGroupMembershipRemovals of the public contact groups.
+// Opting in to a topic creates the membership, opting out of a topic the contact was opted in to creates a removal.
+func syncContactTopicSubscriptions(ctx context.Context, k8sClient client.Client, emailProvider *emailprovider.Service, contact *notificationmiloapiscomv1alpha1.Contact) error {
+	log := logf.FromContext(ctx).WithName("resend-webhook").WithValues("contact", contact.Name)
+
+	subscriptions := map[string]emailprovider.TopicSubscription{}
+	for subscription, err := range emailProvider.ListContactTopicSubscriptions(ctx, contact.Status.ProviderID) {
+		if err != nil {
+			return fmt.Errorf("failed to list contact topic subscriptions: %w", err)
+		}
+		subscriptions[subscription.TopicID] = subscription.Subscription
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	contactGroups := &notificationmiloapiscomv1alpha1.ContactGroupList{}
+	if err := k8sClient.List(ctx, contactGroups); err != nil {
+		return fmt.Errorf("failed to list ContactGroups: %w", err)
+	}
+
+	contactRef := notificationmiloapiscomv1alpha1.ContactReference{Name: contact.Name, Namespace: contact.Namespace}
+	for _, contactGroup := range contactGroups.Items {
+		if contactGroup.Spec.Visibility != notificationmiloapiscomv1alpha1.ContactGroupVisibilityPublic {
+			continue
+		}
+		subscription, ok := subscriptions[contactGroup.Annotations[contactcontroller.ContactGroupTopicIDAnnotation]]
+		if !ok {
+			continue
+		}
+		contactGroupRef := notificationmiloapiscomv1alpha1.ContactGroupReference{Name: contactGroup.Name, Namespace: contactGroup.Namespace}
+		indexKey := buildContactAndContactGroupIndexKey(contactRef, contactGroupRef)
+
+		memberships := &notificationmiloapiscomv1alpha1.ContactGroupMembershipList{}
+		if err := k8sClient.List(ctx, memberships, client.MatchingFields{contactAndContactGroupIndexKey: indexKey}); err != nil {
+			return fmt.Errorf("failed to list ContactGroupMemberships: %w", err)
+		}
+		// Memberships being deleted opt the contact out themselves, which must not be mistaken for an opt out. Neither
+		// must contacts not opted in yet, as contacts are opted out of topics until their membership opts them in.
+		topicID := contactGroup.Annotations[contactcontroller.ContactGroupTopicIDAnnotation]
+		isMember, optedIn := false, false
+		for _, membership := range memberships.Items {
+			if membership.GetDeletionTimestamp().IsZero() {
+				isMember = true
+				optedIn = optedIn || contactcontroller.IsContactGroupTopicOptedIn(&membership, topicID)
+			}
+		}
+		removals := &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalList{}
+		if err := k8sClient.List(ctx, removals, client.MatchingFields{contactAndContactGroupIndexKey: indexKey}); err != nil {
+			return fmt.Errorf("failed to list ContactGroupMembershipRemovals: %w", err)
+		}
+
+		name := contactcontroller.ContactGroupMembershipName(contactRef, contactGroupRef)
+		switch {
+		case subscription == emailprovider.TopicSubscriptionOptIn && !isMember:
+			// A previous opt out must be withdrawn, otherwise it would remove the membership again
+			for _, removal := range removals.Items {
+				if err := k8sClient.Delete(ctx, &removal); err != nil && !errors.IsNotFound(err) {
+					return fmt.Errorf("failed to delete ContactGroupMembershipRemoval: %w", err)
+				}
+			}
+			log.Info("Contact opted in to ContactGroup topic. Creating ContactGroupMembership.", "contactGroup", contactGroup.Name)
+			if err := k8sClient.Create(ctx, &notificationmiloapiscomv1alpha1.ContactGroupMembership{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: contact.Namespace},
+				Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipSpec{
+					ContactRef:      contactRef,
+					ContactGroupRef: contactGroupRef,
+				},
+			}); err != nil && !errors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to create ContactGroupMembership: %w", err)
+			}
+
+		case subscription == emailprovider.TopicSubscriptionOptOut && optedIn && len(removals.Items) == 0:
+			log.Info("Contact opted out of ContactGroup topic. Creating ContactGroupMembershipRemoval.", "contactGroup", contactGroup.Name)
+			if err := k8sClient.Create(ctx, &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: contact.Namespace},
+				Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalSpec{
+					ContactRef:      contactRef,
+					ContactGroupRef: contactGroupRef,
+				},
+			}); err != nil && !errors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to create ContactGroupMembershipRemoval: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
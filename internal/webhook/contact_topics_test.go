@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	contactcontroller "go.miloapis.com/email-provider-resend/internal/controller"
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
+	"go.miloapis.com/email-provider-resend/internal/emailprovider/mockprovider"
+	"go.miloapis.com/email-provider-resend/internal/resend"
+	notificationv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	crtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func cgmContactAndContactGroupIndex(o crtclient.Object) []string {
+	cgm := o.(*notificationv1alpha1.ContactGroupMembership)
+	return []string{buildContactAndContactGroupIndexKey(cgm.Spec.ContactRef, cgm.Spec.ContactGroupRef)}
+}
+
+func cgmrContactAndContactGroupIndex(o crtclient.Object) []string {
+	cgmr := o.(*notificationv1alpha1.ContactGroupMembershipRemoval)
+	return []string{buildContactAndContactGroupIndexKey(cgmr.Spec.ContactRef, cgmr.Spec.ContactGroupRef)}
+}
+
+// handleTopicEvent sends a contact.updated event for a contact subscribed to the given topics, and returns the client.
+func handleTopicEvent(t *testing.T, subscriptions []emailprovider.ContactTopicSubscription, objects ...crtclient.Object) crtclient.Client {
+	t.Helper()
+	scheme := buildContactScheme(t)
+
+	contact := &notificationv1alpha1.Contact{
+		ObjectMeta: metav1.ObjectMeta{Name: "jane", Namespace: "default"},
+		Status:     notificationv1alpha1.ContactStatus{ProviderID: "contact-123"},
+	}
+	contactGroup := &notificationv1alpha1.ContactGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "newsletter",
+			Namespace:   "default",
+			Annotations: map[string]string{contactcontroller.ContactGroupTopicIDAnnotation: "topic-123"},
+		},
+		Spec: notificationv1alpha1.ContactGroupSpec{DisplayName: "Newsletter", Visibility: notificationv1alpha1.ContactGroupVisibilityPublic},
+	}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).
+		WithStatusSubresource(&notificationv1alpha1.Contact{}).
+		WithIndex(&notificationv1alpha1.Contact{}, contactStatusProviderIDIndexKey, contactProviderIDIndex).
+		WithIndex(&notificationv1alpha1.ContactGroupMembership{}, contactAndContactGroupIndexKey, cgmContactAndContactGroupIndex).
+		WithIndex(&notificationv1alpha1.ContactGroupMembershipRemoval{}, contactAndContactGroupIndexKey, cgmrContactAndContactGroupIndex).
+		WithObjects(append(objects, contact, contactGroup)...).Build()
+
+	provider := &mockprovider.MockEmailProvider{ListContactTopicsOutput: subscriptions}
+	wh := NewResendContactWebhookV1(k8sClient, emailprovider.NewService(provider, "", ""))
+
+	evt := resend.ParsedContactEvent{
+		Envelope: resend.ContactEventEnvelope{Type: resend.ContactUpdated},
+		Contact:  resend.ContactBase{ID: "contact-123"},
+	}
+	resp := wh.Handler.Handle(context.TODO(), Request{ContactEvent: &evt})
+	if resp.HttpStatus != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, resp.HttpStatus)
+	}
+
+	return k8sClient
+}
+
+var (
+	janeRef       = notificationv1alpha1.ContactReference{Name: "jane", Namespace: "default"}
+	newsletterRef = notificationv1alpha1.ContactGroupReference{Name: "newsletter", Namespace: "default"}
+)
+
+// optedInMembership returns a ready membership of jane in the newsletter, opted in to its topic.
+func optedInMembership() *notificationv1alpha1.ContactGroupMembership {
+	return &notificationv1alpha1.ContactGroupMembership{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "membership",
+			Namespace:   "default",
+			Annotations: map[string]string{contactcontroller.ContactGroupMembershipTopicOptInAnnotation: "topic-123"},
+		},
+		Spec: notificationv1alpha1.ContactGroupMembershipSpec{ContactRef: janeRef, ContactGroupRef: newsletterRef},
+		Status: notificationv1alpha1.ContactGroupMembershipStatus{
+			Conditions: []metav1.Condition{{
+				Type:   contactcontroller.ResendContactGroupMembershipReadyCondition,
+				Status: metav1.ConditionTrue,
+				Reason: notificationv1alpha1.ContactGroupMembershipCreatedReason,
+			}},
+		},
+	}
+}
+
+func TestContactTopics_OptOutCreatesRemoval(t *testing.T) {
+	k8sClient := handleTopicEvent(t, []emailprovider.ContactTopicSubscription{
+		{TopicID: "topic-123", Subscription: emailprovider.TopicSubscriptionOptOut},
+	}, optedInMembership())
+
+	removals := &notificationv1alpha1.ContactGroupMembershipRemovalList{}
+	if err := k8sClient.List(context.TODO(), removals); err != nil {
+		t.Fatalf("failed to list removals: %v", err)
+	}
+	if len(removals.Items) != 1 {
+		t.Fatalf("expected 1 removal, got %d", len(removals.Items))
+	}
+	if removals.Items[0].Spec.ContactRef != janeRef || removals.Items[0].Spec.ContactGroupRef != newsletterRef {
+		t.Fatalf("unexpected removal spec: %+v", removals.Items[0].Spec)
+	}
+}
+
+func TestContactTopics_OptOutIgnoresMembershipsNotOptedIn(t *testing.T) {
+	notReady := optedInMembership()
+	notReady.Status.Conditions[0].Status = metav1.ConditionFalse
+
+	notOptedIn := optedInMembership()
+	notOptedIn.Annotations = nil
+
+	updateRequested := optedInMembership()
+	updateRequested.Status.Conditions = append(updateRequested.Status.Conditions, metav1.Condition{
+		Type:   notificationv1alpha1.ContactGroupMembershipUpdatedCondition,
+		Status: metav1.ConditionFalse,
+		Reason: notificationv1alpha1.ContactGroupMembershipUpdateRequestedReason,
+	})
+
+	for name, membership := range map[string]*notificationv1alpha1.ContactGroupMembership{
+		"not ready":        notReady,
+		"not opted in":     notOptedIn,
+		"update requested": updateRequested,
+	} {
+		t.Run(name, func(t *testing.T) {
+			k8sClient := handleTopicEvent(t, []emailprovider.ContactTopicSubscription{
+				{TopicID: "topic-123", Subscription: emailprovider.TopicSubscriptionOptOut},
+			}, membership)
+
+			removals := &notificationv1alpha1.ContactGroupMembershipRemovalList{}
+			if err := k8sClient.List(context.TODO(), removals); err != nil {
+				t.Fatalf("failed to list removals: %v", err)
+			}
+			if len(removals.Items) != 0 {
+				t.Fatalf("expected no removal, got %d", len(removals.Items))
+			}
+		})
+	}
+}
+
+func TestContactTopics_OptInCreatesMembership(t *testing.T) {
+	removal := &notificationv1alpha1.ContactGroupMembershipRemoval{
+		ObjectMeta: metav1.ObjectMeta{Name: "removal", Namespace: "default"},
+		Spec:       notificationv1alpha1.ContactGroupMembershipRemovalSpec{ContactRef: janeRef, ContactGroupRef: newsletterRef},
+	}
+
+	k8sClient := handleTopicEvent(t, []emailprovider.ContactTopicSubscription{
+		{TopicID: "topic-123", Subscription: emailprovider.TopicSubscriptionOptIn},
+		{TopicID: "unmanaged-topic", Subscription: emailprovider.TopicSubscriptionOptIn},
+	}, removal)
+
+	memberships := &notificationv1alpha1.ContactGroupMembershipList{}
+	if err := k8sClient.List(context.TODO(), memberships); err != nil {
+		t.Fatalf("failed to list memberships: %v", err)
+	}
+	if len(memberships.Items) != 1 {
+		t.Fatalf("expected 1 membership, got %d", len(memberships.Items))
+	}
+	if memberships.Items[0].Spec.ContactRef != janeRef || memberships.Items[0].Spec.ContactGroupRef != newsletterRef {
+		t.Fatalf("unexpected membership spec: %+v", memberships.Items[0].Spec)
+	}
+
+	// The previous opt out is withdrawn
+	removals := &notificationv1alpha1.ContactGroupMembershipRemovalList{}
+	if err := k8sClient.List(context.TODO(), removals); err != nil {
+		t.Fatalf("failed to list removals: %v", err)
+	}
+	if len(removals.Items) != 0 {
+		t.Fatalf("expected the removal to be deleted, got %d", len(removals.Items))
+	}
+}
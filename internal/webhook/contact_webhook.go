@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"go.miloapis.com/email-provider-resend/internal/emailprovider"
 	"go.miloapis.com/email-provider-resend/internal/resend"
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
@@ -20,7 +21,9 @@ import (
 
 // +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create
 
-func NewResendContactWebhookV1(k8sClient client.Client) *Webhook {
+// NewResendContactWebhookV1 returns the webhook handling Resend contact events. If emailProvider is not nil, topic
+// preference changes are synced into ContactGroupMemberships and ContactGroupMembershipRemovals.
+func NewResendContactWebhookV1(k8sClient client.Client, emailProvider *emailprovider.Service) *Webhook {
 	return &Webhook{
 		Handler: HandlerFunc(func(ctx context.Context, req Request) Response {
 			contactEvent := req.ContactEvent
@@ -144,6 +147,14 @@ func NewResendContactWebhookV1(k8sClient client.Client) *Webhook {
 
 			log.Info("Updated Contact status from webhook", "contact", contact.Name, "condition", condition)
 
+			// Topic preferences are changed on the email provider hosted preferences page, which updates the contact
+			if contactEvent.Envelope.Type == resend.ContactUpdated && emailProvider != nil {
+				if err := syncContactTopicSubscriptions(ctx, k8sClient, emailProvider, contact); err != nil {
+					log.Error(err, "Failed to sync contact topic subscriptions", "contact", contact.Name)
+					return InternalServerErrorResponse()
+				}
+			}
+
 			return OkResponse()
 		}),
 		Endpoint: "/apis/emailnotification.k8s.io/v1/resend/contactgroupmemberships",
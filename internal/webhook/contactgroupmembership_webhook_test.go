@@ -41,7 +41,7 @@ func buildContactScheme(t *testing.T) *runtime.Scheme {
 }
 
 func TestNewResendContactWebhookV1_Endpoint(t *testing.T) {
-	wh := NewResendContactWebhookV1(nil)
+	wh := NewResendContactWebhookV1(nil, nil)
 	expected := "/apis/emailnotification.k8s.io/v1/resend/contactgroupmemberships"
 	if wh.Endpoint != expected {
 		t.Fatalf("unexpected endpoint: got %s want %s", wh.Endpoint, expected)
@@ -54,7 +54,7 @@ func TestNewResendContactWebhookV1_CGMNotFound(t *testing.T) {
 		WithIndex(&notificationv1alpha1.Contact{}, contactStatusProviderIDIndexKey, contactProviderIDIndex).
 		Build()
 
-	wh := NewResendContactWebhookV1(k8sClient)
+	wh := NewResendContactWebhookV1(k8sClient, nil)
 
 	evt := resend.ParsedContactEvent{
 		Envelope: resend.ContactEventEnvelope{Type: resend.ContactCreated},
@@ -86,7 +86,7 @@ func TestNewResendContactWebhookV1_SuccessPath(t *testing.T) {
 		WithIndex(&notificationv1alpha1.Contact{}, contactStatusProviderIDIndexKey, contactProviderIDIndex).
 		WithObjects(contact).Build()
 
-	wh := NewResendContactWebhookV1(k8sClient)
+	wh := NewResendContactWebhookV1(k8sClient, nil)
 
 	evt := resend.ParsedContactEvent{
 		Envelope: resend.ContactEventEnvelope{Type: resend.ContactCreated},
@@ -2,6 +2,8 @@ package webhook
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -16,15 +18,30 @@ import (
 	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
 )
 
+const (
+	// EmailBroadcastStatsAnnotation holds the JSON encoded count of webhook events received for the recipients of a
+	// broadcast Email, keyed by event type.
+	EmailBroadcastStatsAnnotation = "notification.miloapis.com/broadcast-stats"
+	// EmailBroadcastSeenEventsAnnotation holds the JSON encoded list of the most recent webhook events counted in
+	// the broadcast stats, so that events redelivered by Resend after a restart are not counted twice.
+	EmailBroadcastSeenEventsAnnotation = "notification.miloapis.com/broadcast-seen-events"
+)
+
 // +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=emails,verbs=get;list;watch;patch
 
 func NewResendEmailWebhookV1(k8sClient client.Client) *Webhook {
+	stats := newBroadcastStatsBatcher(k8sClient, broadcastStatsWindow)
 	return &Webhook{
 		Handler: HandlerFunc(func(ctx context.Context, req Request) Response {
 			emailEvent := req.EmailEvent
 			log := logf.FromContext(ctx).WithName("resend-webhook")
 			log.Info("Received event", "event", emailEvent.Envelope.Type)
 
+			if emailEvent.Base.BroadcastID != "" {
+				return handleBroadcastEmailEvent(ctx, k8sClient, stats, req.EventID, emailEvent)
+			}
+
 			// Getting Email CR by ProviderID using the indexed field
 			emails := &notificationmiloapiscomv1alpha1.EmailList{}
 			if err := k8sClient.List(ctx, emails, client.MatchingFields{"status.providerID": emailEvent.Base.EmailID}); err != nil {
@@ -87,3 +104,46 @@ func NewResendEmailWebhookV1(k8sClient client.Client) *Webhook {
 		Endpoint: "/apis/emailnotification.k8s.io/v1/resend/emails",
 	}
 }
+
+// handleBroadcastEmailEvent counts an event for a single recipient of a broadcast in the aggregate stats of the
+// Email that created the broadcast. Per recipient events do not change the Email Delivered condition, which is
+// owned by the EmailController, nor emit Kubernetes Events, as a broadcast may have thousands of recipients.
+// The event is acknowledged once counted, so events not counted are retried by Resend.
+func handleBroadcastEmailEvent(ctx context.Context,
+	k8sClient client.Client,
+	stats *broadcastStatsBatcher,
+	eventID string,
+	emailEvent *resend.ParsedEvent,
+) Response {
+	log := logf.FromContext(ctx).WithName("resend-webhook").WithValues("broadcastID", emailEvent.Base.BroadcastID)
+
+	emails := &notificationmiloapiscomv1alpha1.EmailList{}
+	if err := k8sClient.List(ctx, emails, client.MatchingFields{"status.providerID": emailEvent.Base.BroadcastID}); err != nil {
+		log.Error(err, "Failed to list emails by providerID")
+		return InternalServerErrorResponse()
+	}
+	if len(emails.Items) == 0 {
+		log.Info("No email found with broadcast providerID")
+		return NotFoundResponse()
+	}
+	email := &emails.Items[0]
+
+	if err := stats.count(ctx, client.ObjectKeyFromObject(email), broadcastEventKey(eventID, emailEvent), emailEvent.Envelope.Type); err != nil {
+		log.Error(err, "Failed to update broadcast stats", "email", email.Name)
+		return InternalServerErrorResponse()
+	}
+
+	return OkResponse()
+}
+
+// broadcastEventKey returns a short key identifying a broadcast event across redeliveries. It is derived from the
+// svix message id when available, and otherwise from the recipient email, the event type and its timestamp.
+func broadcastEventKey(eventID string, emailEvent *resend.ParsedEvent) string {
+	identity := eventID
+	if identity == "" {
+		identity = fmt.Sprintf("%s/%s/%s", emailEvent.Base.EmailID, emailEvent.Envelope.Type,
+			emailEvent.Envelope.CreatedAt.Format(time.RFC3339Nano))
+	}
+	sum := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(sum[:])[:16]
+}
@@ -22,10 +22,15 @@ type Webhook struct {
 }
 
 type Request struct {
+	// EventID is the svix message id of the event, which stays the same when Resend redelivers it.
+	EventID      string
 	EmailEvent   *resend.ParsedEvent
 	ContactEvent *resend.ParsedContactEvent
 }
 
+// svixIDHeader is the header carrying the svix message id of a webhook delivery.
+const svixIDHeader = "svix-id"
+
 type Response struct {
 	HttpStatus int `json:"HttpStatus"`
 }
@@ -47,6 +52,7 @@ func (w *Webhook) SetupSvix(svixClient *svix.Webhook) {
 
 const (
 	contactStatusProviderIDIndexKey = "contact-status-providerID"
+	contactAndContactGroupIndexKey  = "contact-and-contactgroup-tuple-index"
 )
 
 // SetupIndexes sets up the required field indexes for webhook operations
@@ -84,6 +90,31 @@ func SetupIndexes(mgr ctrl.Manager) error {
 		return fmt.Errorf("failed to createcontact group membership  index for providerID: %w", err)
 	}
 
+	// Index ContactGroupMembership and ContactGroupMembershipRemoval objects by contact and contact group so that the
+	// contact webhook handler can sync topic subscriptions.
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&notificationmiloapiscomv1alpha1.ContactGroupMembership{},
+		contactAndContactGroupIndexKey,
+		func(rawObj client.Object) []string {
+			cgm := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembership)
+			return []string{buildContactAndContactGroupIndexKey(cgm.Spec.ContactRef, cgm.Spec.ContactGroupRef)}
+		},
+	); err != nil {
+		return fmt.Errorf("failed to create contact group membership index for contact and contact group: %w", err)
+	}
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{},
+		contactAndContactGroupIndexKey,
+		func(rawObj client.Object) []string {
+			cgmr := rawObj.(*notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval)
+			return []string{buildContactAndContactGroupIndexKey(cgmr.Spec.ContactRef, cgmr.Spec.ContactGroupRef)}
+		},
+	); err != nil {
+		return fmt.Errorf("failed to create contact group membership removal index for contact and contact group: %w", err)
+	}
+
 	return nil
 }
 
@@ -137,6 +168,7 @@ func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	emailEvent, emailErr := resend.ParseEmailEvent(body)
 	if emailErr == nil {
 		response := wh.Handler.Handle(r.Context(), Request{
+			EventID:    r.Header.Get(svixIDHeader),
 			EmailEvent: emailEvent,
 		})
 		wh.writeResponse(w, response)
@@ -147,6 +179,7 @@ func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	contactEvent, contactErr := resend.ParseContactEvent(body)
 	if contactErr == nil {
 		response := wh.Handler.Handle(r.Context(), Request{
+			EventID:      r.Header.Get(svixIDHeader),
 			ContactEvent: contactEvent,
 		})
 		wh.writeResponse(w, response)
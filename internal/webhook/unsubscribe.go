@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	contactcontroller "go.miloapis.com/email-provider-resend/internal/controller"
+	"go.miloapis.com/email-provider-resend/internal/unsubscribe"
+	notificationmiloapiscomv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroups,verbs=get
+// +kubebuilder:rbac:groups=notification.miloapis.com,resources=contactgroupmembershipremovals,verbs=create
+
+// UnsubscribeEndpoint is the path of the unsubscribe endpoint. The manager must be configured with its public URL.
+const UnsubscribeEndpoint = "/apis/emailnotification.k8s.io/v1/unsubscribe"
+
+// unsubscribePage is served to recipients opening the unsubscribe link. The removal is only created once the form is
+// submitted, so link scanners following the link do not unsubscribe the recipient.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body>
+{{- if .Unsubscribed }}
+<p>You have been unsubscribed from {{ .ContactGroup }}.</p>
+{{- else }}
+<form method="post">
+<p>Do you want to unsubscribe from {{ .ContactGroup }}?</p>
+<button type="submit" name="List-Unsubscribe" value="One-Click">Unsubscribe</button>
+</form>
+{{- end }}
+</body>
+</html>
+`))
+
+// UnsubscribeHandler serves the unsubscribe links added to the emails sent to the members of a ContactGroup.
+// It implements RFC 8058 one-click unsubscription: a POST with a valid token creates a ContactGroupMembershipRemoval.
+type UnsubscribeHandler struct {
+	Endpoint string
+	client   client.Client
+	signer   *unsubscribe.Signer
+}
+
+// NewUnsubscribeHandler returns the handler of the unsubscribe endpoint. Tokens are verified with the signer.
+func NewUnsubscribeHandler(k8sClient client.Client, signer *unsubscribe.Signer) *UnsubscribeHandler {
+	return &UnsubscribeHandler{
+		Endpoint: UnsubscribeEndpoint,
+		client:   k8sClient,
+		signer:   signer,
+	}
+}
+
+// SetupWithManager sets up the unsubscribe endpoint with the Manager
+func (h *UnsubscribeHandler) SetupWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(h.Endpoint, h)
+
+	return nil
+}
+
+func (h *UnsubscribeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log := logf.FromContext(r.Context()).WithName("resend-unsubscribe")
+	log.Info("Handling request", "method", r.Method, "remoteAddr", r.RemoteAddr)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		log.Error(nil, "Method not allowed", "method", r.Method)
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token, err := h.signer.Verify(r.URL.Query().Get(unsubscribe.TokenQueryParameter))
+	if err != nil {
+		log.Error(err, "Failed to verify unsubscribe token")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	log = log.WithValues("contact", token.ContactRef, "contactGroup", token.ContactGroupRef)
+
+	if r.Method == http.MethodPost {
+		if err := h.createContactGroupMembershipRemoval(r, token); err != nil {
+			log.Error(err, "Failed to unsubscribe contact")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		log.Info("Contact unsubscribed from ContactGroup")
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := unsubscribePage.Execute(w, map[string]any{
+		"Unsubscribed": r.Method == http.MethodPost,
+		"ContactGroup": h.contactGroupDisplayName(r, token),
+	}); err != nil {
+		log.Error(err, "Failed to write unsubscribe page")
+	}
+}
+
+// createContactGroupMembershipRemoval removes the contact of the token from its contact group.
+// This is an idempotent operation.
+func (h *UnsubscribeHandler) createContactGroupMembershipRemoval(r *http.Request, token unsubscribe.Token) error {
+	if err := h.client.Create(r.Context(), &notificationmiloapiscomv1alpha1.ContactGroupMembershipRemoval{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      contactcontroller.ContactGroupMembershipName(token.ContactRef, token.ContactGroupRef),
+			Namespace: token.ContactRef.Namespace,
+		},
+		Spec: notificationmiloapiscomv1alpha1.ContactGroupMembershipRemovalSpec{
+			ContactRef:      token.ContactRef,
+			ContactGroupRef: token.ContactGroupRef,
+		},
+	}); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create ContactGroupMembershipRemoval: %w", err)
+	}
+
+	return nil
+}
+
+// contactGroupDisplayName returns the display name of the contact group of the token, falling back to its name.
+func (h *UnsubscribeHandler) contactGroupDisplayName(r *http.Request, token unsubscribe.Token) string {
+	contactGroup := &notificationmiloapiscomv1alpha1.ContactGroup{}
+	if err := h.client.Get(r.Context(), client.ObjectKey{Namespace: token.ContactGroupRef.Namespace, Name: token.ContactGroupRef.Name}, contactGroup); err != nil ||
+		contactGroup.Spec.DisplayName == "" {
+		return token.ContactGroupRef.Name
+	}
+
+	return contactGroup.Spec.DisplayName
+}
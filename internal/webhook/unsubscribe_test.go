@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"go.miloapis.com/email-provider-resend/internal/unsubscribe"
+	notificationv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
+	crtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestUnsubscribeHandler(t *testing.T) {
+	scheme := buildContactScheme(t)
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	signer := unsubscribe.NewSigner("secret")
+	unsubscribeURL, err := signer.URL("https://hooks.example.com"+UnsubscribeEndpoint, unsubscribe.Token{
+		ContactRef:      notificationv1alpha1.ContactReference{Name: "contact", Namespace: "default"},
+		ContactGroupRef: notificationv1alpha1.ContactGroupReference{Name: "newsletter", Namespace: "default"},
+	})
+	if err != nil {
+		t.Fatalf("failed to build unsubscribe URL: %v", err)
+	}
+	u, err := url.Parse(unsubscribeURL)
+	if err != nil {
+		t.Fatalf("failed to parse unsubscribe URL: %v", err)
+	}
+
+	handler := NewUnsubscribeHandler(k8sClient, signer)
+	serve := func(method, target string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader("List-Unsubscribe=One-Click"))
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	removals := func() []notificationv1alpha1.ContactGroupMembershipRemoval {
+		list := &notificationv1alpha1.ContactGroupMembershipRemovalList{}
+		if err := k8sClient.List(context.TODO(), list, crtclient.InNamespace("default")); err != nil {
+			t.Fatalf("failed to list removals: %v", err)
+		}
+		return list.Items
+	}
+
+	if code := serve(http.MethodPost, UnsubscribeEndpoint+"?token=forged"); code != http.StatusBadRequest {
+		t.Fatalf("expected %d for an invalid token, got %d", http.StatusBadRequest, code)
+	}
+
+	// Opening the link only shows the confirmation page
+	if code := serve(http.MethodGet, u.RequestURI()); code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, code)
+	}
+	if len(removals()) != 0 {
+		t.Fatalf("expected no removal to be created on GET")
+	}
+
+	// One-click POSTs are idempotent
+	for range 2 {
+		if code := serve(http.MethodPost, u.RequestURI()); code != http.StatusOK {
+			t.Fatalf("expected %d got %d", http.StatusOK, code)
+		}
+	}
+	items := removals()
+	if len(items) != 1 {
+		t.Fatalf("expected 1 removal, got %d", len(items))
+	}
+	if items[0].Spec.ContactRef.Name != "contact" || items[0].Spec.ContactGroupRef.Name != "newsletter" {
+		t.Fatalf("unexpected removal spec: %+v", items[0].Spec)
+	}
+}
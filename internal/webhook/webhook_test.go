@@ -2,7 +2,10 @@ package webhook
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
@@ -10,6 +13,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 
 	"go.miloapis.com/email-provider-resend/internal/resend"
 	notificationv1alpha1 "go.miloapis.com/milo/pkg/apis/notification/v1alpha1"
@@ -124,3 +128,99 @@ func TestNewResendWebhookV1_SuccessPath(t *testing.T) {
 		t.Fatalf("expected at least 1 event recorded, got 0")
 	}
 }
+
+func TestNewResendWebhookV1_BroadcastEventUpdatesStats(t *testing.T) {
+	scheme := buildScheme(t)
+
+	email := &notificationv1alpha1.Email{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "broadcast-email",
+			Namespace: "default",
+		},
+		Status: notificationv1alpha1.EmailStatus{
+			ProviderID: "broadcast-xyz",
+			Conditions: []metav1.Condition{{
+				Type:   notificationv1alpha1.EmailDeliveredCondition,
+				Status: metav1.ConditionTrue,
+				Reason: notificationv1alpha1.EmailDeliveredReason,
+			}},
+		},
+	}
+
+	var patches atomic.Int32
+	k8sClient := interceptor.NewClient(fake.NewClientBuilder().WithScheme(scheme).
+		WithStatusSubresource(&notificationv1alpha1.Email{}).
+		WithIndex(&notificationv1alpha1.Email{}, "status.providerID", providerIDIndex).
+		WithObjects(email).Build(), interceptor.Funcs{
+		Patch: func(ctx context.Context, c crtclient.WithWatch, obj crtclient.Object, patch crtclient.Patch, opts ...crtclient.PatchOption) error {
+			patches.Add(1)
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	})
+
+	wh := NewResendEmailWebhookV1(k8sClient)
+
+	deliver := func(eventID string, eventType resend.EmailEventType) {
+		evt := resend.ParsedEvent{
+			Envelope: resend.EventEnvelope{Type: eventType},
+			Base:     resend.EmailBase{EmailID: "recipient-email", BroadcastID: "broadcast-xyz"},
+		}
+		resp := wh.Handler.Handle(context.TODO(), Request{EventID: eventID, EmailEvent: &evt})
+		if resp.HttpStatus != http.StatusOK {
+			t.Errorf("expected %d got %d", http.StatusOK, resp.HttpStatus)
+		}
+	}
+
+	// Concurrent events are counted in a single update, and msg-2, redelivered by Resend, is only counted once
+	deliveries := []struct {
+		eventID   string
+		eventType resend.EmailEventType
+	}{
+		{"msg-1", resend.EventTypeDelivered},
+		{"msg-2", resend.EventTypeDelivered},
+		{"msg-2", resend.EventTypeDelivered},
+		{"msg-3", resend.EventTypeBounced},
+	}
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			deliver(delivery.eventID, delivery.eventType)
+		}()
+	}
+	wg.Wait()
+	if got := patches.Load(); got != 1 {
+		t.Fatalf("expected the events to be counted in 1 update, got %d", got)
+	}
+
+	// Later redeliveries are not counted again
+	deliver("msg-1", resend.EventTypeDelivered)
+	if got := patches.Load(); got != 1 {
+		t.Fatalf("expected the redelivery not to update the stats, got %d updates", got)
+	}
+
+	updated := &notificationv1alpha1.Email{}
+	if err := k8sClient.Get(context.TODO(), crtclient.ObjectKey{Namespace: "default", Name: "broadcast-email"}, updated); err != nil {
+		t.Fatalf("failed to fetch updated email: %v", err)
+	}
+	stats := map[resend.EmailEventType]int{}
+	if err := json.Unmarshal([]byte(updated.Annotations[EmailBroadcastStatsAnnotation]), &stats); err != nil {
+		t.Fatalf("failed to decode broadcast stats: %v", err)
+	}
+	if stats[resend.EventTypeDelivered] != 2 || stats[resend.EventTypeBounced] != 1 {
+		t.Fatalf("unexpected broadcast stats: %+v", stats)
+	}
+
+	// Per recipient events must not change the Email delivered condition nor emit Kubernetes Events
+	if updated.Status.Conditions[0].Status != metav1.ConditionTrue {
+		t.Fatalf("delivered condition changed by broadcast event: %+v", updated.Status.Conditions)
+	}
+	evList := &eventsv1.EventList{}
+	if err := k8sClient.List(context.TODO(), evList); err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	if len(evList.Items) != 0 {
+		t.Fatalf("expected no events recorded, got %d", len(evList.Items))
+	}
+}